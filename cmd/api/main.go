@@ -1,15 +1,31 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"maps"
+	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/mordmora/expirapp/internal/config"
 	"github.com/mordmora/expirapp/internal/platform/database"
+	"github.com/mordmora/expirapp/internal/platform/logger"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
 	"github.com/mordmora/expirapp/internal/server"
 )
 
 func main() {
 
+	appCfg := config.Load()
+	logger.Setup(appCfg.Log)
+
+	shutdownTracing, err := tracing.Setup(appCfg.Tracing)
+	if err != nil {
+		slog.Error("failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+
 	db := database.New(database.Config{
 		Host:     "localhost",
 		Port:     5432,
@@ -18,19 +34,45 @@ func main() {
 		DBName:   "expirapp",
 		SSLMode:  "disable",
 		TimeZone: "America/Bogota",
+
+		SlowQueryThreshold: appCfg.SlowQueryThreshold,
 	})
 
 	migrationsPath := filepath.Join(".", "migrations")
 	if err := database.RunMigrations(db, migrationsPath); err != nil {
-		log.Fatalf("failed to run migrations: %v", err)
+		slog.Error("failed to run migrations", "error", err)
+		os.Exit(1)
 	}
 
 	servCfg := server.DefConfig()
+	servCfg.JWTSecret = appCfg.JWTSecret
+	servCfg.TokenTTL = appCfg.TokenTTL
+	servCfg.AppBaseURL = appCfg.AppBaseURL
+	servCfg.RequireVerifiedEmail = appCfg.RequireVerifiedEmail
+	servCfg.Mail = appCfg.Mail
+	servCfg.TwoFactorRequiredRoles = appCfg.TwoFactorRequiredRoles
+	servCfg.OIDC = appCfg.OIDC
+	servCfg.SSORoleMap = appCfg.SSORoleMap
+	servCfg.SSODefaultRoles = appCfg.SSODefaultRoles
+	servCfg.DefaultLanguage = appCfg.DefaultLanguage
+	servCfg.AdminPort = appCfg.AdminPort
+	servCfg.RateLimitStore = appCfg.RateLimitStore
+	maps.Copy(servCfg.RateLimits, appCfg.RateLimits)
+	servCfg.IdempotencyTTL = appCfg.IdempotencyTTL
+	servCfg.TrustedProxies = appCfg.TrustedProxies
 
 	srv := server.New(db, servCfg)
 
 	if err := srv.Start(); err != nil {
-		log.Fatalf("failed to start server: %v", err)
+		slog.Error("failed to start server", "error", err)
+		os.Exit(1)
+	}
+
+	// vacía los spans que queden en el buffer antes de salir
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Warn("could not flush traces", "error", err)
 	}
 
 }
@@ -1 +1,268 @@
 package config
+
+/*
+Este archivo carga la configuración de la aplicación desde variables de entorno
+*/
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/platform/logger"
+	"github.com/mordmora/expirapp/internal/platform/mail"
+	"github.com/mordmora/expirapp/internal/platform/oidc"
+	"github.com/mordmora/expirapp/internal/platform/ratelimit"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+)
+
+/*
+# Config agrupa los valores que dependen del entorno
+* JWTSecret: llave HMAC para firmar los tokens de acceso (JWT_SECRET)
+* TokenTTL: vigencia de un token de acceso (JWT_TTL, p. ej. "24h")
+* AppBaseURL: URL pública usada en los enlaces de los correos (APP_BASE_URL)
+* RequireVerifiedEmail: bloquea el login sin correo verificado (REQUIRE_EMAIL_VERIFICATION)
+* Mail: servidor SMTP (SMTP_HOST, SMTP_PORT, SMTP_USER, SMTP_PASSWORD, MAIL_FROM)
+* TwoFactorRequiredRoles: roles con 2FA obligatorio (TWO_FACTOR_REQUIRED_ROLES, separados por coma)
+* OIDC: IdP corporativo (OIDC_ISSUER, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET, OIDC_REDIRECT_URL, OIDC_SCOPES, OIDC_ROLES_CLAIM)
+* SSORoleMap: grupos del IdP a roles locales (OIDC_ROLE_MAP, p. ej. "ti-admins=administrador,ventas=vendedor")
+* SSODefaultRoles: roles de un usuario aprovisionado sin grupos mapeados (OIDC_DEFAULT_ROLES)
+* Log: nivel y formato de los registros (LOG_LEVEL, LOG_FORMAT)
+* SlowQueryThreshold: desde cuánto una consulta SQL se registra como lenta (DB_SLOW_QUERY_THRESHOLD)
+* DefaultLanguage: idioma de las respuestas sin Accept-Language soportado (DEFAULT_LANGUAGE, "es" o "en")
+* AdminPort: listener de administración con /metrics (ADMIN_PORT, p. ej. ":9090"; "off" lo apaga)
+* Tracing: trazas de OpenTelemetry (TRACE_EXPORTER "none", "stdout" o "file"; TRACE_FILE; TRACE_SAMPLE_RATIO)
+* RateLimitStore: almacén de los límites de solicitudes (RATE_LIMIT_STORE "memory", "postgres" u "off")
+* RateLimits: políticas que reemplazan las por defecto (RATE_LIMIT_AUTH, RATE_LIMIT_REGISTER,
+* RATE_LIMIT_REVIEWS, RATE_LIMIT_PAYMENTS, p. ej. "ip=10/m,user=5/m")
+* IdempotencyTTL: vigencia de una Idempotency-Key (IDEMPOTENCY_TTL, p. ej. "24h")
+* TrustedProxies: proxies cuyo X-Forwarded-For se cree (TRUSTED_PROXIES, IP o CIDR
+* separados por coma); sin ellos la IP del cliente es la de la conexión
+*/
+type Config struct {
+	JWTSecret string
+	TokenTTL  time.Duration
+
+	AppBaseURL           string
+	RequireVerifiedEmail bool
+	Mail                 mail.Config
+
+	TwoFactorRequiredRoles []string
+
+	OIDC            oidc.Config
+	SSORoleMap      map[string]string
+	SSODefaultRoles []string
+
+	Log                logger.Config
+	SlowQueryThreshold time.Duration
+
+	DefaultLanguage string
+
+	AdminPort string
+
+	Tracing tracing.Config
+
+	RateLimitStore string
+	RateLimits     map[string]ratelimit.Policy
+
+	IdempotencyTTL time.Duration
+
+	TrustedProxies []string
+}
+
+func Load() Config {
+	cfg := Config{
+		JWTSecret: os.Getenv("JWT_SECRET"),
+		TokenTTL:  getDuration("JWT_TTL", 24*time.Hour),
+
+		AppBaseURL:           getEnv("APP_BASE_URL", "http://localhost:8080"),
+		RequireVerifiedEmail: getBool("REQUIRE_EMAIL_VERIFICATION", false),
+		Mail: mail.Config{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     getInt("SMTP_PORT", 587),
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     getEnv("MAIL_FROM", "no-reply@expirapp.local"),
+		},
+
+		TwoFactorRequiredRoles: getList("TWO_FACTOR_REQUIRED_ROLES", []string{"administrador"}),
+
+		OIDC: oidc.Config{
+			IssuerURL:    os.Getenv("OIDC_ISSUER"),
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+			Scopes:       getList("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+			RolesClaim:   getEnv("OIDC_ROLES_CLAIM", "groups"),
+		},
+		SSORoleMap:      getMap("OIDC_ROLE_MAP"),
+		SSODefaultRoles: getList("OIDC_DEFAULT_ROLES", nil),
+
+		Log: logger.Config{
+			Level:  getEnv("LOG_LEVEL", "info"),
+			Format: getEnv("LOG_FORMAT", "json"),
+		},
+		SlowQueryThreshold: getDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond),
+
+		DefaultLanguage: getEnv("DEFAULT_LANGUAGE", "es"),
+
+		AdminPort: getEnv("ADMIN_PORT", ":9090"),
+
+		Tracing: tracing.Config{
+			Exporter:    getEnv("TRACE_EXPORTER", "none"),
+			File:        getEnv("TRACE_FILE", "traces.jsonl"),
+			SampleRatio: getFloat("TRACE_SAMPLE_RATIO", 1),
+			ServiceName: "expirapp",
+		},
+
+		RateLimitStore: getEnv("RATE_LIMIT_STORE", "memory"),
+		RateLimits:     getPolicies("RATE_LIMIT_", "auth", "register", "reviews", "payments"),
+
+		IdempotencyTTL: getDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+
+		TrustedProxies: getList("TRUSTED_PROXIES", nil),
+	}
+
+	if cfg.AdminPort == "off" {
+		cfg.AdminPort = ""
+	}
+	if cfg.RateLimitStore == "off" {
+		cfg.RateLimitStore = ""
+	}
+
+	if cfg.JWTSecret == "" {
+		/*
+			# sin secreto configurado se usa uno aleatorio;
+			# los tokens dejan de servir al reiniciar el proceso
+		*/
+		slog.Warn("JWT_SECRET not set, using a random secret")
+		cfg.JWTSecret = randomSecret()
+	}
+
+	return cfg
+}
+
+func getEnv(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+func getInt(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("invalid env value, using default", "key", key, "value", value, "default", def)
+		return def
+	}
+	return n
+}
+
+func getFloat(key string, def float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Warn("invalid env value, using default", "key", key, "value", value, "default", def)
+		return def
+	}
+	return f
+}
+
+func getBool(key string, def bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("invalid env value, using default", "key", key, "value", value, "default", def)
+		return def
+	}
+	return b
+}
+
+func getList(key string, def []string) []string {
+	value, set := os.LookupEnv(key)
+	if !set {
+		return def
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getMap lee pares "clave=valor" separados por coma
+func getMap(key string) map[string]string {
+	m := make(map[string]string)
+	for _, pair := range getList(key, nil) {
+		k, v, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(k) == "" || strings.TrimSpace(v) == "" {
+			slog.Warn("invalid env entry, skipping", "key", key, "entry", pair)
+			continue
+		}
+		m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return m
+}
+
+// getPolicies lee las políticas de límite de prefix+NOMBRE que estén definidas
+func getPolicies(prefix string, names ...string) map[string]ratelimit.Policy {
+	policies := make(map[string]ratelimit.Policy)
+	for _, name := range names {
+		key := prefix + strings.ToUpper(name)
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+
+		policy, err := ratelimit.ParsePolicy(value)
+		if err != nil {
+			slog.Warn("invalid env value, using default", "key", key, "value", value, "error", err)
+			continue
+		}
+		policies[name] = policy
+	}
+	return policies
+}
+
+func getDuration(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Warn("invalid env value, using default", "key", key, "value", value, "default", def)
+		return def
+	}
+	return d
+}
+
+func randomSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		slog.Error("could not generate jwt secret", "error", err)
+		os.Exit(1)
+	}
+	return hex.EncodeToString(b)
+}
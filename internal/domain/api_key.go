@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+/*
+# APIKey es una credencial para integraciones sin login humano (POS, EDI)
+* la clave completa es "exp_<prefijo>_<secreto>"; solo se guarda el SHA-256
+* Prefijo identifica la clave en búsquedas y en la interfaz
+* Permisos: "<recurso>:read", "<recurso>:write" o "<recurso>:*"
+* RangosIP vacío permite cualquier origen
+*/
+type APIKey struct {
+	ID             uint       `gorm:"column:id_clave;primaryKey;autoIncrement"`
+	IDUsuario      uint       `gorm:"column:id_usuario;not null"`
+	Nombre         string     `gorm:"column:nombre;type:varchar(100);not null"`
+	Prefijo        string     `gorm:"column:prefijo;type:varchar(16);uniqueIndex;not null"`
+	Hash           string     `gorm:"column:hash;type:char(64);not null"`
+	Permisos       []string   `gorm:"column:permisos;type:jsonb;serializer:json;not null"`
+	RangosIP       []string   `gorm:"column:rangos_ip;type:jsonb;serializer:json;not null"`
+	ExpiraEn       *time.Time `gorm:"column:expira_en"`
+	RevocadaEn     *time.Time `gorm:"column:revocada_en"`
+	ReemplazadaPor *uint      `gorm:"column:reemplazada_por"`
+	UltimoUso      *time.Time `gorm:"column:ultimo_uso"`
+	UltimaIP       *string    `gorm:"column:ultima_ip;type:varchar(45)"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime"`
+
+	User User `gorm:"foreignKey:IDUsuario;references:ID"`
+}
+
+func (APIKey) TableName() string {
+	return "clave_api"
+}
+
+// Active indica si la clave todavía autentica
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevocadaEn != nil {
+		return false
+	}
+	return k.ExpiraEn == nil || now.Before(*k.ExpiraEn)
+}
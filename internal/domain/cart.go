@@ -0,0 +1,55 @@
+package domain
+
+import "time"
+
+type Cart struct {
+	ID        uint      `gorm:"column:id_carrito;primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	IDCliente   uint    `gorm:"column:id_cliente;not null;uniqueIndex"`
+	CodigoCupon *string `gorm:"column:codigo_cupon;type:varchar(50)"`
+
+	Items []CartItem `gorm:"foreignKey:IDCarrito;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (Cart) TableName() string {
+	return "carrito"
+}
+
+type CartItem struct {
+	ID        uint      `gorm:"column:id_detalle_carrito;primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	IDCarrito  uint `gorm:"column:id_carrito;not null"`
+	IDProducto uint `gorm:"column:id_producto;not null"`
+	Cantidad   int  `gorm:"column:cantidad;not null;check:cantidad > 0"`
+
+	Product     Product           `gorm:"foreignKey:IDProducto;references:ID"`
+	Reservation *StockReservation `gorm:"foreignKey:IDDetalleCarrito;references:ID"`
+}
+
+func (CartItem) TableName() string {
+	return "detalle_carrito"
+}
+
+// StockReservation aparta unidades de un producto para una línea del carrito
+// hasta ExpiraEn. Las reservas vencidas dejan de contar contra el stock.
+type StockReservation struct {
+	ID        uint      `gorm:"column:id_reserva;primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	IDDetalleCarrito uint      `gorm:"column:id_detalle_carrito;not null;uniqueIndex"`
+	IDProducto       uint      `gorm:"column:id_producto;not null"`
+	Cantidad         int       `gorm:"column:cantidad;not null;check:cantidad > 0"`
+	ExpiraEn         time.Time `gorm:"column:expira_en;not null"`
+}
+
+func (StockReservation) TableName() string {
+	return "reserva_stock"
+}
+
+func (r *StockReservation) Active(now time.Time) bool {
+	return r != nil && r.ExpiraEn.After(now)
+}
@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TaxClass string
+
+const (
+	// TaxGeneral es la tarifa general de IVA
+	TaxGeneral TaxClass = "general"
+	// TaxReduced es la tarifa reducida (canasta básica)
+	TaxReduced TaxClass = "reducido"
+	// TaxExempt son los bienes exentos o excluidos de IVA
+	TaxExempt TaxClass = "exento"
+)
+
+// DefaultAlertDays es la anticipación con la que se alerta un vencimiento
+// cuando ninguna categoría define la suya
+const DefaultAlertDays = 7
+
+// MarkdownRule rebaja Descuento% del precio cuando al producto le quedan
+// DiasAntes días o menos para vencer
+type MarkdownRule struct {
+	DiasAntes int     `json:"dias_antes"`
+	Descuento float64 `json:"descuento"`
+}
+
+type MarkdownRules []MarkdownRule
+
+// DiscountFor es el porcentaje de rebaja con daysLeft días para vencer; con
+// varias reglas aplicables gana la de mayor descuento. Un producto vencido
+// no se rebaja: no se vende
+func (rules MarkdownRules) DiscountFor(daysLeft int) float64 {
+	if daysLeft < 0 {
+		return 0
+	}
+	discount := 0.0
+	for _, rule := range rules {
+		if daysLeft <= rule.DiasAntes && rule.Descuento > discount {
+			discount = rule.Descuento
+		}
+	}
+	return discount
+}
+
+/*
+# Category es un nodo del árbol de categorías
+* Ruta son los ids desde la raíz, "/1/4/9/"; los descendientes de una
+* categoría son las que empiezan por su ruta
+* DiasAlerta, ClaseImpuesto y ReglasRebaja en nil se heredan del padre
+*/
+type Category struct {
+	ID        uint      `gorm:"column:id_categoria;primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	IDPadre       *uint         `gorm:"column:id_padre"`
+	Nombre        string        `gorm:"column:nombre;type:varchar(100);not null"`
+	Slug          string        `gorm:"column:slug;type:varchar(120);not null;uniqueIndex"`
+	Descripcion   string        `gorm:"column:descripcion;type:text"`
+	Activa        bool          `gorm:"column:activa;not null;default:true"`
+	Ruta          string        `gorm:"column:ruta;type:text;not null"`
+	DiasAlerta    *int          `gorm:"column:dias_alerta"`
+	ClaseImpuesto *TaxClass     `gorm:"column:clase_impuesto;type:varchar(20)"`
+	ReglasRebaja  MarkdownRules `gorm:"column:reglas_rebaja;type:jsonb;serializer:json"`
+}
+
+func (Category) TableName() string {
+	return "categoria"
+}
+
+// Depth es el nivel de la categoría; las raíces son nivel 1
+func (c *Category) Depth() int {
+	return strings.Count(c.Ruta, "/") - 1
+}
+
+// ChildPath es la ruta que tendría un hijo con id
+func (c *Category) ChildPath(id uint) string {
+	return c.Ruta + strconv.FormatUint(uint64(id), 10) + "/"
+}
+
+// ProductCategory asigna un producto a una categoría; la principal es la que
+// da los valores por defecto y agrupa los reportes
+type ProductCategory struct {
+	IDProducto  uint `gorm:"column:id_producto;primaryKey"`
+	IDCategoria uint `gorm:"column:id_categoria;primaryKey"`
+	Principal   bool `gorm:"column:principal;not null;default:false"`
+}
+
+func (ProductCategory) TableName() string {
+	return "producto_categoria"
+}
+
+// CategoryDefaults son los valores que un producto toma de su categoría
+// principal (o de sus ancestros) para otras funciones: alertas de
+// vencimiento, impuestos y rebajas
+type CategoryDefaults struct {
+	AlertDays     int
+	TaxClass      TaxClass
+	MarkdownRules MarkdownRules
+}
+
+// SystemCategoryDefaults aplica cuando ni la categoría ni sus ancestros
+// definen un valor
+func SystemCategoryDefaults() CategoryDefaults {
+	return CategoryDefaults{AlertDays: DefaultAlertDays, TaxClass: TaxGeneral}
+}
@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+/*
+# errores base; se comparan con errors.Is
+* cada uno corresponde a un estado HTTP en platform/http
+*/
+var (
+	ErrValidation        = errors.New("validation error")
+	ErrNotFound          = errors.New("not found")
+	ErrConflict          = errors.New("conflict")
+	ErrUnprocessable     = errors.New("unprocessable entity")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrForbidden         = errors.New("forbidden")
+	ErrGone              = errors.New("gone")
+	ErrTooManyRequests   = errors.New("too many requests")
+	ErrUnavailable       = errors.New("service unavailable")
+)
+
+/*
+# FieldError describe un campo inválido del body o de la query
+* Args son los valores del mensaje; sirven para traducirlo
+* con Args nil el mensaje ya viene traducido (errores del validador)
+*/
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Args    []any  `json:"-"`
+}
+
+/*
+# Error es un error de negocio con código estable para los clientes
+* Kind: uno de los errores base; define el estado HTTP
+* Code: identificador en snake_case, p. ej. "order_not_found"
+* Message: texto para mostrar al usuario, en español
+* Args: valores con los que se armó Message; los catálogos de
+* platform/i18n los usan para traducir el mensaje a otro idioma
+* Fields: detalle por campo en los errores de validación
+* RetryAfter: cuánto esperar antes de reintentar, si aplica
+*/
+type Error struct {
+	Kind       error
+	Code       string
+	Message    string
+	Args       []any
+	Fields     []FieldError
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func newError(kind error, code, format string, args ...any) *Error {
+	return &Error{Kind: kind, Code: code, Message: fmt.Sprintf(format, args...), Args: args}
+}
+
+func NotFound(code, format string, args ...any) *Error {
+	return newError(ErrNotFound, code, format, args...)
+}
+
+func Conflict(code, format string, args ...any) *Error {
+	return newError(ErrConflict, code, format, args...)
+}
+
+func Validation(code, format string, args ...any) *Error {
+	return newError(ErrValidation, code, format, args...)
+}
+
+// Unprocessable es una solicitud bien formada que no se puede atender tal como viene
+func Unprocessable(code, format string, args ...any) *Error {
+	return newError(ErrUnprocessable, code, format, args...)
+}
+
+// InsufficientStock indica que no hay unidades suficientes de product
+func InsufficientStock(product *Product, available, requested int) *Error {
+	return newError(ErrInsufficientStock, "insufficient_stock",
+		"stock insuficiente para el producto %s (id: %d). Disponible: %d, solicitado: %d",
+		product.Nombre, product.ID, available, requested)
+}
+
+func Unauthorized(code, format string, args ...any) *Error {
+	return newError(ErrUnauthorized, code, format, args...)
+}
+
+func Forbidden(code, format string, args ...any) *Error {
+	return newError(ErrForbidden, code, format, args...)
+}
+
+func Gone(code, format string, args ...any) *Error {
+	return newError(ErrGone, code, format, args...)
+}
+
+func Unavailable(code, format string, args ...any) *Error {
+	return newError(ErrUnavailable, code, format, args...)
+}
+
+// TooManyRequests indica al cliente que reintente después de retryAfter
+func TooManyRequests(code string, retryAfter time.Duration, format string, args ...any) *Error {
+	err := newError(ErrTooManyRequests, code, format, args...)
+	err.RetryAfter = retryAfter
+	return err
+}
+
+// InvalidField es un error de validación sobre un solo campo
+func InvalidField(field, code, format string, args ...any) *Error {
+	err := Validation(code, format, args...)
+	err.Fields = []FieldError{{Field: field, Code: code, Message: err.Message, Args: append([]any{}, err.Args...)}}
+	return err
+}
+
+// InvalidID es el error de un parámetro de ruta que no es un id numérico
+func InvalidID(param string) *Error {
+	return InvalidField(param, "invalid_id", "%s debe ser un número válido", param)
+}
+
+// Required es el error de un campo obligatorio que llegó vacío
+func Required(field string) *Error {
+	return InvalidField(field, "required", "%s es obligatorio", field)
+}
+
+// ErrorCode devuelve el código de err si es un *Error, o ""
+func ErrorCode(err error) string {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+package domain
+
+import "time"
+
+/*
+# Lot es un lote de un producto con su propia fecha de vencimiento
+* Cantidad son las unidades que quedan; las ventas las consumen por
+* orden de vencimiento (FEFO)
+* el stock del producto sigue siendo el total; las unidades que no están en
+* ningún lote son stock sin lote
+* CostoUnitario es el de la entrega; si llegan varias del mismo lote es el
+* promedio ponderado de las unidades
+*/
+type Lot struct {
+	ID        uint      `gorm:"column:id_lote;primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	IDProducto       uint      `gorm:"column:id_producto;not null"`
+	Numero           string    `gorm:"column:numero;type:varchar(20);not null"`
+	FechaVencimiento time.Time `gorm:"column:fecha_vencimiento;type:date;not null"`
+	CantidadRecibida int       `gorm:"column:cantidad_recibida;not null;check:cantidad_recibida > 0"`
+	Cantidad         int       `gorm:"column:cantidad;not null;check:cantidad >= 0"`
+	CostoUnitario    *float64  `gorm:"column:costo_unitario;type:numeric(12,4);check:costo_unitario >= 0"`
+}
+
+func (Lot) TableName() string {
+	return "lote"
+}
+
+const (
+	WriteOffExpired   = "vencimiento"
+	WriteOffDamaged   = "averia"
+	WriteOffShrinkage = "merma"
+	WriteOffCount     = "conteo"
+)
+
+/*
+# LotWriteOff son las unidades que un ajuste negativo de stock sacó de un lote
+* Motivo separa lo que se perdió (vencimiento, avería, merma) de una
+* corrección de conteo; solo lo primero es desperdicio del proveedor
+* CostoUnitario es el del lote al darlas de baja
+*/
+type LotWriteOff struct {
+	ID        uint      `gorm:"column:id_baja_lote;primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	IDLote        uint      `gorm:"column:id_lote;not null"`
+	IDProducto    uint      `gorm:"column:id_producto;not null"`
+	Cantidad      int       `gorm:"column:cantidad;not null;check:cantidad > 0"`
+	CostoUnitario *float64  `gorm:"column:costo_unitario;type:numeric(12,4)"`
+	Motivo        string    `gorm:"column:motivo;type:varchar(12);not null"`
+	Fecha         time.Time `gorm:"column:fecha;type:date;not null"`
+	IDUsuario     *uint     `gorm:"column:id_usuario"`
+}
+
+func (LotWriteOff) TableName() string {
+	return "baja_lote"
+}
+
+type ExpiryStatus string
+
+const (
+	ExpiryExpired ExpiryStatus = "vencido"
+	ExpirySoon    ExpiryStatus = "por_vencer"
+	ExpiryValid   ExpiryStatus = "vigente"
+)
+
+// DaysUntil son los días enteros desde today hasta date (negativo si ya pasó)
+func DaysUntil(date, today time.Time) int {
+	d := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	t := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	return int(d.Sub(t).Hours() / 24)
+}
+
+// ExpiryStatusFor clasifica una fecha de vencimiento; por_vencer es a
+// alertDays días o menos
+func ExpiryStatusFor(date, today time.Time, alertDays int) ExpiryStatus {
+	switch days := DaysUntil(date, today); {
+	case days < 0:
+		return ExpiryExpired
+	case days <= alertDays:
+		return ExpirySoon
+	}
+	return ExpiryValid
+}
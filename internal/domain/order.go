@@ -33,11 +33,28 @@ type OrderItem struct {
 	IDProducto     uint    `gorm:"column:id_producto;not null"`
 	Cantidad       int     `gorm:"column:cantidad;not null;check:cantidad > 0"`
 	PrecioUnitario float64 `gorm:"column:precio_unitario;type:numeric(10,2);not null;check:precio_unitario >= 0"`
-
-	Order   Order   `gorm:"foreignKey:IDCompra;references:ID"`
-	Product Product `gorm:"foreignKey:IDProducto;references:ID"`
+	Descuento      float64 `gorm:"column:descuento;type:numeric(10,2);not null;default:0;check:descuento >= 0"`
+	// CostoUnitario es el costo de las unidades vendidas al registrar la
+	// línea; nil si no se conocía
+	CostoUnitario  *float64 `gorm:"column:costo_unitario;type:numeric(12,4);check:costo_unitario >= 0"`
+
+	Order     Order               `gorm:"foreignKey:IDCompra;references:ID"`
+	Product   Product             `gorm:"foreignKey:IDProducto;references:ID"`
+	Discounts []OrderItemDiscount `gorm:"foreignKey:IDDetalle;references:ID;constraint:OnDelete:CASCADE"`
 }
 
 func (OrderItem) TableName() string {
 	return "detalle_compra"
-}
\ No newline at end of file
+}
+// OrderItemLot son las unidades de una línea que salieron de un lote; si la
+// línea se cancela o baja de cantidad vuelven a ese lote
+type OrderItemLot struct {
+	ID        uint `gorm:"column:id_detalle_lote;primaryKey;autoIncrement"`
+	IDDetalle uint `gorm:"column:id_detalle;not null"`
+	IDLote    uint `gorm:"column:id_lote;not null"`
+	Cantidad  int  `gorm:"column:cantidad;not null;check:cantidad > 0"`
+}
+
+func (OrderItemLot) TableName() string {
+	return "detalle_compra_lote"
+}
@@ -0,0 +1,68 @@
+package domain
+
+import "time"
+
+// PriceSource dice de dónde salió un cambio de precio
+type PriceSource string
+
+const (
+	PriceInitial   PriceSource = "alta"
+	PriceManual    PriceSource = "manual"
+	PriceImport    PriceSource = "importacion"
+	PriceScheduled PriceSource = "programado"
+)
+
+/*
+# PriceChange es un cambio de precio ya aplicado
+* la historia de un producto son sus cambios ordenados por VigenteDesde;
+* el precio en un momento es el del último cambio anterior a él
+* PrecioAnterior es nil en el precio de alta
+* IDUsuario es quién lo hizo (o quién lo programó); nil si la solicitud
+* no traía credenciales
+*/
+type PriceChange struct {
+	ID        uint      `gorm:"column:id_cambio_precio;primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	IDProducto         uint        `gorm:"column:id_producto;not null"`
+	PrecioAnterior     *float64    `gorm:"column:precio_anterior;type:numeric(10,2)"`
+	Precio             float64     `gorm:"column:precio;type:numeric(10,2);not null"`
+	VigenteDesde       time.Time   `gorm:"column:vigente_desde;not null"`
+	Origen             PriceSource `gorm:"column:origen;type:varchar(12);not null"`
+	Motivo             string      `gorm:"column:motivo;type:varchar(255)"`
+	IDUsuario          *uint       `gorm:"column:id_usuario"`
+	IDPrecioProgramado *uint       `gorm:"column:id_precio_programado"`
+}
+
+func (PriceChange) TableName() string {
+	return "precio_historial"
+}
+
+type ScheduledPriceStatus string
+
+const (
+	ScheduledPending   ScheduledPriceStatus = "pendiente"
+	ScheduledApplied   ScheduledPriceStatus = "aplicado"
+	ScheduledCancelled ScheduledPriceStatus = "cancelado"
+)
+
+// ScheduledPrice es un cambio de precio con fecha futura; al llegar
+// VigenteDesde se aplica solo y queda en la historia como programado
+type ScheduledPrice struct {
+	ID        uint      `gorm:"column:id_precio_programado;primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	IDProducto   uint                 `gorm:"column:id_producto;not null"`
+	Precio       float64              `gorm:"column:precio;type:numeric(10,2);not null"`
+	VigenteDesde time.Time            `gorm:"column:vigente_desde;not null"`
+	Motivo       string               `gorm:"column:motivo;type:varchar(255);not null"`
+	IDUsuario    *uint                `gorm:"column:id_usuario"`
+	Estado       ScheduledPriceStatus `gorm:"column:estado;type:varchar(10);not null"`
+	AplicadoEn   *time.Time           `gorm:"column:aplicado_en"`
+	CanceladoPor *uint                `gorm:"column:cancelado_por"`
+}
+
+func (ScheduledPrice) TableName() string {
+	return "precio_programado"
+}
@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// Consent registra cada vez que el titular otorga o revoca una autorización
+// de tratamiento de datos (Ley 1581 de 2012). Es un historial: nunca se
+// actualiza, cada cambio agrega una fila.
+type Consent struct {
+	ID        uint      `gorm:"column:id_consentimiento;primaryKey;autoIncrement"`
+	IDUsuario uint      `gorm:"column:id_usuario;not null"`
+	Tipo      string    `gorm:"column:tipo;type:varchar(50);not null"`
+	Otorgado  bool      `gorm:"column:otorgado;not null"`
+	Version   string    `gorm:"column:version;type:varchar(20)"`
+	IP        *string   `gorm:"column:ip;type:varchar(45)"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (Consent) TableName() string {
+	return "consentimiento"
+}
+
+type DataRequestType string
+
+const (
+	DataRequestExport   DataRequestType = "exportacion"
+	DataRequestDeletion DataRequestType = "supresion"
+)
+
+// DataRequest deja constancia de las solicitudes del titular sobre sus datos
+type DataRequest struct {
+	ID            uint            `gorm:"column:id_solicitud;primaryKey;autoIncrement"`
+	IDUsuario     uint            `gorm:"column:id_usuario;not null"`
+	Tipo          DataRequestType `gorm:"column:tipo;type:varchar(20);not null"`
+	SolicitadoPor uint            `gorm:"column:solicitado_por;not null"`
+	CreatedAt     time.Time       `gorm:"autoCreateTime"`
+	CompletadaEn  *time.Time      `gorm:"column:completada_en"`
+}
+
+func (DataRequest) TableName() string {
+	return "solicitud_datos"
+}
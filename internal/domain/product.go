@@ -17,6 +17,13 @@ type Product struct {
 	Precio           float64   `gorm:"column:precio;type:numeric(10,2);not null;check:precio >= 0"`
 	FechaVencimiento time.Time `gorm:"column:fecha_vencimiento;type:date;not null"`
 	Stock            int       `gorm:"column:stock;type:int;default:0;check:stock >= 0"`
+	// Costo es el costo unitario de reposición: el del último lote recibido
+	// con costo o el cargado a mano; valora el stock sin lote
+	Costo            *float64  `gorm:"column:costo;type:numeric(12,4);check:costo >= 0"`
+
+	// GTIN se guarda normalizado a 14 dígitos (ver gs1.NormalizeGTIN)
+	GTIN             *string   `gorm:"column:gtin;type:varchar(14)"`
+	SKU              *string   `gorm:"column:sku;type:varchar(64)"`
 }
 
 func (Product) TableName() string {
@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+type ImportStatus string
+
+const (
+	ImportPending ImportStatus = "pendiente"
+	ImportRunning ImportStatus = "procesando"
+	ImportDone    ImportStatus = "completada"
+	ImportFailed  ImportStatus = "fallida"
+)
+
+// ImportRowError es un problema de una fila; Row cuenta desde 1 con el
+// encabezado, como se ve en la hoja de cálculo
+type ImportRowError struct {
+	Row     int    `json:"fila"`
+	Field   string `json:"campo,omitempty"`
+	Code    string `json:"codigo"`
+	Message string `json:"mensaje"`
+}
+
+/*
+# ProductImport es una importación de productos en segundo plano
+* Archivo guarda el archivo subido hasta que se procesa; después se borra
+* SoloValidar no escribe nada: Creados y Actualizados son los que se
+* crearían o actualizarían
+* Idioma es el de la solicitud; los mensajes de Errores salen en él
+* Mensaje explica por qué falló la importación completa
+* IDUsuario es quién la subió; a él se atribuyen los cambios de precio
+*/
+type ProductImport struct {
+	ID        uint      `gorm:"column:id_importacion;primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	Estado        ImportStatus     `gorm:"column:estado;type:varchar(12);not null"`
+	Formato       string           `gorm:"column:formato;type:varchar(4);not null"`
+	NombreArchivo string           `gorm:"column:nombre_archivo;type:varchar(255)"`
+	SoloValidar   bool             `gorm:"column:solo_validar;not null"`
+	Idioma        string           `gorm:"column:idioma;type:varchar(5);not null"`
+	IDUsuario     *uint            `gorm:"column:id_usuario"`
+	Archivo       []byte           `gorm:"column:archivo;type:bytea"`
+	Filas         int              `gorm:"column:filas;not null;default:0"`
+	Creados       int              `gorm:"column:creados;not null;default:0"`
+	Actualizados  int              `gorm:"column:actualizados;not null;default:0"`
+	ConError      int              `gorm:"column:con_error;not null;default:0"`
+	Errores       []ImportRowError `gorm:"column:errores;type:jsonb;serializer:json"`
+	Mensaje       string           `gorm:"column:mensaje;type:text"`
+	IniciadaEn    *time.Time       `gorm:"column:iniciada_en"`
+	TerminadaEn   *time.Time       `gorm:"column:terminada_en"`
+}
+
+func (ProductImport) TableName() string {
+	return "importacion_producto"
+}
@@ -0,0 +1,46 @@
+package domain
+
+// Client, Seller y Admin son los perfiles de un usuario. Cada tabla usa el
+// id_usuario como llave primaria, así que un usuario tiene a lo sumo un
+// perfil de cada tipo.
+
+type Client struct {
+	ID        uint    `gorm:"column:id_cliente;primaryKey"`
+	Direccion *string `gorm:"column:direccion;type:varchar(150)"`
+	Telefono  *string `gorm:"column:telefono;type:varchar(20)"`
+
+	User User `gorm:"foreignKey:ID;references:ID"`
+}
+
+func (Client) TableName() string {
+	return "cliente"
+}
+
+type Seller struct {
+	ID              uint    `gorm:"column:id_vendedor;primaryKey"`
+	AreaResponsable *string `gorm:"column:area_responsable;type:varchar(100)"`
+
+	User User `gorm:"foreignKey:ID;references:ID"`
+}
+
+func (Seller) TableName() string {
+	return "vendedor"
+}
+
+type Admin struct {
+	ID                 uint    `gorm:"column:id_admin;primaryKey"`
+	PermisosEspeciales *string `gorm:"column:permisos_especiales;type:text"`
+
+	User User `gorm:"foreignKey:ID;references:ID"`
+}
+
+func (Admin) TableName() string {
+	return "administrador"
+}
+
+// nombres de rol que acompañan a cada perfil en usuario_rol
+const (
+	RoleClient = "cliente"
+	RoleSeller = "vendedor"
+	RoleAdmin  = "administrador"
+)
@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PromotionType string
+
+const (
+	// PromotionPercentage descuenta Valor% del subtotal de cada línea aplicable.
+	PromotionPercentage PromotionType = "porcentaje"
+	// PromotionFixed descuenta Valor por unidad si la promoción es de un
+	// producto o de una categoría, o Valor sobre el total de la compra si
+	// aplica a todo el catálogo.
+	PromotionFixed PromotionType = "monto_fijo"
+	// PromotionBuyXGetY regala CantidadGratis unidades por cada
+	// CantidadCompra + CantidadGratis unidades de la misma línea.
+	PromotionBuyXGetY PromotionType = "lleve_x_pague_y"
+)
+
+type Promotion struct {
+	ID        uint           `gorm:"column:id_promocion;primaryKey;autoIncrement"`
+	CreatedAt time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	Nombre         string        `gorm:"column:nombre;type:varchar(100);not null"`
+	Tipo           PromotionType `gorm:"column:tipo;type:varchar(20);not null"`
+	Valor          float64       `gorm:"column:valor;type:numeric(10,2);not null;default:0"`
+	CantidadCompra int           `gorm:"column:cantidad_compra;default:0"`
+	CantidadGratis int           `gorm:"column:cantidad_gratis;default:0"`
+	IDProducto     *uint         `gorm:"column:id_producto"`
+	IDCategoria    *uint         `gorm:"column:id_categoria"`
+	RequiereCupon  bool          `gorm:"column:requiere_cupon;not null;default:false"`
+	FechaInicio    time.Time     `gorm:"column:fecha_inicio;not null"`
+	FechaFin       *time.Time    `gorm:"column:fecha_fin"`
+	Activa         bool          `gorm:"column:activa;not null;default:true"`
+
+	Coupons []Coupon `gorm:"foreignKey:IDPromocion;references:ID"`
+}
+
+func (Promotion) TableName() string {
+	return "promocion"
+}
+
+type Coupon struct {
+	ID        uint      `gorm:"column:id_cupon;primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	Codigo         string     `gorm:"column:codigo;type:varchar(50);not null;uniqueIndex"`
+	IDPromocion    uint       `gorm:"column:id_promocion;not null"`
+	UsosMaximos    *int       `gorm:"column:usos_maximos"`
+	UsosPorCliente *int       `gorm:"column:usos_por_cliente"`
+	FechaInicio    *time.Time `gorm:"column:fecha_inicio"`
+	FechaFin       *time.Time `gorm:"column:fecha_fin"`
+	Activo         bool       `gorm:"column:activo;not null;default:true"`
+
+	Promotion Promotion `gorm:"foreignKey:IDPromocion;references:ID"`
+}
+
+func (Coupon) TableName() string {
+	return "cupon"
+}
+
+// OrderItemDiscount es una línea de descuento aplicada a un detalle_compra.
+type OrderItemDiscount struct {
+	ID uint `gorm:"column:id_descuento_detalle;primaryKey;autoIncrement"`
+
+	IDDetalle   uint    `gorm:"column:id_detalle;not null"`
+	IDPromocion uint    `gorm:"column:id_promocion;not null"`
+	IDCupon     *uint   `gorm:"column:id_cupon"`
+	Monto       float64 `gorm:"column:monto;type:numeric(10,2);not null;check:monto >= 0"`
+}
+
+func (OrderItemDiscount) TableName() string {
+	return "descuento_detalle"
+}
+
+// PromotionUsage registra cada compra en la que se aplicó una promoción o
+// cupón; de aquí salen los límites de uso y el reporte de promociones.
+type PromotionUsage struct {
+	ID        uint      `gorm:"column:id_uso;primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	IDPromocion    uint    `gorm:"column:id_promocion;not null"`
+	IDCupon        *uint   `gorm:"column:id_cupon"`
+	IDCliente      uint    `gorm:"column:id_cliente;not null"`
+	IDCompra       uint    `gorm:"column:id_compra;not null"`
+	MontoDescuento float64 `gorm:"column:monto_descuento;type:numeric(10,2);not null"`
+}
+
+func (PromotionUsage) TableName() string {
+	return "uso_promocion"
+}
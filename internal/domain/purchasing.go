@@ -0,0 +1,131 @@
+package domain
+
+import "time"
+
+/*
+# Supplier es un proveedor
+* NIT es opcional pero único
+* DiasEntrega es el plazo de entrega pactado; sirve para saber si una
+* orden sin fecha esperada llegó a tiempo
+*/
+type Supplier struct {
+	ID        uint      `gorm:"column:id_proveedor;primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	Nombre      string  `gorm:"column:nombre;type:varchar(150);not null"`
+	NIT         *string `gorm:"column:nit;type:varchar(20)"`
+	Contacto    string  `gorm:"column:contacto;type:varchar(100)"`
+	Email       string  `gorm:"column:email;type:varchar(150)"`
+	Telefono    string  `gorm:"column:telefono;type:varchar(30)"`
+	DiasEntrega *int    `gorm:"column:dias_entrega;check:dias_entrega > 0"`
+	Activo      bool    `gorm:"column:activo;not null;default:true"`
+}
+
+func (Supplier) TableName() string {
+	return "proveedor"
+}
+
+type PurchaseOrderStatus string
+
+const (
+	// PurchaseDraft se puede editar; todavía no se pidió al proveedor
+	PurchaseDraft PurchaseOrderStatus = "borrador"
+	// PurchaseSent se pidió y no ha llegado nada
+	PurchaseSent PurchaseOrderStatus = "enviada"
+	// PurchasePartial llegó parte de lo pedido
+	PurchasePartial PurchaseOrderStatus = "parcial"
+	// PurchaseReceived llegó todo lo pedido
+	PurchaseReceived PurchaseOrderStatus = "recibida"
+	// PurchaseCancelled no se espera nada más; lo ya recibido queda
+	PurchaseCancelled PurchaseOrderStatus = "cancelada"
+)
+
+// Receivable dice si a una orden en este estado le puede llegar mercancía
+func (s PurchaseOrderStatus) Receivable() bool {
+	return s == PurchaseSent || s == PurchasePartial
+}
+
+/*
+# PurchaseOrder es una orden de compra a un proveedor
+* EnviadaEn es cuando se pidió; con la primera recepción da el plazo de
+* entrega real
+* CerradaEn es cuando quedó recibida o cancelada
+*/
+type PurchaseOrder struct {
+	ID        uint      `gorm:"column:id_orden_compra;primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	IDProveedor   uint                `gorm:"column:id_proveedor;not null"`
+	Estado        PurchaseOrderStatus `gorm:"column:estado;type:varchar(10);not null;default:borrador"`
+	FechaEsperada *time.Time          `gorm:"column:fecha_esperada;type:date"`
+	EnviadaEn     *time.Time          `gorm:"column:enviada_en"`
+	CerradaEn     *time.Time          `gorm:"column:cerrada_en"`
+	Notas         string              `gorm:"column:notas;type:text"`
+	IDUsuario     *uint               `gorm:"column:id_usuario"`
+
+	Supplier Supplier            `gorm:"foreignKey:IDProveedor;references:ID"`
+	Lines    []PurchaseOrderLine `gorm:"foreignKey:IDOrdenCompra;references:ID;constraint:OnDelete:CASCADE"`
+	Receipts []GoodsReceipt      `gorm:"foreignKey:IDOrdenCompra;references:ID"`
+}
+
+func (PurchaseOrder) TableName() string {
+	return "orden_compra"
+}
+
+type PurchaseOrderLine struct {
+	ID uint `gorm:"column:id_detalle_orden;primaryKey;autoIncrement"`
+
+	IDOrdenCompra    uint    `gorm:"column:id_orden_compra;not null"`
+	IDProducto       uint    `gorm:"column:id_producto;not null"`
+	Cantidad         int     `gorm:"column:cantidad;not null;check:cantidad > 0"`
+	CantidadRecibida int     `gorm:"column:cantidad_recibida;not null;default:0;check:cantidad_recibida >= 0"`
+	CostoUnitario    float64 `gorm:"column:costo_unitario;type:numeric(12,4);not null;check:costo_unitario >= 0"`
+
+	Product Product `gorm:"foreignKey:IDProducto;references:ID"`
+}
+
+func (PurchaseOrderLine) TableName() string {
+	return "detalle_orden_compra"
+}
+
+// Pending son las unidades que faltan por llegar
+func (l *PurchaseOrderLine) Pending() int {
+	return max(l.Cantidad-l.CantidadRecibida, 0)
+}
+
+// GoodsReceipt es una entrega del proveedor contra una orden de compra
+type GoodsReceipt struct {
+	ID        uint      `gorm:"column:id_recepcion;primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	IDOrdenCompra uint      `gorm:"column:id_orden_compra;not null"`
+	RecibidaEn    time.Time `gorm:"column:recibida_en;not null"`
+	Notas         string    `gorm:"column:notas;type:text"`
+	IDUsuario     *uint     `gorm:"column:id_usuario"`
+
+	Lines []GoodsReceiptLine `gorm:"foreignKey:IDRecepcion;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (GoodsReceipt) TableName() string {
+	return "recepcion"
+}
+
+// GoodsReceiptLine son las unidades de una línea de la orden que llegaron en
+// un lote; Lot es el lote donde quedaron en el inventario
+type GoodsReceiptLine struct {
+	ID uint `gorm:"column:id_detalle_recepcion;primaryKey;autoIncrement"`
+
+	IDRecepcion    uint    `gorm:"column:id_recepcion;not null"`
+	IDDetalleOrden uint    `gorm:"column:id_detalle_orden;not null"`
+	IDLote         uint    `gorm:"column:id_lote;not null"`
+	Cantidad       int     `gorm:"column:cantidad;not null;check:cantidad > 0"`
+	CostoUnitario  float64 `gorm:"column:costo_unitario;type:numeric(12,4);not null;check:costo_unitario >= 0"`
+
+	Lot Lot `gorm:"foreignKey:IDLote;references:ID"`
+}
+
+func (GoodsReceiptLine) TableName() string {
+	return "detalle_recepcion"
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// RecoveryCode reemplaza al código TOTP una sola vez cuando el usuario no
+// tiene su autenticador. Se guarda solo el hash SHA-256.
+type RecoveryCode struct {
+	ID        uint       `gorm:"column:id_codigo;primaryKey;autoIncrement"`
+	IDUsuario uint       `gorm:"column:id_usuario;not null"`
+	Hash      string     `gorm:"column:hash;type:char(64);not null"`
+	UsadoEn   *time.Time `gorm:"column:usado_en"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "codigo_recuperacion"
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// LoginAttempt acumula los fallos de login de una clave ("cuenta:<correo>" o
+// "ip:<dirección>"). Vive en Postgres para que todas las réplicas del API
+// vean el mismo contador.
+type LoginAttempt struct {
+	Clave          string     `gorm:"column:clave;type:varchar(150);primaryKey"`
+	Fallos         int        `gorm:"column:fallos;not null;default:0"`
+	UltimoFallo    time.Time  `gorm:"column:ultimo_fallo;not null"`
+	BloqueadoHasta *time.Time `gorm:"column:bloqueado_hasta"`
+}
+
+func (LoginAttempt) TableName() string {
+	return "intento_login"
+}
+
+type SecurityEventType string
+
+const (
+	SecurityEventLoginFailed     SecurityEventType = "login_fallido"
+	SecurityEventLockout         SecurityEventType = "bloqueo"
+	SecurityEventUnlock          SecurityEventType = "desbloqueo"
+	SecurityEventPasswordChanged SecurityEventType = "cambio_contrasena"
+	SecurityEventPasswordReset   SecurityEventType = "restablecimiento_contrasena"
+	SecurityEventTwoFactorOn     SecurityEventType = "2fa_activado"
+	SecurityEventTwoFactorOff    SecurityEventType = "2fa_desactivado"
+	SecurityEventAPIKeyCreated   SecurityEventType = "clave_api_creada"
+	SecurityEventAPIKeyRotated   SecurityEventType = "clave_api_rotada"
+	SecurityEventAPIKeyRevoked   SecurityEventType = "clave_api_revocada"
+)
+
+type SecurityEvent struct {
+	ID        uint              `gorm:"column:id_evento;primaryKey;autoIncrement"`
+	Tipo      SecurityEventType `gorm:"column:tipo;type:varchar(40);not null"`
+	IDUsuario *uint             `gorm:"column:id_usuario"`
+	Correo    *string           `gorm:"column:correo;type:varchar(100)"`
+	IP        *string           `gorm:"column:ip;type:varchar(45)"`
+	Detalle   string            `gorm:"column:detalle;type:text"`
+	CreatedAt time.Time         `gorm:"autoCreateTime"`
+}
+
+func (SecurityEvent) TableName() string {
+	return "evento_seguridad"
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// ExternalIdentity enlaza un usuario local con su cuenta en un proveedor
+// OIDC; (Emisor, Sujeto) identifica a la persona aunque cambie de correo.
+type ExternalIdentity struct {
+	ID        uint      `gorm:"column:id_identidad;primaryKey;autoIncrement"`
+	IDUsuario uint      `gorm:"column:id_usuario;not null"`
+	Emisor    string    `gorm:"column:emisor;type:varchar(255);not null"`
+	Sujeto    string    `gorm:"column:sujeto;type:varchar(255);not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (ExternalIdentity) TableName() string {
+	return "identidad_externa"
+}
+
+/*
+# SSOState guarda un inicio de sesión OIDC en curso
+* Estado es el SHA-256 del parámetro state que viaja al IdP
+* Verificador es el code_verifier de PKCE; nunca sale del servidor
+* se borra al canjearse, así cada state sirve una sola vez
+*/
+type SSOState struct {
+	Estado      string    `gorm:"column:estado;type:char(64);primaryKey"`
+	Nonce       string    `gorm:"column:nonce;type:varchar(64);not null"`
+	Verificador string    `gorm:"column:verificador;type:varchar(128);not null"`
+	ExpiraEn    time.Time `gorm:"column:expira_en;not null"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+func (SSOState) TableName() string {
+	return "inicio_sso"
+}
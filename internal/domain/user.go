@@ -16,9 +16,28 @@ type User struct {
 	Email    string `gorm:"column:correo;type:varchar(100);uniqueIndex;not null"`
 	Password string `gorm:"column:contrasena;type:varchar(255);not null"`
 
+	CorreoVerificadoEn *time.Time `gorm:"column:correo_verificado_en"`
+
+	// TOTPSecreto queda guardado desde el enrolamiento; el segundo factor
+	// solo se exige cuando TOTPActivadoEn no es nil.
+	TOTPSecreto    *string    `gorm:"column:totp_secreto;type:varchar(64)"`
+	TOTPActivadoEn *time.Time `gorm:"column:totp_activado_en"`
+	TOTPUltimoPaso int64      `gorm:"column:totp_ultimo_paso;not null;default:0"`
+
+	// AnonimizadoEn marca las cuentas suprimidas por solicitud del titular
+	AnonimizadoEn *time.Time `gorm:"column:anonimizado_en"`
+
 	Roles []Role `gorm:"many2many:usuario_rol;foreignKey:ID;joinForeignKey:id_usuario;References:ID;joinReferences:id_rol"`
 }
 
 func (User) TableName() string {
 	return "usuario"
 }
+
+func (u *User) EmailVerified() bool {
+	return u.CorreoVerificadoEn != nil
+}
+
+func (u *User) TwoFactorEnabled() bool {
+	return u.TOTPActivadoEn != nil
+}
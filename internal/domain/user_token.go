@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+type UserTokenType string
+
+const (
+	UserTokenPasswordReset     UserTokenType = "reset"
+	UserTokenEmailVerification UserTokenType = "verificacion"
+)
+
+// UserToken es un token de un solo uso enviado por correo. Solo se guarda el
+// hash SHA-256; el valor en claro viaja únicamente en el enlace.
+type UserToken struct {
+	ID        uint          `gorm:"column:id_token;primaryKey;autoIncrement"`
+	IDUsuario uint          `gorm:"column:id_usuario;not null"`
+	Tipo      UserTokenType `gorm:"column:tipo;type:varchar(20);not null"`
+	Hash      string        `gorm:"column:hash;type:char(64);uniqueIndex;not null"`
+	ExpiraEn  time.Time     `gorm:"column:expira_en;not null"`
+	UsadoEn   *time.Time    `gorm:"column:usado_en"`
+	CreatedAt time.Time     `gorm:"autoCreateTime"`
+}
+
+func (UserToken) TableName() string {
+	return "token_usuario"
+}
+
+// Usable indica si el token aún puede canjearse
+func (t *UserToken) Usable(now time.Time) bool {
+	return t.UsadoEn == nil && now.Before(t.ExpiraEn)
+}
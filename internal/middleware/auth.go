@@ -1 +1,220 @@
 package middleware
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	api "github.com/mordmora/expirapp/internal/platform/http"
+)
+
+// principalKey es la llave del contexto de gin donde queda el usuario autenticado
+const principalKey = "principal"
+
+// ScopeTwoFactorEnroll marca los tokens que solo permiten enrolar 2FA
+const ScopeTwoFactorEnroll = "2fa_enroll"
+
+/*
+# Principal es la identidad autenticada de la solicitud
+* UserID: id_usuario del dueño de la credencial
+* Roles: nombres de rol (cliente, vendedor, administrador)
+* Scope: vacío para acceso completo; si no, el único paso que permite
+* APIKeyID y Permissions solo vienen cuando se autenticó con una clave de API
+*/
+type Principal struct {
+	UserID      uint
+	Roles       []string
+	Scope       string
+	APIKeyID    uint
+	Permissions []string
+}
+
+func (p *Principal) HasRole(roles ...string) bool {
+	for _, have := range p.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Allows indica si el principal puede hacer action ("read" o "write") sobre
+// resource. Los JWT no traen permisos y quedan limitados solo por sus roles.
+func (p *Principal) Allows(resource, action string) bool {
+	if p.APIKeyID == 0 {
+		return true
+	}
+	return slices.Contains(p.Permissions, resource+":"+action) ||
+		slices.Contains(p.Permissions, resource+":*")
+}
+
+/*
+# Authenticator valida una credencial y devuelve su Principal
+* lo implementa el módulo users para los JWT
+*/
+type Authenticator interface {
+	Authenticate(token string) (*Principal, error)
+}
+
+/*
+# KeyAuthenticator valida una clave de API enviada desde ip
+* lo implementa el módulo apikeys
+*/
+type KeyAuthenticator interface {
+	AuthenticateKey(ctx context.Context, key, ip string) (*Principal, error)
+}
+
+/*
+# Auth exige un encabezado "Authorization: Bearer <token>" o "X-API-Key" válido
+* deja el Principal en el contexto para los siguientes handlers
+* rechaza los tokens restringidos a un scope
+* a las claves de API les exige el permiso del recurso de la ruta
+*/
+func Auth(authn Authenticator, keys KeyAuthenticator) gin.HandlerFunc {
+	jwtAuth := AuthWithScopes(authn)
+
+	return func(c *gin.Context) {
+		key := c.GetHeader("X-API-Key")
+		if key == "" || keys == nil {
+			jwtAuth(c)
+			return
+		}
+
+		principal, err := keys.AuthenticateKey(c.Request.Context(), key, c.ClientIP())
+		if err != nil {
+			api.Abort(c, err)
+			return
+		}
+
+		resource, action := RoutePermission(c)
+		if !principal.Allows(resource, action) {
+			api.Abort(c, domain.Forbidden("api_key_permission_denied",
+				"la clave de API no tiene el permiso %s:%s", resource, action))
+			return
+		}
+
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
+
+/*
+# OptionalAuth autentica solo si la solicitud trae credenciales
+* sin Authorization ni X-API-Key deja pasar sin Principal
+* con credenciales se comporta como Auth: una credencial inválida se rechaza
+*/
+func OptionalAuth(authn Authenticator, keys KeyAuthenticator) gin.HandlerFunc {
+	auth := Auth(authn, keys)
+
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" && c.GetHeader("X-API-Key") == "" {
+			c.Next()
+			return
+		}
+		auth(c)
+	}
+}
+
+// RoutePermission deriva el permiso que exige la ruta: el recurso es el primer
+// segmento después de /api/v1 y la acción es read para GET/HEAD y write para
+// el resto.
+func RoutePermission(c *gin.Context) (string, string) {
+	path := strings.TrimPrefix(c.FullPath(), "/api/v1/")
+	resource, _, _ := strings.Cut(path, "/")
+
+	action := "write"
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		action = "read"
+	}
+	return resource, action
+}
+
+/*
+# AuthWithScopes acepta solo JWT, incluidos los restringidos a alguno de scopes
+*/
+func AuthWithScopes(authn Authenticator, scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		token, found := strings.CutPrefix(header, "Bearer ")
+		if !found || token == "" {
+			api.Abort(c, domain.Unauthorized("missing_credentials", "falta el token de acceso"))
+			return
+		}
+
+		principal, err := authn.Authenticate(token)
+		if err != nil {
+			api.Abort(c, err)
+			return
+		}
+
+		if principal.Scope != "" && !slices.Contains(scopes, principal.Scope) {
+			api.Abort(c, domain.Unauthorized("token_scope_invalid", "el token no sirve para esta operación"))
+			return
+		}
+
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
+
+/*
+# RequireRole deja pasar solo a principals con alguno de los roles
+* debe ir después de Auth
+*/
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := CurrentPrincipal(c)
+		if !ok || !principal.HasRole(roles...) {
+			api.Abort(c, domain.Forbidden("insufficient_permissions", "permisos insuficientes"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+/*
+# RequireSelfOrRole deja pasar si el parámetro de ruta param es el propio
+# id_usuario del principal, o si tiene alguno de los roles
+*/
+func RequireSelfOrRole(param string, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param(param), 10, 32)
+		if err != nil {
+			api.Abort(c, domain.InvalidID(param))
+			return
+		}
+
+		if !CanActOn(c, uint(id), roles...) {
+			api.Abort(c, domain.Forbidden("insufficient_permissions", "permisos insuficientes"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// CanActOn indica si el principal es el usuario userID o tiene alguno de los
+// roles. Sirve a los handlers que reciben el id en el body.
+func CanActOn(c *gin.Context, userID uint, roles ...string) bool {
+	principal, ok := CurrentPrincipal(c)
+	if !ok {
+		return false
+	}
+	return principal.UserID == userID || principal.HasRole(roles...)
+}
+
+func CurrentPrincipal(c *gin.Context) (*Principal, bool) {
+	value, exists := c.Get(principalKey)
+	if !exists {
+		return nil, false
+	}
+	principal, ok := value.(*Principal)
+	return principal, ok
+}
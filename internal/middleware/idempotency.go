@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	api "github.com/mordmora/expirapp/internal/platform/http"
+	"github.com/mordmora/expirapp/internal/platform/idempotency"
+)
+
+// IdempotencyKeyHeader es el encabezado con el que el cliente marca sus reintentos
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+/*
+# Idempotency hace seguros los reintentos de una ruta que crea efectos
+* sin Idempotency-Key la solicitud pasa igual que antes
+* la primera solicitud con una clave corre y su respuesta se guarda; un
+* reintento idéntico (misma ruta y body) recibe esa respuesta sin volver a
+* ejecutar nada, con Idempotent-Replayed: true
+* la misma clave con otro body es un 422; si la primera sigue en curso, 409
+* las claves son de cada usuario, así que la ruta tiene que pasar antes por
+* Auth: una clave sin Principal se rechaza con 401 en vez de compartirse
+* entre clientes anónimos
+* si la primera termina en 5xx (o el cliente se desconecta) la clave se
+* libera para que el reintento se ejecute de nuevo
+*/
+func Idempotency(store *idempotency.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if !validIdempotencyKey(key) {
+			api.Abort(c, domain.Validation("invalid_idempotency_key",
+				"Idempotency-Key debe tener entre 1 y 255 caracteres imprimibles"))
+			return
+		}
+
+		scope, ok := idempotencyScope(c)
+		if !ok {
+			api.Abort(c, domain.Unauthorized("idempotency_requires_auth",
+				"Idempotency-Key solo se acepta en solicitudes autenticadas"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			api.Abort(c, err)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.Path, body)
+
+		record, acquired, err := store.Begin(c.Request.Context(), scope, key, fingerprint)
+		if err != nil {
+			api.Abort(c, err)
+			return
+		}
+		if !acquired {
+			replay(c, record, fingerprint)
+			return
+		}
+
+		writer := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		// el efecto ya ocurrió: guardarlo no depende de que el cliente siga ahí
+		ctx := context.WithoutCancel(c.Request.Context())
+		status := writer.Status()
+		if status >= http.StatusInternalServerError || status == api.StatusClientClosedRequest {
+			if err := store.Release(ctx, scope, key); err != nil {
+				slog.ErrorContext(ctx, "could not release idempotency key", "error", err)
+			}
+			return
+		}
+
+		err = store.Complete(ctx, scope, key, status, writer.Header().Get("Content-Type"), writer.body.Bytes())
+		if err != nil {
+			slog.ErrorContext(ctx, "could not save idempotent response", "error", err)
+		}
+	}
+}
+
+func replay(c *gin.Context, record *idempotency.Record, fingerprint string) {
+	switch {
+	case record.Fingerprint != fingerprint:
+		api.Abort(c, domain.Unprocessable("idempotency_key_reused",
+			"la clave de idempotencia ya se usó con otra solicitud"))
+	case record.Status != idempotency.StatusCompleted:
+		api.Abort(c, domain.Conflict("idempotency_request_in_progress",
+			"una solicitud con esta clave de idempotencia todavía se está procesando"))
+	default:
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(record.StatusCode, record.ContentType, record.Body)
+		c.Abort()
+	}
+}
+
+// idempotencyScope separa las claves por ruta y por usuario; sin Principal
+// no hay a quién atribuirlas
+func idempotencyScope(c *gin.Context) (string, bool) {
+	principal, ok := CurrentPrincipal(c)
+	if !ok {
+		return "", false
+	}
+	return c.Request.Method + " " + c.FullPath() + " user:" + strconv.FormatUint(uint64(principal.UserID), 10), true
+}
+
+func requestFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func validIdempotencyKey(key string) bool {
+	if len(key) > 255 {
+		return false
+	}
+	for _, r := range key {
+		if r < 0x20 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// capturingWriter copia el body de la respuesta mientras se escribe
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
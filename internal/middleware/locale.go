@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/platform/i18n"
+)
+
+/*
+# Locale elige el idioma de la respuesta según Accept-Language
+* sin encabezado o sin un idioma soportado se usa def
+* el idioma queda en el contexto de la solicitud y sale en Content-Language
+*/
+func Locale(def string) gin.HandlerFunc {
+	if !i18n.Supported(def) {
+		slog.Warn("unsupported default language, using spanish", "language", def)
+		def = i18n.Spanish
+	}
+
+	return func(c *gin.Context) {
+		lang := i18n.Match(c.GetHeader("Accept-Language"), def)
+
+		c.Header("Content-Language", lang)
+		c.Header("Vary", "Accept-Language")
+		c.Request = c.Request.WithContext(i18n.WithLanguage(c.Request.Context(), lang))
+		c.Next()
+	}
+}
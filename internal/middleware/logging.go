@@ -1 +1,116 @@
 package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	api "github.com/mordmora/expirapp/internal/platform/http"
+	"github.com/mordmora/expirapp/internal/platform/logger"
+)
+
+// RequestIDHeader es el encabezado con el que entra y sale el id de solicitud
+const RequestIDHeader = "X-Request-ID"
+
+/*
+# RequestID asigna un id a cada solicitud
+* respeta el X-Request-ID entrante si es razonable (p. ej. del balanceador)
+* lo devuelve en la respuesta y lo deja en el contexto de la solicitud
+*/
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+/*
+# AccessLog registra cada solicitud al terminar
+* 5xx en error, 4xx en warn y el resto en info; /health va en debug
+* user_id sale del Principal que dejó Auth, si la ruta lo exige
+*/
+func AccessLog(l *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		attrs := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", route,
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"ip", c.ClientIP(),
+			"bytes", c.Writer.Size(),
+			"user_agent", c.Request.UserAgent(),
+		}
+		if principal, ok := CurrentPrincipal(c); ok {
+			attrs = append(attrs, "user_id", principal.UserID)
+			if principal.APIKeyID != 0 {
+				attrs = append(attrs, "api_key_id", principal.APIKeyID)
+			}
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		case route == "/health":
+			level = slog.LevelDebug
+		}
+
+		l.Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// Recovery responde 500 ante un panic y lo deja en el log con su stack
+func Recovery(l *slog.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, err any) {
+		l.ErrorContext(c.Request.Context(), "panic recovered",
+			"error", err,
+			"path", c.Request.URL.Path,
+			"stack", string(debug.Stack()),
+		)
+		api.Abort(c, errors.New("panic recovered"))
+	})
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/platform/metrics"
+)
+
+/*
+# Metrics cuenta cada solicitud y su latencia en Prometheus
+* la ruta es la plantilla de gin ("/api/v1/orders/:id"), no la URL, para
+* no crear una serie por id; lo que no coincide con ninguna ruta va junto
+*/
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		labels := []string{c.Request.Method, route, strconv.Itoa(c.Writer.Status())}
+
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	api "github.com/mordmora/expirapp/internal/platform/http"
+	"github.com/mordmora/expirapp/internal/platform/ratelimit"
+)
+
+/*
+# RateLimit limita las solicitudes a las rutas de la política name
+* cuenta por IP y, si la ruta ya pasó por Auth, también por usuario; la IP
+* es c.ClientIP(), que solo cree X-Forwarded-For si viene de un proxy de
+* confianza (ver server.newEngine), así cambiar el encabezado no da otro
+* bucket
+* una política con PerUser tiene que ir después de Auth u OptionalAuth en
+* la cadena, porque sin Principal ese límite no se aplica
+* las rutas con el mismo name comparten los buckets
+* siempre responde los encabezados RateLimit-* del bucket más justo; al
+* pasarse responde 429 con Retry-After
+* si el almacén falla la solicitud pasa: el límite protege, no es
+* motivo para tumbar el API
+*/
+func RateLimit(store ratelimit.Store, name string, policy ratelimit.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		checks := make([]rateLimitCheck, 0, 2)
+		if policy.PerIP.Enabled() {
+			checks = append(checks, rateLimitCheck{name + ":ip:" + c.ClientIP(), policy.PerIP})
+		}
+		if principal, ok := CurrentPrincipal(c); ok && policy.PerUser.Enabled() {
+			key := name + ":user:" + strconv.FormatUint(uint64(principal.UserID), 10)
+			checks = append(checks, rateLimitCheck{key, policy.PerUser})
+		}
+
+		var tightest *ratelimit.Result
+		for _, check := range checks {
+			result, err := store.Take(c.Request.Context(), check.key, check.limit)
+			if err != nil {
+				slog.ErrorContext(c.Request.Context(), "rate limit store failed", "key", check.key, "error", err)
+				continue
+			}
+
+			if tightest == nil || !result.Allowed || result.Remaining < tightest.Remaining {
+				tightest = &result
+			}
+			if !result.Allowed {
+				break
+			}
+		}
+
+		if tightest == nil {
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(tightest.Limit.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(tightest.Reset)))
+		c.Header("RateLimit-Policy", tightest.Limit.String())
+
+		if !tightest.Allowed {
+			api.Abort(c, domain.TooManyRequests("rate_limited", tightest.RetryAfter,
+				"demasiadas solicitudes, intenta de nuevo en %d segundos", ceilSeconds(tightest.RetryAfter)))
+			return
+		}
+		c.Next()
+	}
+}
+
+type rateLimitCheck struct {
+	key   string
+	limit ratelimit.Limit
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
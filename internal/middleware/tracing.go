@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/platform/logger"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+/*
+# Tracing abre un span por solicitud
+* continúa la traza del traceparent entrante, si viene uno
+* el span se llama "MÉTODO ruta" con la plantilla de gin, no la URL
+* solo los 5xx marcan el span como fallido; un 4xx es culpa del cliente
+*/
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracing.Start(ctx, c.Request.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+				semconv.UserAgentOriginal(c.Request.UserAgent()),
+				attribute.String("request_id", logger.RequestID(ctx)),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		} else {
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetName(c.Request.Method + " " + route)
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+
+		if principal, ok := CurrentPrincipal(c); ok {
+			span.SetAttributes(attribute.Int64("user_id", int64(principal.UserID)))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("status %d", status))
+			if len(c.Errors) > 0 {
+				span.RecordError(c.Errors.Last())
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package apikeys
+
+import "time"
+
+/*
+# CreateAPIKeyRequest; body para POST /api-keys
+* IDUsuario es el dueño; la clave hereda sus roles
+* Permisos: "<recurso>:read", "<recurso>:write" o "<recurso>:*"
+* RangosIP: direcciones o bloques CIDR ("10.0.0.0/24"); vacío = cualquiera
+*/
+type CreateAPIKeyRequest struct {
+	IDUsuario uint       `json:"id_usuario" binding:"required"`
+	Nombre    string     `json:"nombre" binding:"required,min=2,max=100"`
+	Permisos  []string   `json:"permisos" binding:"required,min=1"`
+	RangosIP  []string   `json:"rangos_ip"`
+	ExpiraEn  *time.Time `json:"expira_en"`
+}
+
+/*
+# UpdateAPIKeyRequest; body para PUT /api-keys/:id
+* los campos nulos no se modifican
+*/
+type UpdateAPIKeyRequest struct {
+	Nombre   string    `json:"nombre" binding:"omitempty,min=2,max=100"`
+	Permisos *[]string `json:"permisos"`
+	RangosIP *[]string `json:"rangos_ip"`
+}
+
+/*
+# RotateAPIKeyRequest; body opcional para POST /api-keys/:id/rotate
+* la clave anterior sigue válida GracePeriodMinutes minutos (0 = revocada ya)
+*/
+type RotateAPIKeyRequest struct {
+	GracePeriodMinutes int `json:"periodo_gracia_minutos" binding:"min=0,max=10080"`
+}
+
+type APIKeyResponse struct {
+	ID             uint       `json:"id_clave"`
+	IDUsuario      uint       `json:"id_usuario"`
+	Nombre         string     `json:"nombre"`
+	Prefijo        string     `json:"prefijo"`
+	Permisos       []string   `json:"permisos"`
+	RangosIP       []string   `json:"rangos_ip"`
+	Activa         bool       `json:"activa"`
+	ExpiraEn       *time.Time `json:"expira_en,omitempty"`
+	RevocadaEn     *time.Time `json:"revocada_en,omitempty"`
+	ReemplazadaPor *uint      `json:"reemplazada_por,omitempty"`
+	UltimoUso      *time.Time `json:"ultimo_uso,omitempty"`
+	UltimaIP       *string    `json:"ultima_ip,omitempty"`
+	CreatedAt      time.Time  `json:"fecha_creacion"`
+}
+
+/*
+# CreatedAPIKeyResponse
+* Clave es el valor completo; solo se muestra al crear o rotar
+*/
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Clave string `json:"clave"`
+}
+
+type APIKeyListResponse struct {
+	Keys  []APIKeyResponse `json:"claves"`
+	Total int64            `json:"total"`
+	Page  int              `json:"pagina"`
+	Limit int              `json:"limite"`
+}
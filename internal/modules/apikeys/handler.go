@@ -0,0 +1,173 @@
+package apikeys
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/middleware"
+	api "github.com/mordmora/expirapp/internal/platform/http"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// CreateAPIKey emite una clave; el valor completo solo se devuelve aquí
+// POST /api/v1/api-keys
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	principal, _ := middleware.CurrentPrincipal(c)
+	key, plain, err := h.service.Create(c.Request.Context(), req, principal.UserID)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Created(c, CreatedAPIKeyResponse{
+		APIKeyResponse: h.service.ToResponse(key),
+		Clave:          plain,
+	}, "api_key_created")
+}
+
+// ListAPIKeys lista las claves, opcionalmente de un usuario
+// GET /api/v1/api-keys?id_usuario=
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	var userID *uint
+	if raw := c.Query("id_usuario"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			api.Error(c, domain.InvalidID("id_usuario"))
+			return
+		}
+		uid := uint(id)
+		userID = &uid
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	keys, total, err := h.service.List(c.Request.Context(), userID, page, limit)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	responses := make([]APIKeyResponse, len(keys))
+	for i, key := range keys {
+		responses[i] = h.service.ToResponse(&key)
+	}
+
+	api.OK(c, APIKeyListResponse{
+		Keys:  responses,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	})
+}
+
+// GetAPIKey obtiene una clave (sin el secreto)
+// GET /api/v1/api-keys/:id
+func (h *Handler) GetAPIKey(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, h.service.ToResponse(key))
+}
+
+// UpdateAPIKey cambia nombre, permisos o rangos IP
+// PUT /api/v1/api-keys/:id
+func (h *Handler) UpdateAPIKey(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	key, err := h.service.Update(c.Request.Context(), id, req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, h.service.ToResponse(key), "api_key_updated")
+}
+
+// RotateAPIKey emite una clave nueva y retira la anterior tras el periodo de gracia
+// POST /api/v1/api-keys/:id/rotate
+func (h *Handler) RotateAPIKey(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var req RotateAPIKeyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.BindError(c, err)
+			return
+		}
+	}
+
+	principal, _ := middleware.CurrentPrincipal(c)
+	key, plain, err := h.service.Rotate(c.Request.Context(), id, req, principal.UserID)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Created(c, CreatedAPIKeyResponse{
+		APIKeyResponse: h.service.ToResponse(key),
+		Clave:          plain,
+	}, "api_key_rotated")
+}
+
+// RevokeAPIKey revoca la clave de inmediato
+// DELETE /api/v1/api-keys/:id
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	principal, _ := middleware.CurrentPrincipal(c)
+	if err := h.service.Revoke(c.Request.Context(), id, principal.UserID); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "api_key_revoked")
+}
+
+func parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return 0, false
+	}
+
+	return uint(id), true
+}
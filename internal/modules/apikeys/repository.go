@@ -0,0 +1,130 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(ctx context.Context, key *domain.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *Repository) FindByID(ctx context.Context, id uint) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.db.WithContext(ctx).First(&key, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("api_key_not_found", "clave de API no encontrada")
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// FindByPrefix trae la clave con los roles del dueño para armar el Principal
+func (r *Repository) FindByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.db.WithContext(ctx).Preload("User.Roles").Where("prefijo = ?", prefix).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("api_key_not_found", "clave de API no encontrada")
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *Repository) Update(ctx context.Context, key *domain.APIKey) error {
+	return r.db.WithContext(ctx).Model(key).Select("nombre", "permisos", "rangos_ip").Updates(key).Error
+}
+
+func (r *Repository) List(ctx context.Context, userID *uint, limit, offset int) ([]domain.APIKey, int64, error) {
+	var keys []domain.APIKey
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.APIKey{})
+	if userID != nil {
+		query = query.Where("id_usuario = ?", *userID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&keys).Error
+	return keys, total, err
+}
+
+func (r *Repository) UserExists(ctx context.Context, userID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.User{}).Where("id_usuario = ?", userID).Count(&count).Error
+	return count > 0, err
+}
+
+// Revoke marca la clave como revocada; devuelve false si ya lo estaba
+func (r *Repository) Revoke(ctx context.Context, id uint, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.APIKey{}).
+		Where("id_clave = ? AND revocada_en IS NULL", id).
+		Update("revocada_en", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+/*
+# Rotate crea la clave nueva y deja la anterior apuntando a ella
+* oldExpiresAt acota la validez de la anterior (periodo de gracia)
+* si la anterior ya fue rotada o revocada no hace nada y devuelve false
+*/
+func (r *Repository) Rotate(ctx context.Context, old *domain.APIKey, replacement *domain.APIKey, oldExpiresAt time.Time) (bool, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(replacement).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&domain.APIKey{}).
+			Where("id_clave = ? AND revocada_en IS NULL AND reemplazada_por IS NULL", old.ID).
+			Updates(map[string]interface{}{
+				"reemplazada_por": replacement.ID,
+				"expira_en":       gorm.Expr("LEAST(expira_en, ?)", oldExpiresAt),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errAlreadyRotated
+		}
+		return nil
+	})
+	if errors.Is(err, errAlreadyRotated) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+var errAlreadyRotated = domain.Conflict("api_key_rotated", "la clave de API ya fue rotada")
+
+// TouchLastUsed registra el último uso sin escribir en cada solicitud: solo
+// actualiza si el registro anterior es más viejo que since
+func (r *Repository) TouchLastUsed(ctx context.Context, id uint, ip string, now, since time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.APIKey{}).
+		Where("id_clave = ? AND (ultimo_uso IS NULL OR ultimo_uso < ?)", id, since).
+		Updates(map[string]interface{}{"ultimo_uso": now, "ultima_ip": ip}).Error
+}
+
+func (r *Repository) CreateSecurityEvent(ctx context.Context, event *domain.SecurityEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
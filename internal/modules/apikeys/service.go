@@ -0,0 +1,402 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/middleware"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+)
+
+/*
+# formato de la clave: exp_<prefijo de 8>_<secreto de 32>
+* el prefijo es público y sirve para buscarla; el hash cubre la clave completa
+*/
+const (
+	keyPrefix       = "exp_"
+	prefixLength    = 8
+	secretLength    = 32
+	lastUsedEvery   = time.Minute
+	permissionRead  = "read"
+	permissionWrite = "write"
+	permissionAll   = "*"
+)
+
+/*
+# Resources son los recursos que una clave puede recibir como permiso
+* son los primeros segmentos de ruta bajo /api/v1 que sirven a integraciones
+* el permiso solo se comprueba donde la ruta pasa por middleware.Auth; las
+* lecturas públicas (catálogo, categorías, reseñas, validar un cupón) no lo
+* piden, y un recurso se agrega aquí solo si sus rutas exigen credenciales
+*/
+var Resources = []string{
+	"catalog",
+	"profiles",
+	"orders",
+	"cart",
+	"promotions",
+	"payments",
+	"purchasing",
+	"reviews",
+	"reports",
+}
+
+var keyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToLower(keyEncoding.EncodeToString(b))[:n], nil
+}
+
+// newKey devuelve la clave en claro y su prefijo
+func newKey() (string, string, error) {
+	prefix, err := randomString(prefixLength)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := randomString(secretLength)
+	if err != nil {
+		return "", "", err
+	}
+	return keyPrefix + prefix + "_" + secret, prefix, nil
+}
+
+func validatePermissions(permissions []string) error {
+	for _, permission := range permissions {
+		resource, action, found := strings.Cut(permission, ":")
+		if !found || !slices.Contains(Resources, resource) {
+			return domain.InvalidField("permisos", "invalid_permission", "permiso inválido %q", permission)
+		}
+		if action != permissionRead && action != permissionWrite && action != permissionAll {
+			return domain.InvalidField("permisos", "invalid_permission", "permiso inválido %q", permission)
+		}
+	}
+	return nil
+}
+
+// normalizeRanges convierte direcciones sueltas en bloques /32 o /128
+func normalizeRanges(ranges []string) ([]string, error) {
+	normalized := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		r = strings.TrimSpace(r)
+		if prefix, err := netip.ParsePrefix(r); err == nil {
+			normalized = append(normalized, prefix.Masked().String())
+			continue
+		}
+		addr, err := netip.ParseAddr(r)
+		if err != nil {
+			return nil, domain.InvalidField("rangos_ip", "invalid_ip_range", "rango de ip inválido %q", r)
+		}
+		normalized = append(normalized, netip.PrefixFrom(addr, addr.BitLen()).String())
+	}
+	return normalized, nil
+}
+
+func ipAllowed(ranges []string, ip string) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, r := range ranges {
+		prefix, err := netip.ParsePrefix(r)
+		if err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) Create(ctx context.Context, req CreateAPIKeyRequest, adminID uint) (_ *domain.APIKey, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "apikeys.Service.Create")
+	defer func() { tracing.End(span, err) }()
+
+	exists, err := s.repo.UserExists(ctx, req.IDUsuario)
+	if err != nil {
+		return nil, "", fmt.Errorf("error checking user: %w", err)
+	}
+	if !exists {
+		return nil, "", domain.NotFound("user_not_found", "usuario no encontrado")
+	}
+
+	if err := validatePermissions(req.Permisos); err != nil {
+		return nil, "", err
+	}
+	ranges, err := normalizeRanges(req.RangosIP)
+	if err != nil {
+		return nil, "", err
+	}
+	if req.ExpiraEn != nil && !req.ExpiraEn.After(time.Now()) {
+		return nil, "", domain.InvalidField("expira_en", "expiration_in_past", "expira_en debe ser una fecha futura")
+	}
+
+	plain, prefix, err := newKey()
+	if err != nil {
+		return nil, "", fmt.Errorf("error generating api key: %w", err)
+	}
+
+	key := &domain.APIKey{
+		IDUsuario: req.IDUsuario,
+		Nombre:    req.Nombre,
+		Prefijo:   prefix,
+		Hash:      hashKey(plain),
+		Permisos:  req.Permisos,
+		RangosIP:  ranges,
+		ExpiraEn:  req.ExpiraEn,
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("error creating api key: %w", err)
+	}
+
+	s.logEvent(ctx, domain.SecurityEventAPIKeyCreated, key,
+		fmt.Sprintf("clave %s creada por el administrador %d", key.Prefijo, adminID))
+
+	return key, plain, nil
+}
+
+func (s *Service) GetByID(ctx context.Context, id uint) (_ *domain.APIKey, err error) {
+	ctx, span := tracing.Start(ctx, "apikeys.Service.GetByID")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindByID(ctx, id)
+}
+
+func (s *Service) Update(ctx context.Context, id uint, req UpdateAPIKeyRequest) (_ *domain.APIKey, err error) {
+	ctx, span := tracing.Start(ctx, "apikeys.Service.Update")
+	defer func() { tracing.End(span, err) }()
+
+	key, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevocadaEn != nil {
+		return nil, domain.Conflict("api_key_revoked", "la clave de API está revocada")
+	}
+
+	if req.Nombre != "" {
+		key.Nombre = req.Nombre
+	}
+	if req.Permisos != nil {
+		if len(*req.Permisos) == 0 {
+			return nil, domain.Required("permisos")
+		}
+		if err := validatePermissions(*req.Permisos); err != nil {
+			return nil, err
+		}
+		key.Permisos = *req.Permisos
+	}
+	if req.RangosIP != nil {
+		ranges, err := normalizeRanges(*req.RangosIP)
+		if err != nil {
+			return nil, err
+		}
+		key.RangosIP = ranges
+	}
+
+	if err := s.repo.Update(ctx, key); err != nil {
+		return nil, fmt.Errorf("error updating api key: %w", err)
+	}
+	return key, nil
+}
+
+func (s *Service) List(ctx context.Context, userID *uint, page, limit int) (_ []domain.APIKey, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "apikeys.Service.List")
+	defer func() { tracing.End(span, err) }()
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+	return s.repo.List(ctx, userID, limit, offset)
+}
+
+/*
+# Rotate emite una clave nueva con el mismo dueño, nombre, permisos y rangos
+* la anterior queda válida durante el periodo de gracia para que la
+* integración alcance a cambiarla sin cortes
+*/
+func (s *Service) Rotate(ctx context.Context, id uint, req RotateAPIKeyRequest, adminID uint) (_ *domain.APIKey, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "apikeys.Service.Rotate")
+	defer func() { tracing.End(span, err) }()
+
+	old, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	if !old.Active(now) || old.ReemplazadaPor != nil {
+		return nil, "", domain.Conflict("api_key_inactive", "la clave de API no está activa")
+	}
+
+	plain, prefix, err := newKey()
+	if err != nil {
+		return nil, "", fmt.Errorf("error generating api key: %w", err)
+	}
+
+	replacement := &domain.APIKey{
+		IDUsuario: old.IDUsuario,
+		Nombre:    old.Nombre,
+		Prefijo:   prefix,
+		Hash:      hashKey(plain),
+		Permisos:  old.Permisos,
+		RangosIP:  old.RangosIP,
+		ExpiraEn:  old.ExpiraEn,
+	}
+
+	grace := time.Duration(req.GracePeriodMinutes) * time.Minute
+	rotated, err := s.repo.Rotate(ctx, old, replacement, now.Add(grace))
+	if err != nil {
+		return nil, "", fmt.Errorf("error rotating api key: %w", err)
+	}
+	if !rotated {
+		return nil, "", domain.Conflict("api_key_inactive", "la clave de API no está activa")
+	}
+
+	s.logEvent(ctx, domain.SecurityEventAPIKeyRotated, replacement,
+		fmt.Sprintf("clave %s reemplaza a %s (gracia %s), rotada por el administrador %d",
+			replacement.Prefijo, old.Prefijo, grace, adminID))
+
+	return replacement, plain, nil
+}
+
+func (s *Service) Revoke(ctx context.Context, id, adminID uint) (err error) {
+	ctx, span := tracing.Start(ctx, "apikeys.Service.Revoke")
+	defer func() { tracing.End(span, err) }()
+
+	key, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := s.repo.Revoke(ctx, id, time.Now())
+	if err != nil {
+		return fmt.Errorf("error revoking api key: %w", err)
+	}
+	if !revoked {
+		return domain.Conflict("api_key_revoked", "la clave de API ya está revocada")
+	}
+
+	s.logEvent(ctx, domain.SecurityEventAPIKeyRevoked, key,
+		fmt.Sprintf("clave %s revocada por el administrador %d", key.Prefijo, adminID))
+	return nil
+}
+
+// AuthenticateKey implementa middleware.KeyAuthenticator. Los errores son
+// genéricos para no revelar si el prefijo existe.
+func (s *Service) AuthenticateKey(ctx context.Context, plain, ip string) (_ *middleware.Principal, err error) {
+	ctx, span := tracing.Start(ctx, "apikeys.Service.AuthenticateKey")
+	defer func() { tracing.End(span, err) }()
+
+	invalid := domain.Unauthorized("invalid_api_key", "clave de API inválida")
+
+	rest, found := strings.CutPrefix(plain, keyPrefix)
+	if !found {
+		return nil, invalid
+	}
+	prefix, _, found := strings.Cut(rest, "_")
+	if !found || len(prefix) != prefixLength {
+		return nil, invalid
+	}
+
+	key, err := s.repo.FindByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, invalid
+	}
+	if subtle.ConstantTimeCompare([]byte(hashKey(plain)), []byte(key.Hash)) != 1 {
+		return nil, invalid
+	}
+
+	now := time.Now()
+	if !key.Active(now) {
+		return nil, domain.Unauthorized("api_key_inactive", "la clave de API venció o fue revocada")
+	}
+	if !ipAllowed(key.RangosIP, ip) {
+		return nil, domain.Forbidden("api_key_ip_denied", "la clave de API no está permitida desde esta ip")
+	}
+	if key.User.AnonimizadoEn != nil {
+		return nil, invalid
+	}
+
+	if err := s.repo.TouchLastUsed(ctx, key.ID, ip, now, now.Add(-lastUsedEvery)); err != nil {
+		slog.ErrorContext(ctx, "could not record api key use", "prefix", key.Prefijo, "error", err)
+	}
+
+	roles := make([]string, len(key.User.Roles))
+	for i, role := range key.User.Roles {
+		roles[i] = role.RoleName
+	}
+
+	return &middleware.Principal{
+		UserID:      key.IDUsuario,
+		Roles:       roles,
+		APIKeyID:    key.ID,
+		Permissions: key.Permisos,
+	}, nil
+}
+
+func (s *Service) logEvent(ctx context.Context, tipo domain.SecurityEventType, key *domain.APIKey, detail string) {
+	ctx = context.WithoutCancel(ctx)
+	event := &domain.SecurityEvent{
+		Tipo:      tipo,
+		IDUsuario: &key.IDUsuario,
+		Detalle:   detail,
+	}
+
+	if err := s.repo.CreateSecurityEvent(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "could not save security event", "type", tipo, "error", err)
+	}
+}
+
+func (s *Service) ToResponse(key *domain.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:             key.ID,
+		IDUsuario:      key.IDUsuario,
+		Nombre:         key.Nombre,
+		Prefijo:        key.Prefijo,
+		Permisos:       key.Permisos,
+		RangosIP:       key.RangosIP,
+		Activa:         key.Active(time.Now()),
+		ExpiraEn:       key.ExpiraEn,
+		RevocadaEn:     key.RevocadaEn,
+		ReemplazadaPor: key.ReemplazadaPor,
+		UltimoUso:      key.UltimoUso,
+		UltimaIP:       key.UltimaIP,
+		CreatedAt:      key.CreatedAt,
+	}
+}
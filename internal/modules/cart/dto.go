@@ -0,0 +1,49 @@
+package cart
+
+import (
+	"time"
+
+	"github.com/mordmora/expirapp/internal/modules/promotions"
+)
+
+type AddCartItemRequest struct {
+	IDProducto uint `json:"id_producto" binding:"required"`
+	Cantidad   int  `json:"cantidad" binding:"required,min=1"`
+}
+
+type UpdateCartItemRequest struct {
+	Cantidad int `json:"cantidad" binding:"required,min=1"`
+}
+
+type ApplyCouponRequest struct {
+	CodigoCupon string `json:"codigo_cupon" binding:"required,min=3,max=50"`
+}
+
+type CheckoutRequest struct {
+	IDVendedor *uint `json:"id_vendedor" binding:"omitempty"`
+}
+
+type CartItemResponse struct {
+	IDDetalle      uint                          `json:"id_detalle_carrito"`
+	IDProducto     uint                          `json:"id_producto"`
+	NombreProducto string                        `json:"nombre_producto"`
+	Cantidad       int                           `json:"cantidad"`
+	PrecioUnitario float64                       `json:"precio_unitario"`
+	Descuento      float64                       `json:"descuento"`
+	Descuentos     []promotions.DiscountResponse `json:"descuentos"`
+	Subtotal       float64                       `json:"subtotal"`
+	Reservado      bool                          `json:"reservado"`
+	ReservaExpira  *time.Time                    `json:"reserva_expira,omitempty"`
+}
+
+type CartResponse struct {
+	IDCarrito     uint               `json:"id_carrito"`
+	IDCliente     uint               `json:"id_cliente"`
+	Items         []CartItemResponse `json:"items"`
+	CodigoCupon   *string            `json:"codigo_cupon,omitempty"`
+	CuponError    string             `json:"cupon_error,omitempty"`
+	Subtotal      float64            `json:"subtotal"`
+	Descuento     float64            `json:"descuento"`
+	Total         float64            `json:"total"`
+	ActualizadoEn time.Time          `json:"actualizado_en"`
+}
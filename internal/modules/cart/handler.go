@@ -0,0 +1,215 @@
+package cart
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/modules/orders"
+	api "github.com/mordmora/expirapp/internal/platform/http"
+)
+
+type Handler struct {
+	service      *Service
+	orderService *orders.Service
+}
+
+func NewHandler(service *Service, orderService *orders.Service) *Handler {
+	return &Handler{service: service, orderService: orderService}
+}
+
+// GetCart obtiene el carrito del cliente con precios vigentes
+// GET /api/v1/cart/:clientId
+func (h *Handler) GetCart(c *gin.Context) {
+	clientID, ok := parseClientID(c)
+	if !ok {
+		return
+	}
+
+	cart, err := h.service.GetByClient(c.Request.Context(), clientID)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	h.respondCart(c, http.StatusOK, cart, "")
+}
+
+// AddItem agrega un producto al carrito y reserva su stock
+// POST /api/v1/cart/:clientId/items
+func (h *Handler) AddItem(c *gin.Context) {
+	clientID, ok := parseClientID(c)
+	if !ok {
+		return
+	}
+
+	var req AddCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	cart, err := h.service.AddItem(c.Request.Context(), clientID, req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	h.respondCart(c, http.StatusCreated, cart, "cart_item_added")
+}
+
+// UpdateItem cambia la cantidad de una línea del carrito
+// PUT /api/v1/cart/:clientId/items/:itemId
+func (h *Handler) UpdateItem(c *gin.Context) {
+	clientID, ok := parseClientID(c)
+	if !ok {
+		return
+	}
+
+	itemID, err := strconv.ParseUint(c.Param("itemId"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("itemId"))
+		return
+	}
+
+	var req UpdateCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	cart, err := h.service.UpdateItem(c.Request.Context(), clientID, uint(itemID), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	h.respondCart(c, http.StatusOK, cart, "cart_item_updated")
+}
+
+// RemoveItem quita una línea del carrito y libera su reserva
+// DELETE /api/v1/cart/:clientId/items/:itemId
+func (h *Handler) RemoveItem(c *gin.Context) {
+	clientID, ok := parseClientID(c)
+	if !ok {
+		return
+	}
+
+	itemID, err := strconv.ParseUint(c.Param("itemId"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("itemId"))
+		return
+	}
+
+	cart, err := h.service.RemoveItem(c.Request.Context(), clientID, uint(itemID))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	h.respondCart(c, http.StatusOK, cart, "cart_item_removed")
+}
+
+// ClearCart vacía el carrito
+// DELETE /api/v1/cart/:clientId
+func (h *Handler) ClearCart(c *gin.Context) {
+	clientID, ok := parseClientID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Clear(c.Request.Context(), clientID); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "cart_cleared")
+}
+
+// ApplyCoupon guarda un cupón en el carrito
+// PUT /api/v1/cart/:clientId/coupon
+func (h *Handler) ApplyCoupon(c *gin.Context) {
+	clientID, ok := parseClientID(c)
+	if !ok {
+		return
+	}
+
+	var req ApplyCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	cart, err := h.service.ApplyCoupon(c.Request.Context(), clientID, req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	h.respondCart(c, http.StatusOK, cart, "coupon_applied")
+}
+
+// RemoveCoupon quita el cupón del carrito
+// DELETE /api/v1/cart/:clientId/coupon
+func (h *Handler) RemoveCoupon(c *gin.Context) {
+	clientID, ok := parseClientID(c)
+	if !ok {
+		return
+	}
+
+	cart, err := h.service.RemoveCoupon(c.Request.Context(), clientID)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	h.respondCart(c, http.StatusOK, cart, "coupon_removed")
+}
+
+// Checkout convierte el carrito en una orden
+// POST /api/v1/cart/:clientId/checkout
+func (h *Handler) Checkout(c *gin.Context) {
+	clientID, ok := parseClientID(c)
+	if !ok {
+		return
+	}
+
+	var req CheckoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.BindError(c, err)
+			return
+		}
+	}
+
+	order, err := h.service.Checkout(c.Request.Context(), clientID, req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Created(c, h.orderService.ToOrderResponse(order), "order_created")
+}
+
+// respondCart cotiza el carrito con las promociones vigentes y escribe la
+// respuesta.
+func (h *Handler) respondCart(c *gin.Context, statusCode int, cart *domain.Cart, message string) {
+	response, err := h.service.ToResponse(c.Request.Context(), cart)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, statusCode, response, message)
+}
+
+func parseClientID(c *gin.Context) (uint, bool) {
+	clientID, err := strconv.ParseUint(c.Param("clientId"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("clientId"))
+		return 0, false
+	}
+
+	return uint(clientID), true
+}
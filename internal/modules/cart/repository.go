@@ -0,0 +1,158 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) FindByClientID(ctx context.Context, clientID uint) (*domain.Cart, error) {
+	var cart domain.Cart
+	err := r.db.WithContext(ctx).Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id_detalle_carrito ASC")
+	}).Preload("Items.Product").Preload("Items.Reservation").
+		Where("id_cliente = ?", clientID).First(&cart).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("cart_not_found", "carrito no encontrado")
+		}
+		return nil, err
+	}
+
+	return &cart, nil
+}
+
+// FindOrCreateByClientID devuelve el carrito del cliente, creándolo vacío si
+// todavía no tiene uno.
+func (r *Repository) FindOrCreateByClientID(ctx context.Context, clientID uint) (*domain.Cart, error) {
+	cart := domain.Cart{IDCliente: clientID}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&cart).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindByClientID(ctx, clientID)
+}
+
+func (r *Repository) FindItemByID(ctx context.Context, id uint) (*domain.CartItem, error) {
+	var item domain.CartItem
+	err := r.db.WithContext(ctx).Preload("Product").Preload("Reservation").First(&item, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("cart_item_not_found", "item del carrito no encontrado")
+		}
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+func (r *Repository) FindItemByProduct(ctx context.Context, cartID, productID uint) (*domain.CartItem, error) {
+	var item domain.CartItem
+	err := r.db.WithContext(ctx).Where("id_carrito = ? AND id_producto = ?", cartID, productID).First(&item).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("cart_item_not_found", "item del carrito no encontrado")
+		}
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+func (r *Repository) DeleteItem(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&domain.CartItem{}, id).Error
+}
+
+func (r *Repository) DeleteItemsByCartID(ctx context.Context, cartID uint) error {
+	return r.db.WithContext(ctx).Where("id_carrito = ?", cartID).Delete(&domain.CartItem{}).Error
+}
+
+func (r *Repository) SetCouponCode(ctx context.Context, cartID uint, code *string) error {
+	return r.db.WithContext(ctx).Model(&domain.Cart{}).Where("id_carrito = ?", cartID).Update("codigo_cupon", code).Error
+}
+
+func (r *Repository) Touch(ctx context.Context, cartID uint) error {
+	return r.db.WithContext(ctx).Model(&domain.Cart{}).Where("id_carrito = ?", cartID).Update("updated_at", time.Now()).Error
+}
+
+// SaveItemWithReservation guarda la línea del carrito y aparta su cantidad
+// hasta expiresAt. El producto se bloquea (SELECT ... FOR UPDATE) mientras se
+// calcula el disponible para que dos carritos no reserven las mismas unidades.
+func (r *Repository) SaveItemWithReservation(ctx context.Context, item *domain.CartItem, clientID uint, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var product domain.Product
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, item.IDProducto).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.NotFound("product_not_found", "producto con id %d no encontrado", item.IDProducto)
+			}
+			return err
+		}
+
+		reserved, err := reservedQuantity(tx, item.IDProducto, clientID)
+		if err != nil {
+			return err
+		}
+
+		available := product.Stock - reserved
+		if available < item.Cantidad {
+			return domain.InsufficientStock(&product, available, item.Cantidad)
+		}
+
+		if err := tx.Omit(clause.Associations).Save(item).Error; err != nil {
+			return err
+		}
+
+		reservation := domain.StockReservation{
+			IDDetalleCarrito: item.ID,
+			IDProducto:       item.IDProducto,
+			Cantidad:         item.Cantidad,
+			ExpiraEn:         expiresAt,
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id_detalle_carrito"}},
+			DoUpdates: clause.AssignmentColumns([]string{"cantidad", "expira_en"}),
+		}).Create(&reservation).Error
+	})
+}
+
+// ReservedQuantityTx devuelve las unidades del producto apartadas por carritos
+// vigentes de otros clientes, dentro de la transacción de la orden que las
+// descuenta. Implementa orders.StockReservations.
+func (r *Repository) ReservedQuantityTx(tx *gorm.DB, productID, excludeClientID uint) (int, error) {
+	return reservedQuantity(tx, productID, excludeClientID)
+}
+
+func reservedQuantity(db *gorm.DB, productID, excludeClientID uint) (int, error) {
+	var reserved int
+	query := `
+		SELECT COALESCE(SUM(r.cantidad), 0)
+		FROM reserva_stock r
+		JOIN detalle_carrito d ON d.id_detalle_carrito = r.id_detalle_carrito
+		JOIN carrito c ON c.id_carrito = d.id_carrito
+		WHERE r.id_producto = ?
+			AND r.expira_en > now()
+			AND c.id_cliente <> ?`
+
+	err := db.Raw(query, productID, excludeClientID).Scan(&reserved).Error
+	return reserved, err
+}
+
+func (r *Repository) DeleteExpiredReservations(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expira_en <= ?", now).Delete(&domain.StockReservation{})
+	return result.RowsAffected, result.Error
+}
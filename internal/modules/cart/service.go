@@ -0,0 +1,315 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/modules/orders"
+	"github.com/mordmora/expirapp/internal/modules/promotions"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+)
+
+type Service struct {
+	repo           *Repository
+	orderService   *orders.Service
+	pricing        orders.Pricing
+	promotions     *promotions.Service
+	reservationTTL time.Duration
+}
+
+func NewService(repo *Repository, orderService *orders.Service, pricing orders.Pricing, promotionsService *promotions.Service, reservationTTL time.Duration) *Service {
+	return &Service{
+		repo:           repo,
+		orderService:   orderService,
+		pricing:        pricing,
+		promotions:     promotionsService,
+		reservationTTL: reservationTTL,
+	}
+}
+
+func (s *Service) GetByClient(ctx context.Context, clientID uint) (_ *domain.Cart, err error) {
+	ctx, span := tracing.Start(ctx, "cart.Service.GetByClient")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindOrCreateByClientID(ctx, clientID)
+}
+
+// AddItem agrega un producto al carrito. Si el producto ya está en el carrito
+// se suma la cantidad a la línea existente y se renueva su reserva.
+func (s *Service) AddItem(ctx context.Context, clientID uint, req AddCartItemRequest) (_ *domain.Cart, err error) {
+	ctx, span := tracing.Start(ctx, "cart.Service.AddItem")
+	defer func() { tracing.End(span, err) }()
+
+	cart, err := s.repo.FindOrCreateByClientID(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo carrito: %w", err)
+	}
+
+	item, err := s.repo.FindItemByProduct(ctx, cart.ID, req.IDProducto)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			return nil, err
+		}
+		item = &domain.CartItem{
+			IDCarrito:  cart.ID,
+			IDProducto: req.IDProducto,
+		}
+	}
+
+	item.Cantidad += req.Cantidad
+
+	if err := s.repo.SaveItemWithReservation(ctx, item, clientID, s.reservationExpiry()); err != nil {
+		return nil, err
+	}
+
+	return s.touchAndReload(ctx, cart.ID, clientID)
+}
+
+func (s *Service) UpdateItem(ctx context.Context, clientID, itemID uint, req UpdateCartItemRequest) (_ *domain.Cart, err error) {
+	ctx, span := tracing.Start(ctx, "cart.Service.UpdateItem")
+	defer func() { tracing.End(span, err) }()
+
+	cart, item, err := s.findOwnedItem(ctx, clientID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	item.Cantidad = req.Cantidad
+
+	if err := s.repo.SaveItemWithReservation(ctx, item, clientID, s.reservationExpiry()); err != nil {
+		return nil, err
+	}
+
+	return s.touchAndReload(ctx, cart.ID, clientID)
+}
+
+func (s *Service) RemoveItem(ctx context.Context, clientID, itemID uint) (_ *domain.Cart, err error) {
+	ctx, span := tracing.Start(ctx, "cart.Service.RemoveItem")
+	defer func() { tracing.End(span, err) }()
+
+	cart, _, err := s.findOwnedItem(ctx, clientID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.DeleteItem(ctx, itemID); err != nil {
+		return nil, fmt.Errorf("error eliminando item del carrito: %w", err)
+	}
+
+	return s.touchAndReload(ctx, cart.ID, clientID)
+}
+
+func (s *Service) Clear(ctx context.Context, clientID uint) (err error) {
+	ctx, span := tracing.Start(ctx, "cart.Service.Clear")
+	defer func() { tracing.End(span, err) }()
+
+	cart, err := s.repo.FindByClientID(ctx, clientID)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.DeleteItemsByCartID(ctx, cart.ID)
+}
+
+// Checkout convierte el carrito en una orden con los precios vigentes de los
+// productos. Las reservas del propio cliente no cuentan contra su orden, por
+// lo que la compra no falla por unidades que él mismo tenía apartadas.
+func (s *Service) Checkout(ctx context.Context, clientID uint, req CheckoutRequest) (_ *domain.Order, err error) {
+	ctx, span := tracing.Start(ctx, "cart.Service.Checkout")
+	defer func() { tracing.End(span, err) }()
+
+	cart, err := s.repo.FindByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cart.Items) == 0 {
+		return nil, domain.Validation("cart_empty", "el carrito está vacío")
+	}
+
+	orderReq := orders.CreateOrderRequest{
+		IDCliente:  clientID,
+		IDVendedor: req.IDVendedor,
+		Items:      make([]orders.OrderItemRequest, len(cart.Items)),
+	}
+
+	if cart.CodigoCupon != nil {
+		orderReq.CodigoCupon = *cart.CodigoCupon
+	}
+
+	for i, item := range cart.Items {
+		orderReq.Items[i] = orders.OrderItemRequest{
+			IDProducto: item.IDProducto,
+			Cantidad:   item.Cantidad,
+		}
+	}
+
+	order, err := s.orderService.Create(ctx, orderReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.DeleteItemsByCartID(ctx, cart.ID); err != nil {
+		return nil, fmt.Errorf("orden %d creada pero no se pudo vaciar el carrito: %w", order.ID, err)
+	}
+
+	if err := s.repo.SetCouponCode(ctx, cart.ID, nil); err != nil {
+		return nil, fmt.Errorf("orden %d creada pero no se pudo quitar el cupón del carrito: %w", order.ID, err)
+	}
+
+	return order, nil
+}
+
+// ApplyCoupon guarda el cupón en el carrito si el cliente puede usarlo.
+func (s *Service) ApplyCoupon(ctx context.Context, clientID uint, req ApplyCouponRequest) (_ *domain.Cart, err error) {
+	ctx, span := tracing.Start(ctx, "cart.Service.ApplyCoupon")
+	defer func() { tracing.End(span, err) }()
+
+	cart, err := s.repo.FindOrCreateByClientID(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo carrito: %w", err)
+	}
+
+	coupon, err := s.promotions.ValidateCoupon(ctx, req.CodigoCupon, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetCouponCode(ctx, cart.ID, &coupon.Codigo); err != nil {
+		return nil, fmt.Errorf("error aplicando cupón: %w", err)
+	}
+
+	return s.touchAndReload(ctx, cart.ID, clientID)
+}
+
+func (s *Service) RemoveCoupon(ctx context.Context, clientID uint) (_ *domain.Cart, err error) {
+	ctx, span := tracing.Start(ctx, "cart.Service.RemoveCoupon")
+	defer func() { tracing.End(span, err) }()
+
+	cart, err := s.repo.FindByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetCouponCode(ctx, cart.ID, nil); err != nil {
+		return nil, fmt.Errorf("error quitando cupón: %w", err)
+	}
+
+	return s.touchAndReload(ctx, cart.ID, clientID)
+}
+
+// ReleaseExpiredReservations elimina las reservas vencidas para devolver sus
+// unidades al stock disponible.
+func (s *Service) ReleaseExpiredReservations(ctx context.Context) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "cart.Service.ReleaseExpiredReservations")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.DeleteExpiredReservations(ctx, time.Now())
+}
+
+func (s *Service) findOwnedItem(ctx context.Context, clientID, itemID uint) (*domain.Cart, *domain.CartItem, error) {
+	cart, err := s.repo.FindByClientID(ctx, clientID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	item, err := s.repo.FindItemByID(ctx, itemID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if item.IDCarrito != cart.ID {
+		return nil, nil, domain.NotFound("cart_item_not_found", "el item no pertenece a este carrito")
+	}
+
+	return cart, item, nil
+}
+
+func (s *Service) touchAndReload(ctx context.Context, cartID, clientID uint) (*domain.Cart, error) {
+	if err := s.repo.Touch(ctx, cartID); err != nil {
+		return nil, fmt.Errorf("error actualizando carrito: %w", err)
+	}
+
+	return s.repo.FindByClientID(ctx, clientID)
+}
+
+func (s *Service) reservationExpiry() time.Time {
+	return time.Now().Add(s.reservationTTL)
+}
+
+// ToResponse calcula los precios con el valor actual de cada producto, las
+// rebajas por vencimiento y las promociones vigentes, como en el checkout.
+// Si el cupón guardado ya no es válido el carrito se cotiza sin él y se
+// informa el motivo en cupon_error.
+func (s *Service) ToResponse(ctx context.Context, cart *domain.Cart) (_ CartResponse, err error) {
+	ctx, span := tracing.Start(ctx, "cart.Service.ToResponse")
+	defer func() { tracing.End(span, err) }()
+
+	lines := make([]promotions.Line, len(cart.Items))
+	for i, item := range cart.Items {
+		price, err := s.pricing.SalePrice(ctx, &item.Product, item.Cantidad)
+		if err != nil {
+			return CartResponse{}, err
+		}
+		lines[i] = promotions.Line{
+			IDProducto:     item.IDProducto,
+			Cantidad:       item.Cantidad,
+			PrecioUnitario: price,
+		}
+	}
+
+	var coupon *domain.Coupon
+	var couponErr string
+	if cart.CodigoCupon != nil {
+		var err error
+		coupon, err = s.promotions.ValidateCoupon(ctx, *cart.CodigoCupon, cart.IDCliente)
+		if err != nil {
+			couponErr = err.Error()
+		}
+	}
+
+	eval, err := s.promotions.EvaluateWithCoupon(ctx, lines, coupon)
+	if err != nil {
+		return CartResponse{}, err
+	}
+
+	now := time.Now()
+	items := make([]CartItemResponse, len(cart.Items))
+
+	for i, item := range cart.Items {
+		line := eval.Lines[i]
+		resp := CartItemResponse{
+			IDDetalle:      item.ID,
+			IDProducto:     item.IDProducto,
+			NombreProducto: item.Product.Nombre,
+			Cantidad:       item.Cantidad,
+			PrecioUnitario: line.PrecioUnitario,
+			Descuento:      line.Descuento,
+			Descuentos:     promotions.ToDiscountResponses(line.Descuentos),
+			Subtotal:       line.Total(),
+		}
+
+		if item.Reservation.Active(now) {
+			expira := item.Reservation.ExpiraEn
+			resp.Reservado = true
+			resp.ReservaExpira = &expira
+		}
+
+		items[i] = resp
+	}
+
+	return CartResponse{
+		IDCarrito:     cart.ID,
+		IDCliente:     cart.IDCliente,
+		Items:         items,
+		CodigoCupon:   cart.CodigoCupon,
+		CuponError:    couponErr,
+		Subtotal:      eval.Subtotal,
+		Descuento:     eval.Descuento,
+		Total:         eval.Total,
+		ActualizadoEn: cart.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package cart
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Sweeper libera periódicamente las reservas de stock vencidas.
+type Sweeper struct {
+	service  *Service
+	interval time.Duration
+}
+
+func NewSweeper(service *Service, interval time.Duration) *Sweeper {
+	return &Sweeper{service: service, interval: interval}
+}
+
+// Run se ejecuta hasta que ctx se cancela.
+func (w *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			released, err := w.service.ReleaseExpiredReservations(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "cart sweeper: error releasing reservations", "error", err)
+				continue
+			}
+			if released > 0 {
+				slog.InfoContext(ctx, "cart sweeper: released expired reservations", "released", released)
+			}
+		}
+	}
+}
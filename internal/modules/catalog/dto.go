@@ -1,21 +1,49 @@
 package catalog
 
-import "time"
+import (
+	"time"
 
+	"github.com/mordmora/expirapp/internal/domain"
+)
+
+/*
+# CreateProductRequest
+* gtin acepta EAN-8, UPC-A, EAN-13 o GTIN-14 con su dígito de control
+* sku es el código interno de la tienda
+* costo es el costo unitario de reposición; lo actualiza cada lote recibido con costo
+*/
 type CreateProductRequest struct {
 	Nombre           string    `json:"nombre" binding:"required,min=1,max=100"`
 	Descripcion      string    `json:"descripcion" binding:"omitempty"`
 	Precio           float64   `json:"precio" binding:"required,min=0"`
+	Costo            *float64  `json:"costo" binding:"omitempty,min=0"`
 	FechaVencimiento time.Time `json:"fecha_vencimiento" binding:"required"`
 	Stock            int       `json:"stock" binding:"omitempty,min=0"`
+	GTIN             string    `json:"gtin" binding:"omitempty"`
+	SKU              string    `json:"sku" binding:"omitempty,max=64"`
 }
 
+// UpdateProductRequest; motivo_precio queda en la historia si cambia el precio
 type UpdateProductRequest struct {
 	Nombre           string    `json:"nombre" binding:"omitempty,min=1,max=100"`
 	Descripcion      string    `json:"descripcion" binding:"omitempty"`
 	Precio           float64   `json:"precio" binding:"omitempty,min=0"`
+	Costo            *float64  `json:"costo" binding:"omitempty,min=0"`
 	FechaVencimiento time.Time `json:"fecha_vencimiento" binding:"omitempty"`
 	Stock            int       `json:"stock" binding:"omitempty,min=0"`
+	GTIN             string    `json:"gtin" binding:"omitempty"`
+	SKU              string    `json:"sku" binding:"omitempty,max=64"`
+	MotivoPrecio     string    `json:"motivo_precio" binding:"omitempty,max=255"`
+}
+
+/*
+# UpdateStockRequest; body de PUT /catalog/products/:id/stock
+* cantidad negativa es una baja; motivo dice por qué (vencimiento, averia,
+* merma o conteo) y sin él se deduce del vencimiento de cada lote
+*/
+type UpdateStockRequest struct {
+	Quantity int    `json:"cantidad" binding:"required"`
+	Motivo   string `json:"motivo" binding:"omitempty,oneof=vencimiento averia merma conteo"`
 }
 
 type ProductResponse struct {
@@ -23,8 +51,16 @@ type ProductResponse struct {
 	Nombre           string    `json:"nombre"`
 	Descripcion      string    `json:"descripcion"`
 	Precio           float64   `json:"precio"`
+	Costo            *float64  `json:"costo,omitempty"`
 	FechaVencimiento time.Time `json:"fecha_vencimiento"`
 	Stock            int       `json:"stock"`
+	GTIN             *string   `json:"gtin,omitempty"`
+	SKU              *string   `json:"sku,omitempty"`
+}
+
+// hideCosts quita el costo para quien no puede verlo (ver canSeeCosts)
+func (r *ProductResponse) hideCosts() {
+	r.Costo = nil
 }
 
 type ProductListResponse struct {
@@ -32,4 +68,207 @@ type ProductListResponse struct {
 	Total    int64             `json:"total"`
 	Page     int               `json:"pagina"`
 	Limit    int               `json:"limite"`
-}
\ No newline at end of file
+}
+
+/*
+# SearchProductsRequest; query de GET /catalog/products/search
+* q busca en nombre y descripción, sin importar tildes ni mayúsculas, y
+* acepta prefijos ("lech" encuentra "leche")
+* vence_desde / vence_hasta en formato AAAA-MM-DD
+* categoria incluye las subcategorías
+* orden: relevancia (por defecto con q), nombre (por defecto sin q),
+* precio, vencimiento; con "-" delante es descendente
+*/
+type SearchProductsRequest struct {
+	Query       string     `form:"q" binding:"omitempty,max=200"`
+	MinPrice    *float64   `form:"precio_min" binding:"omitempty,min=0"`
+	MaxPrice    *float64   `form:"precio_max" binding:"omitempty,min=0"`
+	ExpiresFrom *time.Time `form:"vence_desde" time_format:"2006-01-02"`
+	ExpiresTo   *time.Time `form:"vence_hasta" time_format:"2006-01-02"`
+	InStock     bool       `form:"en_stock"`
+	CategoryID  *uint      `form:"categoria"`
+	Sort        string     `form:"orden" binding:"omitempty,oneof=relevancia precio -precio vencimiento -vencimiento nombre -nombre"`
+	Page        int        `form:"page"`
+	Limit       int        `form:"limit"`
+}
+
+type ProductSearchResult struct {
+	ProductResponse
+	Relevancia float64 `json:"relevancia"`
+}
+
+// FacetCount es cuántos productos de la búsqueda caen en un valor de faceta
+type FacetCount struct {
+	Value string `json:"valor"`
+	Count int64  `json:"cantidad"`
+}
+
+// CategoryFacetCount cuenta los productos del subárbol de una categoría
+type CategoryFacetCount struct {
+	ID     uint   `json:"id_categoria"`
+	Nombre string `json:"nombre"`
+	Slug   string `json:"slug"`
+	Count  int64  `json:"cantidad"`
+}
+
+// SearchFacets; categoria son las hijas de la categoría filtrada, o las raíces
+type SearchFacets struct {
+	Price        []FacetCount         `json:"precio"`
+	Expiration   []FacetCount         `json:"vencimiento"`
+	Availability []FacetCount         `json:"disponibilidad"`
+	Category     []CategoryFacetCount `json:"categoria"`
+}
+
+type ProductSearchResponse struct {
+	Products []ProductSearchResult `json:"productos"`
+	Total    int64                 `json:"total"`
+	Page     int                   `json:"pagina"`
+	Limit    int                   `json:"limite"`
+	Facets   SearchFacets          `json:"facetas"`
+}
+
+func (r *ProductSearchResponse) hideCosts() {
+	for i := range r.Products {
+		r.Products[i].hideCosts()
+	}
+}
+
+// ReceiveLotRequest da entrada a un lote; si el número ya existe se suman
+// las unidades. costo_unitario también pasa a ser el costo del producto
+type ReceiveLotRequest struct {
+	Numero           string    `json:"numero" binding:"required,min=1,max=20"`
+	FechaVencimiento time.Time `json:"fecha_vencimiento" binding:"required"`
+	Cantidad         int       `json:"cantidad" binding:"required,min=1"`
+	CostoUnitario    *float64  `json:"costo_unitario" binding:"omitempty,min=0"`
+}
+
+type LotResponse struct {
+	ID               uint      `json:"id_lote"`
+	Numero           string    `json:"numero"`
+	FechaVencimiento time.Time `json:"fecha_vencimiento"`
+	CantidadRecibida int       `json:"cantidad_recibida"`
+	Cantidad         int       `json:"cantidad"`
+	CostoUnitario    *float64  `json:"costo_unitario,omitempty"`
+	DiasParaVencer   int       `json:"dias_para_vencer"`
+	Estado           string    `json:"estado"`
+}
+
+func (r *LotResponse) hideCosts() {
+	r.CostoUnitario = nil
+}
+
+// ScannedLabel son los datos leídos de una etiqueta GS1-128
+type ScannedLabel struct {
+	GTIN             string     `json:"gtin"`
+	Lote             string     `json:"lote,omitempty"`
+	FechaVencimiento *time.Time `json:"fecha_vencimiento,omitempty"`
+}
+
+/*
+# ScanResponse; lo que ve el personal al escanear
+* lotes son los que tienen unidades, del que vence primero al último
+* vencimiento_proximo y estado son del lote que vence primero, o del
+* producto si no tiene lotes
+* con una etiqueta GS1-128, etiqueta es lo leído y lote_escaneado el lote
+* registrado con ese número (si existe)
+*/
+type ScanResponse struct {
+	Product           ProductResponse `json:"producto"`
+	Lots              []LotResponse   `json:"lotes"`
+	UnlottedStock     int             `json:"stock_sin_lote"`
+	NearestExpiration time.Time       `json:"vencimiento_proximo"`
+	DaysUntilExpiry   int             `json:"dias_para_vencer"`
+	Status            string          `json:"estado"`
+	AlertDays         int             `json:"dias_alerta"`
+	Label             *ScannedLabel   `json:"etiqueta,omitempty"`
+	ScannedLot        *LotResponse    `json:"lote_escaneado,omitempty"`
+}
+
+func (r *ScanResponse) hideCosts() {
+	r.Product.hideCosts()
+	for i := range r.Lots {
+		r.Lots[i].hideCosts()
+	}
+	if r.ScannedLot != nil {
+		r.ScannedLot.hideCosts()
+	}
+}
+
+/*
+# LabelBatchRequest pide las etiquetas de varios productos y lotes
+* formato es pdf (por defecto) o zpl
+* copias se aplica a cada producto y a cada lote
+* precio_cambiado_hoy suma los productos cuyo precio cambió desde las 00:00
+*/
+type LabelBatchRequest struct {
+	Formato           string `json:"formato"`
+	Productos         []uint `json:"productos" binding:"omitempty,dive,min=1"`
+	Lotes             []uint `json:"lotes" binding:"omitempty,dive,min=1"`
+	PrecioCambiadoHoy bool   `json:"precio_cambiado_hoy"`
+	Copias            int    `json:"copias"`
+}
+
+/*
+# ImportResponse es el resultado (o el avance) de una importación
+* id_importacion solo viene en las que se procesan en segundo plano
+* con solo_validar, creados y actualizados son los que se crearían o
+* actualizarían
+* errores trae hasta 1000 problemas, con la fila de la hoja
+*/
+type ImportResponse struct {
+	ID           uint                    `json:"id_importacion,omitempty"`
+	Estado       string                  `json:"estado"`
+	Formato      string                  `json:"formato"`
+	Archivo      string                  `json:"archivo,omitempty"`
+	SoloValidar  bool                    `json:"solo_validar"`
+	Filas        int                     `json:"filas"`
+	Creados      int                     `json:"creados"`
+	Actualizados int                     `json:"actualizados"`
+	ConError     int                     `json:"con_error"`
+	Errores      []domain.ImportRowError `json:"errores"`
+	Mensaje      string                  `json:"mensaje,omitempty"`
+	IniciadaEn   *time.Time              `json:"iniciada_en,omitempty"`
+	TerminadaEn  *time.Time              `json:"terminada_en,omitempty"`
+}
+
+// ChangePriceRequest cambia el precio ya mismo
+type ChangePriceRequest struct {
+	Precio float64 `json:"precio" binding:"required,gt=0"`
+	Motivo string  `json:"motivo" binding:"required,max=255"`
+}
+
+// SchedulePriceRequest programa un cambio de precio; vigente_desde debe ser futura
+type SchedulePriceRequest struct {
+	Precio       float64   `json:"precio" binding:"required,gt=0"`
+	Motivo       string    `json:"motivo" binding:"required,max=255"`
+	VigenteDesde time.Time `json:"vigente_desde" binding:"required"`
+}
+
+// PriceChangeResponse; precio_anterior no viene en el precio de alta
+type PriceChangeResponse struct {
+	ID                 uint      `json:"id_cambio_precio"`
+	PrecioAnterior     *float64  `json:"precio_anterior,omitempty"`
+	Precio             float64   `json:"precio"`
+	VigenteDesde       time.Time `json:"vigente_desde"`
+	Origen             string    `json:"origen"`
+	Motivo             string    `json:"motivo,omitempty"`
+	IDUsuario          *uint     `json:"id_usuario,omitempty"`
+	IDPrecioProgramado *uint     `json:"id_precio_programado,omitempty"`
+}
+
+type ScheduledPriceResponse struct {
+	ID           uint      `json:"id_precio_programado"`
+	Precio       float64   `json:"precio"`
+	VigenteDesde time.Time `json:"vigente_desde"`
+	Motivo       string    `json:"motivo"`
+	Estado       string    `json:"estado"`
+	IDUsuario    *uint     `json:"id_usuario,omitempty"`
+	CanceladoPor *uint     `json:"cancelado_por,omitempty"`
+}
+
+type PriceTimelineResponse struct {
+	IDProducto   uint                     `json:"id_producto"`
+	PrecioActual float64                  `json:"precio_actual"`
+	Historial    []PriceChangeResponse    `json:"historial"`
+	Programados  []ScheduledPriceResponse `json:"programados"`
+}
@@ -1,11 +1,19 @@
 package catalog
 
 import (
+	"fmt"
+	"log/slog"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/middleware"
+	api "github.com/mordmora/expirapp/internal/platform/http"
+	"github.com/mordmora/expirapp/internal/platform/spreadsheet"
 )
 
 type Handler struct {
@@ -22,27 +30,18 @@ func (h *Handler) CreateProduct(c *gin.Context) {
 	var req CreateProductRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid request",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 
-	product, err := h.service.Create(req)
+	product, err := h.service.Create(c.Request.Context(), req, actorID(c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "error creating product",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
-	response := h.service.ToResponse(product)
-	c.JSON(http.StatusCreated, gin.H{
-		"data":    response,
-		"message": "product created successfully",
-	})
+	response := h.productResponse(c, product)
+	api.Created(c, response, "product_created")
 }
 
 // GetProduct obtiene un producto por ID
@@ -51,26 +50,18 @@ func (h *Handler) GetProduct(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid product id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
-	product, err := h.service.GetById(uint(id))
+	product, err := h.service.GetById(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "product not found",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
-	response := h.service.ToResponse(product)
-	c.JSON(http.StatusOK, gin.H{
-		"data": response,
-	})
+	response := h.productResponse(c, product)
+	api.OK(c, response)
 }
 
 // UpdateProduct actualiza un producto
@@ -79,40 +70,24 @@ func (h *Handler) UpdateProduct(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid product id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
 	var req UpdateProductRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid request",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 
-	product, err := h.service.Update(uint(id), req)
+	product, err := h.service.Update(c.Request.Context(), uint(id), req, actorID(c))
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "product not found" {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, gin.H{
-			"error":   "error updating product",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
-	response := h.service.ToResponse(product)
-	c.JSON(http.StatusOK, gin.H{
-		"data":    response,
-		"message": "product updated successfully",
-	})
+	response := h.productResponse(c, product)
+	api.Respond(c, http.StatusOK, response, "product_updated")
 }
 
 // DeleteProduct elimina un producto
@@ -121,28 +96,16 @@ func (h *Handler) DeleteProduct(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid product id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
-	if err := h.service.Delete(uint(id)); err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "product not found" {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, gin.H{
-			"error":   "error deleting product",
-			"message": err.Error(),
-		})
+	if err := h.service.Delete(c.Request.Context(), uint(id)); err != nil {
+		api.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "product deleted successfully",
-	})
+	api.Respond(c, http.StatusOK, nil, "product_deleted")
 }
 
 // ListProducts lista productos con paginación
@@ -151,18 +114,15 @@ func (h *Handler) ListProducts(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
-	products, total, err := h.service.List(page, limit)
+	products, total, err := h.service.List(c.Request.Context(), page, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "error listing products",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
 	responses := make([]ProductResponse, len(products))
 	for i, product := range products {
-		responses[i] = h.service.ToResponse(&product)
+		responses[i] = h.productResponse(c, &product)
 	}
 
 	response := ProductListResponse{
@@ -172,8 +132,38 @@ func (h *Handler) ListProducts(c *gin.Context) {
 		Limit:    limit,
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": response,
+	api.OK(c, response)
+}
+
+// ListProductsByCategory lista los productos de una categoría y sus subcategorías
+// GET /api/v1/catalog/categories/:id/products?page=1&limit=10&incluir_subcategorias=true
+func (h *Handler) ListProductsByCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	descendants, _ := strconv.ParseBool(c.DefaultQuery("incluir_subcategorias", "true"))
+
+	products, total, err := h.service.ListByCategory(c.Request.Context(), uint(id), descendants, page, limit)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	responses := make([]ProductResponse, len(products))
+	for i, product := range products {
+		responses[i] = h.productResponse(c, &product)
+	}
+
+	api.OK(c, ProductListResponse{
+		Products: responses,
+		Total:    total,
+		Page:     page,
+		Limit:    limit,
 	})
 }
 
@@ -182,26 +172,18 @@ func (h *Handler) ListProducts(c *gin.Context) {
 func (h *Handler) GetProductByName(c *gin.Context) {
 	name := c.Param("name")
 	if name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid request",
-			"message": "product name is required",
-		})
+		api.Error(c, domain.Required("name"))
 		return
 	}
 
-	product, err := h.service.GetByName(name)
+	product, err := h.service.GetByName(c.Request.Context(), name)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "product not found",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
-	response := h.service.ToResponse(product)
-	c.JSON(http.StatusOK, gin.H{
-		"data": response,
-	})
+	response := h.productResponse(c, product)
+	api.OK(c, response)
 }
 
 // GetProductsByExpirationDate obtiene productos por fecha de vencimiento
@@ -210,30 +192,22 @@ func (h *Handler) GetProductsByExpirationDate(c *gin.Context) {
 	dateParam := c.Param("date")
 	date, err := time.Parse("2006-01-02", dateParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid date format",
-			"message": "date must be in format YYYY-MM-DD",
-		})
+		api.Error(c, domain.InvalidField("date", "invalid_date", "la fecha debe tener el formato AAAA-MM-DD"))
 		return
 	}
 
-	products, err := h.service.GetByExpirationDate(date)
+	products, err := h.service.GetByExpirationDate(c.Request.Context(), date)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "error getting products",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
 	responses := make([]ProductResponse, len(products))
 	for i, product := range products {
-		responses[i] = h.service.ToResponse(&product)
+		responses[i] = h.productResponse(c, &product)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": responses,
-	})
+	api.OK(c, responses)
 }
 
 // GetExpiringSoon obtiene productos que vencen pronto
@@ -241,23 +215,18 @@ func (h *Handler) GetProductsByExpirationDate(c *gin.Context) {
 func (h *Handler) GetExpiringSoon(c *gin.Context) {
 	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
 
-	products, err := h.service.GetExpiringSoon(days)
+	products, err := h.service.GetExpiringSoon(c.Request.Context(), days)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "error getting products",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
 	responses := make([]ProductResponse, len(products))
 	for i, product := range products {
-		responses[i] = h.service.ToResponse(&product)
+		responses[i] = h.productResponse(c, &product)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": responses,
-	})
+	api.OK(c, responses)
 }
 
 // UpdateStock actualiza el stock de un producto
@@ -266,42 +235,411 @@ func (h *Handler) UpdateStock(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid product id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	var req UpdateStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
 		return
 	}
 
-	var req struct {
-		Quantity int `json:"cantidad" binding:"required"`
+	if err := h.service.UpdateStock(c.Request.Context(), uint(id), req.Quantity, req.Motivo, actorID(c)); err != nil {
+		api.Error(c, err)
+		return
 	}
 
+	product, _ := h.service.GetById(c.Request.Context(), uint(id))
+	response := h.productResponse(c, product)
+
+	api.Respond(c, http.StatusOK, response, "stock_updated")
+}
+
+// SearchProducts busca productos por texto con filtros, orden y facetas
+// GET /api/v1/catalog/products/search?q=leche&precio_max=5000&orden=-vencimiento
+func (h *Handler) SearchProducts(c *gin.Context) {
+	var req SearchProductsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	response, err := h.service.Search(c.Request.Context(), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+	if !canSeeCosts(c) {
+		response.hideCosts()
+	}
+
+	api.OK(c, response)
+}
+
+// ScanCode resuelve un código escaneado (GTIN, SKU o etiqueta GS1-128) y
+// devuelve el producto con sus lotes y su estado de vencimiento
+// GET /api/v1/catalog/scan/:code
+func (h *Handler) ScanCode(c *gin.Context) {
+	response, err := h.service.Scan(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+	if !canSeeCosts(c) {
+		response.hideCosts()
+	}
+
+	api.OK(c, response)
+}
+
+// ReceiveLot da entrada a un lote del producto
+// POST /api/v1/catalog/products/:id/lots
+func (h *Handler) ReceiveLot(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	var req ReceiveLotRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid request",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 
-	if err := h.service.UpdateStock(uint(id), req.Quantity); err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "product not found" {
-			statusCode = http.StatusNotFound
+	lot, err := h.service.ReceiveLot(c.Request.Context(), uint(id), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+	if !canSeeCosts(c) {
+		lot.hideCosts()
+	}
+
+	api.Created(c, lot, "lot_received")
+}
+
+// ListLots lista los lotes de un producto
+// GET /api/v1/catalog/products/:id/lots
+func (h *Handler) ListLots(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	lots, err := h.service.Lots(c.Request.Context(), uint(id))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+	if !canSeeCosts(c) {
+		for i := range lots {
+			lots[i].hideCosts()
 		}
-		c.JSON(statusCode, gin.H{
-			"error":   "error updating stock",
-			"message": err.Error(),
-		})
+	}
+
+	api.OK(c, lots)
+}
+
+// GetProductLabel descarga la etiqueta de góndola de un producto
+// GET /api/v1/catalog/products/:id/label?formato=pdf|zpl&copias=1
+func (h *Handler) GetProductLabel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
-	product, _ := h.service.GetById(uint(id))
+	copies, err := strconv.Atoi(c.DefaultQuery("copias", "1"))
+	if err != nil {
+		api.Error(c, domain.InvalidField("copias", "invalid_copies", "copias debe estar entre 1 y %d", MaxLabelCopies))
+		return
+	}
+
+	file, err := h.service.ProductLabels(c.Request.Context(), uint(id), c.DefaultQuery("formato", LabelFormatPDF), copies)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	sendLabelFile(c, file)
+}
+
+// GetLotLabel descarga la etiqueta GS1-128 de un lote
+// GET /api/v1/catalog/products/:id/lots/:lotId/label?formato=pdf|zpl&copias=1
+func (h *Handler) GetLotLabel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	lotID, err := strconv.ParseUint(c.Param("lotId"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("lotId"))
+		return
+	}
+
+	copies, err := strconv.Atoi(c.DefaultQuery("copias", "1"))
+	if err != nil {
+		api.Error(c, domain.InvalidField("copias", "invalid_copies", "copias debe estar entre 1 y %d", MaxLabelCopies))
+		return
+	}
+
+	file, err := h.service.LotLabels(c.Request.Context(), uint(id), uint(lotID), c.DefaultQuery("formato", LabelFormatPDF), copies)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	sendLabelFile(c, file)
+}
+
+// PrintLabels descarga en un archivo las etiquetas de varios productos y lotes
+// POST /api/v1/catalog/labels
+func (h *Handler) PrintLabels(c *gin.Context) {
+	var req LabelBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	file, err := h.service.BatchLabels(c.Request.Context(), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	sendLabelFile(c, file)
+}
+
+func sendLabelFile(c *gin.Context, file *LabelFile) {
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, file.Name))
+	c.Data(http.StatusOK, file.ContentType, file.Data)
+}
+
+// ImportProducts crea o actualiza productos desde un CSV o XLSX (campo archivo)
+// POST /api/v1/catalog/imports?solo_validar=true&async=true
+func (h *Handler) ImportProducts(c *gin.Context) {
+	extendDeadlines(c, transferTimeout)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxImportBytes+1<<20)
+
+	header, err := c.FormFile("archivo")
+	if err != nil {
+		api.Error(c, domain.Required("archivo"))
+		return
+	}
+
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(header.Filename), "."))
+	if f := c.Query("formato"); f != "" {
+		format = f
+	}
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("solo_validar", "false"))
+	async, _ := strconv.ParseBool(c.DefaultQuery("async", "false"))
+
+	file, err := header.Open()
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+	defer file.Close()
+
+	result, queued, err := h.service.ImportProducts(c.Request.Context(), ImportRequest{
+		Format:   format,
+		FileName: filepath.Base(header.Filename),
+		Size:     header.Size,
+		DryRun:   dryRun,
+		Async:    async,
+		Actor:    actorID(c),
+	}, file)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	switch {
+	case queued:
+		c.Header("Location", fmt.Sprintf("/api/v1/catalog/imports/%d", result.ID))
+		api.Respond(c, http.StatusAccepted, result, "import_queued")
+	case dryRun:
+		api.Respond(c, http.StatusOK, result, "import_validated")
+	default:
+		api.Respond(c, http.StatusOK, result, "products_imported")
+	}
+}
+
+// GetImport consulta el avance de una importación en segundo plano
+// GET /api/v1/catalog/imports/:id
+func (h *Handler) GetImport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	result, err := h.service.GetImport(c.Request.Context(), uint(id))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, result)
+}
+
+// ExportProducts descarga todo el catálogo con stock y vencimiento. El archivo
+// se escribe a medida que se lee; un error a mitad de camino corta la
+// descarga y queda en el log
+// GET /api/v1/catalog/export?formato=csv|xlsx
+func (h *Handler) ExportProducts(c *gin.Context) {
+	format := c.DefaultQuery("formato", spreadsheet.FormatCSV)
+	if !spreadsheet.Supported(format) {
+		api.Error(c, domain.InvalidField("formato", "invalid_sheet_format", "formato debe ser csv o xlsx"))
+		return
+	}
+
+	extendDeadlines(c, transferTimeout)
+	contentType := "text/csv; charset=utf-8"
+	if format == spreadsheet.FormatXLSX {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="productos-%s.%s"`, time.Now().Format("2006-01-02"), format))
+
+	if err := h.service.ExportProducts(c.Request.Context(), format, c.Writer); err != nil {
+		if !c.Writer.Written() {
+			api.Error(c, err)
+			return
+		}
+		slog.ErrorContext(c.Request.Context(), "product export interrupted", "error", err)
+	}
+}
+
+// transferTimeout reemplaza los timeouts del servidor en las rutas que
+// suben o bajan archivos grandes
+const transferTimeout = 5 * time.Minute
+
+func extendDeadlines(c *gin.Context, d time.Duration) {
+	rc := http.NewResponseController(c.Writer)
+	deadline := time.Now().Add(d)
+	_ = rc.SetReadDeadline(deadline)
+	_ = rc.SetWriteDeadline(deadline)
+}
+
+// ChangeProductPrice cambia el precio de un producto ya mismo, con motivo
+// PUT /api/v1/catalog/products/:id/price
+func (h *Handler) ChangeProductPrice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	var req ChangePriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	change, err := h.service.ChangePrice(c.Request.Context(), uint(id), req, actorID(c))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, change, "price_changed")
+}
+
+// GetPriceTimeline devuelve el historial de precios y los cambios programados
+// GET /api/v1/catalog/products/:id/prices
+func (h *Handler) GetPriceTimeline(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	timeline, err := h.service.PriceTimeline(c.Request.Context(), uint(id))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, timeline)
+}
+
+// SchedulePrice programa un cambio de precio para una fecha futura
+// POST /api/v1/catalog/products/:id/prices/scheduled
+func (h *Handler) SchedulePrice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	var req SchedulePriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	scheduled, err := h.service.SchedulePrice(c.Request.Context(), uint(id), req, actorID(c))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Created(c, scheduled, "price_scheduled")
+}
+
+// CancelScheduledPrice cancela un cambio de precio programado pendiente
+// DELETE /api/v1/catalog/products/:id/prices/scheduled/:scheduledId
+func (h *Handler) CancelScheduledPrice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+	scheduledID, err := strconv.ParseUint(c.Param("scheduledId"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("scheduledId"))
+		return
+	}
+
+	if err := h.service.CancelScheduledPrice(c.Request.Context(), uint(id), uint(scheduledID), actorID(c)); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "scheduled_price_cancelled")
+}
+
+// actorID es el usuario autenticado que hace el cambio; nil solo si la ruta
+// no pasó por Auth
+/*
+# canSeeCosts indica si la solicitud puede ver costo y costo_unitario
+* los costos dan el margen, que solo ven los administradores; las lecturas
+* del catálogo son públicas y pasan por OptionalAuth para saber quién pide
+*/
+func canSeeCosts(c *gin.Context) bool {
+	principal, ok := middleware.CurrentPrincipal(c)
+	return ok && principal.HasRole(domain.RoleAdmin)
+}
+
+// productResponse es ToResponse sin el costo si quien pide no puede verlo
+func (h *Handler) productResponse(c *gin.Context, product *domain.Product) ProductResponse {
 	response := h.service.ToResponse(product)
+	if !canSeeCosts(c) {
+		response.hideCosts()
+	}
+	return response
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data":    response,
-		"message": "stock updated successfully",
-	})
+func actorID(c *gin.Context) *uint {
+	principal, ok := middleware.CurrentPrincipal(c)
+	if !ok {
+		return nil
+	}
+	return &principal.UserID
 }
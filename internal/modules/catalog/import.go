@@ -0,0 +1,575 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/platform/gs1"
+	"github.com/mordmora/expirapp/internal/platform/i18n"
+	"github.com/mordmora/expirapp/internal/platform/spreadsheet"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+)
+
+const (
+	// ImportSyncMaxBytes: un archivo más grande se procesa en segundo plano
+	ImportSyncMaxBytes = 1 << 20
+	MaxImportBytes     = 20 << 20
+
+	maxImportRows   = 50000
+	maxImportErrors = 1000
+	// cada cuántas filas una importación en segundo plano guarda su avance
+	importProgressEvery = 500
+	// una importación que no avanza en este tiempo se da por abandonada
+	importStaleAfter = 15 * time.Minute
+
+	exportBatchSize = 500
+)
+
+// columnas de la hoja; las demás se ignoran, así un archivo exportado se
+// puede volver a importar tal cual
+var (
+	importRequiredColumns = []string{"nombre", "precio", "fecha_vencimiento"}
+	importOptionalColumns = []string{"descripcion", "costo", "stock", "sku", "gtin"}
+	exportColumns         = []any{"id_producto", "sku", "gtin", "nombre", "descripcion", "precio", "costo", "stock", "fecha_vencimiento", "vencimiento_proximo", "dias_para_vencer"}
+)
+
+// ImportRequest describe un archivo subido para importar
+type ImportRequest struct {
+	Format   string
+	FileName string
+	Size     int64
+	DryRun   bool
+	// Async la manda a segundo plano aunque el archivo sea chico
+	Async bool
+	// Actor es quien sube el archivo; nil sin credenciales
+	Actor *uint
+}
+
+/*
+# ImportProducts crea o actualiza productos desde un CSV o un XLSX
+* cada fila se busca por SKU y, si no, por GTIN: si existe se actualiza y
+* si no se crea, con las mismas validaciones que Create y Update
+* las filas con error no detienen el resto; vuelven en errores con su número
+* con DryRun solo se valida
+* un archivo de más de ImportSyncMaxBytes (o con Async) queda como una
+* importación pendiente; el bool indica que fue así y la respuesta trae su
+* id para consultarla
+*/
+func (s *Service) ImportProducts(ctx context.Context, req ImportRequest, file io.Reader) (_ *ImportResponse, _ bool, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.ImportProducts")
+	defer func() { tracing.End(span, err) }()
+
+	if !spreadsheet.Supported(req.Format) {
+		return nil, false, domain.InvalidField("formato", "invalid_sheet_format", "formato debe ser csv o xlsx")
+	}
+	if req.Size > MaxImportBytes {
+		return nil, false, domain.InvalidField("archivo", "import_too_large", "el archivo supera el máximo de %d MB", MaxImportBytes>>20)
+	}
+
+	job := &domain.ProductImport{
+		Estado:        domain.ImportPending,
+		Formato:       req.Format,
+		NombreArchivo: req.FileName,
+		SoloValidar:   req.DryRun,
+		Idioma:        i18n.Language(ctx),
+		IDUsuario:     req.Actor,
+	}
+
+	if req.Async || req.Size > ImportSyncMaxBytes {
+		data, err := io.ReadAll(io.LimitReader(file, MaxImportBytes+1))
+		if err != nil {
+			return nil, false, fmt.Errorf("error reading import file: %w", err)
+		}
+		if len(data) > MaxImportBytes {
+			return nil, false, domain.InvalidField("archivo", "import_too_large", "el archivo supera el máximo de %d MB", MaxImportBytes>>20)
+		}
+		job.Archivo = data
+		if err := s.repo.CreateImport(ctx, job); err != nil {
+			return nil, false, fmt.Errorf("error creating import: %w", err)
+		}
+		response := ToImportResponse(job)
+		return &response, true, nil
+	}
+
+	now := time.Now()
+	job.Estado = domain.ImportRunning
+	job.IniciadaEn = &now
+	if err := s.runImport(ctx, job, file); err != nil {
+		return nil, false, err
+	}
+	response := ToImportResponse(job)
+	return &response, false, nil
+}
+
+// GetImport consulta una importación en segundo plano
+func (s *Service) GetImport(ctx context.Context, id uint) (_ *ImportResponse, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.GetImport")
+	defer func() { tracing.End(span, err) }()
+
+	job, err := s.repo.FindImport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	response := ToImportResponse(job)
+	return &response, nil
+}
+
+/*
+# ProcessNextImport procesa la importación pendiente más antigua
+* devuelve false si no había ninguna
+* si ctx se cancela a mitad (el servidor se apaga) la importación queda
+* procesando y otra réplica la retoma cuando pasa importStaleAfter; las
+* filas ya aplicadas se vuelven a aplicar como actualizaciones
+*/
+func (s *Service) ProcessNextImport(ctx context.Context) (_ bool, err error) {
+	job, err := s.repo.ClaimImport(ctx, importStaleAfter)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	ctx, span := tracing.Start(ctx, "catalog.Service.ProcessNextImport")
+	defer func() { tracing.End(span, err) }()
+
+	ctx = i18n.WithLanguage(ctx, job.Idioma)
+	job.Filas, job.Creados, job.Actualizados, job.ConError, job.Errores = 0, 0, 0, 0, nil
+
+	err = s.runImport(ctx, job, bytes.NewReader(job.Archivo))
+	if ctx.Err() != nil {
+		return true, ctx.Err()
+	}
+	if err != nil {
+		var domainErr *domain.Error
+		job.Estado = domain.ImportFailed
+		if errors.As(err, &domainErr) {
+			job.Mensaje = translateError(job.Idioma, domainErr.Code, domainErr.Args, domainErr.Message)
+		} else {
+			slog.ErrorContext(ctx, "product import failed", "import_id", job.ID, "error", err)
+			job.Mensaje = translateError(job.Idioma, "import_failed", nil, "la importación no se pudo completar")
+		}
+		now := time.Now()
+		job.TerminadaEn = &now
+	}
+
+	return true, s.repo.FinishImport(ctx, job)
+}
+
+/*
+# runImport lee el archivo fila por fila y aplica cada una
+* la primera fila es el encabezado; las columnas se reconocen por nombre,
+* sin importar mayúsculas ni el orden
+* un error del archivo completo (encabezado, demasiadas filas) o de la base
+* de datos se devuelve; los de cada fila quedan en job.Errores
+*/
+func (s *Service) runImport(ctx context.Context, job *domain.ProductImport, file io.Reader) error {
+	reader, err := spreadsheet.NewReader(job.Formato, file)
+	if err != nil {
+		return domain.Validation("invalid_sheet", "el archivo no es un %s válido", job.Formato)
+	}
+	defer reader.Close()
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return domain.Validation("import_empty", "el archivo no tiene encabezado")
+	}
+	if err != nil {
+		return domain.Validation("invalid_sheet", "el archivo no es un %s válido", job.Formato)
+	}
+
+	im, err := newImporter(s, job, header)
+	if err != nil {
+		return err
+	}
+
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// una fila mal armada (comillas sin cerrar) no detiene el resto
+			if errors.Is(err, spreadsheet.ErrMalformedRow) {
+				job.Filas++
+				im.rowFailed(row, domain.Validation("malformed_row", "la fila no se pudo leer"))
+				continue
+			}
+			return domain.Validation("invalid_sheet", "el archivo no es un %s válido", job.Formato)
+		}
+		if blankRecord(record) {
+			continue
+		}
+
+		if job.Filas >= maxImportRows {
+			return domain.Validation("too_many_rows", "el archivo supera el máximo de %d filas", maxImportRows)
+		}
+		job.Filas++
+		if err := im.apply(ctx, row, record); err != nil {
+			return err
+		}
+
+		if job.ID != 0 && job.Filas%importProgressEvery == 0 {
+			if err := s.repo.UpdateImportProgress(ctx, job); err != nil {
+				return err
+			}
+		}
+	}
+
+	now := time.Now()
+	job.Estado = domain.ImportDone
+	job.TerminadaEn = &now
+	return nil
+}
+
+// importer aplica las filas de una importación
+type importer struct {
+	s       *Service
+	job     *domain.ProductImport
+	columns map[string]int
+	// seen guarda en qué fila apareció cada SKU y GTIN del archivo
+	seen map[string]int
+	// failedRow es la última fila contada en ConError
+	failedRow int
+}
+
+func newImporter(s *Service, job *domain.ProductImport, header []string) (*importer, error) {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+		if _, dup := columns[name]; !dup {
+			columns[name] = i
+		}
+	}
+
+	var missing []string
+	for _, name := range importRequiredColumns {
+		if _, ok := columns[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, domain.Validation("import_missing_columns", "faltan las columnas: %s", strings.Join(missing, ", "))
+	}
+
+	return &importer{s: s, job: job, columns: columns, seen: make(map[string]int)}, nil
+}
+
+func (im *importer) cell(record []string, column string) string {
+	i, ok := im.columns[column]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+/*
+# apply crea o actualiza el producto de una fila
+* en una actualización las celdas vacías dejan el valor actual
+* solo devuelve los errores que detienen la importación
+*/
+func (im *importer) apply(ctx context.Context, row int, record []string) error {
+	sku := strings.ToUpper(im.cell(record, "sku"))
+	gtin := im.cell(record, "gtin")
+
+	if im.duplicated(row, "sku", sku, sku) || im.duplicated(row, "gtin", gtin, normalizedGTIN(gtin)) {
+		return nil
+	}
+
+	price, priceErr := parseNumber(im.cell(record, "precio"))
+	if priceErr != nil {
+		im.rowFailed(row, domain.InvalidField("precio", "invalid_number", "%s debe ser un número", "precio"))
+	}
+	var cost *float64
+	if value := im.cell(record, "costo"); value != "" {
+		if parsed, err := parseNumber(value); err != nil {
+			im.rowFailed(row, domain.InvalidField("costo", "invalid_number", "%s debe ser un número", "costo"))
+		} else {
+			cost = &parsed
+		}
+	}
+	var expiration time.Time
+	if value := im.cell(record, "fecha_vencimiento"); value != "" {
+		var err error
+		if expiration, err = spreadsheet.ParseDate(value); err != nil {
+			im.rowFailed(row, domain.InvalidField("fecha_vencimiento", "invalid_sheet_date",
+				"%s debe ser una fecha AAAA-MM-DD, DD/MM/AAAA o una fecha de Excel", "fecha_vencimiento"))
+		}
+	}
+	stock, stockSet := 0, false
+	if value := im.cell(record, "stock"); value != "" {
+		var err error
+		if stock, err = strconv.Atoi(value); err != nil {
+			im.rowFailed(row, domain.InvalidField("stock", "invalid_integer", "%s debe ser un número entero", "stock"))
+		}
+		stockSet = true
+	}
+	if im.failedRow == row {
+		return nil
+	}
+
+	existing, err := im.s.findByCodes(ctx, sku, gtin)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		req := CreateProductRequest{
+			Nombre:           im.cell(record, "nombre"),
+			Descripcion:      im.cell(record, "descripcion"),
+			Precio:           price,
+			Costo:            cost,
+			FechaVencimiento: expiration,
+			Stock:            stock,
+			GTIN:             gtin,
+			SKU:              sku,
+		}
+		if err := binding.Validator.ValidateStruct(req); err != nil {
+			return im.rowFailed(row, err)
+		}
+		product, err := im.s.newProduct(ctx, req)
+		if err != nil {
+			return im.rowFailed(row, err)
+		}
+		if !im.job.SoloValidar {
+			change := initialPrice(product, im.job.IDUsuario, time.Now())
+			change.Origen = domain.PriceImport
+			change.Motivo = im.priceReason()
+			if err := im.s.repo.Create(ctx, product, change); err != nil {
+				return fmt.Errorf("error creating product: %w", err)
+			}
+		}
+		im.job.Creados++
+		return nil
+	}
+
+	req := UpdateProductRequest{
+		Nombre:           im.cell(record, "nombre"),
+		Descripcion:      im.cell(record, "descripcion"),
+		Precio:           price,
+		Costo:            cost,
+		FechaVencimiento: expiration,
+		Stock:            stock,
+		GTIN:             gtin,
+		SKU:              sku,
+	}
+	if !stockSet {
+		req.Stock = existing.Stock
+	}
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return im.rowFailed(row, err)
+	}
+	previous := existing.Precio
+	if err := im.s.applyUpdate(ctx, existing, req); err != nil {
+		return im.rowFailed(row, err)
+	}
+	if !im.job.SoloValidar {
+		change := priceChange(previous, existing, domain.PriceImport, im.priceReason(), im.job.IDUsuario, time.Now())
+		if err := im.s.repo.Update(ctx, existing, change); err != nil {
+			return fmt.Errorf("error updating product: %w", err)
+		}
+	}
+	im.job.Actualizados++
+	return nil
+}
+
+// priceReason es el motivo con el que quedan en la historia los precios de la importación
+func (im *importer) priceReason() string {
+	return truncate("importación "+im.job.NombreArchivo, 255)
+}
+
+// duplicated marca la fila si key ya apareció en otra fila del archivo
+func (im *importer) duplicated(row int, field, value, key string) bool {
+	if key == "" {
+		return false
+	}
+	key = field + ":" + key
+	if first, ok := im.seen[key]; ok {
+		im.rowFailed(row, domain.InvalidField(field, "duplicate_in_file", "%s %s ya aparece en la fila %d", field, value, first))
+		return true
+	}
+	im.seen[key] = row
+	return false
+}
+
+/*
+# rowFailed agrega los errores de una fila
+* los del validador y los de dominio se traducen al idioma de la
+* importación; cualquier otro error se devuelve para detenerla
+*/
+func (im *importer) rowFailed(row int, err error) error {
+	lang := im.job.Idioma
+	var validationErrs validator.ValidationErrors
+	var domainErr *domain.Error
+
+	var rowErrors []domain.ImportRowError
+	switch {
+	case errors.As(err, &validationErrs):
+		for _, fe := range validationErrs {
+			rowErrors = append(rowErrors, domain.ImportRowError{
+				Row: row, Field: fe.Field(), Code: fe.Tag(), Message: i18n.FieldMessage(lang, fe),
+			})
+		}
+	case errors.As(err, &domainErr) && len(domainErr.Fields) > 0:
+		for _, f := range domainErr.Fields {
+			message := f.Message
+			if f.Args != nil {
+				message = translateError(lang, f.Code, f.Args, f.Message)
+			}
+			rowErrors = append(rowErrors, domain.ImportRowError{Row: row, Field: f.Field, Code: f.Code, Message: message})
+		}
+	case errors.As(err, &domainErr):
+		rowErrors = append(rowErrors, domain.ImportRowError{
+			Row: row, Code: domainErr.Code, Message: translateError(lang, domainErr.Code, domainErr.Args, domainErr.Message),
+		})
+	default:
+		return err
+	}
+
+	if im.failedRow != row {
+		im.failedRow = row
+		im.job.ConError++
+	}
+	for _, e := range rowErrors {
+		if len(im.job.Errores) < maxImportErrors {
+			im.job.Errores = append(im.job.Errores, e)
+		}
+	}
+	return nil
+}
+
+// findByCodes busca el producto de una fila por SKU y, si no, por GTIN
+func (s *Service) findByCodes(ctx context.Context, sku, gtin string) (*domain.Product, error) {
+	if sku != "" {
+		product, err := s.repo.FindBySKU(ctx, sku)
+		if !errors.Is(err, domain.ErrNotFound) {
+			return product, err
+		}
+	}
+	if normalized := normalizedGTIN(gtin); normalized != "" {
+		product, err := s.repo.FindByGTIN(ctx, normalized)
+		if !errors.Is(err, domain.ErrNotFound) {
+			return product, err
+		}
+	}
+	return nil, nil
+}
+
+/*
+# ExportProducts escribe todo el catálogo con su stock y vencimiento
+* vencimiento_proximo es el del lote con unidades que vence primero, o el
+* del producto si no tiene lotes
+* el archivo tiene las columnas de la importación, así se puede editar y
+* volver a subir
+*/
+func (s *Service) ExportProducts(ctx context.Context, format string, w io.Writer) (err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.ExportProducts")
+	defer func() { tracing.End(span, err) }()
+
+	sheet, err := spreadsheet.NewWriter(format, w)
+	if err != nil {
+		return domain.InvalidField("formato", "invalid_sheet_format", "formato debe ser csv o xlsx")
+	}
+	if err := sheet.Write(exportColumns); err != nil {
+		return err
+	}
+
+	today := time.Now()
+	err = s.repo.ExportProducts(ctx, exportBatchSize, func(rows []ExportRow) error {
+		for _, row := range rows {
+			nearest := row.FechaVencimiento
+			if row.NearestLot != nil {
+				nearest = *row.NearestLot
+			}
+			gtin := ""
+			if row.GTIN != nil {
+				gtin = gs1.ShortGTIN(*row.GTIN)
+			}
+			var cost any
+			if row.Costo != nil {
+				cost = *row.Costo
+			}
+			err := sheet.Write([]any{
+				int64(row.ID), deref(row.SKU), gtin, row.Nombre, row.Descripcion, row.Precio, cost, row.Stock,
+				row.FechaVencimiento, nearest, domain.DaysUntil(nearest, today),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return sheet.Close()
+}
+
+func ToImportResponse(job *domain.ProductImport) ImportResponse {
+	errs := job.Errores
+	if errs == nil {
+		errs = []domain.ImportRowError{}
+	}
+	return ImportResponse{
+		ID:           job.ID,
+		Estado:       string(job.Estado),
+		Formato:      job.Formato,
+		Archivo:      job.NombreArchivo,
+		SoloValidar:  job.SoloValidar,
+		Filas:        job.Filas,
+		Creados:      job.Creados,
+		Actualizados: job.Actualizados,
+		ConError:     job.ConError,
+		Errores:      errs,
+		Mensaje:      job.Mensaje,
+		IniciadaEn:   job.IniciadaEn,
+		TerminadaEn:  job.TerminadaEn,
+	}
+}
+
+// translateError traduce un mensaje de error al idioma de la importación;
+// sin entrada en el catálogo deja fallback
+func translateError(lang, code string, args []any, fallback string) string {
+	if message, ok := i18n.ErrorMessage(lang, code, args); ok {
+		return message
+	}
+	return fallback
+}
+
+func normalizedGTIN(gtin string) string {
+	normalized, err := gs1.NormalizeGTIN(gtin)
+	if err != nil {
+		return ""
+	}
+	return normalized
+}
+
+/*
+# parseNumber lee un precio escrito a mano o exportado
+* "12500", "12500.5" y "$ 12.500,50" (miles con punto, decimales con coma)
+* una coma sola es la coma decimal: "12500,5"
+*/
+func parseNumber(value string) (float64, error) {
+	value = strings.NewReplacer("$", "", " ", "").Replace(value)
+	if strings.Contains(value, ",") {
+		value = strings.ReplaceAll(value, ".", "")
+		value = strings.Replace(value, ",", ".", 1)
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func blankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
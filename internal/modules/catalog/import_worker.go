@@ -0,0 +1,44 @@
+package catalog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ImportWorker procesa las importaciones de productos en segundo plano.
+type ImportWorker struct {
+	service  *Service
+	interval time.Duration
+}
+
+func NewImportWorker(service *Service, interval time.Duration) *ImportWorker {
+	return &ImportWorker{service: service, interval: interval}
+}
+
+// Run se ejecuta hasta que ctx se cancela; en cada vuelta procesa todas
+// las importaciones pendientes, de a una.
+func (w *ImportWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				processed, err := w.service.ProcessNextImport(ctx)
+				if err != nil {
+					if ctx.Err() == nil {
+						slog.ErrorContext(ctx, "import worker: error processing import", "error", err)
+					}
+					break
+				}
+				if !processed {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/platform/labels"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+)
+
+const (
+	LabelFormatPDF = "pdf"
+	LabelFormatZPL = "zpl"
+
+	MaxLabelCopies = 50
+	// maxLabels limita las etiquetas de una impresión, copias incluidas
+	maxLabels = 1000
+)
+
+// LabelFile es una impresión lista para descargar
+type LabelFile struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// ProductLabels imprime la etiqueta de góndola de un producto
+func (s *Service) ProductLabels(ctx context.Context, productID uint, format string, copies int) (_ *LabelFile, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.ProductLabels")
+	defer func() { tracing.End(span, err) }()
+
+	if err := checkLabelOptions(format, copies); err != nil {
+		return nil, err
+	}
+
+	product, err := s.repo.FindByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	label, err := s.productLabel(ctx, product, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return renderLabels(fmt.Sprintf("etiqueta-producto-%d", productID), format, repeatLabels(copies, label))
+}
+
+// LotLabels imprime la etiqueta de un lote del producto, con su GS1-128
+func (s *Service) LotLabels(ctx context.Context, productID, lotID uint, format string, copies int) (_ *LabelFile, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.LotLabels")
+	defer func() { tracing.End(span, err) }()
+
+	if err := checkLabelOptions(format, copies); err != nil {
+		return nil, err
+	}
+
+	product, err := s.repo.FindByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	lot, err := s.repo.FindLot(ctx, lotID)
+	if err != nil {
+		return nil, err
+	}
+	if lot.IDProducto != productID {
+		return nil, domain.NotFound("lot_not_found", "lote %d no encontrado", lotID)
+	}
+
+	label := s.lotLabel(ctx, product, lot, time.Now())
+	return renderLabels(fmt.Sprintf("etiqueta-lote-%d", lotID), format, repeatLabels(copies, label))
+}
+
+/*
+# BatchLabels imprime en un solo archivo las etiquetas de varios productos
+# y lotes, en el orden pedido
+* cada id se imprime copias veces
+*/
+func (s *Service) BatchLabels(ctx context.Context, req LabelBatchRequest) (_ *LabelFile, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.BatchLabels")
+	defer func() { tracing.End(span, err) }()
+
+	if req.Formato == "" {
+		req.Formato = LabelFormatPDF
+	}
+	if req.Copias == 0 {
+		req.Copias = 1
+	}
+	if err := checkLabelOptions(req.Formato, req.Copias); err != nil {
+		return nil, err
+	}
+	if req.PrecioCambiadoHoy {
+		now := time.Now()
+		repriced, err := s.repo.RepricedSince(ctx, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range repriced {
+			if !slices.Contains(req.Productos, id) {
+				req.Productos = append(req.Productos, id)
+			}
+		}
+	}
+	if len(req.Productos)+len(req.Lotes) == 0 {
+		return nil, domain.Validation("labels_empty", "indica al menos un producto o un lote")
+	}
+	if total := (len(req.Productos) + len(req.Lotes)) * req.Copias; total > maxLabels {
+		return nil, domain.Validation("too_many_labels", "se pidieron %d etiquetas; el máximo por impresión es %d", total, maxLabels)
+	}
+
+	today := time.Now()
+	var batch []labels.Label
+
+	if len(req.Productos) > 0 {
+		products, err := s.repo.FindByIDs(ctx, req.Productos)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[uint]*domain.Product, len(products))
+		for i := range products {
+			byID[products[i].ID] = &products[i]
+		}
+		for _, id := range req.Productos {
+			product, ok := byID[id]
+			if !ok {
+				return nil, domain.NotFound("product_not_found", "producto %v no encontrado", id)
+			}
+			label, err := s.productLabel(ctx, product, today)
+			if err != nil {
+				return nil, err
+			}
+			batch = append(batch, repeatLabels(req.Copias, label)...)
+		}
+	}
+
+	if len(req.Lotes) > 0 {
+		lots, err := s.repo.FindLots(ctx, req.Lotes)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[uint]*domain.Lot, len(lots))
+		productIDs := make([]uint, 0, len(lots))
+		for i := range lots {
+			byID[lots[i].ID] = &lots[i]
+			productIDs = append(productIDs, lots[i].IDProducto)
+		}
+		products, err := s.repo.FindByIDs(ctx, productIDs)
+		if err != nil {
+			return nil, err
+		}
+		productsByID := make(map[uint]*domain.Product, len(products))
+		for i := range products {
+			productsByID[products[i].ID] = &products[i]
+		}
+		for _, id := range req.Lotes {
+			lot, ok := byID[id]
+			if !ok {
+				return nil, domain.NotFound("lot_not_found", "lote %d no encontrado", id)
+			}
+			product, ok := productsByID[lot.IDProducto]
+			if !ok {
+				return nil, domain.NotFound("product_not_found", "producto %v no encontrado", lot.IDProducto)
+			}
+			batch = append(batch, repeatLabels(req.Copias, s.lotLabel(ctx, product, lot, today))...)
+		}
+	}
+
+	return renderLabels("etiquetas", req.Formato, batch)
+}
+
+// productLabel usa el vencimiento del lote con unidades que vence primero,
+// que es el que está en góndola; sin lotes, el del producto
+func (s *Service) productLabel(ctx context.Context, product *domain.Product, today time.Time) (labels.Label, error) {
+	lots, err := s.repo.ListLots(ctx, product.ID, true)
+	if err != nil {
+		return labels.Label{}, err
+	}
+	expiration := product.FechaVencimiento
+	if len(lots) > 0 {
+		expiration = lots[0].FechaVencimiento
+	}
+
+	label := s.priceLabel(ctx, product, expiration, today)
+	label.Barcode = labels.ProductBarcode(deref(product.GTIN), deref(product.SKU))
+	return label, nil
+}
+
+func (s *Service) lotLabel(ctx context.Context, product *domain.Product, lot *domain.Lot, today time.Time) labels.Label {
+	label := s.priceLabel(ctx, product, lot.FechaVencimiento, today)
+	label.Lot = lot.Numero
+	label.Barcode = labels.LotBarcode(deref(product.GTIN), deref(product.SKU), lot.FechaVencimiento, lot.Numero)
+	return label
+}
+
+/*
+# priceLabel arma nombre, precio y vencimiento
+* el precio es el que cobra SalePrice por una unidad que vence en expiration
+*/
+func (s *Service) priceLabel(ctx context.Context, product *domain.Product, expiration, today time.Time) labels.Label {
+	label := labels.Label{
+		Name:       product.Nombre,
+		Price:      product.Precio,
+		Expiration: expiration,
+	}
+
+	rules := s.categoryDefaults(ctx, product.ID).MarkdownRules
+	if price, discount := markdown(product.Precio, rules, expiration, today); discount > 0 {
+		label.RegularPrice = product.Precio
+		label.Discount = discount
+		label.Price = price
+	}
+	return label
+}
+
+func checkLabelOptions(format string, copies int) error {
+	if format != LabelFormatPDF && format != LabelFormatZPL {
+		return domain.InvalidField("formato", "invalid_label_format", "formato debe ser pdf o zpl")
+	}
+	if copies < 1 || copies > MaxLabelCopies {
+		return domain.InvalidField("copias", "invalid_copies", "copias debe estar entre 1 y %d", MaxLabelCopies)
+	}
+	return nil
+}
+
+func repeatLabels(copies int, label labels.Label) []labels.Label {
+	batch := make([]labels.Label, copies)
+	for i := range batch {
+		batch[i] = label
+	}
+	return batch
+}
+
+func renderLabels(name, format string, batch []labels.Label) (*LabelFile, error) {
+	var buf bytes.Buffer
+	file := &LabelFile{Name: name + "." + format}
+
+	var err error
+	if format == LabelFormatZPL {
+		file.ContentType = "text/plain; charset=utf-8"
+		err = labels.RenderZPL(&buf, batch)
+	} else {
+		file.ContentType = "application/pdf"
+		err = labels.RenderPDF(&buf, batch)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error rendering labels: %w", err)
+	}
+
+	file.Data = buf.Bytes()
+	return file, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,39 @@
+package catalog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// PriceScheduler aplica los cambios de precio programados cuando llega su fecha.
+type PriceScheduler struct {
+	service  *Service
+	interval time.Duration
+}
+
+func NewPriceScheduler(service *Service, interval time.Duration) *PriceScheduler {
+	return &PriceScheduler{service: service, interval: interval}
+}
+
+// Run se ejecuta hasta que ctx se cancela; un cambio se aplica a lo sumo
+// un intervalo después de su vigente_desde.
+func (w *PriceScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			applied, err := w.service.ApplyDuePrices(ctx)
+			if err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "price scheduler: error applying scheduled prices", "error", err)
+			}
+			if applied > 0 {
+				slog.InfoContext(ctx, "price scheduler: scheduled prices applied", "count", applied)
+			}
+		}
+	}
+}
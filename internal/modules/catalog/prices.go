@@ -0,0 +1,251 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+)
+
+// dueBatchSize es cuántos cambios programados se aplican por transacción
+const dueBatchSize = 100
+
+// ChangePrice cambia el precio ya mismo, con motivo y autor
+func (s *Service) ChangePrice(ctx context.Context, productID uint, req ChangePriceRequest, actor *uint) (_ *PriceChangeResponse, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.ChangePrice")
+	defer func() { tracing.End(span, err) }()
+
+	change := &domain.PriceChange{
+		Precio:       req.Precio,
+		VigenteDesde: time.Now(),
+		Origen:       domain.PriceManual,
+		Motivo:       req.Motivo,
+		IDUsuario:    actor,
+	}
+	if err := s.repo.ChangePrice(ctx, productID, change); err != nil {
+		return nil, err
+	}
+
+	response := ToPriceChangeResponse(change)
+	return &response, nil
+}
+
+// SchedulePrice programa un cambio de precio para una fecha futura; se
+// aplica solo (ver ApplyDuePrices)
+func (s *Service) SchedulePrice(ctx context.Context, productID uint, req SchedulePriceRequest, actor *uint) (_ *ScheduledPriceResponse, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.SchedulePrice")
+	defer func() { tracing.End(span, err) }()
+
+	if !req.VigenteDesde.After(time.Now()) {
+		return nil, domain.InvalidField("vigente_desde", "schedule_in_past", "vigente_desde debe ser una fecha futura")
+	}
+
+	if _, err := s.repo.FindByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	scheduled := &domain.ScheduledPrice{
+		IDProducto:   productID,
+		Precio:       req.Precio,
+		VigenteDesde: req.VigenteDesde,
+		Motivo:       req.Motivo,
+		IDUsuario:    actor,
+		Estado:       domain.ScheduledPending,
+	}
+	if err := s.repo.SchedulePrice(ctx, scheduled); err != nil {
+		return nil, err
+	}
+
+	response := ToScheduledPriceResponse(scheduled)
+	return &response, nil
+}
+
+// CancelScheduledPrice cancela un cambio programado que no se ha aplicado
+func (s *Service) CancelScheduledPrice(ctx context.Context, productID, scheduledID uint, actor *uint) (err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.CancelScheduledPrice")
+	defer func() { tracing.End(span, err) }()
+
+	scheduled, err := s.repo.FindScheduledPrice(ctx, scheduledID)
+	if err != nil {
+		return err
+	}
+	if scheduled.IDProducto != productID {
+		return domain.NotFound("scheduled_price_not_found", "cambio de precio programado %d no encontrado", scheduledID)
+	}
+
+	return s.repo.CancelScheduledPrice(ctx, scheduled, actor)
+}
+
+/*
+# PriceTimeline es la línea de tiempo del precio de un producto
+* historial: los cambios aplicados, del primero al último
+* programados: los que vienen (y los cancelados), por fecha
+*/
+func (s *Service) PriceTimeline(ctx context.Context, productID uint) (_ *PriceTimelineResponse, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.PriceTimeline")
+	defer func() { tracing.End(span, err) }()
+
+	product, err := s.repo.FindByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := s.repo.PriceHistory(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	scheduled, err := s.repo.ScheduledPrices(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &PriceTimelineResponse{
+		IDProducto:   product.ID,
+		PrecioActual: product.Precio,
+		Historial:    make([]PriceChangeResponse, len(changes)),
+		Programados:  make([]ScheduledPriceResponse, len(scheduled)),
+	}
+	for i := range changes {
+		response.Historial[i] = ToPriceChangeResponse(&changes[i])
+	}
+	for i := range scheduled {
+		response.Programados[i] = ToScheduledPriceResponse(&scheduled[i])
+	}
+	return response, nil
+}
+
+// ApplyDuePrices aplica los cambios programados cuya fecha ya llegó y
+// devuelve cuántos procesó
+func (s *Service) ApplyDuePrices(ctx context.Context) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.ApplyDuePrices")
+	defer func() { tracing.End(span, err) }()
+
+	total := 0
+	for {
+		applied, err := s.repo.ApplyDuePrices(ctx, time.Now(), dueBatchSize)
+		total += applied
+		if err != nil {
+			return total, fmt.Errorf("error applying scheduled prices: %w", err)
+		}
+		if applied < dueBatchSize {
+			return total, nil
+		}
+	}
+}
+
+/*
+# SalePrice es el precio unitario que se cobra al vender quantity unidades
+* las unidades salen de los lotes en el orden FEFO en que UpdateStock los
+* consume; cada lote lleva la rebaja de las reglas de su categoría para los
+* días que le quedan, la misma que imprime su etiqueta
+* las unidades que no cubre ningún lote usan el vencimiento del producto
+* con rebajas de distinto lote el precio es el promedio, al centavo
+*/
+func (s *Service) SalePrice(ctx context.Context, product *domain.Product, quantity int) (_ float64, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.SalePrice")
+	defer func() { tracing.End(span, err) }()
+
+	rules := s.categoryDefaults(ctx, product.ID).MarkdownRules
+	if len(rules) == 0 || quantity <= 0 {
+		return product.Precio, nil
+	}
+
+	lots, err := s.repo.ListLots(ctx, product.ID, true)
+	if err != nil {
+		return 0, err
+	}
+
+	today := time.Now()
+	total, units := 0.0, quantity
+	for _, lot := range lots {
+		if units == 0 {
+			break
+		}
+		take := min(units, lot.Cantidad)
+		price, _ := markdown(product.Precio, rules, lot.FechaVencimiento, today)
+		total += price * float64(take)
+		units -= take
+	}
+	if units > 0 {
+		price, _ := markdown(product.Precio, rules, product.FechaVencimiento, today)
+		total += price * float64(units)
+	}
+
+	return math.Round(total/float64(quantity)*100) / 100, nil
+}
+
+// markdown aplica a price la rebaja de rules para los días que le quedan a
+// expiration; devuelve el precio, redondeado al peso, y el porcentaje
+func markdown(price float64, rules domain.MarkdownRules, expiration, today time.Time) (float64, float64) {
+	discount := rules.DiscountFor(domain.DaysUntil(expiration, today))
+	if discount <= 0 {
+		return price, 0
+	}
+	return math.Round(price * (1 - discount/100)), discount
+}
+
+// initialPrice es el registro del precio con el que se da de alta un producto
+func initialPrice(product *domain.Product, actor *uint, at time.Time) *domain.PriceChange {
+	return &domain.PriceChange{
+		Precio:       product.Precio,
+		VigenteDesde: at,
+		Origen:       domain.PriceInitial,
+		IDUsuario:    actor,
+	}
+}
+
+// priceChange es el registro del cambio de previous al precio actual de
+// product, o nil si no cambió
+func priceChange(previous float64, product *domain.Product, source domain.PriceSource, reason string, actor *uint, at time.Time) *domain.PriceChange {
+	if product.Precio == previous {
+		return nil
+	}
+	return &domain.PriceChange{
+		PrecioAnterior: &previous,
+		Precio:         product.Precio,
+		VigenteDesde:   at,
+		Origen:         source,
+		Motivo:         reason,
+		IDUsuario:      actor,
+	}
+}
+
+func ToPriceChangeResponse(change *domain.PriceChange) PriceChangeResponse {
+	return PriceChangeResponse{
+		ID:                 change.ID,
+		PrecioAnterior:     change.PrecioAnterior,
+		Precio:             change.Precio,
+		VigenteDesde:       change.VigenteDesde,
+		Origen:             string(change.Origen),
+		Motivo:             change.Motivo,
+		IDUsuario:          change.IDUsuario,
+		IDPrecioProgramado: change.IDPrecioProgramado,
+	}
+}
+
+func ToScheduledPriceResponse(scheduled *domain.ScheduledPrice) ScheduledPriceResponse {
+	return ScheduledPriceResponse{
+		ID:           scheduled.ID,
+		Precio:       scheduled.Precio,
+		VigenteDesde: scheduled.VigenteDesde,
+		Motivo:       scheduled.Motivo,
+		Estado:       string(scheduled.Estado),
+		IDUsuario:    scheduled.IDUsuario,
+		CanceladoPor: scheduled.CanceladoPor,
+	}
+}
+
+// truncate corta s a n bytes sin partir un carácter
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -1,11 +1,15 @@
 package catalog
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/mordmora/expirapp/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
@@ -16,16 +20,22 @@ func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) Create(product *domain.Product) error {
-	return r.db.Create(product).Error
+// Create guarda el producto y, si viene, su precio de alta en la historia
+func (r *Repository) Create(ctx context.Context, product *domain.Product, change *domain.PriceChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(product).Error; err != nil {
+			return err
+		}
+		return recordPriceChange(tx, product.ID, change)
+	})
 }
 
-func (r *Repository) FindByID(id uint) (*domain.Product, error) {
+func (r *Repository) FindByID(ctx context.Context, id uint) (*domain.Product, error) {
 	var product domain.Product
-	err := r.db.First(&product, id).Error
+	err := r.db.WithContext(ctx).First(&product, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("product not found")
+			return nil, domain.NotFound("product_not_found", "producto con id %d no encontrado", id)
 		}
 		return nil, err
 	}
@@ -33,33 +43,47 @@ func (r *Repository) FindByID(id uint) (*domain.Product, error) {
 	return &product, nil
 }
 
-func (r *Repository) Update(product *domain.Product) error {
-	return r.db.Save(product).Error
+// Update guarda el producto y, si cambió el precio, el cambio en la historia
+func (r *Repository) Update(ctx context.Context, product *domain.Product, change *domain.PriceChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(product).Error; err != nil {
+			return err
+		}
+		return recordPriceChange(tx, product.ID, change)
+	})
+}
+
+func recordPriceChange(tx *gorm.DB, productID uint, change *domain.PriceChange) error {
+	if change == nil {
+		return nil
+	}
+	change.IDProducto = productID
+	return tx.Create(change).Error
 }
 
-func (r *Repository) Delete(id uint) error {
-	return r.db.Delete(&domain.Product{}, id).Error
+func (r *Repository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&domain.Product{}, id).Error
 }
 
-func (r *Repository) List(limit, offset int) ([]domain.Product, int64, error) {
+func (r *Repository) List(ctx context.Context, limit, offset int) ([]domain.Product, int64, error) {
 	var products []domain.Product
 	var total int64
 
-	if err := r.db.Model(&domain.Product{}).Count(&total).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&domain.Product{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	err := r.db.Limit(limit).Offset(offset).Find(&products).Error
+	err := r.db.WithContext(ctx).Limit(limit).Offset(offset).Find(&products).Error
 	return products, total, err
 }
 
-func (r *Repository) FindByName(name string) (*domain.Product, error) {
+func (r *Repository) FindByName(ctx context.Context, name string) (*domain.Product, error) {
 	var product domain.Product
 
-	err := r.db.Where("nombre = ?", name).First(&product).Error
+	err := r.db.WithContext(ctx).Where("nombre = ?", name).First(&product).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("product not found")
+			return nil, domain.NotFound("product_not_found", "producto %s no encontrado", name)
 		}
 		return nil, err
 	}
@@ -67,21 +91,922 @@ func (r *Repository) FindByName(name string) (*domain.Product, error) {
 	return &product, nil
 }
 
-func (r *Repository) FindByExpirationDate(date time.Time) ([]domain.Product, error) {
+func (r *Repository) FindByExpirationDate(ctx context.Context, date time.Time) ([]domain.Product, error) {
 	var products []domain.Product
 
-	err := r.db.Where("fecha_vencimiento = ?", date).Find(&products).Error
+	err := r.db.WithContext(ctx).Where("fecha_vencimiento = ?", date).Find(&products).Error
 	return products, err
 }
 
-func (r *Repository) FindExpiringSoon(days int) ([]domain.Product, error) {
+func (r *Repository) FindExpiringSoon(ctx context.Context, days int) ([]domain.Product, error) {
 	var products []domain.Product
 	threshold := time.Now().AddDate(0, 0, days)
 
-	err := r.db.Where("fecha_vencimiento <= ? AND fecha_vencimiento >= ?", threshold, time.Now()).Find(&products).Error
+	err := r.db.WithContext(ctx).Where("fecha_vencimiento <= ? AND fecha_vencimiento >= ?", threshold, time.Now()).Find(&products).Error
+	return products, err
+}
+
+// CountUnitsExpiringSoon suma el stock de los productos que vencen en los próximos days días
+func (r *Repository) CountUnitsExpiringSoon(ctx context.Context, days int) (int64, error) {
+	var units int64
+	now := time.Now()
+
+	err := r.db.WithContext(ctx).Model(&domain.Product{}).
+		Where("fecha_vencimiento >= ? AND fecha_vencimiento <= ?", now, now.AddDate(0, 0, days)).
+		Select("COALESCE(SUM(stock), 0)").
+		Scan(&units).Error
+	return units, err
+}
+
+/*
+# UpdateStock suma quantity al stock del producto
+* una salida (quantity negativa) también descuenta de los lotes, del que
+* vence primero al último (FEFO); lo que no cubren los lotes sale del
+* stock sin lote
+* una entrada no crea lote: esas unidades quedan sin lote
+*/
+func (r *Repository) UpdateStock(ctx context.Context, id uint, quantity int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return UpdateStockTx(tx, id, quantity)
+	})
+}
+
+// UpdateStockTx es UpdateStock dentro de una transacción abierta; las ventas
+// usan TakeStockTx y ReturnStockTx, que saben de qué lotes salen las unidades
+func UpdateStockTx(tx *gorm.DB, id uint, quantity int) error {
+	err := tx.Model(&domain.Product{}).Where("id_producto = ?", id).Update("stock", gorm.Expr("stock + ?", quantity)).Error
+	if err != nil || quantity >= 0 {
+		return err
+	}
+	_, err = consumeLots(tx, id, -quantity)
+	return err
+}
+
+/*
+# WriteOffStock da de baja units unidades del producto fuera de una venta
+* salen de los lotes igual que en UpdateStock (FEFO) y cada lote tocado
+* queda en baja_lote con reason, para medir el desperdicio aunque el lote
+* quede en cero
+* sin reason, lo que sale de un lote ya vencido en today es vencimiento y lo
+* demás merma
+*/
+func (r *Repository) WriteOffStock(ctx context.Context, id uint, units int, reason string, userID *uint, today time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.Product{}).Where("id_producto = ?", id).Update("stock", gorm.Expr("stock - ?", units)).Error
+		if err != nil {
+			return err
+		}
+
+		taken, err := consumeLots(tx, id, units)
+		if err != nil {
+			return err
+		}
+
+		for _, take := range taken {
+			motivo := reason
+			if motivo == "" {
+				motivo = domain.WriteOffShrinkage
+				if domain.DaysUntil(take.lot.FechaVencimiento, today) < 0 {
+					motivo = domain.WriteOffExpired
+				}
+			}
+			writeOff := domain.LotWriteOff{
+				IDLote:        take.lot.ID,
+				IDProducto:    id,
+				Cantidad:      take.units,
+				CostoUnitario: take.lot.CostoUnitario,
+				Motivo:        motivo,
+				Fecha:         today,
+				IDUsuario:     userID,
+			}
+			if err := tx.Create(&writeOff).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReservedFunc devuelve las unidades del producto que otros tienen apartadas
+type ReservedFunc func(tx *gorm.DB, productID uint) (int, error)
+
+/*
+# TakeStockTx descuenta quantity unidades vendidas dentro de la transacción
+# de la venta
+* el producto queda bloqueado (SELECT ... FOR UPDATE) hasta que la
+* transacción termine, así dos ventas no toman las mismas unidades
+* lo disponible es el stock menos lo que devuelve reserved (puede ser nil);
+* si no alcanza falla con InsufficientStock y la venta se deshace entera
+* devuelve cuánto salió de cada lote, para que la venta lo guarde y
+* ReturnStockTx lo pueda reponer; lo que no cubren los lotes sale del stock
+* sin lote y no aparece
+*/
+func TakeStockTx(tx *gorm.DB, productID uint, quantity int, reserved ReservedFunc) ([]LotUnits, error) {
+	var product domain.Product
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, productID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("product_not_found", "producto con id %d no encontrado", productID)
+		}
+		return nil, err
+	}
+
+	available := product.Stock
+	if reserved != nil {
+		held, err := reserved(tx, productID)
+		if err != nil {
+			return nil, err
+		}
+		available -= held
+	}
+	if available < quantity {
+		return nil, domain.InsufficientStock(&product, available, quantity)
+	}
+
+	err = tx.Model(&domain.Product{}).Where("id_producto = ?", productID).Update("stock", gorm.Expr("stock - ?", quantity)).Error
+	if err != nil {
+		return nil, err
+	}
+
+	taken, err := consumeLots(tx, productID, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	units := make([]LotUnits, len(taken))
+	for i, take := range taken {
+		units[i] = LotUnits{LotID: take.lot.ID, Units: take.units}
+	}
+	return units, nil
+}
+
+// LotUnits son unidades de un producto que salieron de un lote
+type LotUnits struct {
+	LotID uint
+	Units int
+}
+
+/*
+# ReturnStockTx devuelve quantity unidades vendidas al stock del producto
+* lots dice a qué lote vuelve cada parte, como lo devolvió TakeStockTx; el
+* resto (quantity menos lo de lots) queda sin lote
+* así stock y la suma de los lotes no se separan al cancelar una venta
+*/
+func ReturnStockTx(tx *gorm.DB, productID uint, quantity int, lots []LotUnits) error {
+	err := tx.Model(&domain.Product{}).Where("id_producto = ?", productID).Update("stock", gorm.Expr("stock + ?", quantity)).Error
+	if err != nil {
+		return err
+	}
+
+	for _, lot := range lots {
+		err := tx.Model(&domain.Lot{}).Where("id_lote = ? AND id_producto = ?", lot.LotID, productID).
+			Update("cantidad", gorm.Expr("cantidad + ?", lot.Units)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lotTake son las unidades que consumeLots sacó de un lote
+type lotTake struct {
+	lot   domain.Lot
+	units int
+}
+
+func consumeLots(tx *gorm.DB, productID uint, units int) ([]lotTake, error) {
+	var lots []domain.Lot
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id_producto = ? AND cantidad > 0", productID).
+		Order("fecha_vencimiento ASC, id_lote ASC").
+		Find(&lots).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var taken []lotTake
+	for _, lot := range lots {
+		if units == 0 {
+			break
+		}
+		take := min(units, lot.Cantidad)
+		err := tx.Model(&domain.Lot{}).Where("id_lote = ?", lot.ID).
+			Update("cantidad", gorm.Expr("cantidad - ?", take)).Error
+		if err != nil {
+			return nil, err
+		}
+		taken = append(taken, lotTake{lot: lot, units: take})
+		units -= take
+	}
+	return taken, nil
+}
+
+/*
+# averageCost promedia el costo de las units unidades que hay con el de
+# added unidades nuevas a cost
+* si un lado no tiene costo conocido queda el del otro
+*/
+func averageCost(current *float64, units int, cost *float64, added int) *float64 {
+	switch {
+	case cost == nil:
+		return current
+	case current == nil || units == 0:
+		return cost
+	}
+	average := (*current*float64(units) + *cost*float64(added)) / float64(units+added)
+	return &average
+}
+
+/*
+# UnitCost es el costo unitario de quantity unidades del producto si se
+# vendieran ahora
+* se toman de los lotes en el orden en que los consume UpdateStock (FEFO);
+* las que no cubren los lotes, y las de lotes sin costo, van al costo del
+* producto
+* nil si alguna unidad no tiene costo conocido
+*/
+func (r *Repository) UnitCost(ctx context.Context, productID uint, quantity int) (*float64, error) {
+	if quantity <= 0 {
+		return nil, nil
+	}
+
+	db := r.db.WithContext(ctx)
+	var product domain.Product
+	if err := db.Select("id_producto", "costo").First(&product, productID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("product_not_found", "producto con id %d no encontrado", productID)
+		}
+		return nil, err
+	}
+
+	var lots []domain.Lot
+	err := db.Where("id_producto = ? AND cantidad > 0", productID).
+		Order("fecha_vencimiento ASC, id_lote ASC").
+		Find(&lots).Error
+	if err != nil {
+		return nil, err
+	}
+
+	total, units := 0.0, quantity
+	for _, lot := range lots {
+		if units == 0 {
+			break
+		}
+		take := min(units, lot.Cantidad)
+		cost := lot.CostoUnitario
+		if cost == nil {
+			cost = product.Costo
+		}
+		if cost == nil {
+			return nil, nil
+		}
+		total += *cost * float64(take)
+		units -= take
+	}
+	if units > 0 {
+		if product.Costo == nil {
+			return nil, nil
+		}
+		total += *product.Costo * float64(units)
+	}
+
+	cost := total / float64(quantity)
+	return &cost, nil
+}
+
+// FindByGTIN busca por el GTIN ya normalizado a 14 dígitos
+func (r *Repository) FindByGTIN(ctx context.Context, gtin string) (*domain.Product, error) {
+	return r.findByCode(ctx, "gtin", gtin)
+}
+
+func (r *Repository) FindBySKU(ctx context.Context, sku string) (*domain.Product, error) {
+	return r.findByCode(ctx, "sku", sku)
+}
+
+func (r *Repository) findByCode(ctx context.Context, column, code string) (*domain.Product, error) {
+	var product domain.Product
+	err := r.db.WithContext(ctx).Where(column+" = ?", code).First(&product).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("product_not_found", "producto %s no encontrado", code)
+		}
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// CodeTaken dice si otro producto distinto de exceptID ya usa code en column (gtin o sku)
+func (r *Repository) CodeTaken(ctx context.Context, column, code string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.Product{}).
+		Where(column+" = ? AND id_producto <> ?", code, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListLots devuelve los lotes del producto, del que vence primero al último;
+// con onlyAvailable solo los que aún tienen unidades
+func (r *Repository) ListLots(ctx context.Context, productID uint, onlyAvailable bool) ([]domain.Lot, error) {
+	query := r.db.WithContext(ctx).Where("id_producto = ?", productID)
+	if onlyAvailable {
+		query = query.Where("cantidad > 0")
+	}
+
+	var lots []domain.Lot
+	err := query.Order("fecha_vencimiento ASC, id_lote ASC").Find(&lots).Error
+	return lots, err
+}
+
+// FindByIDs devuelve los productos de ids; los que no existen no vienen
+func (r *Repository) FindByIDs(ctx context.Context, ids []uint) ([]domain.Product, error) {
+	var products []domain.Product
+	err := r.db.WithContext(ctx).Where("id_producto IN ?", ids).Find(&products).Error
 	return products, err
 }
 
-func (r *Repository) UpdateStock(id uint, quantity int) error {
-	return r.db.Model(&domain.Product{}).Where("id_producto = ?", id).Update("stock", gorm.Expr("stock + ?", quantity)).Error
+func (r *Repository) FindLot(ctx context.Context, id uint) (*domain.Lot, error) {
+	var lot domain.Lot
+	err := r.db.WithContext(ctx).First(&lot, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("lot_not_found", "lote %d no encontrado", id)
+		}
+		return nil, err
+	}
+
+	return &lot, nil
+}
+
+// FindLots devuelve los lotes de ids; los que no existen no vienen
+func (r *Repository) FindLots(ctx context.Context, ids []uint) ([]domain.Lot, error) {
+	var lots []domain.Lot
+	err := r.db.WithContext(ctx).Where("id_lote IN ?", ids).Find(&lots).Error
+	return lots, err
+}
+
+/*
+# ReceiveLot da entrada a quantity unidades de un lote y al stock del producto
+* si el lote ya existe (otra entrega del mismo lote) se suman; su fecha de
+* vencimiento tiene que coincidir y el costo queda promediado
+* con cost, ese pasa a ser también el costo de reposición del producto
+*/
+func (r *Repository) ReceiveLot(ctx context.Context, productID uint, number string, expiration time.Time, quantity int, cost *float64) (*domain.Lot, error) {
+	var lot *domain.Lot
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		lot, err = ReceiveLotTx(tx, productID, number, expiration, quantity, cost)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lot, nil
+}
+
+// ReceiveLotTx es ReceiveLot dentro de una transacción que abre otro módulo,
+// como la recepción de una orden de compra
+func ReceiveLotTx(tx *gorm.DB, productID uint, number string, expiration time.Time, quantity int, cost *float64) (*domain.Lot, error) {
+	var lot domain.Lot
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id_producto = ? AND numero = ?", productID, number).
+		First(&lot).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		lot = domain.Lot{
+			IDProducto:       productID,
+			Numero:           number,
+			FechaVencimiento: expiration,
+			CantidadRecibida: quantity,
+			Cantidad:         quantity,
+			CostoUnitario:    cost,
+		}
+		if err := tx.Create(&lot).Error; err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case !sameDay(lot.FechaVencimiento, expiration):
+		return nil, domain.Conflict("lot_expiration_mismatch",
+			"el lote %s ya existe con vencimiento %s", number, lot.FechaVencimiento.Format("2006-01-02"))
+	default:
+		lot.CostoUnitario = averageCost(lot.CostoUnitario, lot.Cantidad, cost, quantity)
+		lot.CantidadRecibida += quantity
+		lot.Cantidad += quantity
+		err := tx.Model(&lot).Updates(map[string]any{
+			"cantidad_recibida": lot.CantidadRecibida,
+			"cantidad":          lot.Cantidad,
+			"costo_unitario":    lot.CostoUnitario,
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	updates := map[string]any{"stock": gorm.Expr("stock + ?", quantity)}
+	if cost != nil {
+		updates["costo"] = *cost
+	}
+	if err := tx.Model(&domain.Product{}).Where("id_producto = ?", productID).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return &lot, nil
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
+
+// EnsureCategory devuelve category_not_found si la categoría no existe
+func (r *Repository) EnsureCategory(ctx context.Context, id uint) error {
+	var count int64
+	err := r.db.WithContext(ctx).Table("categoria").Where("id_categoria = ?", id).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return domain.NotFound("category_not_found", "categoría con id %d no encontrada", id)
+	}
+	return nil
+}
+
+/*
+# inCategory limita a los productos asignados a la categoría id
+* con descendants también a los de sus subcategorías, por prefijo de ruta
+*/
+func inCategory(id uint, descendants bool) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if !descendants {
+			return db.Where(`EXISTS (
+				SELECT 1 FROM producto_categoria pc
+				WHERE pc.id_producto = producto.id_producto AND pc.id_categoria = ?)`, id)
+		}
+		return db.Where(`EXISTS (
+			SELECT 1 FROM producto_categoria pc
+			JOIN categoria c ON c.id_categoria = pc.id_categoria
+			WHERE pc.id_producto = producto.id_producto
+				AND c.ruta LIKE (SELECT ruta FROM categoria WHERE id_categoria = ?) || '%')`, id)
+	}
+}
+
+// ListByCategory lista los productos de una categoría y, con descendants, de sus subcategorías
+func (r *Repository) ListByCategory(ctx context.Context, categoryID uint, descendants bool, limit, offset int) ([]domain.Product, int64, error) {
+	var products []domain.Product
+	var total int64
+
+	scope := inCategory(categoryID, descendants)
+	if err := r.db.WithContext(ctx).Model(&domain.Product{}).Scopes(scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := r.db.WithContext(ctx).Scopes(scope).
+		Order("nombre ASC").Order("id_producto ASC").
+		Limit(limit).Offset(offset).
+		Find(&products).Error
+	return products, total, err
+}
+
+/*
+# SearchFilter son los filtros de una búsqueda, ya validados
+* Query es una tsquery armada por el servicio ("leche:* & entera:*")
+* Sort es una de las claves de searchSorts
+*/
+type SearchFilter struct {
+	Query       string
+	MinPrice    *float64
+	MaxPrice    *float64
+	ExpiresFrom *time.Time
+	ExpiresTo   *time.Time
+	InStock     bool
+	CategoryID  *uint
+	Sort        string
+	Limit       int
+	Offset      int
+}
+
+// SearchHit es un producto encontrado con su relevancia (0 sin Query)
+type SearchHit struct {
+	domain.Product
+	Rank float64 `gorm:"column:relevancia"`
+}
+
+var searchSorts = map[string]string{
+	"relevancia":   "relevancia DESC",
+	"precio":       "precio ASC",
+	"-precio":      "precio DESC",
+	"vencimiento":  "fecha_vencimiento ASC",
+	"-vencimiento": "fecha_vencimiento DESC",
+	"nombre":       "nombre ASC",
+	"-nombre":      "nombre DESC",
+}
+
+func (f SearchFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.Query != "" {
+		db = db.Where("busqueda @@ to_tsquery('es_unaccent', ?)", f.Query)
+	}
+	if f.MinPrice != nil {
+		db = db.Where("precio >= ?", *f.MinPrice)
+	}
+	if f.MaxPrice != nil {
+		db = db.Where("precio <= ?", *f.MaxPrice)
+	}
+	if f.ExpiresFrom != nil {
+		db = db.Where("fecha_vencimiento >= ?", *f.ExpiresFrom)
+	}
+	if f.ExpiresTo != nil {
+		db = db.Where("fecha_vencimiento <= ?", *f.ExpiresTo)
+	}
+	if f.InStock {
+		db = db.Where("stock > 0")
+	}
+	if f.CategoryID != nil {
+		db = db.Scopes(inCategory(*f.CategoryID, true))
+	}
+	return db
+}
+
+// Search devuelve una página de productos que cumplen filter y el total
+func (r *Repository) Search(ctx context.Context, filter SearchFilter) ([]SearchHit, int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).Model(&domain.Product{}).Scopes(filter.apply).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := r.db.WithContext(ctx).Model(&domain.Product{}).Scopes(filter.apply)
+	if filter.Query != "" {
+		query = query.Select("producto.*, ts_rank_cd(busqueda, to_tsquery('es_unaccent', ?)) AS relevancia", filter.Query)
+	} else {
+		query = query.Select("producto.*, 0 AS relevancia")
+	}
+
+	order, ok := searchSorts[filter.Sort]
+	if !ok {
+		order = searchSorts["nombre"]
+	}
+
+	var hits []SearchHit
+	err = query.Order(order).Order("id_producto ASC").
+		Limit(filter.Limit).Offset(filter.Offset).
+		Find(&hits).Error
+	return hits, total, err
+}
+
+/*
+# FacetBucket es un valor de faceta y la condición SQL que lo define
+* los valores de una faceta no se solapan
+*/
+type FacetBucket struct {
+	Facet string
+	Value string
+	Where string
+	Args  []any
+}
+
+// CountFacets cuenta, sobre todos los productos que cumplen filter, cuántos
+// caen en cada bucket; el resultado va en el mismo orden que buckets
+func (r *Repository) CountFacets(ctx context.Context, filter SearchFilter, buckets []FacetBucket) ([]int64, error) {
+	columns := make([]string, len(buckets))
+	var args []any
+	for i, b := range buckets {
+		columns[i] = fmt.Sprintf("COUNT(*) FILTER (WHERE %s) AS f%d", b.Where, i)
+		args = append(args, b.Args...)
+	}
+
+	row := map[string]any{}
+	err := r.db.WithContext(ctx).Model(&domain.Product{}).Scopes(filter.apply).
+		Select(strings.Join(columns, ", "), args...).
+		Scan(&row).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]int64, len(buckets))
+	for i := range buckets {
+		if n, ok := row[fmt.Sprintf("f%d", i)].(int64); ok {
+			counts[i] = n
+		}
+	}
+	return counts, nil
+}
+
+// CategoryCount es cuántos productos de la búsqueda hay en el subárbol de una categoría
+type CategoryCount struct {
+	ID     uint   `gorm:"column:id_categoria"`
+	Nombre string `gorm:"column:nombre"`
+	Slug   string `gorm:"column:slug"`
+	Count  int64  `gorm:"column:cantidad"`
+}
+
+/*
+# CountByCategory cuenta los productos que cumplen filter bajo cada hija
+* activa de parentID (las raíces si es nil), sumando sus subcategorías
+* un producto en dos subcategorías de la misma hija cuenta una vez
+*/
+func (r *Repository) CountByCategory(ctx context.Context, filter SearchFilter, parentID *uint) ([]CategoryCount, error) {
+	query := r.db.WithContext(ctx).Model(&domain.Product{}).Scopes(filter.apply).
+		Select("g.id_categoria, g.nombre, g.slug, COUNT(DISTINCT producto.id_producto) AS cantidad").
+		Joins("JOIN producto_categoria pc ON pc.id_producto = producto.id_producto").
+		Joins("JOIN categoria c ON c.id_categoria = pc.id_categoria").
+		Joins("JOIN categoria g ON c.ruta LIKE g.ruta || '%'").
+		Where("g.activa")
+	if parentID != nil {
+		query = query.Where("g.id_padre = ?", *parentID)
+	} else {
+		query = query.Where("g.id_padre IS NULL")
+	}
+
+	var counts []CategoryCount
+	err := query.Group("g.id_categoria, g.nombre, g.slug").
+		Order("cantidad DESC").Order("g.nombre ASC").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// ExportRow es un producto con el vencimiento y las unidades de sus lotes disponibles
+type ExportRow struct {
+	domain.Product
+	NearestLot *time.Time `gorm:"column:vencimiento_lote"`
+	LotStock   int        `gorm:"column:stock_lotes"`
+}
+
+/*
+# ExportProducts recorre todo el catálogo de a batchSize productos, por id
+* se pagina por id (keyset) para no cargar el catálogo completo ni
+* depender de OFFSET
+*/
+func (r *Repository) ExportProducts(ctx context.Context, batchSize int, fn func([]ExportRow) error) error {
+	var lastID uint
+	for {
+		var rows []ExportRow
+		err := r.db.WithContext(ctx).Model(&domain.Product{}).
+			Select("producto.*, l.vencimiento_lote, COALESCE(l.stock_lotes, 0) AS stock_lotes").
+			Joins(`LEFT JOIN (
+				SELECT id_producto, MIN(fecha_vencimiento) AS vencimiento_lote, SUM(cantidad) AS stock_lotes
+				FROM lote WHERE cantidad > 0 GROUP BY id_producto
+			) l ON l.id_producto = producto.id_producto`).
+			Where("producto.id_producto > ?", lastID).
+			Order("producto.id_producto ASC").
+			Limit(batchSize).
+			Find(&rows).Error
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		if err := fn(rows); err != nil {
+			return err
+		}
+		if len(rows) < batchSize {
+			return nil
+		}
+		lastID = rows[len(rows)-1].ID
+	}
+}
+
+func (r *Repository) CreateImport(ctx context.Context, job *domain.ProductImport) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+// FindImport devuelve la importación sin el archivo
+func (r *Repository) FindImport(ctx context.Context, id uint) (*domain.ProductImport, error) {
+	var job domain.ProductImport
+	err := r.db.WithContext(ctx).Omit("archivo").First(&job, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("import_not_found", "importación %d no encontrada", id)
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+/*
+# ClaimImport toma la importación pendiente más antigua y la marca procesando
+* SKIP LOCKED deja que varias réplicas tomen importaciones distintas
+* una que lleva staleAfter sin avanzar se da por abandonada (la réplica
+* que la procesaba se cayó) y se vuelve a tomar
+* devuelve nil si no hay ninguna
+*/
+func (r *Repository) ClaimImport(ctx context.Context, staleAfter time.Duration) (*domain.ProductImport, error) {
+	var job domain.ProductImport
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("estado = ? OR (estado = ? AND updated_at < ?)",
+				domain.ImportPending, domain.ImportRunning, time.Now().Add(-staleAfter)).
+			Order("created_at ASC").
+			First(&job).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		job.Estado = domain.ImportRunning
+		job.IniciadaEn = &now
+		return tx.Model(&job).Updates(map[string]any{
+			"estado":      job.Estado,
+			"iniciada_en": now,
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// UpdateImportProgress guarda los contadores de una importación en curso
+func (r *Repository) UpdateImportProgress(ctx context.Context, job *domain.ProductImport) error {
+	return r.db.WithContext(ctx).Model(job).Updates(map[string]any{
+		"filas":        job.Filas,
+		"creados":      job.Creados,
+		"actualizados": job.Actualizados,
+		"con_error":    job.ConError,
+	}).Error
+}
+
+// FinishImport guarda el resultado y borra el archivo
+func (r *Repository) FinishImport(ctx context.Context, job *domain.ProductImport) error {
+	job.Archivo = nil
+	return r.db.WithContext(ctx).Model(job).
+		Select("estado", "filas", "creados", "actualizados", "con_error", "errores", "mensaje", "terminada_en", "archivo").
+		Updates(job).Error
+}
+
+/*
+# ChangePrice cambia el precio ya mismo y lo deja en la historia
+* el precio anterior se lee con la fila bloqueada, así dos cambios a la vez
+* quedan encadenados en la historia
+*/
+func (r *Repository) ChangePrice(ctx context.Context, productID uint, change *domain.PriceChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var product domain.Product
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, productID).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.NotFound("product_not_found", "producto con id %d no encontrado", productID)
+			}
+			return err
+		}
+		if product.Precio == change.Precio {
+			return domain.Unprocessable("price_unchanged", "el producto ya tiene el precio %v", change.Precio)
+		}
+
+		previous := product.Precio
+		if err := tx.Model(&product).Update("precio", change.Precio).Error; err != nil {
+			return err
+		}
+		change.PrecioAnterior = &previous
+		return recordPriceChange(tx, productID, change)
+	})
+}
+
+// SchedulePrice programa un cambio; no puede haber otro pendiente del mismo
+// producto en el mismo instante
+func (r *Repository) SchedulePrice(ctx context.Context, scheduled *domain.ScheduledPrice) error {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.ScheduledPrice{}).
+		Where("id_producto = ? AND vigente_desde = ? AND estado = ?", scheduled.IDProducto, scheduled.VigenteDesde, domain.ScheduledPending).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return domain.Conflict("price_already_scheduled", "ya hay un cambio de precio programado para %s", scheduled.VigenteDesde.Format(time.RFC3339))
+	}
+
+	return r.db.WithContext(ctx).Create(scheduled).Error
+}
+
+// PriceHistory devuelve los cambios aplicados, del más antiguo al más reciente
+func (r *Repository) PriceHistory(ctx context.Context, productID uint) ([]domain.PriceChange, error) {
+	var changes []domain.PriceChange
+	err := r.db.WithContext(ctx).Where("id_producto = ?", productID).
+		Order("vigente_desde ASC, id_cambio_precio ASC").
+		Find(&changes).Error
+	return changes, err
+}
+
+// ScheduledPrices devuelve los cambios programados que no se aplicaron
+// (pendientes y cancelados), por fecha
+func (r *Repository) ScheduledPrices(ctx context.Context, productID uint) ([]domain.ScheduledPrice, error) {
+	var scheduled []domain.ScheduledPrice
+	err := r.db.WithContext(ctx).
+		Where("id_producto = ? AND estado <> ?", productID, domain.ScheduledApplied).
+		Order("vigente_desde ASC, id_precio_programado ASC").
+		Find(&scheduled).Error
+	return scheduled, err
+}
+
+func (r *Repository) FindScheduledPrice(ctx context.Context, id uint) (*domain.ScheduledPrice, error) {
+	var scheduled domain.ScheduledPrice
+	err := r.db.WithContext(ctx).First(&scheduled, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("scheduled_price_not_found", "cambio de precio programado %d no encontrado", id)
+		}
+		return nil, err
+	}
+
+	return &scheduled, nil
+}
+
+// CancelScheduledPrice cancela un cambio que sigue pendiente
+func (r *Repository) CancelScheduledPrice(ctx context.Context, scheduled *domain.ScheduledPrice, cancelledBy *uint) error {
+	result := r.db.WithContext(ctx).Model(scheduled).
+		Where("estado = ?", domain.ScheduledPending).
+		Updates(map[string]any{"estado": domain.ScheduledCancelled, "cancelado_por": cancelledBy})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.Conflict("scheduled_price_not_pending", "el cambio de precio programado %d ya no está pendiente", scheduled.ID)
+	}
+	scheduled.Estado = domain.ScheduledCancelled
+	scheduled.CanceladoPor = cancelledBy
+	return nil
+}
+
+/*
+# ApplyDuePrices aplica hasta limit cambios programados que ya llegaron a su fecha
+* van en orden de fecha, así si se acumularon varios del mismo producto el
+* último es el que queda
+* SKIP LOCKED deja que varias réplicas trabajen sin aplicar dos veces el
+* mismo cambio
+* el de un producto borrado se cancela
+* devuelve cuántos tomó; si son limit puede haber más
+*/
+func (r *Repository) ApplyDuePrices(ctx context.Context, now time.Time, limit int) (int, error) {
+	var due []domain.ScheduledPrice
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("estado = ? AND vigente_desde <= ?", domain.ScheduledPending, now).
+			Order("vigente_desde ASC, id_precio_programado ASC").
+			Limit(limit).
+			Find(&due).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range due {
+			scheduled := &due[i]
+			var product domain.Product
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, scheduled.IDProducto).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				if err := tx.Model(scheduled).Update("estado", domain.ScheduledCancelled).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if product.Precio != scheduled.Precio {
+				previous := product.Precio
+				if err := tx.Model(&product).Update("precio", scheduled.Precio).Error; err != nil {
+					return err
+				}
+				err := recordPriceChange(tx, product.ID, &domain.PriceChange{
+					PrecioAnterior:     &previous,
+					Precio:             scheduled.Precio,
+					VigenteDesde:       scheduled.VigenteDesde,
+					Origen:             domain.PriceScheduled,
+					Motivo:             scheduled.Motivo,
+					IDUsuario:          scheduled.IDUsuario,
+					IDPrecioProgramado: &scheduled.ID,
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			err = tx.Model(scheduled).Updates(map[string]any{
+				"estado":      domain.ScheduledApplied,
+				"aplicado_en": now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(due), err
+}
+
+// RepricedSince devuelve los productos con algún cambio de precio desde since
+func (r *Repository) RepricedSince(ctx context.Context, since time.Time) ([]uint, error) {
+	var ids []uint
+	db := r.db.WithContext(ctx)
+	err := db.Model(&domain.PriceChange{}).
+		Where("vigente_desde >= ?", since).
+		Where("id_producto IN (?)", db.Model(&domain.Product{}).Select("id_producto")).
+		Distinct().Order("id_producto ASC").
+		Pluck("id_producto", &ids).Error
+	return ids, err
 }
@@ -0,0 +1,224 @@
+package catalog
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/platform/gs1"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+)
+
+var skuPattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9._-]*$`)
+
+// CategoryDefaults da los valores que un producto hereda de su categoría;
+// de aquí sale la anticipación con la que un lote pasa a por_vencer
+type CategoryDefaults interface {
+	DefaultsForProduct(ctx context.Context, productID uint) (domain.CategoryDefaults, error)
+}
+
+// SetCategoryDefaults registra la fuente de valores por categoría. Sin ella
+// se usan los del sistema.
+func (s *Service) SetCategoryDefaults(defaults CategoryDefaults) {
+	s.defaults = defaults
+}
+
+// categoryDefaults resuelve los valores del producto; si no se pueden
+// resolver se usan los del sistema
+func (s *Service) categoryDefaults(ctx context.Context, productID uint) domain.CategoryDefaults {
+	if s.defaults == nil {
+		return domain.SystemCategoryDefaults()
+	}
+	defaults, err := s.defaults.DefaultsForProduct(ctx, productID)
+	if err != nil {
+		slog.WarnContext(ctx, "could not resolve category defaults", "product_id", productID, "error", err)
+		return domain.SystemCategoryDefaults()
+	}
+	return defaults
+}
+
+func (s *Service) alertDays(ctx context.Context, productID uint) int {
+	return s.categoryDefaults(ctx, productID).AlertDays
+}
+
+/*
+# applyCodes valida y asigna el GTIN y el SKU que vengan en la solicitud
+* el GTIN se guarda en 14 dígitos para que el EAN-13 del empaque y el
+* GTIN-14 de una etiqueta GS1-128 encuentren el mismo producto
+* el SKU se guarda en mayúsculas; no puede parecer un GTIN porque al
+* escanearlo se leería como tal
+*/
+func (s *Service) applyCodes(ctx context.Context, product *domain.Product, gtin, sku string) error {
+	if gtin = strings.TrimSpace(gtin); gtin != "" {
+		normalized, err := gs1.NormalizeGTIN(gtin)
+		if err != nil {
+			return domain.InvalidField("gtin", "invalid_gtin", "el GTIN debe tener 8, 12, 13 o 14 dígitos con su dígito de control correcto")
+		}
+		taken, err := s.repo.CodeTaken(ctx, "gtin", normalized, product.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return domain.Conflict("gtin_taken", "ya existe un producto con el GTIN %s", gtin)
+		}
+		product.GTIN = &normalized
+	}
+
+	if sku = strings.ToUpper(strings.TrimSpace(sku)); sku != "" {
+		if !skuPattern.MatchString(sku) || (gs1.IsDigits(sku) && gs1.IsGTINLength(len(sku))) {
+			return domain.InvalidField("sku", "invalid_sku", "el SKU solo puede tener letras, números, puntos, guiones y guiones bajos, y no puede tener la forma de un GTIN")
+		}
+		taken, err := s.repo.CodeTaken(ctx, "sku", sku, product.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return domain.Conflict("sku_taken", "ya existe un producto con el SKU %s", sku)
+		}
+		product.SKU = &sku
+	}
+	return nil
+}
+
+// ReceiveLot da entrada a un lote del producto y suma sus unidades al stock
+func (s *Service) ReceiveLot(ctx context.Context, productID uint, req ReceiveLotRequest) (_ *LotResponse, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.ReceiveLot")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.repo.FindByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	today := time.Now()
+	if domain.DaysUntil(req.FechaVencimiento, today) < 0 {
+		return nil, domain.InvalidField("fecha_vencimiento", "expiration_in_past", "la fecha de vencimiento no puede ser en el pasado")
+	}
+
+	lot, err := s.repo.ReceiveLot(ctx, productID, strings.TrimSpace(req.Numero), req.FechaVencimiento, req.Cantidad, req.CostoUnitario)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.ToLotResponse(lot, today, s.alertDays(ctx, productID))
+	return &response, nil
+}
+
+// Lots lista los lotes del producto, incluidos los agotados
+func (s *Service) Lots(ctx context.Context, productID uint) (_ []LotResponse, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.Lots")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.repo.FindByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	lots, err := s.repo.ListLots(ctx, productID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	today := time.Now()
+	alertDays := s.alertDays(ctx, productID)
+	responses := make([]LotResponse, len(lots))
+	for i := range lots {
+		responses[i] = s.ToLotResponse(&lots[i], today, alertDays)
+	}
+	return responses, nil
+}
+
+/*
+# Scan resuelve un código escaneado en el piso de venta
+* una cadena GS1-128 se interpreta (GTIN, lote y vencimiento)
+* 8, 12, 13 o 14 dígitos son un GTIN y se valida su dígito de control
+* cualquier otra cosa se busca como SKU
+*/
+func (s *Service) Scan(ctx context.Context, code string) (_ *ScanResponse, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.Scan")
+	defer func() { tracing.End(span, err) }()
+
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, domain.Required("code")
+	}
+
+	var product *domain.Product
+	var label *gs1.Label
+	switch {
+	case gs1.LooksLikeElementString(code):
+		label, err = gs1.Parse(code)
+		if err != nil {
+			return nil, domain.InvalidField("code", "invalid_gs1_label", "la etiqueta GS1-128 no se pudo interpretar")
+		}
+		if label.GTIN == "" {
+			return nil, domain.InvalidField("code", "gs1_without_gtin", "la etiqueta GS1-128 no trae GTIN (01)")
+		}
+		product, err = s.repo.FindByGTIN(ctx, label.GTIN)
+	case gs1.IsDigits(code) && gs1.IsGTINLength(len(code)):
+		gtin, normErr := gs1.NormalizeGTIN(code)
+		if normErr != nil {
+			return nil, domain.InvalidField("code", "invalid_gtin", "el GTIN debe tener 8, 12, 13 o 14 dígitos con su dígito de control correcto")
+		}
+		product, err = s.repo.FindByGTIN(ctx, gtin)
+	default:
+		product, err = s.repo.FindBySKU(ctx, strings.ToUpper(code))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lots, err := s.repo.ListLots(ctx, product.ID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	today := time.Now()
+	alertDays := s.alertDays(ctx, product.ID)
+
+	response := &ScanResponse{
+		Product:           s.ToResponse(product),
+		Lots:              make([]LotResponse, len(lots)),
+		UnlottedStock:     product.Stock,
+		NearestExpiration: product.FechaVencimiento,
+		AlertDays:         alertDays,
+	}
+	for i := range lots {
+		response.Lots[i] = s.ToLotResponse(&lots[i], today, alertDays)
+		response.UnlottedStock -= lots[i].Cantidad
+	}
+	response.UnlottedStock = max(response.UnlottedStock, 0)
+	if len(lots) > 0 {
+		response.NearestExpiration = lots[0].FechaVencimiento
+	}
+	response.DaysUntilExpiry = domain.DaysUntil(response.NearestExpiration, today)
+	response.Status = string(domain.ExpiryStatusFor(response.NearestExpiration, today, alertDays))
+
+	if label != nil {
+		response.Label = &ScannedLabel{GTIN: label.GTIN, Lote: label.Batch, FechaVencimiento: label.Expiration}
+		if label.Batch != "" {
+			for _, lot := range response.Lots {
+				if lot.Numero == label.Batch {
+					response.ScannedLot = &lot
+					break
+				}
+			}
+		}
+	}
+
+	return response, nil
+}
+
+func (s *Service) ToLotResponse(lot *domain.Lot, today time.Time, alertDays int) LotResponse {
+	return LotResponse{
+		ID:               lot.ID,
+		Numero:           lot.Numero,
+		FechaVencimiento: lot.FechaVencimiento,
+		CantidadRecibida: lot.CantidadRecibida,
+		Cantidad:         lot.Cantidad,
+		CostoUnitario:    lot.CostoUnitario,
+		DiasParaVencer:   domain.DaysUntil(lot.FechaVencimiento, today),
+		Estado:           string(domain.ExpiryStatusFor(lot.FechaVencimiento, today, alertDays)),
+	}
+}
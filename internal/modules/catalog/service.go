@@ -1,56 +1,107 @@
 package catalog
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/mordmora/expirapp/internal/domain"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/mordmora/expirapp/internal/platform/metrics"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
 )
 
 type Service struct {
-	repo *Repository
+	repo     *Repository
+	defaults CategoryDefaults
 }
 
 func NewService(repo *Repository) *Service {
 	return &Service{repo: repo}
 }
 
-func (s *Service) Create(req CreateProductRequest) (*domain.Product, error) {
+// Create crea el producto; actor es quien lo crea (nil sin credenciales) y
+// queda en la historia de precios
+func (s *Service) Create(ctx context.Context, req CreateProductRequest, actor *uint) (_ *domain.Product, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.Create")
+	defer func() { tracing.End(span, err) }()
+
+	product, err := s.newProduct(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	change := initialPrice(product, actor, time.Now())
+	if err := s.repo.Create(ctx, product, change); err != nil {
+		return nil, fmt.Errorf("error creating product: %w", err)
+	}
+
+	return product, nil
+}
+
+// newProduct valida req y arma el producto, sin guardarlo
+func (s *Service) newProduct(ctx context.Context, req CreateProductRequest) (*domain.Product, error) {
 	if req.FechaVencimiento.Before(time.Now()) {
-		return nil, errors.New("la fecha de vencimiento no puede ser en el pasado")
+		return nil, domain.InvalidField("fecha_vencimiento", "expiration_in_past", "la fecha de vencimiento no puede ser en el pasado")
 	}
 
 	product := &domain.Product{
 		Nombre:           req.Nombre,
 		Descripcion:      req.Descripcion,
 		Precio:           req.Precio,
+		Costo:            req.Costo,
 		FechaVencimiento: req.FechaVencimiento,
 		Stock:            req.Stock,
 	}
 
-	if err := s.repo.Create(product); err != nil {
-		return nil, fmt.Errorf("error creating product: %w", err)
+	if err := s.applyCodes(ctx, product, req.GTIN, req.SKU); err != nil {
+		return nil, err
 	}
 
 	return product, nil
 }
 
-func (s *Service) GetById(id uint) (*domain.Product, error) {
-	return s.repo.FindByID(id)
+func (s *Service) GetById(ctx context.Context, id uint) (_ *domain.Product, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.GetById")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindByID(ctx, id)
 }
 
-func (s *Service) GetByName(name string) (*domain.Product, error) {
-	return s.repo.FindByName(name)
+func (s *Service) GetByName(ctx context.Context, name string) (_ *domain.Product, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.GetByName")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindByName(ctx, name)
 }
 
-func (s *Service) Update(id uint, req UpdateProductRequest) (*domain.Product, error) {
-	product, err := s.repo.FindByID(id)
+// Update actualiza el producto; si cambia el precio, queda en la historia
+// con motivo_precio y actor
+func (s *Service) Update(ctx context.Context, id uint, req UpdateProductRequest, actor *uint) (_ *domain.Product, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.Update")
+	defer func() { tracing.End(span, err) }()
+
+	product, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
+	previous := product.Precio
+	if err := s.applyUpdate(ctx, product, req); err != nil {
+		return nil, err
+	}
+
+	change := priceChange(previous, product, domain.PriceManual, req.MotivoPrecio, actor, time.Now())
+	if err := s.repo.Update(ctx, product, change); err != nil {
+		return nil, fmt.Errorf("error updating product: %w", err)
+	}
+
+	return product, nil
+}
+
+// applyUpdate valida req y lo aplica sobre product, sin guardarlo
+func (s *Service) applyUpdate(ctx context.Context, product *domain.Product, req UpdateProductRequest) error {
 	if req.Nombre != "" {
 		product.Nombre = req.Nombre
 	}
@@ -63,9 +114,13 @@ func (s *Service) Update(id uint, req UpdateProductRequest) (*domain.Product, er
 		product.Precio = req.Precio
 	}
 
+	if req.Costo != nil {
+		product.Costo = req.Costo
+	}
+
 	if !req.FechaVencimiento.IsZero() {
 		if req.FechaVencimiento.Before(time.Now()) {
-			return nil, errors.New("la fecha de vencimiento no puede ser en el pasado")
+			return domain.InvalidField("fecha_vencimiento", "expiration_in_past", "la fecha de vencimiento no puede ser en el pasado")
 		}
 		product.FechaVencimiento = req.FechaVencimiento
 	}
@@ -74,23 +129,25 @@ func (s *Service) Update(id uint, req UpdateProductRequest) (*domain.Product, er
 		product.Stock = req.Stock
 	}
 
-	if err := s.repo.Update(product); err != nil {
-		return nil, fmt.Errorf("error updating product: %w", err)
-	}
-
-	return product, nil
+	return s.applyCodes(ctx, product, req.GTIN, req.SKU)
 }
 
-func (s *Service) Delete(id uint) error {
-	_, err := s.repo.FindByID(id)
+func (s *Service) Delete(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.repo.FindByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	return s.repo.Delete(id)
+	return s.repo.Delete(ctx, id)
 }
 
-func (s *Service) List(page, limit int) ([]domain.Product, int64, error) {
+func (s *Service) List(ctx context.Context, page, limit int) (_ []domain.Product, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.List")
+	defer func() { tracing.End(span, err) }()
+
 	if page < 1 {
 		page = 1
 	}
@@ -99,32 +156,75 @@ func (s *Service) List(page, limit int) ([]domain.Product, int64, error) {
 	}
 
 	offset := (page - 1) * limit
-	return s.repo.List(limit, offset)
+	return s.repo.List(ctx, limit, offset)
 }
 
-func (s *Service) GetByExpirationDate(date time.Time) ([]domain.Product, error) {
-	return s.repo.FindByExpirationDate(date)
+// ListByCategory lista los productos de una categoría; con descendants
+// también los de sus subcategorías
+func (s *Service) ListByCategory(ctx context.Context, categoryID uint, descendants bool, page, limit int) (_ []domain.Product, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.ListByCategory")
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.repo.EnsureCategory(ctx, categoryID); err != nil {
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+	return s.repo.ListByCategory(ctx, categoryID, descendants, limit, offset)
 }
 
-func (s *Service) GetExpiringSoon(days int) ([]domain.Product, error) {
+func (s *Service) GetByExpirationDate(ctx context.Context, date time.Time) (_ []domain.Product, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.GetByExpirationDate")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindByExpirationDate(ctx, date)
+}
+
+func (s *Service) GetExpiringSoon(ctx context.Context, days int) (_ []domain.Product, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.GetExpiringSoon")
+	defer func() { tracing.End(span, err) }()
+
 	if days < 1 {
 		days = 7 
 	}
-	return s.repo.FindExpiringSoon(days)
+	return s.repo.FindExpiringSoon(ctx, days)
 }
 
-func (s *Service) UpdateStock(id uint, quantity int) error {
-	product, err := s.repo.FindByID(id)
+/*
+# UpdateStock ajusta el stock del producto en quantity unidades
+* un ajuste negativo es una baja: queda registrada por lote con reason
+* (vencimiento, averia, merma o conteo) y userID, ver WriteOffStock
+*/
+func (s *Service) UpdateStock(ctx context.Context, id uint, quantity int, reason string, userID *uint) (err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.UpdateStock")
+	defer func() { tracing.End(span, err) }()
+
+	product, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	newStock := product.Stock + quantity
 	if newStock < 0 {
-		return errors.New("stock insuficiente: no se puede reducir el stock por debajo de 0")
+		return domain.InsufficientStock(product, product.Stock, -quantity)
+	}
+
+	if quantity >= 0 {
+		return s.repo.UpdateStock(ctx, id, quantity)
 	}
 
-	return s.repo.UpdateStock(id, quantity)
+	if err := s.repo.WriteOffStock(ctx, id, -quantity, reason, userID, time.Now()); err != nil {
+		return err
+	}
+	metrics.StockWriteOffs.Add(float64(-quantity))
+	return nil
 }
 
 func (s *Service) ToResponse(product *domain.Product) ProductResponse {
@@ -133,7 +233,164 @@ func (s *Service) ToResponse(product *domain.Product) ProductResponse {
 		Nombre:           product.Nombre,
 		Descripcion:      product.Descripcion,
 		Precio:           product.Precio,
+		Costo:            product.Costo,
 		FechaVencimiento: product.FechaVencimiento,
 		Stock:            product.Stock,
+		GTIN:             product.GTIN,
+		SKU:              product.SKU,
+	}
+}
+
+// maxSearchTerms limita las palabras de q que llegan a la tsquery
+const maxSearchTerms = 10
+
+/*
+# Search busca productos por texto y filtros, y cuenta las facetas
+* las facetas se cuentan sobre todos los resultados, no solo la página
+*/
+func (s *Service) Search(ctx context.Context, req SearchProductsRequest) (_ *ProductSearchResponse, err error) {
+	ctx, span := tracing.Start(ctx, "catalog.Service.Search")
+	defer func() { tracing.End(span, err) }()
+
+	if req.MinPrice != nil && req.MaxPrice != nil && *req.MinPrice > *req.MaxPrice {
+		return nil, domain.InvalidField("precio_max", "invalid_price_range", "precio_max debe ser mayor o igual a precio_min")
+	}
+	if req.ExpiresFrom != nil && req.ExpiresTo != nil && req.ExpiresTo.Before(*req.ExpiresFrom) {
+		return nil, domain.InvalidField("vence_hasta", "invalid_expiration_range", "vence_hasta debe ser posterior a vence_desde")
+	}
+
+	if req.CategoryID != nil {
+		if err := s.repo.EnsureCategory(ctx, *req.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 || req.Limit > 100 {
+		req.Limit = 10
+	}
+
+	filter := SearchFilter{
+		Query:       prefixQuery(req.Query),
+		MinPrice:    req.MinPrice,
+		MaxPrice:    req.MaxPrice,
+		ExpiresFrom: req.ExpiresFrom,
+		ExpiresTo:   req.ExpiresTo,
+		InStock:     req.InStock,
+		CategoryID:  req.CategoryID,
+		Sort:        req.Sort,
+		Limit:       req.Limit,
+		Offset:      (req.Page - 1) * req.Limit,
+	}
+	// sin texto no hay relevancia que ordenar
+	switch {
+	case filter.Query == "" && (filter.Sort == "" || filter.Sort == "relevancia"):
+		filter.Sort = "nombre"
+	case filter.Sort == "":
+		filter.Sort = "relevancia"
+	}
+
+	hits, total, err := s.repo.Search(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	facets, err := s.searchFacets(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ProductSearchResult, len(hits))
+	for i := range hits {
+		results[i] = ProductSearchResult{
+			ProductResponse: s.ToResponse(&hits[i].Product),
+			Relevancia:      hits[i].Rank,
+		}
+	}
+
+	return &ProductSearchResponse{
+		Products: results,
+		Total:    total,
+		Page:     req.Page,
+		Limit:    req.Limit,
+		Facets:   facets,
+	}, nil
+}
+
+/*
+# searchFacets arma los buckets de cada faceta y los cuenta en una consulta
+* precio: rangos fijos en pesos
+* vencimiento: relativo a hoy
+* disponibilidad: con y sin stock
+* categoria: un nivel más abajo de la categoría filtrada
+*/
+func (s *Service) searchFacets(ctx context.Context, filter SearchFilter) (SearchFacets, error) {
+	today := time.Now().Truncate(24 * time.Hour)
+	days := func(n int) time.Time { return today.AddDate(0, 0, n) }
+
+	buckets := []FacetBucket{
+		{Facet: "precio", Value: "0-5000", Where: "precio < ?", Args: []any{5000}},
+		{Facet: "precio", Value: "5000-10000", Where: "precio >= ? AND precio < ?", Args: []any{5000, 10000}},
+		{Facet: "precio", Value: "10000-20000", Where: "precio >= ? AND precio < ?", Args: []any{10000, 20000}},
+		{Facet: "precio", Value: "20000-50000", Where: "precio >= ? AND precio < ?", Args: []any{20000, 50000}},
+		{Facet: "precio", Value: "50000+", Where: "precio >= ?", Args: []any{50000}},
+
+		{Facet: "vencimiento", Value: "vencido", Where: "fecha_vencimiento < ?", Args: []any{today}},
+		{Facet: "vencimiento", Value: "7_dias", Where: "fecha_vencimiento >= ? AND fecha_vencimiento < ?", Args: []any{today, days(7)}},
+		{Facet: "vencimiento", Value: "30_dias", Where: "fecha_vencimiento >= ? AND fecha_vencimiento < ?", Args: []any{days(7), days(30)}},
+		{Facet: "vencimiento", Value: "90_dias", Where: "fecha_vencimiento >= ? AND fecha_vencimiento < ?", Args: []any{days(30), days(90)}},
+		{Facet: "vencimiento", Value: "mas_de_90", Where: "fecha_vencimiento >= ?", Args: []any{days(90)}},
+
+		{Facet: "disponibilidad", Value: "en_stock", Where: "stock > 0"},
+		{Facet: "disponibilidad", Value: "agotado", Where: "stock <= 0"},
+	}
+
+	counts, err := s.repo.CountFacets(ctx, filter, buckets)
+	if err != nil {
+		return SearchFacets{}, err
+	}
+
+	facets := SearchFacets{}
+	for i, b := range buckets {
+		count := FacetCount{Value: b.Value, Count: counts[i]}
+		switch b.Facet {
+		case "precio":
+			facets.Price = append(facets.Price, count)
+		case "vencimiento":
+			facets.Expiration = append(facets.Expiration, count)
+		case "disponibilidad":
+			facets.Availability = append(facets.Availability, count)
+		}
+	}
+
+	categories, err := s.repo.CountByCategory(ctx, filter, filter.CategoryID)
+	if err != nil {
+		return SearchFacets{}, err
+	}
+	facets.Category = make([]CategoryFacetCount, len(categories))
+	for i, c := range categories {
+		facets.Category[i] = CategoryFacetCount(c)
+	}
+	return facets, nil
+}
+
+/*
+# prefixQuery convierte el texto del usuario en una tsquery de prefijos
+* "Leche entera" -> "leche:* & entera:*"
+* solo deja letras y dígitos, así ningún carácter del usuario llega a la
+* sintaxis de tsquery
+*/
+func prefixQuery(q string) string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > maxSearchTerms {
+		words = words[:maxSearchTerms]
+	}
+	for i, w := range words {
+		words[i] = w + ":*"
 	}
+	return strings.Join(words, " & ")
 }
@@ -0,0 +1,80 @@
+package categories
+
+import "github.com/mordmora/expirapp/internal/domain"
+
+type MarkdownRuleRequest struct {
+	DiasAntes int     `json:"dias_antes" binding:"min=0,max=365"`
+	Descuento float64 `json:"descuento" binding:"gt=0,max=100"`
+}
+
+/*
+# CreateCategoryRequest
+* sin slug se genera a partir del nombre
+* dias_alerta, clase_impuesto y reglas_rebaja sin enviar se heredan del padre
+*/
+type CreateCategoryRequest struct {
+	IDPadre       *uint                  `json:"id_padre" binding:"omitempty"`
+	Nombre        string                 `json:"nombre" binding:"required,min=1,max=100"`
+	Slug          string                 `json:"slug" binding:"omitempty,max=120"`
+	Descripcion   string                 `json:"descripcion" binding:"omitempty"`
+	DiasAlerta    *int                   `json:"dias_alerta" binding:"omitempty,min=1,max=365"`
+	ClaseImpuesto *string                `json:"clase_impuesto" binding:"omitempty,oneof=general reducido exento"`
+	ReglasRebaja  *[]MarkdownRuleRequest `json:"reglas_rebaja" binding:"omitempty,max=10,dive"`
+}
+
+/*
+# UpdateCategoryRequest
+* id_padre mueve la categoría con todo su subárbol; 0 la vuelve raíz
+* heredar vuelve a heredar del padre los valores listados
+*/
+type UpdateCategoryRequest struct {
+	IDPadre       *uint                  `json:"id_padre" binding:"omitempty"`
+	Nombre        string                 `json:"nombre" binding:"omitempty,min=1,max=100"`
+	Slug          string                 `json:"slug" binding:"omitempty,max=120"`
+	Descripcion   *string                `json:"descripcion" binding:"omitempty"`
+	Activa        *bool                  `json:"activa" binding:"omitempty"`
+	DiasAlerta    *int                   `json:"dias_alerta" binding:"omitempty,min=1,max=365"`
+	ClaseImpuesto *string                `json:"clase_impuesto" binding:"omitempty,oneof=general reducido exento"`
+	ReglasRebaja  *[]MarkdownRuleRequest `json:"reglas_rebaja" binding:"omitempty,max=10,dive"`
+	Heredar       []string               `json:"heredar" binding:"omitempty,dive,oneof=dias_alerta clase_impuesto reglas_rebaja"`
+}
+
+// SetProductCategoriesRequest reemplaza las categorías de un producto; sin
+// principal, la principal es la primera de la lista
+type SetProductCategoriesRequest struct {
+	Categorias []uint `json:"categorias" binding:"max=20"`
+	Principal  *uint  `json:"principal" binding:"omitempty"`
+}
+
+// DefaultsResponse son los valores efectivos, ya resueltos con la herencia
+type DefaultsResponse struct {
+	DiasAlerta    int                   `json:"dias_alerta"`
+	ClaseImpuesto string                `json:"clase_impuesto"`
+	ReglasRebaja  []domain.MarkdownRule `json:"reglas_rebaja"`
+}
+
+type CategoryResponse struct {
+	ID            uint                  `json:"id_categoria"`
+	IDPadre       *uint                 `json:"id_padre"`
+	Nombre        string                `json:"nombre"`
+	Slug          string                `json:"slug"`
+	Descripcion   string                `json:"descripcion"`
+	Activa        bool                  `json:"activa"`
+	Nivel         int                   `json:"nivel"`
+	DiasAlerta    *int                  `json:"dias_alerta"`
+	ClaseImpuesto *string               `json:"clase_impuesto"`
+	ReglasRebaja  []domain.MarkdownRule `json:"reglas_rebaja"`
+	Efectivos     *DefaultsResponse     `json:"valores_efectivos,omitempty"`
+	Subcategorias []CategoryResponse    `json:"subcategorias,omitempty"`
+}
+
+type ProductCategoryResponse struct {
+	CategoryResponse
+	Principal bool `json:"principal"`
+}
+
+type ProductCategoriesResponse struct {
+	IDProducto uint                      `json:"id_producto"`
+	Categorias []ProductCategoryResponse `json:"categorias"`
+	Efectivos  DefaultsResponse          `json:"valores_efectivos"`
+}
@@ -0,0 +1,161 @@
+package categories
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	api "github.com/mordmora/expirapp/internal/platform/http"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// CreateCategory crea una categoría, raíz o hija de id_padre
+// POST /api/v1/catalog/categories
+func (h *Handler) CreateCategory(c *gin.Context) {
+	var req CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	category, err := h.service.Create(c.Request.Context(), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Created(c, h.service.ToResponse(category), "category_created")
+}
+
+// ListCategories devuelve el árbol de categorías
+// GET /api/v1/catalog/categories?incluir_inactivas=true
+func (h *Handler) ListCategories(c *gin.Context) {
+	includeInactive, _ := strconv.ParseBool(c.DefaultQuery("incluir_inactivas", "false"))
+
+	tree, err := h.service.Tree(c.Request.Context(), includeInactive)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, tree)
+}
+
+// GetCategory obtiene una categoría con sus valores efectivos
+// GET /api/v1/catalog/categories/:id
+func (h *Handler) GetCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	category, err := h.service.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	defaults, err := h.service.Defaults(c.Request.Context(), category)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	response := h.service.ToResponse(category)
+	effective := ToDefaultsResponse(defaults)
+	response.Efectivos = &effective
+	api.OK(c, response)
+}
+
+// UpdateCategory actualiza o mueve una categoría
+// PUT /api/v1/catalog/categories/:id
+func (h *Handler) UpdateCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	var req UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	category, err := h.service.Update(c.Request.Context(), uint(id), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, h.service.ToResponse(category), "category_updated")
+}
+
+// DeleteCategory elimina una categoría sin subcategorías
+// DELETE /api/v1/catalog/categories/:id
+func (h *Handler) DeleteCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), uint(id)); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "category_deleted")
+}
+
+// GetProductCategories lista las categorías de un producto y los valores
+// que toma de la principal
+// GET /api/v1/catalog/products/:id/categories
+func (h *Handler) GetProductCategories(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	response, err := h.service.ProductCategories(c.Request.Context(), uint(id))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, response)
+}
+
+// SetProductCategories reemplaza las categorías de un producto
+// PUT /api/v1/catalog/products/:id/categories
+func (h *Handler) SetProductCategories(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	var req SetProductCategoriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	response, err := h.service.SetProductCategories(c.Request.Context(), uint(id), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, response, "product_categories_updated")
+}
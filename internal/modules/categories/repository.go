@@ -0,0 +1,223 @@
+package categories
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Create guarda la categoría bajo parent (nil para una raíz); la ruta
+// depende del id, así que se completa en la misma transacción
+func (r *Repository) Create(ctx context.Context, category *domain.Category, parent *domain.Category) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(category).Error; err != nil {
+			return err
+		}
+
+		root := &domain.Category{Ruta: "/"}
+		if parent != nil {
+			root = parent
+		}
+		category.Ruta = root.ChildPath(category.ID)
+		return tx.Model(category).Update("ruta", category.Ruta).Error
+	})
+}
+
+func (r *Repository) FindByID(ctx context.Context, id uint) (*domain.Category, error) {
+	var category domain.Category
+	err := r.db.WithContext(ctx).First(&category, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("category_not_found", "categoría con id %d no encontrada", id)
+		}
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+// FindBySlug devuelve nil sin error si el slug está libre
+func (r *Repository) FindBySlug(ctx context.Context, slug string) (*domain.Category, error) {
+	var category domain.Category
+	err := r.db.WithContext(ctx).Where("slug = ?", slug).First(&category).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *Repository) FindByIDs(ctx context.Context, ids []uint) ([]domain.Category, error) {
+	var categories []domain.Category
+	err := r.db.WithContext(ctx).Where("id_categoria IN ?", ids).Find(&categories).Error
+	return categories, err
+}
+
+// List devuelve todas las categorías ordenadas por nombre; el servicio arma el árbol
+func (r *Repository) List(ctx context.Context) ([]domain.Category, error) {
+	var categories []domain.Category
+	err := r.db.WithContext(ctx).Order("nombre ASC").Find(&categories).Error
+	return categories, err
+}
+
+// Ancestors devuelve la categoría y sus ancestros, del más cercano a la raíz
+func (r *Repository) Ancestors(ctx context.Context, category *domain.Category) ([]domain.Category, error) {
+	ids := pathIDs(category.Ruta)
+
+	found, err := r.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]domain.Category, len(found))
+	for _, c := range found {
+		byID[c.ID] = c
+	}
+
+	chain := make([]domain.Category, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		if c, ok := byID[ids[i]]; ok {
+			chain = append(chain, c)
+		}
+	}
+	return chain, nil
+}
+
+func (r *Repository) Update(ctx context.Context, category *domain.Category) error {
+	return r.db.WithContext(ctx).Save(category).Error
+}
+
+/*
+# Move cuelga la categoría de parent (nil para volverla raíz)
+* reescribe la ruta de la categoría y de todos sus descendientes en un
+* solo UPDATE
+*/
+func (r *Repository) Move(ctx context.Context, category *domain.Category, parent *domain.Category) error {
+	root := &domain.Category{Ruta: "/"}
+	var parentID *uint
+	if parent != nil {
+		root = parent
+		parentID = &parent.ID
+	}
+	oldPath := category.Ruta
+	newPath := root.ChildPath(category.ID)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.Category{}).Where("id_categoria = ?", category.ID).
+			Update("id_padre", parentID).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec(`
+			UPDATE categoria
+			SET ruta = ? || substr(ruta, ?), updated_at = now()
+			WHERE ruta LIKE ?`,
+			newPath, len(oldPath)+1, oldPath+"%").Error
+	})
+	if err != nil {
+		return err
+	}
+
+	category.IDPadre = parentID
+	category.Ruta = newPath
+	return nil
+}
+
+func (r *Repository) HasChildren(ctx context.Context, id uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.Category{}).Where("id_padre = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// HasPromotions dice si alguna promoción, incluso borrada, apunta a la categoría
+func (r *Repository) HasPromotions(ctx context.Context, id uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&domain.Promotion{}).Where("id_categoria = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// Delete borra la categoría; sus asignaciones a productos se borran en cascada
+func (r *Repository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&domain.Category{}, id).Error
+}
+
+// ProductAssignment es una categoría asignada a un producto
+type ProductAssignment struct {
+	domain.Category
+	Principal bool `gorm:"column:principal"`
+}
+
+// ProductCategories devuelve las categorías del producto, la principal primero
+func (r *Repository) ProductCategories(ctx context.Context, productID uint) ([]ProductAssignment, error) {
+	var assignments []ProductAssignment
+	err := r.db.WithContext(ctx).Model(&domain.Category{}).
+		Select("categoria.*, pc.principal").
+		Joins("JOIN producto_categoria pc ON pc.id_categoria = categoria.id_categoria").
+		Where("pc.id_producto = ?", productID).
+		Order("pc.principal DESC, categoria.nombre ASC").
+		Find(&assignments).Error
+	return assignments, err
+}
+
+// SetProductCategories reemplaza las categorías del producto
+func (r *Repository) SetProductCategories(ctx context.Context, productID uint, categoryIDs []uint, principal uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("id_producto = ?", productID).Delete(&domain.ProductCategory{}).Error
+		if err != nil {
+			return err
+		}
+		if len(categoryIDs) == 0 {
+			return nil
+		}
+
+		rows := make([]domain.ProductCategory, len(categoryIDs))
+		for i, id := range categoryIDs {
+			rows[i] = domain.ProductCategory{IDProducto: productID, IDCategoria: id, Principal: id == principal}
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// PrincipalCategory devuelve la categoría principal del producto, o nil si no tiene
+func (r *Repository) PrincipalCategory(ctx context.Context, productID uint) (*domain.Category, error) {
+	var category domain.Category
+	err := r.db.WithContext(ctx).
+		Joins("JOIN producto_categoria pc ON pc.id_categoria = categoria.id_categoria").
+		Where("pc.id_producto = ? AND pc.principal", productID).
+		First(&category).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// pathIDs extrae los ids de una ruta "/1/4/9/", de la raíz a la hoja
+func pathIDs(path string) []uint {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	ids := make([]uint, 0, len(parts))
+	for _, part := range parts {
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
@@ -0,0 +1,460 @@
+package categories
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/modules/catalog"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type Service struct {
+	repo        *Repository
+	catalogRepo *catalog.Repository
+}
+
+func NewService(repo *Repository, catalogRepo *catalog.Repository) *Service {
+	return &Service{
+		repo:        repo,
+		catalogRepo: catalogRepo,
+	}
+}
+
+func (s *Service) Create(ctx context.Context, req CreateCategoryRequest) (_ *domain.Category, err error) {
+	ctx, span := tracing.Start(ctx, "categories.Service.Create")
+	defer func() { tracing.End(span, err) }()
+
+	var parent *domain.Category
+	if req.IDPadre != nil {
+		found, err := s.repo.FindByID(ctx, *req.IDPadre)
+		if err != nil {
+			return nil, err
+		}
+		parent = found
+	}
+
+	slug := req.Slug
+	if slug == "" {
+		slug = Slugify(req.Nombre)
+	}
+	if err := s.checkSlug(ctx, slug, 0); err != nil {
+		return nil, err
+	}
+
+	category := &domain.Category{
+		IDPadre:     req.IDPadre,
+		Nombre:      req.Nombre,
+		Slug:        slug,
+		Descripcion: req.Descripcion,
+		Activa:      true,
+		DiasAlerta:  req.DiasAlerta,
+	}
+	if req.ClaseImpuesto != nil {
+		taxClass := domain.TaxClass(*req.ClaseImpuesto)
+		category.ClaseImpuesto = &taxClass
+	}
+	if req.ReglasRebaja != nil {
+		rules, err := markdownRules(*req.ReglasRebaja)
+		if err != nil {
+			return nil, err
+		}
+		category.ReglasRebaja = rules
+	}
+
+	if err := s.repo.Create(ctx, category, parent); err != nil {
+		return nil, fmt.Errorf("error creating category: %w", err)
+	}
+
+	return category, nil
+}
+
+func (s *Service) GetByID(ctx context.Context, id uint) (_ *domain.Category, err error) {
+	ctx, span := tracing.Start(ctx, "categories.Service.GetByID")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindByID(ctx, id)
+}
+
+func (s *Service) Update(ctx context.Context, id uint, req UpdateCategoryRequest) (_ *domain.Category, err error) {
+	ctx, span := tracing.Start(ctx, "categories.Service.Update")
+	defer func() { tracing.End(span, err) }()
+
+	category, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Nombre != "" {
+		category.Nombre = req.Nombre
+	}
+	if req.Slug != "" && req.Slug != category.Slug {
+		if err := s.checkSlug(ctx, req.Slug, category.ID); err != nil {
+			return nil, err
+		}
+		category.Slug = req.Slug
+	}
+	if req.Descripcion != nil {
+		category.Descripcion = *req.Descripcion
+	}
+	if req.Activa != nil {
+		category.Activa = *req.Activa
+	}
+
+	if req.DiasAlerta != nil {
+		category.DiasAlerta = req.DiasAlerta
+	}
+	if req.ClaseImpuesto != nil {
+		taxClass := domain.TaxClass(*req.ClaseImpuesto)
+		category.ClaseImpuesto = &taxClass
+	}
+	if req.ReglasRebaja != nil {
+		rules, err := markdownRules(*req.ReglasRebaja)
+		if err != nil {
+			return nil, err
+		}
+		category.ReglasRebaja = rules
+	}
+	for _, field := range req.Heredar {
+		switch field {
+		case "dias_alerta":
+			category.DiasAlerta = nil
+		case "clase_impuesto":
+			category.ClaseImpuesto = nil
+		case "reglas_rebaja":
+			category.ReglasRebaja = nil
+		}
+	}
+
+	if req.IDPadre != nil {
+		if err := s.move(ctx, category, *req.IDPadre); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Update(ctx, category); err != nil {
+		return nil, fmt.Errorf("error updating category: %w", err)
+	}
+
+	return category, nil
+}
+
+// move cuelga la categoría de parentID (0 la vuelve raíz) si cambió
+func (s *Service) move(ctx context.Context, category *domain.Category, parentID uint) error {
+	if parentID == 0 {
+		if category.IDPadre == nil {
+			return nil
+		}
+		return s.repo.Move(ctx, category, nil)
+	}
+	if category.IDPadre != nil && *category.IDPadre == parentID {
+		return nil
+	}
+
+	parent, err := s.repo.FindByID(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	// el nuevo padre no puede estar en el subárbol de la categoría
+	if strings.HasPrefix(parent.Ruta, category.Ruta) {
+		return domain.InvalidField("id_padre", "category_cycle",
+			"una categoría no puede quedar dentro de sí misma ni de una de sus subcategorías")
+	}
+
+	return s.repo.Move(ctx, category, parent)
+}
+
+func (s *Service) Delete(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "categories.Service.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
+		return err
+	}
+
+	hasChildren, err := s.repo.HasChildren(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hasChildren {
+		return domain.Conflict("category_has_children", "la categoría tiene subcategorías y no se puede eliminar")
+	}
+
+	hasPromotions, err := s.repo.HasPromotions(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hasPromotions {
+		return domain.Conflict("category_has_promotions", "hay promociones de la categoría y no se puede eliminar")
+	}
+
+	return s.repo.Delete(ctx, id)
+}
+
+/*
+# Tree devuelve el árbol de categorías
+* sin includeInactive una categoría inactiva se omite junto con su subárbol
+*/
+func (s *Service) Tree(ctx context.Context, includeInactive bool) (_ []CategoryResponse, err error) {
+	ctx, span := tracing.Start(ctx, "categories.Service.Tree")
+	defer func() { tracing.End(span, err) }()
+
+	categories, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]domain.Category)
+	var roots []domain.Category
+	for _, c := range categories {
+		if c.IDPadre == nil {
+			roots = append(roots, c)
+			continue
+		}
+		children[*c.IDPadre] = append(children[*c.IDPadre], c)
+	}
+
+	var build func(nodes []domain.Category) []CategoryResponse
+	build = func(nodes []domain.Category) []CategoryResponse {
+		responses := make([]CategoryResponse, 0, len(nodes))
+		for _, node := range nodes {
+			if !node.Activa && !includeInactive {
+				continue
+			}
+			response := s.ToResponse(&node)
+			response.Subcategorias = build(children[node.ID])
+			responses = append(responses, response)
+		}
+		return responses
+	}
+
+	return build(roots), nil
+}
+
+// Defaults resuelve los valores por defecto de la categoría subiendo por
+// sus ancestros hasta encontrar cada uno
+func (s *Service) Defaults(ctx context.Context, category *domain.Category) (_ domain.CategoryDefaults, err error) {
+	ctx, span := tracing.Start(ctx, "categories.Service.Defaults")
+	defer func() { tracing.End(span, err) }()
+
+	defaults := domain.SystemCategoryDefaults()
+	chain, err := s.repo.Ancestors(ctx, category)
+	if err != nil {
+		return defaults, err
+	}
+
+	var alertDays, taxClass, markdown bool
+	for _, c := range chain {
+		if c.DiasAlerta != nil && !alertDays {
+			defaults.AlertDays, alertDays = *c.DiasAlerta, true
+		}
+		if c.ClaseImpuesto != nil && !taxClass {
+			defaults.TaxClass, taxClass = *c.ClaseImpuesto, true
+		}
+		if c.ReglasRebaja != nil && !markdown {
+			defaults.MarkdownRules, markdown = c.ReglasRebaja, true
+		}
+	}
+	return defaults, nil
+}
+
+/*
+# DefaultsForProduct son los valores por defecto de un producto
+* salen de su categoría principal; sin categoría aplican los del sistema
+* es la puerta para que alertas, impuestos y rebajas no dependan del árbol
+*/
+func (s *Service) DefaultsForProduct(ctx context.Context, productID uint) (_ domain.CategoryDefaults, err error) {
+	ctx, span := tracing.Start(ctx, "categories.Service.DefaultsForProduct")
+	defer func() { tracing.End(span, err) }()
+
+	principal, err := s.repo.PrincipalCategory(ctx, productID)
+	if err != nil {
+		return domain.SystemCategoryDefaults(), err
+	}
+	if principal == nil {
+		return domain.SystemCategoryDefaults(), nil
+	}
+	return s.Defaults(ctx, principal)
+}
+
+func (s *Service) ProductCategories(ctx context.Context, productID uint) (_ *ProductCategoriesResponse, err error) {
+	ctx, span := tracing.Start(ctx, "categories.Service.ProductCategories")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.catalogRepo.FindByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	assignments, err := s.repo.ProductCategories(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults := domain.SystemCategoryDefaults()
+	responses := make([]ProductCategoryResponse, len(assignments))
+	for i := range assignments {
+		responses[i] = ProductCategoryResponse{
+			CategoryResponse: s.ToResponse(&assignments[i].Category),
+			Principal:        assignments[i].Principal,
+		}
+		if assignments[i].Principal {
+			defaults, err = s.Defaults(ctx, &assignments[i].Category)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &ProductCategoriesResponse{
+		IDProducto: productID,
+		Categorias: responses,
+		Efectivos:  ToDefaultsResponse(defaults),
+	}, nil
+}
+
+func (s *Service) SetProductCategories(ctx context.Context, productID uint, req SetProductCategoriesRequest) (_ *ProductCategoriesResponse, err error) {
+	ctx, span := tracing.Start(ctx, "categories.Service.SetProductCategories")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.catalogRepo.FindByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(req.Categorias))
+	for _, id := range req.Categorias {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	var principal uint
+	if len(ids) > 0 {
+		principal = ids[0]
+		found, err := s.repo.FindByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			if !slices.ContainsFunc(found, func(c domain.Category) bool { return c.ID == id }) {
+				return nil, domain.NotFound("category_not_found", "categoría con id %d no encontrada", id)
+			}
+		}
+	}
+	if req.Principal != nil {
+		if !slices.Contains(ids, *req.Principal) {
+			return nil, domain.InvalidField("principal", "principal_not_assigned",
+				"la categoría principal debe estar entre las categorías del producto")
+		}
+		principal = *req.Principal
+	}
+
+	if err := s.repo.SetProductCategories(ctx, productID, ids, principal); err != nil {
+		return nil, fmt.Errorf("error assigning categories: %w", err)
+	}
+
+	return s.ProductCategories(ctx, productID)
+}
+
+// checkSlug valida el formato del slug y que no lo use otra categoría que selfID
+func (s *Service) checkSlug(ctx context.Context, slug string, selfID uint) error {
+	if !slugPattern.MatchString(slug) {
+		return domain.InvalidField("slug", "invalid_slug",
+			"el slug solo puede tener minúsculas, números y guiones")
+	}
+
+	existing, err := s.repo.FindBySlug(ctx, slug)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != selfID {
+		return domain.Conflict("category_slug_taken", "ya existe una categoría con el slug %s", slug)
+	}
+	return nil
+}
+
+// markdownRules valida las reglas y las ordena de la más lejana al vencimiento
+// a la más cercana
+func markdownRules(req []MarkdownRuleRequest) (domain.MarkdownRules, error) {
+	rules := make(domain.MarkdownRules, 0, len(req))
+	for _, r := range req {
+		if slices.ContainsFunc(rules, func(m domain.MarkdownRule) bool { return m.DiasAntes == r.DiasAntes }) {
+			return nil, domain.InvalidField("reglas_rebaja", "duplicate_markdown_rule",
+				"hay dos reglas de rebaja para %d días antes del vencimiento", r.DiasAntes)
+		}
+		rules = append(rules, domain.MarkdownRule{DiasAntes: r.DiasAntes, Descuento: r.Descuento})
+	}
+
+	slices.SortFunc(rules, func(a, b domain.MarkdownRule) int { return b.DiasAntes - a.DiasAntes })
+	return rules, nil
+}
+
+/*
+# Slugify arma un slug a partir de un nombre
+* "Lácteos y Huevos" -> "lacteos-y-huevos"
+*/
+func Slugify(name string) string {
+	plain, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn))), name)
+	if err != nil {
+		plain = name
+	}
+
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(plain) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	slug := strings.TrimSuffix(b.String(), "-")
+	if len(slug) > 120 {
+		slug = strings.TrimSuffix(slug[:120], "-")
+	}
+	return slug
+}
+
+func (s *Service) ToResponse(category *domain.Category) CategoryResponse {
+	response := CategoryResponse{
+		ID:           category.ID,
+		IDPadre:      category.IDPadre,
+		Nombre:       category.Nombre,
+		Slug:         category.Slug,
+		Descripcion:  category.Descripcion,
+		Activa:       category.Activa,
+		Nivel:        category.Depth(),
+		DiasAlerta:   category.DiasAlerta,
+		ReglasRebaja: category.ReglasRebaja,
+	}
+	if category.ClaseImpuesto != nil {
+		taxClass := string(*category.ClaseImpuesto)
+		response.ClaseImpuesto = &taxClass
+	}
+	return response
+}
+
+func ToDefaultsResponse(defaults domain.CategoryDefaults) DefaultsResponse {
+	rules := defaults.MarkdownRules
+	if rules == nil {
+		rules = domain.MarkdownRules{}
+	}
+	return DefaultsResponse{
+		DiasAlerta:    defaults.AlertDays,
+		ClaseImpuesto: string(defaults.TaxClass),
+		ReglasRebaja:  rules,
+	}
+}
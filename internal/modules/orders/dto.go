@@ -3,15 +3,17 @@ package orders
 import "time"
 
 type CreateOrderRequest struct {
-	IDCliente  uint                `json:"id_cliente" binding:"required"`
-	IDVendedor *uint               `json:"id_vendedor" binding:"omitempty"`
-	Items      []OrderItemRequest  `json:"items" binding:"required,min=1,dive"`
+	IDCliente   uint                `json:"id_cliente" binding:"required"`
+	IDVendedor  *uint               `json:"id_vendedor" binding:"omitempty"`
+	CodigoCupon string              `json:"codigo_cupon" binding:"omitempty,max=50"`
+	Items       []OrderItemRequest  `json:"items" binding:"required,min=1,dive"`
 }
 
+// El precio unitario lo fija el catálogo; los descuentos salen de las
+// promociones vigentes y del cupón de la orden.
 type OrderItemRequest struct {
-	IDProducto     uint    `json:"id_producto" binding:"required"`
-	Cantidad       int     `json:"cantidad" binding:"required,min=1"`
-	PrecioUnitario float64 `json:"precio_unitario" binding:"required,min=0"`
+	IDProducto uint `json:"id_producto" binding:"required"`
+	Cantidad   int  `json:"cantidad" binding:"required,min=1"`
 }
 
 type UpdateOrderRequest struct {
@@ -19,22 +21,28 @@ type UpdateOrderRequest struct {
 }
 
 type AddOrderItemRequest struct {
-	IDProducto     uint    `json:"id_producto" binding:"required"`
-	Cantidad       int     `json:"cantidad" binding:"required,min=1"`
-	PrecioUnitario float64 `json:"precio_unitario" binding:"required,min=0"`
+	IDProducto uint `json:"id_producto" binding:"required"`
+	Cantidad   int  `json:"cantidad" binding:"required,min=1"`
 }
 
 type UpdateOrderItemRequest struct {
-	Cantidad       int     `json:"cantidad" binding:"omitempty,min=1"`
-	PrecioUnitario float64 `json:"precio_unitario" binding:"omitempty,min=0"`
+	Cantidad int `json:"cantidad" binding:"required,min=1"`
+}
+
+type OrderItemDiscountResponse struct {
+	IDPromocion uint    `json:"id_promocion"`
+	IDCupon     *uint   `json:"id_cupon,omitempty"`
+	Monto       float64 `json:"monto"`
 }
 
 type OrderItemResponse struct {
-	IDDetalle      uint    `json:"id_detalle"`
-	IDProducto     uint    `json:"id_producto"`
-	Cantidad       int     `json:"cantidad"`
-	PrecioUnitario float64 `json:"precio_unitario"`
-	Subtotal       float64 `json:"subtotal"`
+	IDDetalle      uint                        `json:"id_detalle"`
+	IDProducto     uint                        `json:"id_producto"`
+	Cantidad       int                         `json:"cantidad"`
+	PrecioUnitario float64                     `json:"precio_unitario"`
+	Descuento      float64                     `json:"descuento"`
+	Descuentos     []OrderItemDiscountResponse `json:"descuentos"`
+	Subtotal       float64                     `json:"subtotal"`
 }
 
 type OrderResponse struct {
@@ -43,6 +51,7 @@ type OrderResponse struct {
 	IDVendedor    *uint               `json:"id_vendedor,omitempty"`
 	FechaCompra   time.Time           `json:"fecha_compra"`
 	Items         []OrderItemResponse `json:"items"`
+	Descuento     float64             `json:"descuento"`
 	Total         float64             `json:"total"`
 }
 
@@ -5,6 +5,9 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/middleware"
+	api "github.com/mordmora/expirapp/internal/platform/http"
 )
 
 type Handler struct {
@@ -21,27 +24,23 @@ func (h *Handler) CreateOrder(c *gin.Context) {
 	var req CreateOrderRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid request",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 
-	order, err := h.service.Create(req)
+	if !middleware.CanActOn(c, req.IDCliente, domain.RoleAdmin, domain.RoleSeller) {
+		api.Error(c, domain.Forbidden("insufficient_permissions", "permisos insuficientes"))
+		return
+	}
+
+	order, err := h.service.Create(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "error creating order",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
 	response := h.service.ToOrderResponse(order)
-	c.JSON(http.StatusCreated, gin.H{
-		"data":    response,
-		"message": "order created successfully",
-	})
+	api.Created(c, response, "order_created")
 }
 
 // GetOrder obtiene una orden por ID
@@ -50,26 +49,23 @@ func (h *Handler) GetOrder(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid order id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
-	order, err := h.service.GetById(uint(id))
+	order, err := h.service.GetById(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "order not found",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
+		return
+	}
+
+	if !middleware.CanActOn(c, order.IDCliente, domain.RoleAdmin, domain.RoleSeller) {
+		api.Error(c, domain.Forbidden("insufficient_permissions", "permisos insuficientes"))
 		return
 	}
 
 	response := h.service.ToOrderResponse(order)
-	c.JSON(http.StatusOK, gin.H{
-		"data": response,
-	})
+	api.OK(c, response)
 }
 
 // UpdateOrder actualiza una orden
@@ -78,40 +74,24 @@ func (h *Handler) UpdateOrder(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid order id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
 	var req UpdateOrderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid request",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 
-	order, err := h.service.Update(uint(id), req)
+	order, err := h.service.Update(c.Request.Context(), uint(id), req)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "order not found" {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, gin.H{
-			"error":   "error updating order",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
 	response := h.service.ToOrderResponse(order)
-	c.JSON(http.StatusOK, gin.H{
-		"data":    response,
-		"message": "order updated successfully",
-	})
+	api.Respond(c, http.StatusOK, response, "order_updated")
 }
 
 // DeleteOrder elimina una orden
@@ -120,28 +100,16 @@ func (h *Handler) DeleteOrder(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid order id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
-	if err := h.service.Delete(uint(id)); err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "order not found" {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, gin.H{
-			"error":   "error deleting order",
-			"message": err.Error(),
-		})
+	if err := h.service.Delete(c.Request.Context(), uint(id)); err != nil {
+		api.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "order deleted successfully",
-	})
+	api.Respond(c, http.StatusOK, nil, "order_deleted")
 }
 
 // ListOrders lista órdenes con paginación
@@ -150,12 +118,9 @@ func (h *Handler) ListOrders(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
-	orders, total, err := h.service.List(page, limit)
+	orders, total, err := h.service.List(c.Request.Context(), page, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "error listing orders",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
@@ -171,9 +136,7 @@ func (h *Handler) ListOrders(c *gin.Context) {
 		Limit:  limit,
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": response,
-	})
+	api.OK(c, response)
 }
 
 // ListOrdersByClient lista órdenes de un cliente específico
@@ -182,22 +145,16 @@ func (h *Handler) ListOrdersByClient(c *gin.Context) {
 	clientIDParam := c.Param("clientId")
 	clientID, err := strconv.ParseUint(clientIDParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid client id",
-			"message": "client id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("clientId"))
 		return
 	}
 
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
-	orders, total, err := h.service.ListByClient(uint(clientID), page, limit)
+	orders, total, err := h.service.ListByClient(c.Request.Context(), uint(clientID), page, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "error listing orders",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
@@ -213,9 +170,7 @@ func (h *Handler) ListOrdersByClient(c *gin.Context) {
 		Limit:  limit,
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": response,
-	})
+	api.OK(c, response)
 }
 
 // ListOrdersBySeller lista órdenes de un vendedor específico
@@ -224,22 +179,16 @@ func (h *Handler) ListOrdersBySeller(c *gin.Context) {
 	sellerIDParam := c.Param("sellerId")
 	sellerID, err := strconv.ParseUint(sellerIDParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid seller id",
-			"message": "seller id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("sellerId"))
 		return
 	}
 
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
-	orders, total, err := h.service.ListBySeller(uint(sellerID), page, limit)
+	orders, total, err := h.service.ListBySeller(c.Request.Context(), uint(sellerID), page, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "error listing orders",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
@@ -255,9 +204,7 @@ func (h *Handler) ListOrdersBySeller(c *gin.Context) {
 		Limit:  limit,
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": response,
-	})
+	api.OK(c, response)
 }
 
 // AddOrderItem agrega un item a una orden
@@ -266,40 +213,24 @@ func (h *Handler) AddOrderItem(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid order id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
 	var req AddOrderItemRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid request",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 
-	item, err := h.service.AddOrderItem(uint(id), req)
+	item, err := h.service.AddOrderItem(c.Request.Context(), uint(id), req)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "order not found" {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, gin.H{
-			"error":   "error adding item to order",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
 	response := h.service.ToOrderItemResponse(item)
-	c.JSON(http.StatusCreated, gin.H{
-		"data":    response,
-		"message": "item added to order successfully",
-	})
+	api.Created(c, response, "order_item_added")
 }
 
 // UpdateOrderItem actualiza un item de una orden
@@ -308,50 +239,31 @@ func (h *Handler) UpdateOrderItem(c *gin.Context) {
 	idParam := c.Param("id")
 	orderID, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid order id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
 	itemIDParam := c.Param("itemId")
 	itemID, err := strconv.ParseUint(itemIDParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid item id",
-			"message": "item id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("itemId"))
 		return
 	}
 
 	var req UpdateOrderItemRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid request",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 
-	item, err := h.service.UpdateOrderItem(uint(orderID), uint(itemID), req)
+	item, err := h.service.UpdateOrderItem(c.Request.Context(), uint(orderID), uint(itemID), req)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "order item not found" || err.Error() == "el item no pertenece a esta orden" {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, gin.H{
-			"error":   "error updating order item",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
 	response := h.service.ToOrderItemResponse(item)
-	c.JSON(http.StatusOK, gin.H{
-		"data":    response,
-		"message": "order item updated successfully",
-	})
+	api.Respond(c, http.StatusOK, response, "order_item_updated")
 }
 
 // DeleteOrderItem elimina un item de una orden
@@ -360,36 +272,21 @@ func (h *Handler) DeleteOrderItem(c *gin.Context) {
 	idParam := c.Param("id")
 	orderID, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid order id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
 	itemIDParam := c.Param("itemId")
 	itemID, err := strconv.ParseUint(itemIDParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid item id",
-			"message": "item id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("itemId"))
 		return
 	}
 
-	if err := h.service.DeleteOrderItem(uint(orderID), uint(itemID)); err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "order item not found" || err.Error() == "el item no pertenece a esta orden" {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, gin.H{
-			"error":   "error deleting order item",
-			"message": err.Error(),
-		})
+	if err := h.service.DeleteOrderItem(c.Request.Context(), uint(orderID), uint(itemID)); err != nil {
+		api.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "order item deleted successfully",
-	})
+	api.Respond(c, http.StatusOK, nil, "order_item_deleted")
 }
@@ -1,10 +1,14 @@
 package orders
 
 import (
+	"context"
 	"errors"
 
 	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/modules/catalog"
+	"github.com/mordmora/expirapp/internal/modules/promotions"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
@@ -15,33 +19,121 @@ func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) Create(order *domain.Order) error {
-	return r.db.Create(order).Error
+func (r *Repository) Create(ctx context.Context, order *domain.Order) error {
+	return r.db.WithContext(ctx).Create(order).Error
 }
 
-func (r *Repository) CreateWithItems(order *domain.Order, items []domain.OrderItem) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(order).Error; err != nil {
+// StockTaker descuenta del stock las unidades de una línea dentro de la
+// transacción que la guarda y dice de qué lotes salieron; ver
+// catalog.TakeStockTx.
+type StockTaker func(tx *gorm.DB, productID uint, quantity int) ([]catalog.LotUnits, error)
+
+// recordLotsTx suma a la línea itemID las unidades que tomó de cada lote
+func recordLotsTx(tx *gorm.DB, itemID uint, taken []catalog.LotUnits) error {
+	for _, lot := range taken {
+		row := domain.OrderItemLot{IDDetalle: itemID, IDLote: lot.LotID, Cantidad: lot.Units}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id_detalle"}, {Name: "id_lote"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"cantidad": gorm.Expr("detalle_compra_lote.cantidad + excluded.cantidad")}),
+		}).Create(&row).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+# returnStockTx devuelve quantity unidades de la línea item al stock
+* vuelven primero a los lotes que vencen más tarde: con una venta más chica
+* FEFO no los habría tocado; lo que no sale de los lotes registrados queda
+* sin lote, como en las líneas anteriores a detalle_compra_lote
+*/
+func returnStockTx(tx *gorm.DB, item *domain.OrderItem, quantity int) error {
+	var rows []domain.OrderItemLot
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "detalle_compra_lote"}}).
+		Joins("JOIN lote l ON l.id_lote = detalle_compra_lote.id_lote").
+		Where("detalle_compra_lote.id_detalle = ?", item.ID).
+		Order("l.fecha_vencimiento DESC, l.id_lote DESC").
+		Find(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	var lots []catalog.LotUnits
+	remaining := quantity
+	for _, row := range rows {
+		if remaining == 0 {
+			break
+		}
+		units := min(remaining, row.Cantidad)
+		if units == row.Cantidad {
+			err = tx.Delete(&domain.OrderItemLot{}, row.ID).Error
+		} else {
+			err = tx.Model(&domain.OrderItemLot{}).Where("id_detalle_lote = ?", row.ID).
+				Update("cantidad", gorm.Expr("cantidad - ?", units)).Error
+		}
+		if err != nil {
+			return err
+		}
+		lots = append(lots, catalog.LotUnits{LotID: row.IDLote, Units: units})
+		remaining -= units
+	}
+
+	return catalog.ReturnStockTx(tx, item.IDProducto, quantity, lots)
+}
+
+// CreateWithItems guarda la orden, sus líneas con sus descuentos y el uso de
+// promociones, y descuenta el stock con take, en una sola transacción. Si una
+// línea no tiene stock, o el cupón llegó a su límite de usos, no queda nada
+// de la orden.
+func (r *Repository) CreateWithItems(ctx context.Context, order *domain.Order, items []domain.OrderItem, usages []domain.PromotionUsage, take StockTaker) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, usage := range usages {
+			if usage.IDCupon == nil {
+				continue
+			}
+			if err := promotions.CheckCouponLimitsTx(tx, *usage.IDCupon, usage.IDCliente); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Omit("Items").Create(order).Error; err != nil {
 			return err
 		}
 
 		for i := range items {
+			taken, err := take(tx, items[i].IDProducto, items[i].Cantidad)
+			if err != nil {
+				return err
+			}
 			items[i].IDCompra = order.ID
-			if err := tx.Create(&items[i]).Error; err != nil {
+			if err := tx.Omit("Order", "Product").Create(&items[i]).Error; err != nil {
+				return err
+			}
+			if err := recordLotsTx(tx, items[i].ID, taken); err != nil {
 				return err
 			}
 		}
 
+		for i := range usages {
+			usages[i].IDCompra = order.ID
+			if err := tx.Create(&usages[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		order.Items = items
 		return nil
 	})
 }
 
-func (r *Repository) FindByID(id uint) (*domain.Order, error) {
+func (r *Repository) FindByID(ctx context.Context, id uint) (*domain.Order, error) {
 	var order domain.Order
-	err := r.db.Preload("Items").Preload("Items.Product").First(&order, id).Error
+	err := r.db.WithContext(ctx).Preload("Items").Preload("Items.Product").Preload("Items.Discounts").First(&order, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("order not found")
+			return nil, domain.NotFound("order_not_found", "orden con id %d no encontrada", id)
 		}
 		return nil, err
 	}
@@ -49,12 +141,12 @@ func (r *Repository) FindByID(id uint) (*domain.Order, error) {
 	return &order, nil
 }
 
-func (r *Repository) FindByIDWithItems(id uint) (*domain.Order, error) {
+func (r *Repository) FindByIDWithItems(ctx context.Context, id uint) (*domain.Order, error) {
 	var order domain.Order
-	err := r.db.Preload("Items").Preload("Items.Product").First(&order, id).Error
+	err := r.db.WithContext(ctx).Preload("Items").Preload("Items.Product").Preload("Items.Discounts").First(&order, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("order not found")
+			return nil, domain.NotFound("order_not_found", "orden con id %d no encontrada", id)
 		}
 		return nil, err
 	}
@@ -62,60 +154,79 @@ func (r *Repository) FindByIDWithItems(id uint) (*domain.Order, error) {
 	return &order, nil
 }
 
-func (r *Repository) Update(order *domain.Order) error {
-	return r.db.Save(order).Error
+func (r *Repository) Update(ctx context.Context, order *domain.Order) error {
+	return r.db.WithContext(ctx).Save(order).Error
 }
 
-func (r *Repository) Delete(id uint) error {
-	return r.db.Delete(&domain.Order{}, id).Error
+// Delete borra la orden y devuelve las unidades de sus líneas a los lotes de
+// donde salieron
+func (r *Repository) Delete(ctx context.Context, order *domain.Order) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range order.Items {
+			if err := returnStockTx(tx, &order.Items[i], order.Items[i].Cantidad); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&domain.Order{}, order.ID).Error
+	})
 }
 
-func (r *Repository) List(limit, offset int) ([]domain.Order, int64, error) {
+func (r *Repository) List(ctx context.Context, limit, offset int) ([]domain.Order, int64, error) {
 	var orders []domain.Order
 	var total int64
 
-	if err := r.db.Model(&domain.Order{}).Count(&total).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&domain.Order{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	err := r.db.Preload("Items").Preload("Items.Product").Limit(limit).Offset(offset).Order("fecha_compra DESC").Find(&orders).Error
+	err := r.db.WithContext(ctx).Preload("Items").Preload("Items.Product").Preload("Items.Discounts").Limit(limit).Offset(offset).Order("fecha_compra DESC").Find(&orders).Error
 	return orders, total, err
 }
 
-func (r *Repository) FindByClientID(clientID uint, limit, offset int) ([]domain.Order, int64, error) {
+func (r *Repository) FindByClientID(ctx context.Context, clientID uint, limit, offset int) ([]domain.Order, int64, error) {
 	var orders []domain.Order
 	var total int64
 
-	if err := r.db.Model(&domain.Order{}).Where("id_cliente = ?", clientID).Count(&total).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&domain.Order{}).Where("id_cliente = ?", clientID).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	err := r.db.Preload("Items").Preload("Items.Product").Where("id_cliente = ?", clientID).Limit(limit).Offset(offset).Order("fecha_compra DESC").Find(&orders).Error
+	err := r.db.WithContext(ctx).Preload("Items").Preload("Items.Product").Preload("Items.Discounts").Where("id_cliente = ?", clientID).Limit(limit).Offset(offset).Order("fecha_compra DESC").Find(&orders).Error
 	return orders, total, err
 }
 
-func (r *Repository) FindBySellerID(sellerID uint, limit, offset int) ([]domain.Order, int64, error) {
+func (r *Repository) FindBySellerID(ctx context.Context, sellerID uint, limit, offset int) ([]domain.Order, int64, error) {
 	var orders []domain.Order
 	var total int64
 
-	if err := r.db.Model(&domain.Order{}).Where("id_vendedor = ?", sellerID).Count(&total).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&domain.Order{}).Where("id_vendedor = ?", sellerID).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	err := r.db.Preload("Items").Preload("Items.Product").Where("id_vendedor = ?", sellerID).Limit(limit).Offset(offset).Order("fecha_compra DESC").Find(&orders).Error
+	err := r.db.WithContext(ctx).Preload("Items").Preload("Items.Product").Preload("Items.Discounts").Where("id_vendedor = ?", sellerID).Limit(limit).Offset(offset).Order("fecha_compra DESC").Find(&orders).Error
 	return orders, total, err
 }
 
-func (r *Repository) CreateOrderItem(item *domain.OrderItem) error {
-	return r.db.Create(item).Error
+// CreateOrderItem guarda la línea y descuenta sus unidades con take
+func (r *Repository) CreateOrderItem(ctx context.Context, item *domain.OrderItem, take StockTaker) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		taken, err := take(tx, item.IDProducto, item.Cantidad)
+		if err != nil {
+			return err
+		}
+		if err := tx.Omit("Order", "Product").Create(item).Error; err != nil {
+			return err
+		}
+		return recordLotsTx(tx, item.ID, taken)
+	})
 }
 
-func (r *Repository) FindOrderItemByID(id uint) (*domain.OrderItem, error) {
+func (r *Repository) FindOrderItemByID(ctx context.Context, id uint) (*domain.OrderItem, error) {
 	var item domain.OrderItem
-	err := r.db.Preload("Product").First(&item, id).Error
+	err := r.db.WithContext(ctx).Preload("Product").Preload("Discounts").First(&item, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("order item not found")
+			return nil, domain.NotFound("order_item_not_found", "item de la orden no encontrado")
 		}
 		return nil, err
 	}
@@ -123,20 +234,63 @@ func (r *Repository) FindOrderItemByID(id uint) (*domain.OrderItem, error) {
 	return &item, nil
 }
 
-func (r *Repository) FindOrderItemsByOrderID(orderID uint) ([]domain.OrderItem, error) {
+func (r *Repository) FindOrderItemsByOrderID(ctx context.Context, orderID uint) ([]domain.OrderItem, error) {
 	var items []domain.OrderItem
-	err := r.db.Preload("Product").Where("id_compra = ?", orderID).Find(&items).Error
+	err := r.db.WithContext(ctx).Preload("Product").Where("id_compra = ?", orderID).Find(&items).Error
 	return items, err
 }
 
-func (r *Repository) UpdateOrderItem(item *domain.OrderItem) error {
-	return r.db.Save(item).Error
+// UpdateOrderItem guarda la línea y reemplaza sus líneas de descuento. Si la
+// cantidad subió en added unidades las descuenta con take; si bajó (added
+// negativo) las devuelve a los lotes de donde salieron.
+func (r *Repository) UpdateOrderItem(ctx context.Context, item *domain.OrderItem, added int, take StockTaker) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		switch {
+		case added > 0:
+			taken, err := take(tx, item.IDProducto, added)
+			if err != nil {
+				return err
+			}
+			if err := recordLotsTx(tx, item.ID, taken); err != nil {
+				return err
+			}
+		case added < 0:
+			if err := returnStockTx(tx, item, -added); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Omit(clause.Associations).Save(item).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("id_detalle = ?", item.ID).Delete(&domain.OrderItemDiscount{}).Error; err != nil {
+			return err
+		}
+
+		for i := range item.Discounts {
+			item.Discounts[i].ID = 0
+			item.Discounts[i].IDDetalle = item.ID
+			if err := tx.Create(&item.Discounts[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 }
 
-func (r *Repository) DeleteOrderItem(id uint) error {
-	return r.db.Delete(&domain.OrderItem{}, id).Error
+// DeleteOrderItem borra la línea y devuelve sus unidades a los lotes de donde
+// salieron
+func (r *Repository) DeleteOrderItem(ctx context.Context, item *domain.OrderItem) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := returnStockTx(tx, item, item.Cantidad); err != nil {
+			return err
+		}
+		return tx.Delete(&domain.OrderItem{}, item.ID).Error
+	})
 }
 
-func (r *Repository) DeleteOrderItemsByOrderID(orderID uint) error {
-	return r.db.Where("id_compra = ?", orderID).Delete(&domain.OrderItem{}).Error
+func (r *Repository) DeleteOrderItemsByOrderID(ctx context.Context, orderID uint) error {
+	return r.db.WithContext(ctx).Where("id_compra = ?", orderID).Delete(&domain.OrderItem{}).Error
 }
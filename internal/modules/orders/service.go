@@ -1,47 +1,131 @@
 package orders
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/mordmora/expirapp/internal/domain"
 	catalogRepo "github.com/mordmora/expirapp/internal/modules/catalog"
+	"github.com/mordmora/expirapp/internal/modules/profiles"
+	"github.com/mordmora/expirapp/internal/modules/promotions"
+	"github.com/mordmora/expirapp/internal/platform/metrics"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+	"gorm.io/gorm"
 )
 
+// StockReservations expone las unidades apartadas en carritos de compra para
+// que una orden no consuma stock reservado por otro cliente. Se consulta
+// dentro de la transacción de la orden, con el producto ya bloqueado.
+type StockReservations interface {
+	ReservedQuantityTx(tx *gorm.DB, productID, excludeClientID uint) (int, error)
+}
+
+// Pricing da el precio unitario que se cobra por un producto, con las
+// rebajas por vencimiento de su categoría; lo implementa catalog.Service
+type Pricing interface {
+	SalePrice(ctx context.Context, product *domain.Product, quantity int) (float64, error)
+}
+
 type Service struct {
-	repo        *Repository
-	catalogRepo *catalogRepo.Repository
+	repo         *Repository
+	catalogRepo  *catalogRepo.Repository
+	pricing      Pricing
+	promotions   *promotions.Service
+	profiles     *profiles.Service
+	reservations StockReservations
 }
 
-func NewService(repo *Repository, catalogRepo *catalogRepo.Repository) *Service {
+func NewService(repo *Repository, catalogRepo *catalogRepo.Repository, pricing Pricing, promotionsService *promotions.Service, profilesService *profiles.Service) *Service {
 	return &Service{
 		repo:        repo,
 		catalogRepo: catalogRepo,
+		pricing:     pricing,
+		promotions:  promotionsService,
+		profiles:    profilesService,
+	}
+}
+
+// SetReservations registra la fuente de reservas de stock. Sin ella el stock
+// disponible es el stock del producto.
+func (s *Service) SetReservations(reservations StockReservations) {
+	s.reservations = reservations
+}
+
+// takeStock descuenta las unidades de una orden de clientID sin tocar las
+// reservadas en carritos de otros clientes.
+func (s *Service) takeStock(clientID uint) StockTaker {
+	var reserved catalogRepo.ReservedFunc
+	if s.reservations != nil {
+		reserved = func(tx *gorm.DB, productID uint) (int, error) {
+			held, err := s.reservations.ReservedQuantityTx(tx, productID, clientID)
+			if err != nil {
+				return 0, fmt.Errorf("error consultando reservas del producto %d: %w", productID, err)
+			}
+			return held, nil
+		}
+	}
+
+	return func(tx *gorm.DB, productID uint, quantity int) ([]catalogRepo.LotUnits, error) {
+		return catalogRepo.TakeStockTx(tx, productID, quantity, reserved)
 	}
 }
 
-func (s *Service) Create(req CreateOrderRequest) (*domain.Order, error) {
-	orderItems := make([]domain.OrderItem, len(req.Items))
-	
+// Create registra la orden con el precio vigente de cada producto, rebajado
+// según el vencimiento de las unidades que se venden, y los descuentos de
+// las promociones aplicables y del cupón, si viene uno.
+func (s *Service) Create(ctx context.Context, req CreateOrderRequest) (_ *domain.Order, err error) {
+	ctx, span := tracing.Start(ctx, "orders.Service.Create")
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.profiles.ValidateClient(ctx, req.IDCliente); err != nil {
+		return nil, err
+	}
+	if req.IDVendedor != nil {
+		if err := s.profiles.ValidateSeller(ctx, *req.IDVendedor); err != nil {
+			return nil, err
+		}
+	}
+
+	lines := make([]promotions.Line, len(req.Items))
+
 	for i, itemReq := range req.Items {
-		product, err := s.catalogRepo.FindByID(itemReq.IDProducto)
+		product, err := s.catalogRepo.FindByID(ctx, itemReq.IDProducto)
 		if err != nil {
-			return nil, fmt.Errorf("producto con id %d no encontrado", itemReq.IDProducto)
+			return nil, domain.NotFound("product_not_found", "producto con id %d no encontrado", itemReq.IDProducto)
 		}
-
-		if product.Stock < itemReq.Cantidad {
-			return nil, fmt.Errorf("stock insuficiente para el producto %s (id: %d). Stock disponible: %d, solicitado: %d", 
-				product.Nombre, itemReq.IDProducto, product.Stock, itemReq.Cantidad)
+		price, err := s.pricing.SalePrice(ctx, product, itemReq.Cantidad)
+		if err != nil {
+			return nil, err
 		}
 
-		orderItems[i] = domain.OrderItem{
+		lines[i] = promotions.Line{
 			IDProducto:     itemReq.IDProducto,
 			Cantidad:       itemReq.Cantidad,
-			PrecioUnitario: itemReq.PrecioUnitario,
+			PrecioUnitario: price,
 		}
 	}
 
+	eval, err := s.promotions.Evaluate(ctx, req.IDCliente, lines, req.CodigoCupon)
+	if err != nil {
+		return nil, err
+	}
+
+	orderItems := make([]domain.OrderItem, len(eval.Lines))
+	for i, line := range eval.Lines {
+		orderItems[i] = domain.OrderItem{
+			IDProducto:     line.IDProducto,
+			Cantidad:       line.Cantidad,
+			PrecioUnitario: line.PrecioUnitario,
+			Descuento:      line.Descuento,
+			Discounts:      toItemDiscounts(line.Descuentos),
+		}
+	}
+
+	if err := s.snapshotCosts(ctx, orderItems); err != nil {
+		return nil, err
+	}
+
 	order := &domain.Order{
 		IDCliente:   req.IDCliente,
 		IDVendedor:  req.IDVendedor,
@@ -49,56 +133,61 @@ func (s *Service) Create(req CreateOrderRequest) (*domain.Order, error) {
 		Items:       orderItems,
 	}
 
-	if err := s.repo.CreateWithItems(order, orderItems); err != nil {
+	// el stock se descuenta en la misma transacción que guarda la orden
+	if err := s.repo.CreateWithItems(ctx, order, orderItems, eval.Usages(req.IDCliente), s.takeStock(req.IDCliente)); err != nil {
 		return nil, fmt.Errorf("error creating order: %w", err)
 	}
 
-	for _, item := range orderItems {
-		if err := s.catalogRepo.UpdateStock(item.IDProducto, -item.Cantidad); err != nil {
-			return nil, fmt.Errorf("error updating stock for product %d: %w", item.IDProducto, err)
-		}
-	}
-
+	metrics.OrdersCreated.Inc()
 	return order, nil
 }
 
-func (s *Service) GetById(id uint) (*domain.Order, error) {
-	return s.repo.FindByID(id)
+func (s *Service) GetById(ctx context.Context, id uint) (_ *domain.Order, err error) {
+	ctx, span := tracing.Start(ctx, "orders.Service.GetById")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindByID(ctx, id)
 }
 
-func (s *Service) Update(id uint, req UpdateOrderRequest) (*domain.Order, error) {
-	order, err := s.repo.FindByID(id)
+func (s *Service) Update(ctx context.Context, id uint, req UpdateOrderRequest) (_ *domain.Order, err error) {
+	ctx, span := tracing.Start(ctx, "orders.Service.Update")
+	defer func() { tracing.End(span, err) }()
+
+	order, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
 	if req.IDVendedor != nil {
+		if err := s.profiles.ValidateSeller(ctx, *req.IDVendedor); err != nil {
+			return nil, err
+		}
 		order.IDVendedor = req.IDVendedor
 	}
 
-	if err := s.repo.Update(order); err != nil {
+	if err := s.repo.Update(ctx, order); err != nil {
 		return nil, fmt.Errorf("error updating order: %w", err)
 	}
 
 	return order, nil
 }
 
-func (s *Service) Delete(id uint) error {
-	order, err := s.repo.FindByID(id)
+func (s *Service) Delete(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "orders.Service.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	order, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	for _, item := range order.Items {
-		if err := s.catalogRepo.UpdateStock(item.IDProducto, item.Cantidad); err != nil {
-			fmt.Printf("warning: error restoring stock for product %d: %v\n", item.IDProducto, err)
-		}
-	}
-
-	return s.repo.Delete(id)
+	return s.repo.Delete(ctx, order)
 }
 
-func (s *Service) List(page, limit int) ([]domain.Order, int64, error) {
+func (s *Service) List(ctx context.Context, page, limit int) (_ []domain.Order, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "orders.Service.List")
+	defer func() { tracing.End(span, err) }()
+
 	if page < 1 {
 		page = 1
 	}
@@ -107,10 +196,13 @@ func (s *Service) List(page, limit int) ([]domain.Order, int64, error) {
 	}
 
 	offset := (page - 1) * limit
-	return s.repo.List(limit, offset)
+	return s.repo.List(ctx, limit, offset)
 }
 
-func (s *Service) ListByClient(clientID uint, page, limit int) ([]domain.Order, int64, error) {
+func (s *Service) ListByClient(ctx context.Context, clientID uint, page, limit int) (_ []domain.Order, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "orders.Service.ListByClient")
+	defer func() { tracing.End(span, err) }()
+
 	if page < 1 {
 		page = 1
 	}
@@ -119,10 +211,13 @@ func (s *Service) ListByClient(clientID uint, page, limit int) ([]domain.Order,
 	}
 
 	offset := (page - 1) * limit
-	return s.repo.FindByClientID(clientID, limit, offset)
+	return s.repo.FindByClientID(ctx, clientID, limit, offset)
 }
 
-func (s *Service) ListBySeller(sellerID uint, page, limit int) ([]domain.Order, int64, error) {
+func (s *Service) ListBySeller(ctx context.Context, sellerID uint, page, limit int) (_ []domain.Order, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "orders.Service.ListBySeller")
+	defer func() { tracing.End(span, err) }()
+
 	if page < 1 {
 		page = 1
 	}
@@ -131,122 +226,223 @@ func (s *Service) ListBySeller(sellerID uint, page, limit int) ([]domain.Order,
 	}
 
 	offset := (page - 1) * limit
-	return s.repo.FindBySellerID(sellerID, limit, offset)
+	return s.repo.FindBySellerID(ctx, sellerID, limit, offset)
 }
 
-func (s *Service) AddOrderItem(orderID uint, req AddOrderItemRequest) (*domain.OrderItem, error) {
-	order, err := s.repo.FindByID(orderID)
+func (s *Service) AddOrderItem(ctx context.Context, orderID uint, req AddOrderItemRequest) (_ *domain.OrderItem, err error) {
+	ctx, span := tracing.Start(ctx, "orders.Service.AddOrderItem")
+	defer func() { tracing.End(span, err) }()
+
+	order, err := s.repo.FindByID(ctx, orderID)
 	if err != nil {
 		return nil, err
 	}
 
-	product, err := s.catalogRepo.FindByID(req.IDProducto)
+	product, err := s.catalogRepo.FindByID(ctx, req.IDProducto)
 	if err != nil {
-		return nil, fmt.Errorf("producto con id %d no encontrado", req.IDProducto)
+		return nil, domain.NotFound("product_not_found", "producto con id %d no encontrado", req.IDProducto)
 	}
-
-	if product.Stock < req.Cantidad {
-		return nil, fmt.Errorf("stock insuficiente para el producto %s. Stock disponible: %d, solicitado: %d", 
-			product.Nombre, product.Stock, req.Cantidad)
+	price, err := s.pricing.SalePrice(ctx, product, req.Cantidad)
+	if err != nil {
+		return nil, err
 	}
 
 	item := &domain.OrderItem{
 		IDCompra:       orderID,
 		IDProducto:     req.IDProducto,
 		Cantidad:       req.Cantidad,
-		PrecioUnitario: req.PrecioUnitario,
+		PrecioUnitario: price,
 	}
 
-	if err := s.repo.CreateOrderItem(item); err != nil {
-		return nil, fmt.Errorf("error adding item to order: %w", err)
+	if err := s.applyLineDiscounts(ctx, item); err != nil {
+		return nil, err
+	}
+
+	item.CostoUnitario, err = s.catalogRepo.UnitCost(ctx, req.IDProducto, req.Cantidad)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.catalogRepo.UpdateStock(req.IDProducto, -req.Cantidad); err != nil {
-		return nil, fmt.Errorf("error updating stock: %w", err)
+	if err := s.repo.CreateOrderItem(ctx, item, s.takeStock(order.IDCliente)); err != nil {
+		return nil, fmt.Errorf("error adding item to order: %w", err)
 	}
 
 	return item, nil
 }
 
-func (s *Service) UpdateOrderItem(orderID, itemID uint, req UpdateOrderItemRequest) (*domain.OrderItem, error) {
-	item, err := s.repo.FindOrderItemByID(itemID)
+func (s *Service) UpdateOrderItem(ctx context.Context, orderID, itemID uint, req UpdateOrderItemRequest) (_ *domain.OrderItem, err error) {
+	ctx, span := tracing.Start(ctx, "orders.Service.UpdateOrderItem")
+	defer func() { tracing.End(span, err) }()
+
+	item, err := s.repo.FindOrderItemByID(ctx, itemID)
 	if err != nil {
 		return nil, err
 	}
 
 	if item.IDCompra != orderID {
-		return nil, errors.New("el item no pertenece a esta orden")
+		return nil, domain.NotFound("order_item_not_found", "el item no pertenece a esta orden")
+	}
+
+	order, err := s.repo.FindByID(ctx, orderID)
+	if err != nil {
+		return nil, err
 	}
 
-	if req.Cantidad > 0 {
-		product, err := s.catalogRepo.FindByID(item.IDProducto)
+	stockDifference := req.Cantidad - item.Cantidad
+	if stockDifference > 0 {
+		// las unidades agregadas se costean como una venta nueva y el
+		// costo de la línea queda promediado
+		added, err := s.catalogRepo.UnitCost(ctx, item.IDProducto, stockDifference)
 		if err != nil {
-			return nil, fmt.Errorf("producto no encontrado: %w", err)
+			return nil, err
 		}
-
-		stockDifference := req.Cantidad - item.Cantidad
-		if stockDifference > 0 {
-			if product.Stock < stockDifference {
-				return nil, fmt.Errorf("stock insuficiente. Stock disponible: %d, necesario: %d", 
-					product.Stock, stockDifference)
-			}
-			if err := s.catalogRepo.UpdateStock(item.IDProducto, -stockDifference); err != nil {
-				return nil, fmt.Errorf("error updating stock: %w", err)
-			}
-		} else if stockDifference < 0 {
-			if err := s.catalogRepo.UpdateStock(item.IDProducto, -stockDifference); err != nil {
-				return nil, fmt.Errorf("error updating stock: %w", err)
-			}
+		if item.CostoUnitario != nil && added != nil {
+			cost := (*item.CostoUnitario*float64(item.Cantidad) + *added*float64(stockDifference)) / float64(req.Cantidad)
+			item.CostoUnitario = &cost
+		} else {
+			item.CostoUnitario = nil
 		}
-
-		item.Cantidad = req.Cantidad
 	}
+	item.Cantidad = req.Cantidad
 
-	if req.PrecioUnitario > 0 {
-		item.PrecioUnitario = req.PrecioUnitario
+	if err := s.applyLineDiscounts(ctx, item); err != nil {
+		return nil, err
 	}
 
-	if err := s.repo.UpdateOrderItem(item); err != nil {
+	// el stock cambia en la misma transacción que guarda la línea
+	if err := s.repo.UpdateOrderItem(ctx, item, stockDifference, s.takeStock(order.IDCliente)); err != nil {
 		return nil, fmt.Errorf("error updating order item: %w", err)
 	}
 
 	return item, nil
 }
 
-func (s *Service) DeleteOrderItem(orderID, itemID uint) error {
-	item, err := s.repo.FindOrderItemByID(itemID)
+func (s *Service) DeleteOrderItem(ctx context.Context, orderID, itemID uint) (err error) {
+	ctx, span := tracing.Start(ctx, "orders.Service.DeleteOrderItem")
+	defer func() { tracing.End(span, err) }()
+
+	item, err := s.repo.FindOrderItemByID(ctx, itemID)
 	if err != nil {
 		return err
 	}
 
 	if item.IDCompra != orderID {
-		return errors.New("el item no pertenece a esta orden")
+		return domain.NotFound("order_item_not_found", "el item no pertenece a esta orden")
+	}
+
+	return s.repo.DeleteOrderItem(ctx, item)
+}
+
+/*
+# snapshotCosts guarda en cada línea el costo de las unidades que va a
+# descontar del stock
+* las líneas del mismo producto se costean juntas, como las consume el
+* stock, y toman el mismo costo promedio
+*/
+func (s *Service) snapshotCosts(ctx context.Context, items []domain.OrderItem) error {
+	quantities := make(map[uint]int)
+	for _, item := range items {
+		quantities[item.IDProducto] += item.Cantidad
+	}
+
+	costs := make(map[uint]*float64, len(quantities))
+	for productID, quantity := range quantities {
+		cost, err := s.catalogRepo.UnitCost(ctx, productID, quantity)
+		if err != nil {
+			return err
+		}
+		costs[productID] = cost
+	}
+
+	for i := range items {
+		items[i].CostoUnitario = costs[items[i].IDProducto]
+	}
+	return nil
+}
+
+// applyLineDiscounts recalcula las promociones automáticas de una línea
+// agregada o modificada después de crear la orden. Los descuentos de cupón
+// que ya tenía la línea se conservan sin superar su nuevo subtotal.
+func (s *Service) applyLineDiscounts(ctx context.Context, item *domain.OrderItem) error {
+	line := promotions.Line{
+		IDProducto:     item.IDProducto,
+		Cantidad:       item.Cantidad,
+		PrecioUnitario: item.PrecioUnitario,
 	}
 
-	if err := s.catalogRepo.UpdateStock(item.IDProducto, item.Cantidad); err != nil {
-		return fmt.Errorf("error restoring stock: %w", err)
+	eval, err := s.promotions.EvaluateWithCoupon(ctx, []promotions.Line{line}, nil)
+	if err != nil {
+		return err
 	}
 
-	return s.repo.DeleteOrderItem(itemID)
+	result := eval.Lines[0]
+	discounts := toItemDiscounts(result.Descuentos)
+	total := result.Descuento
+	remaining := result.Total()
+
+	for _, d := range item.Discounts {
+		if d.IDCupon == nil || remaining <= 0 {
+			continue
+		}
+		amount := d.Monto
+		if amount > remaining {
+			amount = remaining
+		}
+		discounts = append(discounts, domain.OrderItemDiscount{
+			IDPromocion: d.IDPromocion,
+			IDCupon:     d.IDCupon,
+			Monto:       amount,
+		})
+		total += amount
+		remaining -= amount
+	}
+
+	item.Discounts = discounts
+	item.Descuento = total
+	return nil
+}
+
+func toItemDiscounts(applied []promotions.AppliedDiscount) []domain.OrderItemDiscount {
+	discounts := make([]domain.OrderItemDiscount, len(applied))
+	for i, d := range applied {
+		discounts[i] = domain.OrderItemDiscount{
+			IDPromocion: d.IDPromocion,
+			IDCupon:     d.IDCupon,
+			Monto:       d.Monto,
+		}
+	}
+	return discounts
 }
 
 func (s *Service) ToOrderItemResponse(item *domain.OrderItem) OrderItemResponse {
+	discounts := make([]OrderItemDiscountResponse, len(item.Discounts))
+	for i, d := range item.Discounts {
+		discounts[i] = OrderItemDiscountResponse{
+			IDPromocion: d.IDPromocion,
+			IDCupon:     d.IDCupon,
+			Monto:       d.Monto,
+		}
+	}
+
 	return OrderItemResponse{
 		IDDetalle:      item.ID,
 		IDProducto:     item.IDProducto,
 		Cantidad:       item.Cantidad,
 		PrecioUnitario: item.PrecioUnitario,
-		Subtotal:       float64(item.Cantidad) * item.PrecioUnitario,
+		Descuento:      item.Descuento,
+		Descuentos:     discounts,
+		Subtotal:       float64(item.Cantidad)*item.PrecioUnitario - item.Descuento,
 	}
 }
 
 func (s *Service) ToOrderResponse(order *domain.Order) OrderResponse {
 	items := make([]OrderItemResponse, len(order.Items))
-	var total float64
+	var total, discount float64
 
 	for i, item := range order.Items {
 		items[i] = s.ToOrderItemResponse(&item)
 		total += items[i].Subtotal
+		discount += items[i].Descuento
 	}
 
 	return OrderResponse{
@@ -255,6 +451,7 @@ func (s *Service) ToOrderResponse(order *domain.Order) OrderResponse {
 		IDVendedor:  order.IDVendedor,
 		FechaCompra: order.FechaCompra,
 		Items:       items,
+		Descuento:   discount,
 		Total:       total,
 	}
 }
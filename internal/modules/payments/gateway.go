@@ -2,8 +2,12 @@ package payments
 
 import (
 	"context"
-	"errors"
 	"fmt"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type PaymentStatus string
@@ -72,14 +76,14 @@ func NewMockGateway(apiKey, apiSecret, baseURL string) *MockGateway {
 
 func (g *MockGateway) ProcessPayment(ctx context.Context, req PaymentGatewayRequest) (*PaymentGatewayResponse, error) {
 	if req.Amount <= 0 {
-		return nil, errors.New("el monto debe ser mayor a cero")
+		return nil, domain.InvalidField("monto", "must_be_positive", "%s debe ser mayor a cero", "monto")
 	}
 
 	if req.Currency == "" {
 		req.Currency = "USD"
 	}
 
-	transactionID := fmt.Sprintf("mock_txn_%d_%d", req.OrderID, req.Amount)
+	transactionID := fmt.Sprintf("mock_txn_%d_%.2f", req.OrderID, req.Amount)
 
 	return &PaymentGatewayResponse{
 		TransactionID: transactionID,
@@ -97,7 +101,7 @@ func (g *MockGateway) ProcessPayment(ctx context.Context, req PaymentGatewayRequ
 
 func (g *MockGateway) GetPaymentStatus(ctx context.Context, transactionID string) (*PaymentGatewayResponse, error) {
 	if transactionID == "" {
-		return nil, errors.New("transaction ID es requerido")
+		return nil, domain.Validation("transaction_id_required", "transaction ID es requerido")
 	}
 
 	return &PaymentGatewayResponse{
@@ -114,11 +118,11 @@ func (g *MockGateway) GetPaymentStatus(ctx context.Context, transactionID string
 
 func (g *MockGateway) Refund(ctx context.Context, req RefundRequest) (*RefundResponse, error) {
 	if req.TransactionID == "" {
-		return nil, errors.New("transaction ID es requerido")
+		return nil, domain.Validation("transaction_id_required", "transaction ID es requerido")
 	}
 
 	if req.Amount <= 0 {
-		return nil, errors.New("el monto del reembolso debe ser mayor a cero")
+		return nil, domain.InvalidField("monto", "must_be_positive", "%s debe ser mayor a cero", "monto")
 	}
 
 	refundID := fmt.Sprintf("mock_refund_%s", req.TransactionID)
@@ -140,7 +144,7 @@ func (g *MockGateway) VerifyWebhook(ctx context.Context, payload []byte, signatu
 	// En un gateway real, esto verificaría la firma del webhook --> Si quieres hacerlo me avisas.
 	// Para el mock, siempre retornamos true si hay una firma
 	if signature == "" {
-		return false, errors.New("signature es requerida")
+		return false, domain.Validation("signature_required", "signature es requerida")
 	}
 
 	return true, nil
@@ -149,7 +153,7 @@ func (g *MockGateway) VerifyWebhook(ctx context.Context, payload []byte, signatu
 // ParseWebhook parsea un webhook simulado
 func (g *MockGateway) ParseWebhook(ctx context.Context, payload []byte) (*PaymentGatewayResponse, error) {
 	if len(payload) == 0 {
-		return nil, errors.New("payload vacío")
+		return nil, domain.Validation("empty_payload", "payload vacío")
 	}
 
 	// En un gateway real, esto parsearía el payload del webhook
@@ -174,18 +178,80 @@ func NewGatewayFactory() *GatewayFactory {
 func (f *GatewayFactory) CreateGateway(gatewayType string, config map[string]string) (Gateway, error) {
 	switch gatewayType {
 	case "mock":
-		return NewMockGateway(
+		return NewTracedGateway(gatewayType, NewMockGateway(
 			config["api_key"],
 			config["api_secret"],
 			config["base_url"],
-		), nil
+		)), nil
 	case "stripe":
 		// TODO: Implementar StripeGateway cuando se necesite
-		return nil, fmt.Errorf("gateway tipo '%s' no implementado aún", gatewayType)
+		return nil, domain.Validation("gateway_not_implemented", "gateway tipo '%s' no implementado aún", gatewayType)
 	case "paypal":
 		// TODO: Implementar PayPalGateway cuando se necesite
-		return nil, fmt.Errorf("gateway tipo '%s' no implementado aún", gatewayType)
+		return nil, domain.Validation("gateway_not_implemented", "gateway tipo '%s' no implementado aún", gatewayType)
 	default:
-		return nil, fmt.Errorf("tipo de gateway desconocido: %s", gatewayType)
+		return nil, domain.Validation("unknown_gateway", "tipo de gateway desconocido: %s", gatewayType)
 	}
 }
+
+/*
+# tracedGateway envuelve un Gateway con un span por llamada
+* el span lleva el nombre de la pasarela y, si la hay, la transacción
+* así la latencia de la pasarela se ve aparte de la de la base de datos
+*/
+type tracedGateway struct {
+	name string
+	next Gateway
+}
+
+func NewTracedGateway(name string, next Gateway) Gateway {
+	return &tracedGateway{name: name, next: next}
+}
+
+func (g *tracedGateway) start(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "gateway."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(attrs, attribute.String("payment.gateway", g.name))...),
+	)
+}
+
+func (g *tracedGateway) ProcessPayment(ctx context.Context, req PaymentGatewayRequest) (resp *PaymentGatewayResponse, err error) {
+	ctx, span := g.start(ctx, "ProcessPayment", attribute.Int64("order_id", int64(req.OrderID)))
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(attribute.String("payment.transaction_id", resp.TransactionID),
+				attribute.String("payment.status", string(resp.Status)))
+		}
+		tracing.End(span, err)
+	}()
+
+	return g.next.ProcessPayment(ctx, req)
+}
+
+func (g *tracedGateway) GetPaymentStatus(ctx context.Context, transactionID string) (resp *PaymentGatewayResponse, err error) {
+	ctx, span := g.start(ctx, "GetPaymentStatus", attribute.String("payment.transaction_id", transactionID))
+	defer func() { tracing.End(span, err) }()
+
+	return g.next.GetPaymentStatus(ctx, transactionID)
+}
+
+func (g *tracedGateway) Refund(ctx context.Context, req RefundRequest) (resp *RefundResponse, err error) {
+	ctx, span := g.start(ctx, "Refund", attribute.String("payment.transaction_id", req.TransactionID))
+	defer func() { tracing.End(span, err) }()
+
+	return g.next.Refund(ctx, req)
+}
+
+func (g *tracedGateway) VerifyWebhook(ctx context.Context, payload []byte, signature string) (valid bool, err error) {
+	ctx, span := g.start(ctx, "VerifyWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	return g.next.VerifyWebhook(ctx, payload, signature)
+}
+
+func (g *tracedGateway) ParseWebhook(ctx context.Context, payload []byte) (resp *PaymentGatewayResponse, err error) {
+	ctx, span := g.start(ctx, "ParseWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	return g.next.ParseWebhook(ctx, payload)
+}
@@ -5,6 +5,9 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/middleware"
+	api "github.com/mordmora/expirapp/internal/platform/http"
 )
 
 type Handler struct {
@@ -21,27 +24,22 @@ func (h *Handler) CreatePayment(c *gin.Context) {
 	var req CreatePaymentRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid request",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 
-	payment, err := h.service.Create(req)
+	if !h.canAccessOrder(c, req.IDCompra) {
+		return
+	}
+
+	payment, err := h.service.Create(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "error creating payment",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
 	response := h.service.ToPaymentResponse(payment)
-	c.JSON(http.StatusCreated, gin.H{
-		"data":    response,
-		"message": "payment created successfully",
-	})
+	api.Created(c, response, "payment_created")
 }
 
 // GetPayment obtiene un pago por ID
@@ -50,26 +48,22 @@ func (h *Handler) GetPayment(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid payment id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
-	payment, err := h.service.GetById(uint(id))
+	payment, err := h.service.GetById(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "payment not found",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
+		return
+	}
+
+	if !h.canAccessOrder(c, payment.IDCompra) {
 		return
 	}
 
 	response := h.service.ToPaymentResponse(payment)
-	c.JSON(http.StatusOK, gin.H{
-		"data": response,
-	})
+	api.OK(c, response)
 }
 
 // UpdatePayment actualiza un pago
@@ -78,40 +72,24 @@ func (h *Handler) UpdatePayment(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid payment id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
 	var req UpdatePaymentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid request",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 
-	payment, err := h.service.Update(uint(id), req)
+	payment, err := h.service.Update(c.Request.Context(), uint(id), req)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "payment not found" {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, gin.H{
-			"error":   "error updating payment",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
 	response := h.service.ToPaymentResponse(payment)
-	c.JSON(http.StatusOK, gin.H{
-		"data":    response,
-		"message": "payment updated successfully",
-	})
+	api.Respond(c, http.StatusOK, response, "payment_updated")
 }
 
 // DeletePayment elimina un pago
@@ -120,28 +98,16 @@ func (h *Handler) DeletePayment(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid payment id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
-	if err := h.service.Delete(uint(id)); err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "payment not found" {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, gin.H{
-			"error":   "error deleting payment",
-			"message": err.Error(),
-		})
+	if err := h.service.Delete(c.Request.Context(), uint(id)); err != nil {
+		api.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "payment deleted successfully",
-	})
+	api.Respond(c, http.StatusOK, nil, "payment_deleted")
 }
 
 // ListPayments lista pagos con paginación
@@ -150,12 +116,9 @@ func (h *Handler) ListPayments(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
-	payments, total, err := h.service.List(page, limit)
+	payments, total, err := h.service.List(c.Request.Context(), page, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "error listing payments",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
@@ -171,9 +134,7 @@ func (h *Handler) ListPayments(c *gin.Context) {
 		Limit:    limit,
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": response,
-	})
+	api.OK(c, response)
 }
 
 // GetPaymentsByOrder obtiene todos los pagos de una orden
@@ -182,19 +143,17 @@ func (h *Handler) GetPaymentsByOrder(c *gin.Context) {
 	orderIDParam := c.Param("orderId")
 	orderID, err := strconv.ParseUint(orderIDParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid order id",
-			"message": "order id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("orderId"))
+		return
+	}
+
+	if !h.canAccessOrder(c, uint(orderID)) {
 		return
 	}
 
-	payments, err := h.service.GetByOrderID(uint(orderID))
+	payments, err := h.service.GetByOrderID(c.Request.Context(), uint(orderID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "error getting payments",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
@@ -203,9 +162,7 @@ func (h *Handler) GetPaymentsByOrder(c *gin.Context) {
 		responses[i] = h.service.ToPaymentResponse(&payment)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": responses,
-	})
+	api.OK(c, responses)
 }
 
 // GetPaymentStatusByOrder obtiene el estado de pagos de una orden
@@ -214,29 +171,21 @@ func (h *Handler) GetPaymentStatusByOrder(c *gin.Context) {
 	orderIDParam := c.Param("orderId")
 	orderID, err := strconv.ParseUint(orderIDParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid order id",
-			"message": "order id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("orderId"))
 		return
 	}
 
-	status, err := h.service.GetPaymentStatusByOrderID(uint(orderID))
+	if !h.canAccessOrder(c, uint(orderID)) {
+		return
+	}
+
+	status, err := h.service.GetPaymentStatusByOrderID(c.Request.Context(), uint(orderID))
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "orden con id "+orderIDParam+" no encontrada" {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, gin.H{
-			"error":   "error getting payment status",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": status,
-	})
+	api.OK(c, status)
 }
 
 // CreatePaymentMethod crea un nuevo método de pago
@@ -247,27 +196,18 @@ func (h *Handler) CreatePaymentMethod(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid request",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 
-	method, err := h.service.CreatePaymentMethod(req.Nombre)
+	method, err := h.service.CreatePaymentMethod(c.Request.Context(), req.Nombre)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "error creating payment method",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
 	response := h.service.ToPaymentMethodResponse(method)
-	c.JSON(http.StatusCreated, gin.H{
-		"data":    response,
-		"message": "payment method created successfully",
-	})
+	api.Created(c, response, "payment_method_created")
 }
 
 // GetPaymentMethod obtiene un método de pago por ID
@@ -276,37 +216,26 @@ func (h *Handler) GetPaymentMethod(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid payment method id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
-	method, err := h.service.GetPaymentMethodByID(uint(id))
+	method, err := h.service.GetPaymentMethodByID(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "payment method not found",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
 	response := h.service.ToPaymentMethodResponse(method)
-	c.JSON(http.StatusOK, gin.H{
-		"data": response,
-	})
+	api.OK(c, response)
 }
 
 // ListPaymentMethods lista todos los métodos de pago
 // GET /api/v1/payments/methods
 func (h *Handler) ListPaymentMethods(c *gin.Context) {
-	methods, err := h.service.ListPaymentMethods()
+	methods, err := h.service.ListPaymentMethods(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "error listing payment methods",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
@@ -320,9 +249,7 @@ func (h *Handler) ListPaymentMethods(c *gin.Context) {
 		Total:   int64(len(responses)),
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": response,
-	})
+	api.OK(c, response)
 }
 
 // UpdatePaymentMethod actualiza un método de pago
@@ -331,10 +258,7 @@ func (h *Handler) UpdatePaymentMethod(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid payment method id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
@@ -343,31 +267,18 @@ func (h *Handler) UpdatePaymentMethod(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid request",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 
-	method, err := h.service.UpdatePaymentMethod(uint(id), req.Nombre)
+	method, err := h.service.UpdatePaymentMethod(c.Request.Context(), uint(id), req.Nombre)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "payment method not found" {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, gin.H{
-			"error":   "error updating payment method",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
 	response := h.service.ToPaymentMethodResponse(method)
-	c.JSON(http.StatusOK, gin.H{
-		"data":    response,
-		"message": "payment method updated successfully",
-	})
+	api.Respond(c, http.StatusOK, response, "payment_method_updated")
 }
 
 // DeletePaymentMethod elimina un método de pago
@@ -376,26 +287,29 @@ func (h *Handler) DeletePaymentMethod(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid payment method id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
-	if err := h.service.DeletePaymentMethod(uint(id)); err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "payment method not found" {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, gin.H{
-			"error":   "error deleting payment method",
-			"message": err.Error(),
-		})
+	if err := h.service.DeletePaymentMethod(c.Request.Context(), uint(id)); err != nil {
+		api.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "payment method deleted successfully",
-	})
+	api.Respond(c, http.StatusOK, nil, "payment_method_deleted")
+}
+
+// canAccessOrder comprueba que el principal sea el cliente de la orden o
+// personal de la tienda; si no, responde el error y devuelve false
+func (h *Handler) canAccessOrder(c *gin.Context, orderID uint) bool {
+	clientID, err := h.service.OrderClientID(c.Request.Context(), orderID)
+	if err != nil {
+		api.Error(c, err)
+		return false
+	}
+	if !middleware.CanActOn(c, clientID, domain.RoleAdmin, domain.RoleSeller) {
+		api.Error(c, domain.Forbidden("insufficient_permissions", "permisos insuficientes"))
+		return false
+	}
+	return true
 }
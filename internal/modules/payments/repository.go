@@ -1,6 +1,7 @@
 package payments
 
 import (
+	"context"
 	"errors"
 
 	"github.com/mordmora/expirapp/internal/domain"
@@ -15,16 +16,16 @@ func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) Create(payment *domain.Payment) error {
-	return r.db.Create(payment).Error
+func (r *Repository) Create(ctx context.Context, payment *domain.Payment) error {
+	return r.db.WithContext(ctx).Create(payment).Error
 }
 
-func (r *Repository) FindByID(id uint) (*domain.Payment, error) {
+func (r *Repository) FindByID(ctx context.Context, id uint) (*domain.Payment, error) {
 	var payment domain.Payment
-	err := r.db.Preload("Order").Preload("PaymentMethod").First(&payment, id).Error
+	err := r.db.WithContext(ctx).Preload("Order").Preload("PaymentMethod").First(&payment, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("payment not found")
+			return nil, domain.NotFound("payment_not_found", "pago no encontrado")
 		}
 		return nil, err
 	}
@@ -32,58 +33,58 @@ func (r *Repository) FindByID(id uint) (*domain.Payment, error) {
 	return &payment, nil
 }
 
-func (r *Repository) Update(payment *domain.Payment) error {
-	return r.db.Save(payment).Error
+func (r *Repository) Update(ctx context.Context, payment *domain.Payment) error {
+	return r.db.WithContext(ctx).Save(payment).Error
 }
 
-func (r *Repository) Delete(id uint) error {
-	return r.db.Delete(&domain.Payment{}, id).Error
+func (r *Repository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&domain.Payment{}, id).Error
 }
 
-func (r *Repository) List(limit, offset int) ([]domain.Payment, int64, error) {
+func (r *Repository) List(ctx context.Context, limit, offset int) ([]domain.Payment, int64, error) {
 	var payments []domain.Payment
 	var total int64
 
-	if err := r.db.Model(&domain.Payment{}).Count(&total).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&domain.Payment{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	err := r.db.Preload("Order").Preload("PaymentMethod").Limit(limit).Offset(offset).Order("fecha_pago DESC").Find(&payments).Error
+	err := r.db.WithContext(ctx).Preload("Order").Preload("PaymentMethod").Limit(limit).Offset(offset).Order("fecha_pago DESC").Find(&payments).Error
 	return payments, total, err
 }
 
-func (r *Repository) FindByOrderID(orderID uint) ([]domain.Payment, error) {
+func (r *Repository) FindByOrderID(ctx context.Context, orderID uint) ([]domain.Payment, error) {
 	var payments []domain.Payment
-	err := r.db.Preload("PaymentMethod").Where("id_compra = ?", orderID).Order("fecha_pago DESC").Find(&payments).Error
+	err := r.db.WithContext(ctx).Preload("PaymentMethod").Where("id_compra = ?", orderID).Order("fecha_pago DESC").Find(&payments).Error
 	return payments, err
 }
 
-func (r *Repository) GetTotalPaidByOrderID(orderID uint) (float64, error) {
+func (r *Repository) GetTotalPaidByOrderID(ctx context.Context, orderID uint) (float64, error) {
 	var total float64
-	err := r.db.Model(&domain.Payment{}).
+	err := r.db.WithContext(ctx).Model(&domain.Payment{}).
 		Where("id_compra = ?", orderID).
 		Select("COALESCE(SUM(monto), 0)").
 		Scan(&total).Error
 	return total, err
 }
 
-func (r *Repository) CountByOrderID(orderID uint) (int64, error) {
+func (r *Repository) CountByOrderID(ctx context.Context, orderID uint) (int64, error) {
 	var count int64
-	err := r.db.Model(&domain.Payment{}).Where("id_compra = ?", orderID).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&domain.Payment{}).Where("id_compra = ?", orderID).Count(&count).Error
 	return count, err
 }
 
 // PaymentMethod methods
-func (r *Repository) CreatePaymentMethod(method *domain.PaymentMethod) error {
-	return r.db.Create(method).Error
+func (r *Repository) CreatePaymentMethod(ctx context.Context, method *domain.PaymentMethod) error {
+	return r.db.WithContext(ctx).Create(method).Error
 }
 
-func (r *Repository) FindPaymentMethodByID(id uint) (*domain.PaymentMethod, error) {
+func (r *Repository) FindPaymentMethodByID(ctx context.Context, id uint) (*domain.PaymentMethod, error) {
 	var method domain.PaymentMethod
-	err := r.db.First(&method, id).Error
+	err := r.db.WithContext(ctx).First(&method, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("payment method not found")
+			return nil, domain.NotFound("payment_method_not_found", "método de pago con id %d no encontrado", id)
 		}
 		return nil, err
 	}
@@ -91,12 +92,12 @@ func (r *Repository) FindPaymentMethodByID(id uint) (*domain.PaymentMethod, erro
 	return &method, nil
 }
 
-func (r *Repository) FindPaymentMethodByName(name string) (*domain.PaymentMethod, error) {
+func (r *Repository) FindPaymentMethodByName(ctx context.Context, name string) (*domain.PaymentMethod, error) {
 	var method domain.PaymentMethod
-	err := r.db.Where("nombre = ?", name).First(&method).Error
+	err := r.db.WithContext(ctx).Where("nombre = ?", name).First(&method).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("payment method not found")
+			return nil, domain.NotFound("payment_method_not_found", "método de pago %s no encontrado", name)
 		}
 		return nil, err
 	}
@@ -104,16 +105,16 @@ func (r *Repository) FindPaymentMethodByName(name string) (*domain.PaymentMethod
 	return &method, nil
 }
 
-func (r *Repository) ListPaymentMethods() ([]domain.PaymentMethod, error) {
+func (r *Repository) ListPaymentMethods(ctx context.Context) ([]domain.PaymentMethod, error) {
 	var methods []domain.PaymentMethod
-	err := r.db.Order("nombre ASC").Find(&methods).Error
+	err := r.db.WithContext(ctx).Order("nombre ASC").Find(&methods).Error
 	return methods, err
 }
 
-func (r *Repository) UpdatePaymentMethod(method *domain.PaymentMethod) error {
-	return r.db.Save(method).Error
+func (r *Repository) UpdatePaymentMethod(ctx context.Context, method *domain.PaymentMethod) error {
+	return r.db.WithContext(ctx).Save(method).Error
 }
 
-func (r *Repository) DeletePaymentMethod(id uint) error {
-	return r.db.Delete(&domain.PaymentMethod{}, id).Error
+func (r *Repository) DeletePaymentMethod(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&domain.PaymentMethod{}, id).Error
 }
@@ -1,12 +1,14 @@
 package payments
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/mordmora/expirapp/internal/domain"
 	ordersRepo "github.com/mordmora/expirapp/internal/modules/orders"
+	"github.com/mordmora/expirapp/internal/platform/metrics"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
 )
 
 type Service struct {
@@ -21,107 +23,141 @@ func NewService(repo *Repository, ordersRepo *ordersRepo.Repository) *Service {
 	}
 }
 
-func (s *Service) Create(req CreatePaymentRequest) (*domain.Payment, error) {
-	order, err := s.ordersRepo.FindByID(req.IDCompra)
+// Create registra un pago; cada intento cuenta en las métricas como
+// completed o failed
+func (s *Service) Create(ctx context.Context, req CreatePaymentRequest) (payment *domain.Payment, err error) {
+	ctx, span := tracing.Start(ctx, "payments.Service.Create")
+	defer func() { tracing.End(span, err) }()
+
+	defer func() {
+		status := PaymentStatusCompleted
+		if err != nil {
+			status = PaymentStatusFailed
+		}
+		recordPayment(status)
+	}()
+
+	order, err := s.ordersRepo.FindByID(ctx, req.IDCompra)
 	if err != nil {
-		return nil, fmt.Errorf("orden con id %d no encontrada", req.IDCompra)
+		return nil, domain.NotFound("order_not_found", "orden con id %d no encontrada", req.IDCompra)
 	}
 
 	var orderTotal float64
 	for _, item := range order.Items {
-		orderTotal += float64(item.Cantidad) * item.PrecioUnitario
+		orderTotal += float64(item.Cantidad)*item.PrecioUnitario - item.Descuento
 	}
 
-	totalPaid, err := s.repo.GetTotalPaidByOrderID(req.IDCompra)
+	totalPaid, err := s.repo.GetTotalPaidByOrderID(ctx, req.IDCompra)
 	if err != nil {
 		return nil, fmt.Errorf("error calculando total pagado: %w", err)
 	}
 
 	pending := orderTotal - totalPaid
 	if req.Monto > pending {
-		return nil, fmt.Errorf("el monto excede el pendiente. Monto solicitado: %.2f, Pendiente: %.2f", req.Monto, pending)
+		return nil, domain.Validation("amount_exceeds_balance", "el monto excede el pendiente. Monto solicitado: %.2f, Pendiente: %.2f", req.Monto, pending)
 	}
 
 	if req.IDMetodoPago != nil {
-		_, err := s.repo.FindPaymentMethodByID(*req.IDMetodoPago)
+		_, err := s.repo.FindPaymentMethodByID(ctx, *req.IDMetodoPago)
 		if err != nil {
-			return nil, fmt.Errorf("método de pago con id %d no encontrado", *req.IDMetodoPago)
+			return nil, domain.NotFound("payment_method_not_found", "método de pago con id %d no encontrado", *req.IDMetodoPago)
 		}
 	}
 
-	payment := &domain.Payment{
+	payment = &domain.Payment{
 		IDCompra:     req.IDCompra,
 		IDMetodoPago: req.IDMetodoPago,
 		Monto:        req.Monto,
 		FechaPago:    time.Now(),
 	}
 
-	if err := s.repo.Create(payment); err != nil {
+	if err := s.repo.Create(ctx, payment); err != nil {
 		return nil, fmt.Errorf("error creating payment: %w", err)
 	}
 
 	return payment, nil
 }
 
-func (s *Service) GetById(id uint) (*domain.Payment, error) {
-	return s.repo.FindByID(id)
+func (s *Service) GetById(ctx context.Context, id uint) (_ *domain.Payment, err error) {
+	ctx, span := tracing.Start(ctx, "payments.Service.GetById")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindByID(ctx, id)
 }
 
-func (s *Service) Update(id uint, req UpdatePaymentRequest) (*domain.Payment, error) {
-	payment, err := s.repo.FindByID(id)
+func (s *Service) Update(ctx context.Context, id uint, req UpdatePaymentRequest) (_ *domain.Payment, err error) {
+	ctx, span := tracing.Start(ctx, "payments.Service.Update")
+	defer func() { tracing.End(span, err) }()
+
+	payment, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
 	if req.Monto > 0 {
-		order, err := s.ordersRepo.FindByID(payment.IDCompra)
+		order, err := s.ordersRepo.FindByID(ctx, payment.IDCompra)
 		if err != nil {
 			return nil, fmt.Errorf("error obteniendo orden: %w", err)
 		}
 
 		var orderTotal float64
 		for _, item := range order.Items {
-			orderTotal += float64(item.Cantidad) * item.PrecioUnitario
+			orderTotal += float64(item.Cantidad)*item.PrecioUnitario - item.Descuento
 		}
 
-		totalPaid, err := s.repo.GetTotalPaidByOrderID(payment.IDCompra)
+		totalPaid, err := s.repo.GetTotalPaidByOrderID(ctx, payment.IDCompra)
 		if err != nil {
 			return nil, fmt.Errorf("error calculando total pagado: %w", err)
 		}
 
 		newTotalPaid := totalPaid - payment.Monto + req.Monto
 		if newTotalPaid > orderTotal {
-			return nil, fmt.Errorf("el nuevo monto excedería el total de la orden. Total orden: %.2f, Nuevo total pagado: %.2f", orderTotal, newTotalPaid)
+			return nil, domain.Validation("amount_exceeds_total", "el nuevo monto excedería el total de la orden. Total orden: %.2f, Nuevo total pagado: %.2f", orderTotal, newTotalPaid)
 		}
 
 		payment.Monto = req.Monto
 	}
 
 	if req.IDMetodoPago != nil {
-		_, err := s.repo.FindPaymentMethodByID(*req.IDMetodoPago)
+		_, err := s.repo.FindPaymentMethodByID(ctx, *req.IDMetodoPago)
 		if err != nil {
-			return nil, fmt.Errorf("método de pago con id %d no encontrado", *req.IDMetodoPago)
+			return nil, domain.NotFound("payment_method_not_found", "método de pago con id %d no encontrado", *req.IDMetodoPago)
 		}
 		payment.IDMetodoPago = req.IDMetodoPago
 	}
 
-	if err := s.repo.Update(payment); err != nil {
+	if err := s.repo.Update(ctx, payment); err != nil {
 		return nil, fmt.Errorf("error updating payment: %w", err)
 	}
 
 	return payment, nil
 }
 
-func (s *Service) Delete(id uint) error {
-	_, err := s.repo.FindByID(id)
+func (s *Service) Delete(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "payments.Service.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.repo.FindByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	return s.repo.Delete(id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	recordPayment(PaymentStatusCancelled)
+	return nil
 }
 
-func (s *Service) List(page, limit int) ([]domain.Payment, int64, error) {
+func recordPayment(status PaymentStatus) {
+	metrics.Payments.WithLabelValues(string(status)).Inc()
+}
+
+func (s *Service) List(ctx context.Context, page, limit int) (_ []domain.Payment, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "payments.Service.List")
+	defer func() { tracing.End(span, err) }()
+
 	if page < 1 {
 		page = 1
 	}
@@ -130,30 +166,49 @@ func (s *Service) List(page, limit int) ([]domain.Payment, int64, error) {
 	}
 
 	offset := (page - 1) * limit
-	return s.repo.List(limit, offset)
+	return s.repo.List(ctx, limit, offset)
 }
 
-func (s *Service) GetByOrderID(orderID uint) ([]domain.Payment, error) {
-	return s.repo.FindByOrderID(orderID)
+func (s *Service) GetByOrderID(ctx context.Context, orderID uint) (_ []domain.Payment, err error) {
+	ctx, span := tracing.Start(ctx, "payments.Service.GetByOrderID")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindByOrderID(ctx, orderID)
+}
+
+// OrderClientID devuelve el cliente dueño de la orden, para que el handler
+// compruebe que quien consulta o paga la orden es ese cliente
+func (s *Service) OrderClientID(ctx context.Context, orderID uint) (_ uint, err error) {
+	ctx, span := tracing.Start(ctx, "payments.Service.OrderClientID")
+	defer func() { tracing.End(span, err) }()
+
+	order, err := s.ordersRepo.FindByID(ctx, orderID)
+	if err != nil {
+		return 0, domain.NotFound("order_not_found", "orden con id %d no encontrada", orderID)
+	}
+	return order.IDCliente, nil
 }
 
-func (s *Service) GetPaymentStatusByOrderID(orderID uint) (*PaymentByOrderResponse, error) {
-	order, err := s.ordersRepo.FindByID(orderID)
+func (s *Service) GetPaymentStatusByOrderID(ctx context.Context, orderID uint) (_ *PaymentByOrderResponse, err error) {
+	ctx, span := tracing.Start(ctx, "payments.Service.GetPaymentStatusByOrderID")
+	defer func() { tracing.End(span, err) }()
+
+	order, err := s.ordersRepo.FindByID(ctx, orderID)
 	if err != nil {
-		return nil, fmt.Errorf("orden con id %d no encontrada", orderID)
+		return nil, domain.NotFound("order_not_found", "orden con id %d no encontrada", orderID)
 	}
 
 	var orderTotal float64
 	for _, item := range order.Items {
-		orderTotal += float64(item.Cantidad) * item.PrecioUnitario
+		orderTotal += float64(item.Cantidad)*item.PrecioUnitario - item.Descuento
 	}
 
-	payments, err := s.repo.FindByOrderID(orderID)
+	payments, err := s.repo.FindByOrderID(ctx, orderID)
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo pagos: %w", err)
 	}
 
-	totalPaid, err := s.repo.GetTotalPaidByOrderID(orderID)
+	totalPaid, err := s.repo.GetTotalPaidByOrderID(ctx, orderID)
 	if err != nil {
 		return nil, fmt.Errorf("error calculando total pagado: %w", err)
 	}
@@ -177,58 +232,73 @@ func (s *Service) GetPaymentStatusByOrderID(orderID uint) (*PaymentByOrderRespon
 	}, nil
 }
 
-func (s *Service) CreatePaymentMethod(nombre string) (*domain.PaymentMethod, error) {
-	_, err := s.repo.FindPaymentMethodByName(nombre)
+func (s *Service) CreatePaymentMethod(ctx context.Context, nombre string) (_ *domain.PaymentMethod, err error) {
+	ctx, span := tracing.Start(ctx, "payments.Service.CreatePaymentMethod")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.repo.FindPaymentMethodByName(ctx, nombre)
 	if err == nil {
-		return nil, errors.New("ya existe un método de pago con ese nombre")
+		return nil, domain.Conflict("payment_method_exists", "ya existe un método de pago con ese nombre")
 	}
 
 	method := &domain.PaymentMethod{
 		Nombre: nombre,
 	}
 
-	if err := s.repo.CreatePaymentMethod(method); err != nil {
+	if err := s.repo.CreatePaymentMethod(ctx, method); err != nil {
 		return nil, fmt.Errorf("error creating payment method: %w", err)
 	}
 
 	return method, nil
 }
 
-func (s *Service) GetPaymentMethodByID(id uint) (*domain.PaymentMethod, error) {
-	return s.repo.FindPaymentMethodByID(id)
+func (s *Service) GetPaymentMethodByID(ctx context.Context, id uint) (_ *domain.PaymentMethod, err error) {
+	ctx, span := tracing.Start(ctx, "payments.Service.GetPaymentMethodByID")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindPaymentMethodByID(ctx, id)
 }
 
-func (s *Service) ListPaymentMethods() ([]domain.PaymentMethod, error) {
-	return s.repo.ListPaymentMethods()
+func (s *Service) ListPaymentMethods(ctx context.Context) (_ []domain.PaymentMethod, err error) {
+	ctx, span := tracing.Start(ctx, "payments.Service.ListPaymentMethods")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.ListPaymentMethods(ctx)
 }
 
-func (s *Service) UpdatePaymentMethod(id uint, nombre string) (*domain.PaymentMethod, error) {
-	method, err := s.repo.FindPaymentMethodByID(id)
+func (s *Service) UpdatePaymentMethod(ctx context.Context, id uint, nombre string) (_ *domain.PaymentMethod, err error) {
+	ctx, span := tracing.Start(ctx, "payments.Service.UpdatePaymentMethod")
+	defer func() { tracing.End(span, err) }()
+
+	method, err := s.repo.FindPaymentMethodByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	existing, err := s.repo.FindPaymentMethodByName(nombre)
+	existing, err := s.repo.FindPaymentMethodByName(ctx, nombre)
 	if err == nil && existing.ID != id {
-		return nil, errors.New("ya existe un método de pago con ese nombre")
+		return nil, domain.Conflict("payment_method_exists", "ya existe un método de pago con ese nombre")
 	}
 
 	method.Nombre = nombre
 
-	if err := s.repo.UpdatePaymentMethod(method); err != nil {
+	if err := s.repo.UpdatePaymentMethod(ctx, method); err != nil {
 		return nil, fmt.Errorf("error updating payment method: %w", err)
 	}
 
 	return method, nil
 }
 
-func (s *Service) DeletePaymentMethod(id uint) error {
-	_, err := s.repo.FindPaymentMethodByID(id)
+func (s *Service) DeletePaymentMethod(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "payments.Service.DeletePaymentMethod")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.repo.FindPaymentMethodByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	return s.repo.DeletePaymentMethod(id)
+	return s.repo.DeletePaymentMethod(ctx, id)
 }
 
 func (s *Service) ToPaymentResponse(payment *domain.Payment) PaymentResponse {
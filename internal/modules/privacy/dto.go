@@ -0,0 +1,91 @@
+package privacy
+
+import "time"
+
+/*
+# ConsentRequest; body para POST /privacy/users/:id/consents
+* Tipo: finalidad autorizada (p. ej. "tratamiento_datos", "marketing")
+* Otorgado: false registra la revocación
+*/
+type ConsentRequest struct {
+	Tipo     string `json:"tipo" binding:"required,max=50"`
+	Otorgado *bool  `json:"otorgado" binding:"required"`
+	Version  string `json:"version" binding:"omitempty,max=20"`
+}
+
+type ConsentResponse struct {
+	ID        uint      `json:"id_consentimiento"`
+	Tipo      string    `json:"tipo"`
+	Otorgado  bool      `json:"otorgado"`
+	Version   string    `json:"version,omitempty"`
+	CreatedAt time.Time `json:"fecha"`
+}
+
+type DataRequestResponse struct {
+	ID            uint       `json:"id_solicitud"`
+	Tipo          string     `json:"tipo"`
+	SolicitadoPor uint       `json:"solicitado_por"`
+	CreatedAt     time.Time  `json:"fecha_solicitud"`
+	CompletadaEn  *time.Time `json:"fecha_completada,omitempty"`
+}
+
+/*
+# ExportDocument es el paquete de datos personales que recibe el titular
+*/
+type ExportDocument struct {
+	GeneratedAt time.Time             `json:"generado_en"`
+	User        ExportUser            `json:"usuario"`
+	Client      *ExportClient         `json:"cliente,omitempty"`
+	Orders      []ExportOrder         `json:"compras"`
+	Payments    []ExportPayment       `json:"pagos"`
+	Reviews     []ExportReview        `json:"resenas"`
+	Consents    []ConsentResponse     `json:"consentimientos"`
+	Requests    []DataRequestResponse `json:"solicitudes"`
+}
+
+type ExportUser struct {
+	ID                 uint       `json:"id_usuario"`
+	Nombre             string     `json:"nombre"`
+	Correo             string     `json:"correo"`
+	Roles              []string   `json:"roles"`
+	CorreoVerificadoEn *time.Time `json:"correo_verificado_en,omitempty"`
+	DobleFactor        bool       `json:"doble_factor"`
+	CreatedAt          time.Time  `json:"fecha_registro"`
+}
+
+type ExportClient struct {
+	Direccion *string `json:"direccion"`
+	Telefono  *string `json:"telefono"`
+}
+
+type ExportOrder struct {
+	ID          uint              `json:"id_compra"`
+	FechaCompra time.Time         `json:"fecha_compra"`
+	Items       []ExportOrderItem `json:"items"`
+	Total       float64           `json:"total"`
+}
+
+type ExportOrderItem struct {
+	IDProducto     uint    `json:"id_producto"`
+	Producto       string  `json:"producto"`
+	Cantidad       int     `json:"cantidad"`
+	PrecioUnitario float64 `json:"precio_unitario"`
+	Descuento      float64 `json:"descuento"`
+}
+
+type ExportPayment struct {
+	ID         uint      `json:"id_pago"`
+	IDCompra   uint      `json:"id_compra"`
+	Monto      float64   `json:"monto"`
+	MetodoPago string    `json:"metodo_pago,omitempty"`
+	FechaPago  time.Time `json:"fecha_pago"`
+}
+
+type ExportReview struct {
+	ID           uint      `json:"id_resena"`
+	IDProducto   uint      `json:"id_producto"`
+	Producto     string    `json:"producto"`
+	Calificacion int       `json:"calificacion"`
+	Comentario   string    `json:"comentario"`
+	CreatedAt    time.Time `json:"fecha"`
+}
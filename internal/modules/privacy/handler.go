@@ -0,0 +1,143 @@
+package privacy
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/middleware"
+	api "github.com/mordmora/expirapp/internal/platform/http"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// ExportData descarga los datos personales del usuario en JSON o ZIP
+// GET /api/v1/privacy/users/:id/export?formato=json|zip
+func (h *Handler) ExportData(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("formato", "json")
+	if format != "json" && format != "zip" {
+		api.Error(c, domain.InvalidField("formato", "invalid_format", "formato debe ser json o zip"))
+		return
+	}
+
+	principal, _ := middleware.CurrentPrincipal(c)
+	doc, err := h.service.Export(c.Request.Context(), id, principal.UserID)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	fileName := fmt.Sprintf("datos-usuario-%d-%s", id, doc.GeneratedAt.Format("20060102"))
+
+	if format == "json" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, fileName))
+		c.IndentedJSON(http.StatusOK, doc)
+		return
+	}
+
+	archive, err := h.service.ExportZip(doc)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, fileName))
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// AnonymizeUser suprime los datos personales; compras y pagos se conservan
+// POST /api/v1/privacy/users/:id/anonymize
+func (h *Handler) AnonymizeUser(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	principal, _ := middleware.CurrentPrincipal(c)
+	if err := h.service.Anonymize(c.Request.Context(), id, principal.UserID); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "user_data_anonymized")
+}
+
+// RecordConsent registra que el titular otorga o revoca una autorización
+// POST /api/v1/privacy/users/:id/consents
+func (h *Handler) RecordConsent(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var req ConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	consent, err := h.service.RecordConsent(c.Request.Context(), id, req, c.ClientIP())
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Created(c, h.service.ToConsentResponse(consent), "consent_recorded")
+}
+
+// ListConsents lista el historial de autorizaciones del titular
+// GET /api/v1/privacy/users/:id/consents
+func (h *Handler) ListConsents(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	consents, err := h.service.ListConsents(c.Request.Context(), id)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, consents)
+}
+
+// ListDataRequests lista las solicitudes de exportación y supresión
+// GET /api/v1/privacy/users/:id/requests
+func (h *Handler) ListDataRequests(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	requests, err := h.service.ListDataRequests(c.Request.Context(), id)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, requests)
+}
+
+func parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return 0, false
+	}
+
+	return uint(id), true
+}
@@ -0,0 +1,131 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) FindUser(ctx context.Context, id uint) (*domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).Preload("Roles").First(&user, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("user_not_found", "usuario no encontrado")
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// FindClient devuelve nil sin error si el usuario no tiene perfil de cliente
+func (r *Repository) FindClient(ctx context.Context, id uint) (*domain.Client, error) {
+	var client domain.Client
+	err := r.db.WithContext(ctx).Where("id_cliente = ?", id).Limit(1).Find(&client).Error
+	if err != nil {
+		return nil, err
+	}
+	if client.ID == 0 {
+		return nil, nil
+	}
+
+	return &client, nil
+}
+
+func (r *Repository) ListOrders(ctx context.Context, clientID uint) ([]domain.Order, error) {
+	var orders []domain.Order
+	err := r.db.WithContext(ctx).Preload("Items").Preload("Items.Product").Preload("Items.Discounts").
+		Where("id_cliente = ?", clientID).Order("id_compra ASC").Find(&orders).Error
+	return orders, err
+}
+
+func (r *Repository) ListPayments(ctx context.Context, clientID uint) ([]domain.Payment, error) {
+	var payments []domain.Payment
+	err := r.db.WithContext(ctx).Preload("PaymentMethod").
+		Joins("JOIN compra c ON c.id_compra = pago.id_compra").
+		Where("c.id_cliente = ?", clientID).Order("pago.id_pago ASC").Find(&payments).Error
+	return payments, err
+}
+
+func (r *Repository) ListReviews(ctx context.Context, clientID uint) ([]domain.Review, error) {
+	var reviews []domain.Review
+	err := r.db.WithContext(ctx).Preload("Product").Where("id_cliente = ?", clientID).Order("id_resena ASC").Find(&reviews).Error
+	return reviews, err
+}
+
+func (r *Repository) CreateConsent(ctx context.Context, consent *domain.Consent) error {
+	return r.db.WithContext(ctx).Create(consent).Error
+}
+
+func (r *Repository) ListConsents(ctx context.Context, userID uint) ([]domain.Consent, error) {
+	var consents []domain.Consent
+	err := r.db.WithContext(ctx).Where("id_usuario = ?", userID).Order("created_at ASC").Find(&consents).Error
+	return consents, err
+}
+
+func (r *Repository) CreateDataRequest(ctx context.Context, request *domain.DataRequest) error {
+	return r.db.WithContext(ctx).Create(request).Error
+}
+
+func (r *Repository) ListDataRequests(ctx context.Context, userID uint) ([]domain.DataRequest, error) {
+	var requests []domain.DataRequest
+	err := r.db.WithContext(ctx).Where("id_usuario = ?", userID).Order("created_at ASC").Find(&requests).Error
+	return requests, err
+}
+
+// Anonymize borra los datos personales del titular en una sola transacción.
+// Compras y pagos se conservan para contabilidad; solo pierden el vínculo con
+// datos que identifiquen a la persona. Los eventos de seguridad pierden
+// también el detalle, que en los más viejos puede traer el correo, y los que
+// quedaron sin id_usuario se encuentran por el correo.
+func (r *Repository) Anonymize(ctx context.Context, user *domain.User, request *domain.DataRequest, now time.Time) error {
+	email := strings.ToLower(user.Email)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		statements := []struct {
+			sql  string
+			args []interface{}
+		}{
+			{`UPDATE usuario SET nombre = ?, correo = ?, contrasena = '!',
+				correo_verificado_en = NULL, totp_secreto = NULL, totp_activado_en = NULL,
+				totp_ultimo_paso = 0, anonimizado_en = ?
+			WHERE id_usuario = ?`,
+				[]interface{}{"Usuario anonimizado", fmt.Sprintf("anonimo-%d@anonimizado.invalid", user.ID), now, user.ID}},
+			{`UPDATE cliente SET direccion = NULL, telefono = NULL WHERE id_cliente = ?`, []interface{}{user.ID}},
+			{`UPDATE resena SET comentario = '' WHERE id_cliente = ?`, []interface{}{user.ID}},
+			{`UPDATE evento_seguridad SET correo = NULL, ip = NULL, detalle = ''
+			WHERE id_usuario = ? OR lower(correo) = ? OR strpos(lower(detalle), ?) > 0`,
+				[]interface{}{user.ID, email, email}},
+			{`UPDATE consentimiento SET ip = NULL WHERE id_usuario = ?`, []interface{}{user.ID}},
+			{`DELETE FROM identidad_externa WHERE id_usuario = ?`, []interface{}{user.ID}},
+			{`DELETE FROM token_usuario WHERE id_usuario = ?`, []interface{}{user.ID}},
+			{`UPDATE clave_api SET revocada_en = ? WHERE id_usuario = ? AND revocada_en IS NULL`, []interface{}{now, user.ID}},
+			{`DELETE FROM codigo_recuperacion WHERE id_usuario = ?`, []interface{}{user.ID}},
+			{`DELETE FROM usuario_rol WHERE id_usuario = ?`, []interface{}{user.ID}},
+			{`DELETE FROM carrito WHERE id_cliente = ?`, []interface{}{user.ID}},
+			{`DELETE FROM intento_login WHERE clave = ?`, []interface{}{"cuenta:" + email}},
+		}
+
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt.sql, stmt.args...).Error; err != nil {
+				return err
+			}
+		}
+
+		request.CompletadaEn = &now
+		return tx.Create(request).Error
+	})
+}
@@ -0,0 +1,171 @@
+package privacy
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// statement es una sentencia que llegó a la base con sus argumentos
+type statement struct {
+	sql  string
+	args []driver.Value
+}
+
+/*
+# recorder es un driver de database/sql que solo anota lo que se ejecuta
+* las escrituras afectan una fila y las consultas no devuelven nada
+* committed / rolledBack dicen cómo terminó la transacción
+*/
+type recorder struct {
+	mu         sync.Mutex
+	statements []statement
+	committed  bool
+	rolledBack bool
+}
+
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return recorderConn{r}, nil }
+func (r *recorder) Driver() driver.Driver                        { return nil }
+
+type recorderConn struct{ r *recorder }
+
+func (c recorderConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepare not supported")
+}
+func (c recorderConn) Close() error              { return nil }
+func (c recorderConn) Begin() (driver.Tx, error) { return recorderTx(c), nil }
+
+func (c recorderConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c recorderConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	c.r.statements = append(c.r.statements, statement{sql: query, args: values})
+	return result{}, nil
+}
+
+func (c recorderConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return emptyRows{}, nil
+}
+
+type recorderTx struct{ r *recorder }
+
+func (t recorderTx) Commit() error   { t.r.committed = true; return nil }
+func (t recorderTx) Rollback() error { t.r.rolledBack = true; return nil }
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string         { return nil }
+func (emptyRows) Close() error              { return nil }
+func (emptyRows) Next([]driver.Value) error { return io.EOF }
+
+// result es el de una escritura de una fila con id 1
+type result struct{}
+
+func (result) LastInsertId() (int64, error) { return 1, nil }
+func (result) RowsAffected() (int64, error) { return 1, nil }
+
+func newRecordingRepository(t *testing.T) (*Repository, *recorder) {
+	t.Helper()
+
+	rec := &recorder{}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(rec), WithoutReturning: true}),
+		&gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("opening gorm: %v", err)
+	}
+	return NewRepository(db), rec
+}
+
+var spaces = regexp.MustCompile(`\s+`)
+
+func TestAnonymizeScrubsPersonalData(t *testing.T) {
+	repo, rec := newRecordingRepository(t)
+	user := &domain.User{ID: 7, Name: "Ana Pérez", Email: "Ana@Example.com"}
+	request := &domain.DataRequest{IDUsuario: 7, Tipo: domain.DataRequestDeletion, SolicitadoPor: 7}
+
+	if err := repo.Anonymize(context.Background(), user, request, time.Now()); err != nil {
+		t.Fatalf("Anonymize: %v", err)
+	}
+	if !rec.committed || rec.rolledBack {
+		t.Fatalf("transaction committed=%v rolledBack=%v", rec.committed, rec.rolledBack)
+	}
+
+	executed := make(map[string]statement, len(rec.statements))
+	for _, stmt := range rec.statements {
+		executed[spaces.ReplaceAllString(stmt.sql, " ")] = stmt
+	}
+	find := func(prefix string) (statement, bool) {
+		for sql, stmt := range executed {
+			if strings.HasPrefix(sql, prefix) {
+				return stmt, true
+			}
+		}
+		return statement{}, false
+	}
+
+	// cada lugar donde quedan datos personales del titular
+	scrubs := []string{
+		"UPDATE usuario SET nombre = $1, correo = $2, contrasena = '!', correo_verificado_en = NULL, totp_secreto = NULL",
+		"UPDATE cliente SET direccion = NULL, telefono = NULL WHERE id_cliente = $1",
+		"UPDATE resena SET comentario = '' WHERE id_cliente = $1",
+		"UPDATE evento_seguridad SET correo = NULL, ip = NULL, detalle = '' WHERE id_usuario = $1 OR lower(correo) = $2 OR strpos(lower(detalle), $3) > 0",
+		"UPDATE consentimiento SET ip = NULL WHERE id_usuario = $1",
+		"DELETE FROM identidad_externa WHERE id_usuario = $1",
+		"DELETE FROM token_usuario WHERE id_usuario = $1",
+		"DELETE FROM codigo_recuperacion WHERE id_usuario = $1",
+		"DELETE FROM carrito WHERE id_cliente = $1",
+		"DELETE FROM intento_login WHERE clave = $1",
+	}
+	for _, prefix := range scrubs {
+		if _, ok := find(prefix); !ok {
+			t.Errorf("missing statement %q", prefix)
+		}
+	}
+
+	// los valores nuevos del usuario no pueden conservar su nombre ni su correo
+	stmt, ok := find("UPDATE usuario SET")
+	if !ok {
+		t.Fatal("usuario was not updated")
+	}
+	for _, value := range stmt.args[:2] {
+		text := strings.ToLower(fmt.Sprint(value))
+		if strings.Contains(text, "ana") || strings.Contains(text, "example.com") {
+			t.Errorf("anonymized usuario keeps personal data: %q", value)
+		}
+	}
+
+	// los eventos viejos se buscan por el correo normalizado
+	if stmt, ok := find("UPDATE evento_seguridad SET"); ok {
+		if stmt.args[1] != "ana@example.com" || stmt.args[2] != "ana@example.com" {
+			t.Errorf("security events matched with %v, want the lower-cased email", stmt.args[1:])
+		}
+	}
+	if stmt, ok := find("DELETE FROM intento_login"); ok && stmt.args[0] != "cuenta:ana@example.com" {
+		t.Errorf("login attempts cleared for %v", stmt.args[0])
+	}
+
+	if request.CompletadaEn == nil {
+		t.Error("deletion request was not marked completed")
+	}
+	if _, ok := find("INSERT INTO \"solicitud_datos\""); !ok {
+		t.Error("deletion request was not recorded")
+	}
+}
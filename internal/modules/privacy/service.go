@@ -0,0 +1,290 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+)
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Export reúne los datos personales del usuario y registra la solicitud.
+// requestedBy es quien la hizo (el titular o un administrador).
+func (s *Service) Export(ctx context.Context, userID, requestedBy uint) (_ *ExportDocument, err error) {
+	ctx, span := tracing.Start(ctx, "privacy.Service.Export")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.repo.FindUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.AnonimizadoEn != nil {
+		return nil, domain.Gone("user_anonymized", "los datos del usuario ya fueron anonimizados")
+	}
+
+	doc := &ExportDocument{
+		GeneratedAt: time.Now(),
+		User:        toExportUser(user),
+		Orders:      []ExportOrder{},
+		Payments:    []ExportPayment{},
+		Reviews:     []ExportReview{},
+	}
+
+	client, err := s.repo.FindClient(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading client profile: %w", err)
+	}
+
+	if client != nil {
+		doc.Client = &ExportClient{Direccion: client.Direccion, Telefono: client.Telefono}
+
+		orders, err := s.repo.ListOrders(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("error loading orders: %w", err)
+		}
+		for _, order := range orders {
+			doc.Orders = append(doc.Orders, toExportOrder(&order))
+		}
+
+		payments, err := s.repo.ListPayments(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("error loading payments: %w", err)
+		}
+		for _, payment := range payments {
+			doc.Payments = append(doc.Payments, toExportPayment(&payment))
+		}
+	}
+
+	reviews, err := s.repo.ListReviews(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading reviews: %w", err)
+	}
+	for _, review := range reviews {
+		doc.Reviews = append(doc.Reviews, ExportReview{
+			ID:           review.ID,
+			IDProducto:   review.ProductID,
+			Producto:     review.Product.Nombre,
+			Calificacion: review.Rating,
+			Comentario:   review.Comment,
+			CreatedAt:    review.CreatedAt,
+		})
+	}
+
+	now := time.Now()
+	request := &domain.DataRequest{
+		IDUsuario:     userID,
+		Tipo:          domain.DataRequestExport,
+		SolicitadoPor: requestedBy,
+		CompletadaEn:  &now,
+	}
+	if err := s.repo.CreateDataRequest(ctx, request); err != nil {
+		return nil, fmt.Errorf("error recording export request: %w", err)
+	}
+
+	if doc.Consents, err = s.ListConsents(ctx, userID); err != nil {
+		return nil, err
+	}
+	if doc.Requests, err = s.ListDataRequests(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// ExportZip empaqueta el documento en un ZIP con un JSON por sección
+func (s *Service) ExportZip(doc *ExportDocument) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	sections := []struct {
+		name string
+		data interface{}
+	}{
+		{"usuario.json", doc.User},
+		{"cliente.json", doc.Client},
+		{"compras.json", doc.Orders},
+		{"pagos.json", doc.Payments},
+		{"resenas.json", doc.Reviews},
+		{"consentimientos.json", doc.Consents},
+		{"solicitudes.json", doc.Requests},
+	}
+
+	for _, section := range sections {
+		w, err := archive.Create(section.name)
+		if err != nil {
+			return nil, err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(section.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Anonymize suprime los datos personales del usuario. No se puede deshacer.
+func (s *Service) Anonymize(ctx context.Context, userID, requestedBy uint) (err error) {
+	ctx, span := tracing.Start(ctx, "privacy.Service.Anonymize")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.repo.FindUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.AnonimizadoEn != nil {
+		return domain.Gone("user_anonymized", "los datos del usuario ya fueron anonimizados")
+	}
+
+	request := &domain.DataRequest{
+		IDUsuario:     userID,
+		Tipo:          domain.DataRequestDeletion,
+		SolicitadoPor: requestedBy,
+	}
+
+	if err := s.repo.Anonymize(ctx, user, request, time.Now()); err != nil {
+		return fmt.Errorf("error anonymizing user: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) RecordConsent(ctx context.Context, userID uint, req ConsentRequest, ip string) (_ *domain.Consent, err error) {
+	ctx, span := tracing.Start(ctx, "privacy.Service.RecordConsent")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.repo.FindUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	consent := &domain.Consent{
+		IDUsuario: userID,
+		Tipo:      req.Tipo,
+		Otorgado:  *req.Otorgado,
+		Version:   req.Version,
+	}
+	if ip != "" {
+		consent.IP = &ip
+	}
+
+	if err := s.repo.CreateConsent(ctx, consent); err != nil {
+		return nil, fmt.Errorf("error recording consent: %w", err)
+	}
+	return consent, nil
+}
+
+func (s *Service) ListConsents(ctx context.Context, userID uint) (_ []ConsentResponse, err error) {
+	ctx, span := tracing.Start(ctx, "privacy.Service.ListConsents")
+	defer func() { tracing.End(span, err) }()
+
+	consents, err := s.repo.ListConsents(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading consents: %w", err)
+	}
+
+	resp := make([]ConsentResponse, len(consents))
+	for i, c := range consents {
+		resp[i] = s.ToConsentResponse(&c)
+	}
+	return resp, nil
+}
+
+func (s *Service) ListDataRequests(ctx context.Context, userID uint) (_ []DataRequestResponse, err error) {
+	ctx, span := tracing.Start(ctx, "privacy.Service.ListDataRequests")
+	defer func() { tracing.End(span, err) }()
+
+	requests, err := s.repo.ListDataRequests(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading data requests: %w", err)
+	}
+
+	resp := make([]DataRequestResponse, len(requests))
+	for i, r := range requests {
+		resp[i] = DataRequestResponse{
+			ID:            r.ID,
+			Tipo:          string(r.Tipo),
+			SolicitadoPor: r.SolicitadoPor,
+			CreatedAt:     r.CreatedAt,
+			CompletadaEn:  r.CompletadaEn,
+		}
+	}
+	return resp, nil
+}
+
+func (s *Service) ToConsentResponse(consent *domain.Consent) ConsentResponse {
+	return ConsentResponse{
+		ID:        consent.ID,
+		Tipo:      consent.Tipo,
+		Otorgado:  consent.Otorgado,
+		Version:   consent.Version,
+		CreatedAt: consent.CreatedAt,
+	}
+}
+
+func toExportUser(user *domain.User) ExportUser {
+	roles := make([]string, len(user.Roles))
+	for i, role := range user.Roles {
+		roles[i] = role.RoleName
+	}
+
+	return ExportUser{
+		ID:                 user.ID,
+		Nombre:             user.Name,
+		Correo:             user.Email,
+		Roles:              roles,
+		CorreoVerificadoEn: user.CorreoVerificadoEn,
+		DobleFactor:        user.TwoFactorEnabled(),
+		CreatedAt:          user.CreatedAt,
+	}
+}
+
+func toExportOrder(order *domain.Order) ExportOrder {
+	exported := ExportOrder{
+		ID:          order.ID,
+		FechaCompra: order.FechaCompra,
+		Items:       make([]ExportOrderItem, len(order.Items)),
+	}
+
+	for i, item := range order.Items {
+		exported.Items[i] = ExportOrderItem{
+			IDProducto:     item.IDProducto,
+			Producto:       item.Product.Nombre,
+			Cantidad:       item.Cantidad,
+			PrecioUnitario: item.PrecioUnitario,
+			Descuento:      item.Descuento,
+		}
+		exported.Total += float64(item.Cantidad)*item.PrecioUnitario - item.Descuento
+	}
+	exported.Total = math.Round(exported.Total*100) / 100
+
+	return exported
+}
+
+func toExportPayment(payment *domain.Payment) ExportPayment {
+	exported := ExportPayment{
+		ID:        payment.ID,
+		IDCompra:  payment.IDCompra,
+		Monto:     payment.Monto,
+		FechaPago: payment.FechaPago,
+	}
+	if payment.PaymentMethod != nil {
+		exported.MetodoPago = payment.PaymentMethod.Nombre
+	}
+	return exported
+}
@@ -0,0 +1,91 @@
+package profiles
+
+/*
+# CreateClientRequest; body para POST /profiles/clients
+*/
+type CreateClientRequest struct {
+	IDUsuario uint    `json:"id_usuario" binding:"required"`
+	Direccion *string `json:"direccion" binding:"omitempty,max=150"`
+	Telefono  *string `json:"telefono" binding:"omitempty,max=20"`
+}
+
+/*
+# UpdateClientRequest; body para PUT /profiles/clients/:id
+*/
+type UpdateClientRequest struct {
+	Direccion *string `json:"direccion" binding:"omitempty,max=150"`
+	Telefono  *string `json:"telefono" binding:"omitempty,max=20"`
+}
+
+/*
+# CreateSellerRequest; body para POST /profiles/sellers
+*/
+type CreateSellerRequest struct {
+	IDUsuario       uint    `json:"id_usuario" binding:"required"`
+	AreaResponsable *string `json:"area_responsable" binding:"omitempty,max=100"`
+}
+
+/*
+# UpdateSellerRequest; body para PUT /profiles/sellers/:id
+*/
+type UpdateSellerRequest struct {
+	AreaResponsable *string `json:"area_responsable" binding:"omitempty,max=100"`
+}
+
+/*
+# CreateAdminRequest; body para POST /profiles/admins
+*/
+type CreateAdminRequest struct {
+	IDUsuario          uint    `json:"id_usuario" binding:"required"`
+	PermisosEspeciales *string `json:"permisos_especiales"`
+}
+
+/*
+# UpdateAdminRequest; body para PUT /profiles/admins/:id
+*/
+type UpdateAdminRequest struct {
+	PermisosEspeciales *string `json:"permisos_especiales"`
+}
+
+type ClientResponse struct {
+	ID        uint    `json:"id_cliente"`
+	Nombre    string  `json:"nombre"`
+	Correo    string  `json:"correo"`
+	Direccion *string `json:"direccion"`
+	Telefono  *string `json:"telefono"`
+}
+
+type SellerResponse struct {
+	ID              uint    `json:"id_vendedor"`
+	Nombre          string  `json:"nombre"`
+	Correo          string  `json:"correo"`
+	AreaResponsable *string `json:"area_responsable"`
+}
+
+type AdminResponse struct {
+	ID                 uint    `json:"id_admin"`
+	Nombre             string  `json:"nombre"`
+	Correo             string  `json:"correo"`
+	PermisosEspeciales *string `json:"permisos_especiales"`
+}
+
+type ClientListResponse struct {
+	Clients []ClientResponse `json:"clientes"`
+	Total   int64            `json:"total"`
+	Page    int              `json:"pagina"`
+	Limit   int              `json:"limite"`
+}
+
+type SellerListResponse struct {
+	Sellers []SellerResponse `json:"vendedores"`
+	Total   int64            `json:"total"`
+	Page    int              `json:"pagina"`
+	Limit   int              `json:"limite"`
+}
+
+type AdminListResponse struct {
+	Admins []AdminResponse `json:"administradores"`
+	Total  int64           `json:"total"`
+	Page   int             `json:"pagina"`
+	Limit  int             `json:"limite"`
+}
@@ -0,0 +1,332 @@
+package profiles
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/middleware"
+	api "github.com/mordmora/expirapp/internal/platform/http"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// CreateClient registra a un usuario existente como cliente
+// POST /api/v1/profiles/clients
+func (h *Handler) CreateClient(c *gin.Context) {
+	var req CreateClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	// un usuario puede registrarse a sí mismo como cliente
+	if !middleware.CanActOn(c, req.IDUsuario, domain.RoleAdmin) {
+		api.Error(c, domain.Forbidden("insufficient_permissions", "permisos insuficientes"))
+		return
+	}
+
+	client, err := h.service.CreateClient(c.Request.Context(), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Created(c, h.service.ToClientResponse(client), "client_created")
+}
+
+// GetClient obtiene el perfil de cliente de un usuario
+// GET /api/v1/profiles/clients/:id
+func (h *Handler) GetClient(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	client, err := h.service.GetClient(c.Request.Context(), id)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, h.service.ToClientResponse(client))
+}
+
+// UpdateClient actualiza los datos del perfil de cliente
+// PUT /api/v1/profiles/clients/:id
+func (h *Handler) UpdateClient(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	client, err := h.service.UpdateClient(c.Request.Context(), id, req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, h.service.ToClientResponse(client), "client_updated")
+}
+
+// DeleteClient quita el perfil de cliente; el usuario se conserva
+// DELETE /api/v1/profiles/clients/:id
+func (h *Handler) DeleteClient(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteClient(c.Request.Context(), id); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "client_deleted")
+}
+
+// ListClients lista los perfiles de cliente con paginación
+// GET /api/v1/profiles/clients
+func (h *Handler) ListClients(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	items, total, err := h.service.ListClients(c.Request.Context(), page, limit)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	responses := make([]ClientResponse, len(items))
+	for i, item := range items {
+		responses[i] = h.service.ToClientResponse(&item)
+	}
+
+	api.OK(c, ClientListResponse{
+		Clients: responses,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+	})
+}
+
+// CreateSeller registra a un usuario existente como vendedor
+// POST /api/v1/profiles/sellers
+func (h *Handler) CreateSeller(c *gin.Context) {
+	var req CreateSellerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	seller, err := h.service.CreateSeller(c.Request.Context(), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Created(c, h.service.ToSellerResponse(seller), "seller_created")
+}
+
+// GetSeller obtiene el perfil de vendedor de un usuario
+// GET /api/v1/profiles/sellers/:id
+func (h *Handler) GetSeller(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	seller, err := h.service.GetSeller(c.Request.Context(), id)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, h.service.ToSellerResponse(seller))
+}
+
+// UpdateSeller actualiza los datos del perfil de vendedor
+// PUT /api/v1/profiles/sellers/:id
+func (h *Handler) UpdateSeller(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateSellerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	seller, err := h.service.UpdateSeller(c.Request.Context(), id, req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, h.service.ToSellerResponse(seller), "seller_updated")
+}
+
+// DeleteSeller quita el perfil de vendedor; el usuario se conserva
+// DELETE /api/v1/profiles/sellers/:id
+func (h *Handler) DeleteSeller(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSeller(c.Request.Context(), id); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "seller_deleted")
+}
+
+// ListSellers lista los perfiles de vendedor con paginación
+// GET /api/v1/profiles/sellers
+func (h *Handler) ListSellers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	items, total, err := h.service.ListSellers(c.Request.Context(), page, limit)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	responses := make([]SellerResponse, len(items))
+	for i, item := range items {
+		responses[i] = h.service.ToSellerResponse(&item)
+	}
+
+	api.OK(c, SellerListResponse{
+		Sellers: responses,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+	})
+}
+
+// CreateAdmin registra a un usuario existente como administrador
+// POST /api/v1/profiles/admins
+func (h *Handler) CreateAdmin(c *gin.Context) {
+	var req CreateAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	admin, err := h.service.CreateAdmin(c.Request.Context(), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Created(c, h.service.ToAdminResponse(admin), "admin_created")
+}
+
+// GetAdmin obtiene el perfil de administrador de un usuario
+// GET /api/v1/profiles/admins/:id
+func (h *Handler) GetAdmin(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	admin, err := h.service.GetAdmin(c.Request.Context(), id)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, h.service.ToAdminResponse(admin))
+}
+
+// UpdateAdmin actualiza los datos del perfil de administrador
+// PUT /api/v1/profiles/admins/:id
+func (h *Handler) UpdateAdmin(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	admin, err := h.service.UpdateAdmin(c.Request.Context(), id, req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, h.service.ToAdminResponse(admin), "admin_updated")
+}
+
+// DeleteAdmin quita el perfil de administrador; el usuario se conserva
+// DELETE /api/v1/profiles/admins/:id
+func (h *Handler) DeleteAdmin(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteAdmin(c.Request.Context(), id); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "admin_deleted")
+}
+
+// ListAdmins lista los perfiles de administrador con paginación
+// GET /api/v1/profiles/admins
+func (h *Handler) ListAdmins(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	items, total, err := h.service.ListAdmins(c.Request.Context(), page, limit)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	responses := make([]AdminResponse, len(items))
+	for i, item := range items {
+		responses[i] = h.service.ToAdminResponse(&item)
+	}
+
+	api.OK(c, AdminListResponse{
+		Admins: responses,
+		Total:  total,
+		Page:   page,
+		Limit:  limit,
+	})
+}
+
+func parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return 0, false
+	}
+
+	return uint(id), true
+}
@@ -0,0 +1,192 @@
+package profiles
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) UserExists(ctx context.Context, userID uint) (bool, error) {
+	var c int64
+	err := r.db.WithContext(ctx).Model(&domain.User{}).Where("id_usuario = ?", userID).Count(&c).Error
+	return c > 0, err
+}
+
+// createWithRole inserta el perfil y asigna al usuario el rol correspondiente
+// en la misma transacción.
+func (r *Repository) createWithRole(ctx context.Context, profile interface{}, userID uint, roleName string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(profile).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`INSERT INTO usuario_rol (id_usuario, id_rol)
+			SELECT ?, id_rol FROM rol WHERE nombre = ?
+			ON CONFLICT DO NOTHING`, userID, roleName).Error
+	})
+}
+
+// deleteWithRole borra el perfil y le quita al usuario el rol correspondiente.
+func (r *Repository) deleteWithRole(ctx context.Context, profile interface{}, userID uint, roleName string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(profile, userID).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`DELETE FROM usuario_rol
+			WHERE id_usuario = ? AND id_rol IN (SELECT id_rol FROM rol WHERE nombre = ?)`,
+			userID, roleName).Error
+	})
+}
+
+func (r *Repository) CreateClient(ctx context.Context, client *domain.Client) error {
+	return r.createWithRole(ctx, client, client.ID, domain.RoleClient)
+}
+
+func (r *Repository) FindClientByID(ctx context.Context, id uint) (*domain.Client, error) {
+	var client domain.Client
+	err := r.db.WithContext(ctx).Preload("User").First(&client, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("client_not_found", "cliente con id %d no encontrado", id)
+		}
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+func (r *Repository) UpdateClient(ctx context.Context, client *domain.Client) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(client).Error
+}
+
+func (r *Repository) DeleteClient(ctx context.Context, id uint) error {
+	return r.deleteWithRole(ctx, &domain.Client{}, id, domain.RoleClient)
+}
+
+func (r *Repository) ListClients(ctx context.Context, limit, offset int) ([]domain.Client, int64, error) {
+	var clients []domain.Client
+	var total int64
+
+	if err := r.db.WithContext(ctx).Model(&domain.Client{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := r.db.WithContext(ctx).Preload("User").Order("id_cliente ASC").Limit(limit).Offset(offset).Find(&clients).Error
+	return clients, total, err
+}
+
+func (r *Repository) ClientExists(ctx context.Context, id uint) (bool, error) {
+	var c int64
+	err := r.db.WithContext(ctx).Model(&domain.Client{}).Where("id_cliente = ?", id).Count(&c).Error
+	return c > 0, err
+}
+
+func (r *Repository) CreateSeller(ctx context.Context, seller *domain.Seller) error {
+	return r.createWithRole(ctx, seller, seller.ID, domain.RoleSeller)
+}
+
+func (r *Repository) FindSellerByID(ctx context.Context, id uint) (*domain.Seller, error) {
+	var seller domain.Seller
+	err := r.db.WithContext(ctx).Preload("User").First(&seller, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("seller_not_found", "vendedor con id %d no encontrado", id)
+		}
+		return nil, err
+	}
+
+	return &seller, nil
+}
+
+func (r *Repository) UpdateSeller(ctx context.Context, seller *domain.Seller) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(seller).Error
+}
+
+func (r *Repository) DeleteSeller(ctx context.Context, id uint) error {
+	return r.deleteWithRole(ctx, &domain.Seller{}, id, domain.RoleSeller)
+}
+
+func (r *Repository) ListSellers(ctx context.Context, limit, offset int) ([]domain.Seller, int64, error) {
+	var sellers []domain.Seller
+	var total int64
+
+	if err := r.db.WithContext(ctx).Model(&domain.Seller{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := r.db.WithContext(ctx).Preload("User").Order("id_vendedor ASC").Limit(limit).Offset(offset).Find(&sellers).Error
+	return sellers, total, err
+}
+
+func (r *Repository) SellerExists(ctx context.Context, id uint) (bool, error) {
+	var c int64
+	err := r.db.WithContext(ctx).Model(&domain.Seller{}).Where("id_vendedor = ?", id).Count(&c).Error
+	return c > 0, err
+}
+
+func (r *Repository) CreateAdmin(ctx context.Context, admin *domain.Admin) error {
+	return r.createWithRole(ctx, admin, admin.ID, domain.RoleAdmin)
+}
+
+func (r *Repository) FindAdminByID(ctx context.Context, id uint) (*domain.Admin, error) {
+	var admin domain.Admin
+	err := r.db.WithContext(ctx).Preload("User").First(&admin, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("admin_not_found", "administrador no encontrado")
+		}
+		return nil, err
+	}
+
+	return &admin, nil
+}
+
+func (r *Repository) UpdateAdmin(ctx context.Context, admin *domain.Admin) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(admin).Error
+}
+
+func (r *Repository) DeleteAdmin(ctx context.Context, id uint) error {
+	return r.deleteWithRole(ctx, &domain.Admin{}, id, domain.RoleAdmin)
+}
+
+func (r *Repository) ListAdmins(ctx context.Context, limit, offset int) ([]domain.Admin, int64, error) {
+	var admins []domain.Admin
+	var total int64
+
+	if err := r.db.WithContext(ctx).Model(&domain.Admin{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := r.db.WithContext(ctx).Preload("User").Order("id_admin ASC").Limit(limit).Offset(offset).Find(&admins).Error
+	return admins, total, err
+}
+
+func (r *Repository) AdminExists(ctx context.Context, id uint) (bool, error) {
+	var c int64
+	err := r.db.WithContext(ctx).Model(&domain.Admin{}).Where("id_admin = ?", id).Count(&c).Error
+	return c > 0, err
+}
+
+// CountOrdersAsClient y CountOrdersAsSeller impiden borrar perfiles que ya
+// están referenciados por compras.
+func (r *Repository) CountOrdersAsClient(ctx context.Context, id uint) (int64, error) {
+	var c int64
+	err := r.db.WithContext(ctx).Model(&domain.Order{}).Unscoped().Where("id_cliente = ?", id).Count(&c).Error
+	return c, err
+}
+
+func (r *Repository) CountOrdersAsSeller(ctx context.Context, id uint) (int64, error) {
+	var c int64
+	err := r.db.WithContext(ctx).Model(&domain.Order{}).Unscoped().Where("id_vendedor = ?", id).Count(&c).Error
+	return c, err
+}
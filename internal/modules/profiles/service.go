@@ -0,0 +1,339 @@
+package profiles
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+)
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) checkUser(ctx context.Context, userID uint) error {
+	exists, err := s.repo.UserExists(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error checking user existence: %w", err)
+	}
+	if !exists {
+		return domain.NotFound("user_not_found", "usuario no encontrado")
+	}
+	return nil
+}
+
+func paginate(page, limit int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	return limit, (page - 1) * limit
+}
+
+func (s *Service) CreateClient(ctx context.Context, req CreateClientRequest) (_ *domain.Client, err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.CreateClient")
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.checkUser(ctx, req.IDUsuario); err != nil {
+		return nil, err
+	}
+
+	exists, err := s.repo.ClientExists(ctx, req.IDUsuario)
+	if err != nil {
+		return nil, fmt.Errorf("error checking client existence: %w", err)
+	}
+	if exists {
+		return nil, domain.Conflict("already_client", "el usuario ya es cliente")
+	}
+
+	client := &domain.Client{
+		ID:        req.IDUsuario,
+		Direccion: req.Direccion,
+		Telefono:  req.Telefono,
+	}
+
+	if err := s.repo.CreateClient(ctx, client); err != nil {
+		return nil, fmt.Errorf("error creating client: %w", err)
+	}
+
+	return s.repo.FindClientByID(ctx, client.ID)
+}
+
+func (s *Service) GetClient(ctx context.Context, id uint) (_ *domain.Client, err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.GetClient")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindClientByID(ctx, id)
+}
+
+func (s *Service) UpdateClient(ctx context.Context, id uint, req UpdateClientRequest) (_ *domain.Client, err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.UpdateClient")
+	defer func() { tracing.End(span, err) }()
+
+	client, err := s.repo.FindClientByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Direccion != nil {
+		client.Direccion = req.Direccion
+	}
+	if req.Telefono != nil {
+		client.Telefono = req.Telefono
+	}
+
+	if err := s.repo.UpdateClient(ctx, client); err != nil {
+		return nil, fmt.Errorf("error updating client: %w", err)
+	}
+
+	return client, nil
+}
+
+func (s *Service) DeleteClient(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.DeleteClient")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.repo.FindClientByID(ctx, id); err != nil {
+		return err
+	}
+
+	orders, err := s.repo.CountOrdersAsClient(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error checking client orders: %w", err)
+	}
+	if orders > 0 {
+		return domain.Conflict("client_has_orders", "el cliente tiene órdenes y no se puede eliminar")
+	}
+
+	return s.repo.DeleteClient(ctx, id)
+}
+
+func (s *Service) ListClients(ctx context.Context, page, limit int) (_ []domain.Client, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.ListClients")
+	defer func() { tracing.End(span, err) }()
+
+	limit, offset := paginate(page, limit)
+	return s.repo.ListClients(ctx, limit, offset)
+}
+
+// ValidateClient verifica que id_cliente corresponda a un perfil de cliente.
+// La usan las órdenes antes de insertar para no depender del error de FK.
+func (s *Service) ValidateClient(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.ValidateClient")
+	defer func() { tracing.End(span, err) }()
+
+	exists, err := s.repo.ClientExists(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error checking client existence: %w", err)
+	}
+	if !exists {
+		return domain.NotFound("client_not_found", "cliente con id %d no encontrado", id)
+	}
+	return nil
+}
+
+func (s *Service) CreateSeller(ctx context.Context, req CreateSellerRequest) (_ *domain.Seller, err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.CreateSeller")
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.checkUser(ctx, req.IDUsuario); err != nil {
+		return nil, err
+	}
+
+	exists, err := s.repo.SellerExists(ctx, req.IDUsuario)
+	if err != nil {
+		return nil, fmt.Errorf("error checking seller existence: %w", err)
+	}
+	if exists {
+		return nil, domain.Conflict("already_seller", "el usuario ya es vendedor")
+	}
+
+	seller := &domain.Seller{
+		ID:              req.IDUsuario,
+		AreaResponsable: req.AreaResponsable,
+	}
+
+	if err := s.repo.CreateSeller(ctx, seller); err != nil {
+		return nil, fmt.Errorf("error creating seller: %w", err)
+	}
+
+	return s.repo.FindSellerByID(ctx, seller.ID)
+}
+
+func (s *Service) GetSeller(ctx context.Context, id uint) (_ *domain.Seller, err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.GetSeller")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindSellerByID(ctx, id)
+}
+
+func (s *Service) UpdateSeller(ctx context.Context, id uint, req UpdateSellerRequest) (_ *domain.Seller, err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.UpdateSeller")
+	defer func() { tracing.End(span, err) }()
+
+	seller, err := s.repo.FindSellerByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.AreaResponsable != nil {
+		seller.AreaResponsable = req.AreaResponsable
+	}
+
+	if err := s.repo.UpdateSeller(ctx, seller); err != nil {
+		return nil, fmt.Errorf("error updating seller: %w", err)
+	}
+
+	return seller, nil
+}
+
+func (s *Service) DeleteSeller(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.DeleteSeller")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.repo.FindSellerByID(ctx, id); err != nil {
+		return err
+	}
+
+	orders, err := s.repo.CountOrdersAsSeller(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error checking seller orders: %w", err)
+	}
+	if orders > 0 {
+		return domain.Conflict("seller_has_orders", "el vendedor tiene órdenes y no se puede eliminar")
+	}
+
+	return s.repo.DeleteSeller(ctx, id)
+}
+
+func (s *Service) ListSellers(ctx context.Context, page, limit int) (_ []domain.Seller, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.ListSellers")
+	defer func() { tracing.End(span, err) }()
+
+	limit, offset := paginate(page, limit)
+	return s.repo.ListSellers(ctx, limit, offset)
+}
+
+// ValidateSeller verifica que id_vendedor corresponda a un perfil de vendedor.
+func (s *Service) ValidateSeller(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.ValidateSeller")
+	defer func() { tracing.End(span, err) }()
+
+	exists, err := s.repo.SellerExists(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error checking seller existence: %w", err)
+	}
+	if !exists {
+		return domain.NotFound("seller_not_found", "vendedor con id %d no encontrado", id)
+	}
+	return nil
+}
+
+func (s *Service) CreateAdmin(ctx context.Context, req CreateAdminRequest) (_ *domain.Admin, err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.CreateAdmin")
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.checkUser(ctx, req.IDUsuario); err != nil {
+		return nil, err
+	}
+
+	exists, err := s.repo.AdminExists(ctx, req.IDUsuario)
+	if err != nil {
+		return nil, fmt.Errorf("error checking admin existence: %w", err)
+	}
+	if exists {
+		return nil, domain.Conflict("already_admin", "el usuario ya es administrador")
+	}
+
+	admin := &domain.Admin{
+		ID:                 req.IDUsuario,
+		PermisosEspeciales: req.PermisosEspeciales,
+	}
+
+	if err := s.repo.CreateAdmin(ctx, admin); err != nil {
+		return nil, fmt.Errorf("error creating admin: %w", err)
+	}
+
+	return s.repo.FindAdminByID(ctx, admin.ID)
+}
+
+func (s *Service) GetAdmin(ctx context.Context, id uint) (_ *domain.Admin, err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.GetAdmin")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindAdminByID(ctx, id)
+}
+
+func (s *Service) UpdateAdmin(ctx context.Context, id uint, req UpdateAdminRequest) (_ *domain.Admin, err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.UpdateAdmin")
+	defer func() { tracing.End(span, err) }()
+
+	admin, err := s.repo.FindAdminByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.PermisosEspeciales != nil {
+		admin.PermisosEspeciales = req.PermisosEspeciales
+	}
+
+	if err := s.repo.UpdateAdmin(ctx, admin); err != nil {
+		return nil, fmt.Errorf("error updating admin: %w", err)
+	}
+
+	return admin, nil
+}
+
+func (s *Service) DeleteAdmin(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.DeleteAdmin")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.repo.FindAdminByID(ctx, id); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteAdmin(ctx, id)
+}
+
+func (s *Service) ListAdmins(ctx context.Context, page, limit int) (_ []domain.Admin, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "profiles.Service.ListAdmins")
+	defer func() { tracing.End(span, err) }()
+
+	limit, offset := paginate(page, limit)
+	return s.repo.ListAdmins(ctx, limit, offset)
+}
+
+func (s *Service) ToClientResponse(client *domain.Client) ClientResponse {
+	return ClientResponse{
+		ID:        client.ID,
+		Nombre:    client.User.Name,
+		Correo:    client.User.Email,
+		Direccion: client.Direccion,
+		Telefono:  client.Telefono,
+	}
+}
+
+func (s *Service) ToSellerResponse(seller *domain.Seller) SellerResponse {
+	return SellerResponse{
+		ID:              seller.ID,
+		Nombre:          seller.User.Name,
+		Correo:          seller.User.Email,
+		AreaResponsable: seller.AreaResponsable,
+	}
+}
+
+func (s *Service) ToAdminResponse(admin *domain.Admin) AdminResponse {
+	return AdminResponse{
+		ID:                 admin.ID,
+		Nombre:             admin.User.Name,
+		Correo:             admin.User.Email,
+		PermisosEspeciales: admin.PermisosEspeciales,
+	}
+}
@@ -0,0 +1,83 @@
+package promotions
+
+import "time"
+
+type CreatePromotionRequest struct {
+	Nombre         string     `json:"nombre" binding:"required,min=1,max=100"`
+	Tipo           string     `json:"tipo" binding:"required,oneof=porcentaje monto_fijo lleve_x_pague_y"`
+	Valor          float64    `json:"valor" binding:"omitempty,min=0"`
+	CantidadCompra int        `json:"cantidad_compra" binding:"omitempty,min=1"`
+	CantidadGratis int        `json:"cantidad_gratis" binding:"omitempty,min=1"`
+	IDProducto     *uint      `json:"id_producto" binding:"omitempty"`
+	IDCategoria    *uint      `json:"id_categoria" binding:"omitempty"`
+	RequiereCupon  bool       `json:"requiere_cupon"`
+	FechaInicio    time.Time  `json:"fecha_inicio" binding:"required"`
+	FechaFin       *time.Time `json:"fecha_fin" binding:"omitempty"`
+}
+
+type UpdatePromotionRequest struct {
+	Nombre         string     `json:"nombre" binding:"omitempty,min=1,max=100"`
+	Valor          float64    `json:"valor" binding:"omitempty,min=0"`
+	CantidadCompra int        `json:"cantidad_compra" binding:"omitempty,min=1"`
+	CantidadGratis int        `json:"cantidad_gratis" binding:"omitempty,min=1"`
+	FechaInicio    time.Time  `json:"fecha_inicio" binding:"omitempty"`
+	FechaFin       *time.Time `json:"fecha_fin" binding:"omitempty"`
+	Activa         *bool      `json:"activa" binding:"omitempty"`
+}
+
+type CreateCouponRequest struct {
+	Codigo         string     `json:"codigo" binding:"required,min=3,max=50"`
+	UsosMaximos    *int       `json:"usos_maximos" binding:"omitempty,min=1"`
+	UsosPorCliente *int       `json:"usos_por_cliente" binding:"omitempty,min=1"`
+	FechaInicio    *time.Time `json:"fecha_inicio" binding:"omitempty"`
+	FechaFin       *time.Time `json:"fecha_fin" binding:"omitempty"`
+}
+
+type UpdateCouponRequest struct {
+	UsosMaximos    *int       `json:"usos_maximos" binding:"omitempty,min=1"`
+	UsosPorCliente *int       `json:"usos_por_cliente" binding:"omitempty,min=1"`
+	FechaInicio    *time.Time `json:"fecha_inicio" binding:"omitempty"`
+	FechaFin       *time.Time `json:"fecha_fin" binding:"omitempty"`
+	Activo         *bool      `json:"activo" binding:"omitempty"`
+}
+
+type CouponResponse struct {
+	IDCupon        uint       `json:"id_cupon"`
+	Codigo         string     `json:"codigo"`
+	IDPromocion    uint       `json:"id_promocion"`
+	UsosMaximos    *int       `json:"usos_maximos,omitempty"`
+	UsosPorCliente *int       `json:"usos_por_cliente,omitempty"`
+	FechaInicio    *time.Time `json:"fecha_inicio,omitempty"`
+	FechaFin       *time.Time `json:"fecha_fin,omitempty"`
+	Activo         bool       `json:"activo"`
+}
+
+type PromotionResponse struct {
+	IDPromocion    uint             `json:"id_promocion"`
+	Nombre         string           `json:"nombre"`
+	Tipo           string           `json:"tipo"`
+	Valor          float64          `json:"valor"`
+	CantidadCompra int              `json:"cantidad_compra,omitempty"`
+	CantidadGratis int              `json:"cantidad_gratis,omitempty"`
+	IDProducto     *uint            `json:"id_producto,omitempty"`
+	IDCategoria    *uint            `json:"id_categoria,omitempty"`
+	RequiereCupon  bool             `json:"requiere_cupon"`
+	FechaInicio    time.Time        `json:"fecha_inicio"`
+	FechaFin       *time.Time       `json:"fecha_fin,omitempty"`
+	Activa         bool             `json:"activa"`
+	Cupones        []CouponResponse `json:"cupones"`
+}
+
+type PromotionListResponse struct {
+	Promotions []PromotionResponse `json:"promociones"`
+	Total      int64               `json:"total"`
+	Page       int                 `json:"pagina"`
+	Limit      int                 `json:"limite"`
+}
+
+type DiscountResponse struct {
+	IDPromocion uint    `json:"id_promocion"`
+	IDCupon     *uint   `json:"id_cupon,omitempty"`
+	Nombre      string  `json:"nombre"`
+	Monto       float64 `json:"monto"`
+}
@@ -0,0 +1,241 @@
+package promotions
+
+import (
+	"math"
+	"slices"
+
+	"github.com/mordmora/expirapp/internal/domain"
+)
+
+// Line es una línea de carrito u orden a la que se le calculan descuentos.
+// Categorias son las categorías de promociones vigentes que contienen al
+// producto; las llena el servicio al evaluar.
+type Line struct {
+	IDProducto     uint
+	Cantidad       int
+	PrecioUnitario float64
+	Categorias     []uint
+}
+
+func (l Line) Subtotal() float64 {
+	return round(float64(l.Cantidad) * l.PrecioUnitario)
+}
+
+type AppliedDiscount struct {
+	IDPromocion uint
+	IDCupon     *uint
+	Nombre      string
+	Monto       float64
+}
+
+type LineResult struct {
+	Line
+	Descuentos []AppliedDiscount
+	Descuento  float64
+}
+
+func (l LineResult) Total() float64 {
+	return round(l.Subtotal() - l.Descuento)
+}
+
+type Evaluation struct {
+	Lines     []LineResult
+	Subtotal  float64
+	Descuento float64
+	Total     float64
+	Cupon     *domain.Coupon
+}
+
+// Usages agrupa los descuentos por promoción y cupón para registrar su uso en
+// la compra.
+func (e *Evaluation) Usages(clientID uint) []domain.PromotionUsage {
+	type key struct {
+		promotion uint
+		coupon    uint
+	}
+
+	var usages []domain.PromotionUsage
+	index := map[key]int{}
+
+	for _, line := range e.Lines {
+		for _, d := range line.Descuentos {
+			k := key{promotion: d.IDPromocion}
+			if d.IDCupon != nil {
+				k.coupon = *d.IDCupon
+			}
+
+			i, ok := index[k]
+			if !ok {
+				usages = append(usages, domain.PromotionUsage{
+					IDPromocion: d.IDPromocion,
+					IDCupon:     d.IDCupon,
+					IDCliente:   clientID,
+				})
+				i = len(usages) - 1
+				index[k] = i
+			}
+			usages[i].MontoDescuento = round(usages[i].MontoDescuento + d.Monto)
+		}
+	}
+
+	return usages
+}
+
+// evaluate aplica a cada línea la mejor promoción automática de línea, luego
+// el mejor descuento automático sobre el total y por último el cupón. Ningún
+// descuento deja una línea por debajo de cero.
+func evaluate(promotions []domain.Promotion, coupon *domain.Coupon, lines []Line) *Evaluation {
+	results := make([]LineResult, len(lines))
+	for i, line := range lines {
+		results[i] = LineResult{Line: line}
+	}
+
+	for i := range results {
+		var best *domain.Promotion
+		var bestAmount float64
+
+		for j := range promotions {
+			p := &promotions[j]
+			if isOrderLevel(p) || !appliesTo(p, results[i].Line) {
+				continue
+			}
+
+			if amount := lineDiscount(p, results[i].Line); amount > bestAmount {
+				best, bestAmount = p, amount
+			}
+		}
+
+		if best != nil {
+			addDiscount(&results[i], best, nil, bestAmount)
+		}
+	}
+
+	var bestOrderLevel *domain.Promotion
+	for j := range promotions {
+		p := &promotions[j]
+		if isOrderLevel(p) && (bestOrderLevel == nil || p.Valor > bestOrderLevel.Valor) {
+			bestOrderLevel = p
+		}
+	}
+	if bestOrderLevel != nil {
+		prorate(results, bestOrderLevel, nil, bestOrderLevel.Valor)
+	}
+
+	if coupon != nil {
+		p := &coupon.Promotion
+		couponID := coupon.ID
+		if isOrderLevel(p) {
+			prorate(results, p, &couponID, p.Valor)
+		} else {
+			for i := range results {
+				if appliesTo(p, results[i].Line) {
+					addDiscount(&results[i], p, &couponID, lineDiscount(p, results[i].Line))
+				}
+			}
+		}
+	}
+
+	eval := &Evaluation{Lines: results, Cupon: coupon}
+	for _, r := range results {
+		eval.Subtotal += r.Subtotal()
+		eval.Descuento += r.Descuento
+	}
+	eval.Subtotal = round(eval.Subtotal)
+	eval.Descuento = round(eval.Descuento)
+	eval.Total = round(eval.Subtotal - eval.Descuento)
+
+	return eval
+}
+
+func isOrderLevel(p *domain.Promotion) bool {
+	return p.Tipo == domain.PromotionFixed && p.IDProducto == nil && p.IDCategoria == nil
+}
+
+func appliesTo(p *domain.Promotion, line Line) bool {
+	switch {
+	case p.IDProducto != nil:
+		return *p.IDProducto == line.IDProducto
+	case p.IDCategoria != nil:
+		return slices.Contains(line.Categorias, *p.IDCategoria)
+	default:
+		return true
+	}
+}
+
+func lineDiscount(p *domain.Promotion, line Line) float64 {
+	switch p.Tipo {
+	case domain.PromotionPercentage:
+		return round(line.Subtotal() * p.Valor / 100)
+	case domain.PromotionFixed:
+		return round(p.Valor * float64(line.Cantidad))
+	case domain.PromotionBuyXGetY:
+		group := p.CantidadCompra + p.CantidadGratis
+		if p.CantidadCompra <= 0 || p.CantidadGratis <= 0 {
+			return 0
+		}
+		free := (line.Cantidad / group) * p.CantidadGratis
+		return round(float64(free) * line.PrecioUnitario)
+	default:
+		return 0
+	}
+}
+
+// addDiscount agrega el descuento a la línea sin superar lo que le queda por
+// pagar.
+func addDiscount(line *LineResult, p *domain.Promotion, couponID *uint, amount float64) {
+	remaining := line.Total()
+	if amount > remaining {
+		amount = remaining
+	}
+	if amount <= 0 {
+		return
+	}
+
+	line.Descuentos = append(line.Descuentos, AppliedDiscount{
+		IDPromocion: p.ID,
+		IDCupon:     couponID,
+		Nombre:      p.Nombre,
+		Monto:       amount,
+	})
+	line.Descuento = round(line.Descuento + amount)
+}
+
+// prorate reparte un descuento sobre el total entre las líneas según lo que
+// le queda por pagar a cada una; la última absorbe el redondeo. Ninguna parte
+// pasa de lo que falta por repartir, así los centavos redondeados hacia
+// arriba no suman más que el descuento.
+func prorate(lines []LineResult, p *domain.Promotion, couponID *uint, amount float64) {
+	var remaining float64
+	last := -1
+	for i, line := range lines {
+		if line.Total() > 0 {
+			remaining += line.Total()
+			last = i
+		}
+	}
+	if last < 0 {
+		return
+	}
+
+	if amount > remaining {
+		amount = remaining
+	}
+
+	var assigned float64
+	for i := range lines {
+		if lines[i].Total() <= 0 {
+			continue
+		}
+
+		share := min(round(amount*lines[i].Total()/remaining), round(amount-assigned))
+		if i == last {
+			share = round(amount - assigned)
+		}
+		assigned += share
+		addDiscount(&lines[i], p, couponID, share)
+	}
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,208 @@
+package promotions
+
+import (
+	"testing"
+
+	"github.com/mordmora/expirapp/internal/domain"
+)
+
+func uintPtr(v uint) *uint { return &v }
+
+// discounts devuelve los montos de cada línea para la promoción id
+func discounts(eval *Evaluation, id uint) []float64 {
+	amounts := make([]float64, len(eval.Lines))
+	for i, line := range eval.Lines {
+		for _, d := range line.Descuentos {
+			if d.IDPromocion == id {
+				amounts[i] = round(amounts[i] + d.Monto)
+			}
+		}
+	}
+	return amounts
+}
+
+func TestEvaluateStacksLineOrderAndCoupon(t *testing.T) {
+	promotions := []domain.Promotion{
+		{ID: 1, Nombre: "10% en café", Tipo: domain.PromotionPercentage, Valor: 10, IDProducto: uintPtr(1)},
+		{ID: 2, Nombre: "5 de descuento", Tipo: domain.PromotionFixed, Valor: 5},
+	}
+	coupon := &domain.Coupon{ID: 9, Codigo: "BIENVENIDA", Promotion: domain.Promotion{
+		ID: 3, Nombre: "10% con cupón", Tipo: domain.PromotionPercentage, Valor: 10,
+	}}
+	lines := []Line{
+		{IDProducto: 1, Cantidad: 2, PrecioUnitario: 10},
+		{IDProducto: 2, Cantidad: 1, PrecioUnitario: 30},
+	}
+
+	eval := evaluate(promotions, coupon, lines)
+
+	// la de línea va primero, el monto fijo se reparte sobre lo que queda
+	// (18 y 30) y el cupón se calcula sobre el subtotal de cada línea
+	want := map[uint][]float64{
+		1: {2, 0},
+		2: {1.88, 3.12},
+		3: {2, 3},
+	}
+	for id, amounts := range want {
+		got := discounts(eval, id)
+		for i := range amounts {
+			if got[i] != amounts[i] {
+				t.Errorf("promotion %d: discounts = %v, want %v", id, got, amounts)
+				break
+			}
+		}
+	}
+
+	if eval.Subtotal != 50 || eval.Descuento != 12 || eval.Total != 38 {
+		t.Errorf("subtotal, discount, total = %v, %v, %v; want 50, 12, 38", eval.Subtotal, eval.Descuento, eval.Total)
+	}
+
+	usages := eval.Usages(4)
+	if len(usages) != 3 {
+		t.Fatalf("got %d usages, want 3", len(usages))
+	}
+	for _, u := range usages {
+		if u.IDPromocion == 3 && (u.IDCupon == nil || *u.IDCupon != 9 || u.MontoDescuento != 5) {
+			t.Errorf("coupon usage = %+v", u)
+		}
+	}
+}
+
+func TestEvaluateProratedDiscountsAddUp(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount float64
+		prices []float64
+		want   float64
+	}{
+		{"even split with a remainder", 10, []float64{10, 10, 10}, 10},
+		{"uneven lines", 7.77, []float64{1.11, 2.22, 3.33, 4.44}, 7.77},
+		{"one cent lines", 0.05, []float64{0.01, 0.01, 0.01, 0.01, 0.01, 0.01, 0.01}, 0.05},
+		{"more than the order", 100, []float64{19.99, 0.01}, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := make([]Line, len(tt.prices))
+			for i, price := range tt.prices {
+				lines[i] = Line{IDProducto: uint(i + 1), Cantidad: 1, PrecioUnitario: price}
+			}
+			promotions := []domain.Promotion{{ID: 1, Tipo: domain.PromotionFixed, Valor: tt.amount}}
+
+			eval := evaluate(promotions, nil, lines)
+
+			var sum float64
+			for i, amount := range discounts(eval, 1) {
+				if amount < 0 || amount > tt.prices[i] {
+					t.Errorf("line %d: discount %v outside [0, %v]", i, amount, tt.prices[i])
+				}
+				sum += amount
+			}
+			if round(sum) != tt.want || eval.Descuento != tt.want {
+				t.Errorf("line discounts add up to %v (order %v), want %v", round(sum), eval.Descuento, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateProrateSkipsPaidLines(t *testing.T) {
+	// la primera línea queda en cero con su promoción; el monto fijo cae
+	// entero en la segunda
+	promotions := []domain.Promotion{
+		{ID: 1, Tipo: domain.PromotionPercentage, Valor: 100, IDProducto: uintPtr(1)},
+		{ID: 2, Tipo: domain.PromotionFixed, Valor: 4},
+	}
+	lines := []Line{
+		{IDProducto: 1, Cantidad: 1, PrecioUnitario: 10},
+		{IDProducto: 2, Cantidad: 1, PrecioUnitario: 10},
+	}
+
+	eval := evaluate(promotions, nil, lines)
+
+	if got := discounts(eval, 2); got[0] != 0 || got[1] != 4 {
+		t.Errorf("order discount split = %v, want [0 4]", got)
+	}
+	if eval.Total != 6 {
+		t.Errorf("total = %v, want 6", eval.Total)
+	}
+}
+
+func TestEvaluateExclusions(t *testing.T) {
+	lines := []Line{
+		{IDProducto: 1, Cantidad: 7, PrecioUnitario: 10, Categorias: []uint{5}},
+		{IDProducto: 2, Cantidad: 1, PrecioUnitario: 10},
+	}
+
+	tests := []struct {
+		name       string
+		promotions []domain.Promotion
+		coupon     *domain.Coupon
+		want       map[uint][]float64
+	}{
+		{
+			name: "only the best line promotion applies",
+			promotions: []domain.Promotion{
+				{ID: 1, Tipo: domain.PromotionPercentage, Valor: 10, IDProducto: uintPtr(1)},
+				{ID: 2, Tipo: domain.PromotionPercentage, Valor: 20, IDProducto: uintPtr(1)},
+			},
+			want: map[uint][]float64{1: {0, 0}, 2: {14, 0}},
+		},
+		{
+			name: "category promotion skips lines outside the category",
+			promotions: []domain.Promotion{
+				{ID: 1, Tipo: domain.PromotionFixed, Valor: 1, IDCategoria: uintPtr(5)},
+				{ID: 2, Tipo: domain.PromotionFixed, Valor: 1, IDCategoria: uintPtr(6)},
+			},
+			want: map[uint][]float64{1: {7, 0}, 2: {0, 0}},
+		},
+		{
+			name: "only the best order discount applies",
+			promotions: []domain.Promotion{
+				{ID: 1, Tipo: domain.PromotionFixed, Valor: 8},
+				{ID: 2, Tipo: domain.PromotionFixed, Valor: 16},
+			},
+			want: map[uint][]float64{1: {0, 0}, 2: {14, 2}},
+		},
+		{
+			name: "buy two get one",
+			promotions: []domain.Promotion{
+				{ID: 1, Tipo: domain.PromotionBuyXGetY, CantidadCompra: 2, CantidadGratis: 1, IDProducto: uintPtr(1)},
+				{ID: 2, Tipo: domain.PromotionBuyXGetY, CantidadCompra: 1, CantidadGratis: 0, IDProducto: uintPtr(2)},
+			},
+			want: map[uint][]float64{1: {20, 0}, 2: {0, 0}},
+		},
+		{
+			name: "discounts stop at the line total",
+			promotions: []domain.Promotion{
+				{ID: 1, Tipo: domain.PromotionFixed, Valor: 15, IDProducto: uintPtr(2)},
+			},
+			coupon: &domain.Coupon{ID: 1, Promotion: domain.Promotion{ID: 2, Tipo: domain.PromotionPercentage, Valor: 50}},
+			want:   map[uint][]float64{1: {0, 10}, 2: {35, 0}},
+		},
+		{
+			name: "product coupon skips other products",
+			coupon: &domain.Coupon{ID: 1, Promotion: domain.Promotion{
+				ID: 1, Tipo: domain.PromotionPercentage, Valor: 50, IDProducto: uintPtr(2),
+			}},
+			want: map[uint][]float64{1: {0, 5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eval := evaluate(tt.promotions, tt.coupon, lines)
+
+			for id, amounts := range tt.want {
+				got := discounts(eval, id)
+				if got[0] != amounts[0] || got[1] != amounts[1] {
+					t.Errorf("promotion %d: discounts = %v, want %v", id, got, amounts)
+				}
+			}
+			for i, line := range eval.Lines {
+				if line.Total() < 0 {
+					t.Errorf("line %d total = %v", i, line.Total())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package promotions
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	api "github.com/mordmora/expirapp/internal/platform/http"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// CreatePromotion crea una nueva promoción
+// POST /api/v1/promotions
+func (h *Handler) CreatePromotion(c *gin.Context) {
+	var req CreatePromotionRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	promotion, err := h.service.Create(c.Request.Context(), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Created(c, h.service.ToResponse(promotion), "promotion_created")
+}
+
+// GetPromotion obtiene una promoción por ID
+// GET /api/v1/promotions/:id
+func (h *Handler) GetPromotion(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	promotion, err := h.service.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, h.service.ToResponse(promotion))
+}
+
+// UpdatePromotion actualiza una promoción
+// PUT /api/v1/promotions/:id
+func (h *Handler) UpdatePromotion(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	var req UpdatePromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	promotion, err := h.service.Update(c.Request.Context(), uint(id), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, h.service.ToResponse(promotion), "promotion_updated")
+}
+
+// DeletePromotion elimina una promoción
+// DELETE /api/v1/promotions/:id
+func (h *Handler) DeletePromotion(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), uint(id)); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "promotion_deleted")
+}
+
+// ListPromotions lista promociones con paginación
+// GET /api/v1/promotions?page=1&limit=10&activas=true
+func (h *Handler) ListPromotions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	onlyActive := c.Query("activas") == "true"
+
+	promotions, total, err := h.service.List(c.Request.Context(), onlyActive, page, limit)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	responses := make([]PromotionResponse, len(promotions))
+	for i, promotion := range promotions {
+		responses[i] = h.service.ToResponse(&promotion)
+	}
+
+	api.OK(c, PromotionListResponse{
+		Promotions: responses,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+	})
+}
+
+// CreateCoupon crea un cupón para una promoción
+// POST /api/v1/promotions/:id/coupons
+func (h *Handler) CreateCoupon(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	var req CreateCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	coupon, err := h.service.CreateCoupon(c.Request.Context(), uint(id), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Created(c, h.service.ToCouponResponse(coupon), "coupon_created")
+}
+
+// ListCoupons lista los cupones de una promoción
+// GET /api/v1/promotions/:id/coupons
+func (h *Handler) ListCoupons(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	coupons, err := h.service.ListCoupons(c.Request.Context(), uint(id))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	responses := make([]CouponResponse, len(coupons))
+	for i, coupon := range coupons {
+		responses[i] = h.service.ToCouponResponse(&coupon)
+	}
+
+	api.OK(c, responses)
+}
+
+// UpdateCoupon actualiza un cupón
+// PUT /api/v1/promotions/coupons/:couponId
+func (h *Handler) UpdateCoupon(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("couponId"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("couponId"))
+		return
+	}
+
+	var req UpdateCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	coupon, err := h.service.UpdateCoupon(c.Request.Context(), uint(id), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, h.service.ToCouponResponse(coupon), "coupon_updated")
+}
+
+// DeleteCoupon elimina un cupón
+// DELETE /api/v1/promotions/coupons/:couponId
+func (h *Handler) DeleteCoupon(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("couponId"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("couponId"))
+		return
+	}
+
+	if err := h.service.DeleteCoupon(c.Request.Context(), uint(id)); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "coupon_deleted")
+}
+
+// ValidateCoupon verifica si un cliente puede usar un cupón
+// GET /api/v1/promotions/coupons/validate/:code?id_cliente=1
+func (h *Handler) ValidateCoupon(c *gin.Context) {
+	clientID, err := strconv.ParseUint(c.Query("id_cliente"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id_cliente"))
+		return
+	}
+
+	coupon, err := h.service.ValidateCoupon(c.Request.Context(), c.Param("code"), uint(clientID))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, gin.H{
+		"cupon":     h.service.ToCouponResponse(coupon),
+		"promocion": h.service.ToResponse(&coupon.Promotion),
+	})
+}
@@ -0,0 +1,211 @@
+package promotions
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(ctx context.Context, promotion *domain.Promotion) error {
+	return r.db.WithContext(ctx).Create(promotion).Error
+}
+
+func (r *Repository) FindByID(ctx context.Context, id uint) (*domain.Promotion, error) {
+	var promotion domain.Promotion
+	err := r.db.WithContext(ctx).Preload("Coupons").First(&promotion, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("promotion_not_found", "promoción no encontrada")
+		}
+		return nil, err
+	}
+
+	return &promotion, nil
+}
+
+func (r *Repository) Update(ctx context.Context, promotion *domain.Promotion) error {
+	return r.db.WithContext(ctx).Omit("Coupons").Save(promotion).Error
+}
+
+func (r *Repository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&domain.Promotion{}, id).Error
+}
+
+func (r *Repository) List(ctx context.Context, onlyActive bool, limit, offset int) ([]domain.Promotion, int64, error) {
+	var promotions []domain.Promotion
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.Promotion{})
+	if onlyActive {
+		query = query.Where("activa = ?", true)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Coupons").Limit(limit).Offset(offset).Order("fecha_inicio DESC").Find(&promotions).Error
+	return promotions, total, err
+}
+
+// FindApplicable devuelve las promociones automáticas (sin cupón) activas y
+// vigentes en now.
+func (r *Repository) FindApplicable(ctx context.Context, now time.Time) ([]domain.Promotion, error) {
+	var promotions []domain.Promotion
+	err := r.db.WithContext(ctx).
+		Where("activa = ? AND requiere_cupon = ?", true, false).
+		Where("fecha_inicio <= ? AND (fecha_fin IS NULL OR fecha_fin >= ?)", now, now).
+		Find(&promotions).Error
+	return promotions, err
+}
+
+/*
+# CategoryMembers dice cuáles de productIDs están en cada una de categoryIDs
+* un producto está en una categoría si tiene asignada esa categoría o una
+* de sus descendientes (las que empiezan por su categoria.ruta)
+*/
+func (r *Repository) CategoryMembers(ctx context.Context, categoryIDs, productIDs []uint) (map[uint][]uint, error) {
+	var rows []struct {
+		CategoryID uint
+		ProductID  uint
+	}
+	query := `
+		SELECT DISTINCT c.id_categoria AS category_id, pc.id_producto AS product_id
+		FROM categoria c
+		JOIN categoria d ON d.ruta LIKE c.ruta || '%'
+		JOIN producto_categoria pc ON pc.id_categoria = d.id_categoria
+		WHERE c.id_categoria IN ? AND pc.id_producto IN ?`
+
+	if err := r.db.WithContext(ctx).Raw(query, categoryIDs, productIDs).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	members := make(map[uint][]uint)
+	for _, row := range rows {
+		members[row.ProductID] = append(members[row.ProductID], row.CategoryID)
+	}
+	return members, nil
+}
+
+func (r *Repository) CreateCoupon(ctx context.Context, coupon *domain.Coupon) error {
+	return r.db.WithContext(ctx).Omit("Promotion").Create(coupon).Error
+}
+
+func (r *Repository) FindCouponByID(ctx context.Context, id uint) (*domain.Coupon, error) {
+	var coupon domain.Coupon
+	err := r.db.WithContext(ctx).Preload("Promotion").First(&coupon, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("coupon_not_found", "el cupón %d no existe", id)
+		}
+		return nil, err
+	}
+
+	return &coupon, nil
+}
+
+func (r *Repository) FindCouponByCode(ctx context.Context, code string) (*domain.Coupon, error) {
+	var coupon domain.Coupon
+	err := r.db.WithContext(ctx).Preload("Promotion").Where("codigo = ?", code).First(&coupon).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("coupon_not_found", "el cupón %s no existe", code)
+		}
+		return nil, err
+	}
+
+	return &coupon, nil
+}
+
+func (r *Repository) ExistsCouponByCode(ctx context.Context, code string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.Coupon{}).Where("codigo = ?", code).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *Repository) ListCouponsByPromotion(ctx context.Context, promotionID uint) ([]domain.Coupon, error) {
+	var coupons []domain.Coupon
+	err := r.db.WithContext(ctx).Where("id_promocion = ?", promotionID).Order("codigo ASC").Find(&coupons).Error
+	return coupons, err
+}
+
+func (r *Repository) UpdateCoupon(ctx context.Context, coupon *domain.Coupon) error {
+	return r.db.WithContext(ctx).Omit("Promotion").Save(coupon).Error
+}
+
+func (r *Repository) DeleteCoupon(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&domain.Coupon{}, id).Error
+}
+
+// CountCouponUsage cuenta las compras vigentes en las que se usó el cupón.
+// Con clientID distinto de cero cuenta solo las de ese cliente.
+func (r *Repository) CountCouponUsage(ctx context.Context, couponID, clientID uint) (int64, error) {
+	return countCouponUsage(r.db.WithContext(ctx), couponID, clientID)
+}
+
+/*
+# CheckCouponLimitsTx vuelve a comprobar los límites de uso del cupón dentro
+# de la transacción que registra la compra
+* la fila del cupón queda bloqueada (SELECT ... FOR UPDATE) hasta el final
+* de la transacción: dos compras con el mismo cupón se registran una tras
+* otra y la segunda ya cuenta el uso de la primera
+* ValidateCoupon hace la misma cuenta antes, sin bloqueo, para responder
+* rápido; esta es la que decide
+*/
+func CheckCouponLimitsTx(tx *gorm.DB, couponID, clientID uint) error {
+	var coupon domain.Coupon
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&coupon, couponID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.NotFound("coupon_not_found", "el cupón %d no existe", couponID)
+		}
+		return err
+	}
+
+	if coupon.UsosMaximos != nil {
+		used, err := countCouponUsage(tx, coupon.ID, 0)
+		if err != nil {
+			return err
+		}
+		if used >= int64(*coupon.UsosMaximos) {
+			return domain.Validation("coupon_exhausted", "el cupón %s alcanzó su límite de usos", coupon.Codigo)
+		}
+	}
+
+	if coupon.UsosPorCliente != nil {
+		used, err := countCouponUsage(tx, coupon.ID, clientID)
+		if err != nil {
+			return err
+		}
+		if used >= int64(*coupon.UsosPorCliente) {
+			return domain.Validation("coupon_client_limit", "el cliente ya usó el cupón %s el máximo de veces permitido", coupon.Codigo)
+		}
+	}
+
+	return nil
+}
+
+func countCouponUsage(db *gorm.DB, couponID, clientID uint) (int64, error) {
+	var count int64
+	query := db.Model(&domain.PromotionUsage{}).
+		Joins("JOIN compra c ON c.id_compra = uso_promocion.id_compra AND c.deleted_at IS NULL").
+		Where("uso_promocion.id_cupon = ?", couponID)
+	if clientID != 0 {
+		query = query.Where("uso_promocion.id_cliente = ?", clientID)
+	}
+
+	err := query.Distinct("uso_promocion.id_compra").Count(&count).Error
+	return count, err
+}
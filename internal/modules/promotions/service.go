@@ -0,0 +1,460 @@
+package promotions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/modules/catalog"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+)
+
+// CategoryFinder busca las categorías a las que puede apuntar una promoción
+type CategoryFinder interface {
+	FindByID(ctx context.Context, id uint) (*domain.Category, error)
+}
+
+type Service struct {
+	repo        *Repository
+	catalogRepo *catalog.Repository
+	categories  CategoryFinder
+}
+
+func NewService(repo *Repository, catalogRepo *catalog.Repository, categories CategoryFinder) *Service {
+	return &Service{
+		repo:        repo,
+		catalogRepo: catalogRepo,
+		categories:  categories,
+	}
+}
+
+func (s *Service) Create(ctx context.Context, req CreatePromotionRequest) (_ *domain.Promotion, err error) {
+	ctx, span := tracing.Start(ctx, "promotions.Service.Create")
+	defer func() { tracing.End(span, err) }()
+
+	promotion := &domain.Promotion{
+		Nombre:         req.Nombre,
+		Tipo:           domain.PromotionType(req.Tipo),
+		Valor:          req.Valor,
+		CantidadCompra: req.CantidadCompra,
+		CantidadGratis: req.CantidadGratis,
+		IDProducto:     req.IDProducto,
+		IDCategoria:    req.IDCategoria,
+		RequiereCupon:  req.RequiereCupon,
+		FechaInicio:    req.FechaInicio,
+		FechaFin:       req.FechaFin,
+		Activa:         true,
+	}
+
+	if err := s.validate(ctx, promotion); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, promotion); err != nil {
+		return nil, fmt.Errorf("error creating promotion: %w", err)
+	}
+
+	return promotion, nil
+}
+
+func (s *Service) GetByID(ctx context.Context, id uint) (_ *domain.Promotion, err error) {
+	ctx, span := tracing.Start(ctx, "promotions.Service.GetByID")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindByID(ctx, id)
+}
+
+func (s *Service) Update(ctx context.Context, id uint, req UpdatePromotionRequest) (_ *domain.Promotion, err error) {
+	ctx, span := tracing.Start(ctx, "promotions.Service.Update")
+	defer func() { tracing.End(span, err) }()
+
+	promotion, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Nombre != "" {
+		promotion.Nombre = req.Nombre
+	}
+
+	if req.Valor > 0 {
+		promotion.Valor = req.Valor
+	}
+
+	if req.CantidadCompra > 0 {
+		promotion.CantidadCompra = req.CantidadCompra
+	}
+
+	if req.CantidadGratis > 0 {
+		promotion.CantidadGratis = req.CantidadGratis
+	}
+
+	if !req.FechaInicio.IsZero() {
+		promotion.FechaInicio = req.FechaInicio
+	}
+
+	if req.FechaFin != nil {
+		promotion.FechaFin = req.FechaFin
+	}
+
+	if req.Activa != nil {
+		promotion.Activa = *req.Activa
+	}
+
+	if err := s.validate(ctx, promotion); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, promotion); err != nil {
+		return nil, fmt.Errorf("error updating promotion: %w", err)
+	}
+
+	return promotion, nil
+}
+
+func (s *Service) Delete(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "promotions.Service.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *Service) List(ctx context.Context, onlyActive bool, page, limit int) (_ []domain.Promotion, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "promotions.Service.List")
+	defer func() { tracing.End(span, err) }()
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+	return s.repo.List(ctx, onlyActive, limit, offset)
+}
+
+func (s *Service) validate(ctx context.Context, p *domain.Promotion) error {
+	switch p.Tipo {
+	case domain.PromotionPercentage:
+		if p.Valor <= 0 || p.Valor > 100 {
+			return domain.InvalidField("valor", "out_of_range", "el porcentaje de descuento debe estar entre 0 y 100")
+		}
+	case domain.PromotionFixed:
+		if p.Valor <= 0 {
+			return domain.InvalidField("valor", "must_be_positive", "%s debe ser mayor a cero", "valor")
+		}
+	case domain.PromotionBuyXGetY:
+		if p.CantidadCompra < 1 || p.CantidadGratis < 1 {
+			return domain.Validation("invalid_quantities", "cantidad_compra y cantidad_gratis deben ser mayores a cero")
+		}
+	default:
+		return domain.InvalidField("tipo", "invalid_promotion_type", "tipo de promoción desconocido: %s", p.Tipo)
+	}
+
+	if p.FechaFin != nil && p.FechaFin.Before(p.FechaInicio) {
+		return domain.InvalidField("fecha_fin", "invalid_date_range", "fecha_fin debe ser posterior a fecha_inicio")
+	}
+
+	if p.IDProducto != nil && p.IDCategoria != nil {
+		return domain.InvalidField("id_categoria", "promotion_scope_conflict", "una promoción aplica a un producto o a una categoría, no a ambos")
+	}
+
+	if p.IDProducto != nil {
+		if _, err := s.catalogRepo.FindByID(ctx, *p.IDProducto); err != nil {
+			return domain.NotFound("product_not_found", "producto con id %d no encontrado", *p.IDProducto)
+		}
+	}
+
+	if p.IDCategoria != nil {
+		if _, err := s.categories.FindByID(ctx, *p.IDCategoria); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) CreateCoupon(ctx context.Context, promotionID uint, req CreateCouponRequest) (_ *domain.Coupon, err error) {
+	ctx, span := tracing.Start(ctx, "promotions.Service.CreateCoupon")
+	defer func() { tracing.End(span, err) }()
+
+	promotion, err := s.repo.FindByID(ctx, promotionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !promotion.RequiereCupon {
+		return nil, domain.Validation("promotion_without_coupon", "solo se pueden crear cupones para promociones que requieren cupón")
+	}
+
+	code := normalizeCode(req.Codigo)
+	exists, err := s.repo.ExistsCouponByCode(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("error checking coupon code: %w", err)
+	}
+	if exists {
+		return nil, domain.Conflict("coupon_exists", "ya existe un cupón con el código %s", code)
+	}
+
+	coupon := &domain.Coupon{
+		Codigo:         code,
+		IDPromocion:    promotionID,
+		UsosMaximos:    req.UsosMaximos,
+		UsosPorCliente: req.UsosPorCliente,
+		FechaInicio:    req.FechaInicio,
+		FechaFin:       req.FechaFin,
+		Activo:         true,
+	}
+
+	if coupon.FechaInicio != nil && coupon.FechaFin != nil && coupon.FechaFin.Before(*coupon.FechaInicio) {
+		return nil, domain.InvalidField("fecha_fin", "invalid_date_range", "fecha_fin debe ser posterior a fecha_inicio")
+	}
+
+	if err := s.repo.CreateCoupon(ctx, coupon); err != nil {
+		return nil, fmt.Errorf("error creating coupon: %w", err)
+	}
+
+	return coupon, nil
+}
+
+func (s *Service) ListCoupons(ctx context.Context, promotionID uint) (_ []domain.Coupon, err error) {
+	ctx, span := tracing.Start(ctx, "promotions.Service.ListCoupons")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.repo.FindByID(ctx, promotionID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListCouponsByPromotion(ctx, promotionID)
+}
+
+func (s *Service) UpdateCoupon(ctx context.Context, id uint, req UpdateCouponRequest) (_ *domain.Coupon, err error) {
+	ctx, span := tracing.Start(ctx, "promotions.Service.UpdateCoupon")
+	defer func() { tracing.End(span, err) }()
+
+	coupon, err := s.repo.FindCouponByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.UsosMaximos != nil {
+		coupon.UsosMaximos = req.UsosMaximos
+	}
+
+	if req.UsosPorCliente != nil {
+		coupon.UsosPorCliente = req.UsosPorCliente
+	}
+
+	if req.FechaInicio != nil {
+		coupon.FechaInicio = req.FechaInicio
+	}
+
+	if req.FechaFin != nil {
+		coupon.FechaFin = req.FechaFin
+	}
+
+	if req.Activo != nil {
+		coupon.Activo = *req.Activo
+	}
+
+	if coupon.FechaInicio != nil && coupon.FechaFin != nil && coupon.FechaFin.Before(*coupon.FechaInicio) {
+		return nil, domain.InvalidField("fecha_fin", "invalid_date_range", "fecha_fin debe ser posterior a fecha_inicio")
+	}
+
+	if err := s.repo.UpdateCoupon(ctx, coupon); err != nil {
+		return nil, fmt.Errorf("error updating coupon: %w", err)
+	}
+
+	return coupon, nil
+}
+
+func (s *Service) DeleteCoupon(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "promotions.Service.DeleteCoupon")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.repo.FindCouponByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.DeleteCoupon(ctx, id)
+}
+
+// ValidateCoupon verifica que el cupón exista, esté activo y vigente y que no
+// haya superado sus límites de uso global ni del cliente.
+func (s *Service) ValidateCoupon(ctx context.Context, code string, clientID uint) (_ *domain.Coupon, err error) {
+	ctx, span := tracing.Start(ctx, "promotions.Service.ValidateCoupon")
+	defer func() { tracing.End(span, err) }()
+
+	code = normalizeCode(code)
+	coupon, err := s.repo.FindCouponByCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, domain.NotFound("coupon_not_found", "el cupón %s no existe", code)
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	promotion := coupon.Promotion
+
+	if !coupon.Activo || promotion.ID == 0 || !promotion.Activa {
+		return nil, domain.Validation("coupon_inactive", "el cupón %s no está activo", code)
+	}
+
+	if now.Before(promotion.FechaInicio) || (promotion.FechaFin != nil && now.After(*promotion.FechaFin)) ||
+		(coupon.FechaInicio != nil && now.Before(*coupon.FechaInicio)) ||
+		(coupon.FechaFin != nil && now.After(*coupon.FechaFin)) {
+		return nil, domain.Validation("coupon_expired", "el cupón %s no está vigente", code)
+	}
+
+	if coupon.UsosMaximos != nil {
+		used, err := s.repo.CountCouponUsage(ctx, coupon.ID, 0)
+		if err != nil {
+			return nil, fmt.Errorf("error contando usos del cupón: %w", err)
+		}
+		if used >= int64(*coupon.UsosMaximos) {
+			return nil, domain.Validation("coupon_exhausted", "el cupón %s alcanzó su límite de usos", code)
+		}
+	}
+
+	if coupon.UsosPorCliente != nil {
+		used, err := s.repo.CountCouponUsage(ctx, coupon.ID, clientID)
+		if err != nil {
+			return nil, fmt.Errorf("error contando usos del cupón: %w", err)
+		}
+		if used >= int64(*coupon.UsosPorCliente) {
+			return nil, domain.Validation("coupon_client_limit", "el cliente ya usó el cupón %s el máximo de veces permitido", code)
+		}
+	}
+
+	return coupon, nil
+}
+
+// Evaluate calcula los descuentos de las líneas para el cliente. Un cupón
+// inválido hace fallar la evaluación.
+func (s *Service) Evaluate(ctx context.Context, clientID uint, lines []Line, couponCode string) (_ *Evaluation, err error) {
+	ctx, span := tracing.Start(ctx, "promotions.Service.Evaluate")
+	defer func() { tracing.End(span, err) }()
+
+	var coupon *domain.Coupon
+	if strings.TrimSpace(couponCode) != "" {
+		var err error
+		coupon, err = s.ValidateCoupon(ctx, couponCode, clientID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.EvaluateWithCoupon(ctx, lines, coupon)
+}
+
+// EvaluateWithCoupon calcula los descuentos con un cupón ya validado (o nil).
+func (s *Service) EvaluateWithCoupon(ctx context.Context, lines []Line, coupon *domain.Coupon) (_ *Evaluation, err error) {
+	ctx, span := tracing.Start(ctx, "promotions.Service.EvaluateWithCoupon")
+	defer func() { tracing.End(span, err) }()
+
+	promotions, err := s.repo.FindApplicable(ctx, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo promociones vigentes: %w", err)
+	}
+
+	lines, err = s.withCategories(ctx, promotions, coupon, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	return evaluate(promotions, coupon, lines), nil
+}
+
+// withCategories devuelve una copia de las líneas con las categorías de
+// promoción que contienen a cada producto. Sin promociones por categoría no
+// consulta nada.
+func (s *Service) withCategories(ctx context.Context, promotions []domain.Promotion, coupon *domain.Coupon, lines []Line) ([]Line, error) {
+	var categoryIDs []uint
+	for _, p := range promotions {
+		if p.IDCategoria != nil {
+			categoryIDs = append(categoryIDs, *p.IDCategoria)
+		}
+	}
+	if coupon != nil && coupon.Promotion.IDCategoria != nil {
+		categoryIDs = append(categoryIDs, *coupon.Promotion.IDCategoria)
+	}
+	if len(categoryIDs) == 0 || len(lines) == 0 {
+		return lines, nil
+	}
+
+	productIDs := make([]uint, len(lines))
+	for i, line := range lines {
+		productIDs[i] = line.IDProducto
+	}
+
+	members, err := s.repo.CategoryMembers(ctx, categoryIDs, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo categorías de los productos: %w", err)
+	}
+
+	scoped := make([]Line, len(lines))
+	for i, line := range lines {
+		line.Categorias = members[line.IDProducto]
+		scoped[i] = line
+	}
+	return scoped, nil
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (s *Service) ToCouponResponse(coupon *domain.Coupon) CouponResponse {
+	return CouponResponse{
+		IDCupon:        coupon.ID,
+		Codigo:         coupon.Codigo,
+		IDPromocion:    coupon.IDPromocion,
+		UsosMaximos:    coupon.UsosMaximos,
+		UsosPorCliente: coupon.UsosPorCliente,
+		FechaInicio:    coupon.FechaInicio,
+		FechaFin:       coupon.FechaFin,
+		Activo:         coupon.Activo,
+	}
+}
+
+func (s *Service) ToResponse(promotion *domain.Promotion) PromotionResponse {
+	coupons := make([]CouponResponse, len(promotion.Coupons))
+	for i, coupon := range promotion.Coupons {
+		coupons[i] = s.ToCouponResponse(&coupon)
+	}
+
+	return PromotionResponse{
+		IDPromocion:    promotion.ID,
+		Nombre:         promotion.Nombre,
+		Tipo:           string(promotion.Tipo),
+		Valor:          promotion.Valor,
+		CantidadCompra: promotion.CantidadCompra,
+		CantidadGratis: promotion.CantidadGratis,
+		IDProducto:     promotion.IDProducto,
+		IDCategoria:    promotion.IDCategoria,
+		RequiereCupon:  promotion.RequiereCupon,
+		FechaInicio:    promotion.FechaInicio,
+		FechaFin:       promotion.FechaFin,
+		Activa:         promotion.Activa,
+		Cupones:        coupons,
+	}
+}
+
+func ToDiscountResponses(discounts []AppliedDiscount) []DiscountResponse {
+	resp := make([]DiscountResponse, len(discounts))
+	for i, d := range discounts {
+		resp[i] = DiscountResponse(d)
+	}
+	return resp
+}
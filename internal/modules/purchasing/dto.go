@@ -0,0 +1,138 @@
+package purchasing
+
+import "time"
+
+// CreateSupplierRequest; dias_entrega es el plazo pactado en días
+type CreateSupplierRequest struct {
+	Nombre      string `json:"nombre" binding:"required,min=1,max=150"`
+	NIT         string `json:"nit" binding:"omitempty,max=20"`
+	Contacto    string `json:"contacto" binding:"omitempty,max=100"`
+	Email       string `json:"email" binding:"omitempty,email,max=150"`
+	Telefono    string `json:"telefono" binding:"omitempty,max=30"`
+	DiasEntrega *int   `json:"dias_entrega" binding:"omitempty,min=1,max=365"`
+}
+
+// UpdateSupplierRequest; activo en false lo deja fuera de las órdenes nuevas
+type UpdateSupplierRequest struct {
+	Nombre      string  `json:"nombre" binding:"omitempty,min=1,max=150"`
+	NIT         *string `json:"nit" binding:"omitempty,max=20"`
+	Contacto    *string `json:"contacto" binding:"omitempty,max=100"`
+	Email       *string `json:"email" binding:"omitempty,email,max=150"`
+	Telefono    *string `json:"telefono" binding:"omitempty,max=30"`
+	DiasEntrega *int    `json:"dias_entrega" binding:"omitempty,min=1,max=365"`
+	Activo      *bool   `json:"activo" binding:"omitempty"`
+}
+
+type SupplierResponse struct {
+	ID          uint    `json:"id_proveedor"`
+	Nombre      string  `json:"nombre"`
+	NIT         *string `json:"nit,omitempty"`
+	Contacto    string  `json:"contacto,omitempty"`
+	Email       string  `json:"email,omitempty"`
+	Telefono    string  `json:"telefono,omitempty"`
+	DiasEntrega *int    `json:"dias_entrega,omitempty"`
+	Activo      bool    `json:"activo"`
+}
+
+type PurchaseOrderLineRequest struct {
+	IDProducto    uint    `json:"id_producto" binding:"required"`
+	Cantidad      int     `json:"cantidad" binding:"required,min=1"`
+	CostoUnitario float64 `json:"costo_unitario" binding:"min=0"`
+}
+
+/*
+# CreatePurchaseOrderRequest; la orden queda en borrador
+* un producto va en una sola línea
+*/
+type CreatePurchaseOrderRequest struct {
+	IDProveedor   uint                       `json:"id_proveedor" binding:"required"`
+	FechaEsperada *time.Time                 `json:"fecha_esperada"`
+	Notas         string                     `json:"notas" binding:"omitempty,max=1000"`
+	Lineas        []PurchaseOrderLineRequest `json:"lineas" binding:"required,min=1,max=200,dive"`
+}
+
+// UpdatePurchaseOrderRequest solo aplica en borrador; lineas reemplaza todas
+type UpdatePurchaseOrderRequest struct {
+	FechaEsperada *time.Time                 `json:"fecha_esperada"`
+	Notas         *string                    `json:"notas" binding:"omitempty,max=1000"`
+	Lineas        []PurchaseOrderLineRequest `json:"lineas" binding:"omitempty,min=1,max=200,dive"`
+}
+
+// ListPurchaseOrdersRequest; query de GET /purchasing/orders
+type ListPurchaseOrdersRequest struct {
+	Estado      string `form:"estado" binding:"omitempty,oneof=borrador enviada parcial recibida cancelada"`
+	IDProveedor uint   `form:"proveedor"`
+	Page        int    `form:"page"`
+	Limit       int    `form:"limit"`
+}
+
+/*
+# ReceiptLineRequest son las unidades de una línea de la orden que llegaron
+# en un lote
+* una línea puede llegar repartida en varios lotes
+* sin costo_unitario se toma el de la orden
+*/
+type ReceiptLineRequest struct {
+	IDDetalleOrden   uint      `json:"id_detalle_orden" binding:"required"`
+	Cantidad         int       `json:"cantidad" binding:"required,min=1"`
+	NumeroLote       string    `json:"numero_lote" binding:"required,min=1,max=20"`
+	FechaVencimiento time.Time `json:"fecha_vencimiento" binding:"required"`
+	CostoUnitario    *float64  `json:"costo_unitario" binding:"omitempty,min=0"`
+}
+
+// CreateReceiptRequest registra una entrega; sin recibida_en es ahora
+type CreateReceiptRequest struct {
+	RecibidaEn *time.Time           `json:"recibida_en"`
+	Notas      string               `json:"notas" binding:"omitempty,max=1000"`
+	Lineas     []ReceiptLineRequest `json:"lineas" binding:"required,min=1,max=200,dive"`
+}
+
+type PurchaseOrderLineResponse struct {
+	ID               uint    `json:"id_detalle_orden"`
+	IDProducto       uint    `json:"id_producto"`
+	NombreProducto   string  `json:"nombre_producto"`
+	Cantidad         int     `json:"cantidad"`
+	CantidadRecibida int     `json:"cantidad_recibida"`
+	Pendiente        int     `json:"pendiente"`
+	CostoUnitario    float64 `json:"costo_unitario"`
+}
+
+type ReceiptLineResponse struct {
+	IDDetalleOrden   uint      `json:"id_detalle_orden"`
+	IDLote           uint      `json:"id_lote"`
+	NumeroLote       string    `json:"numero_lote"`
+	FechaVencimiento time.Time `json:"fecha_vencimiento"`
+	Cantidad         int       `json:"cantidad"`
+	CostoUnitario    float64   `json:"costo_unitario"`
+}
+
+type ReceiptResponse struct {
+	ID         uint                  `json:"id_recepcion"`
+	RecibidaEn time.Time             `json:"recibida_en"`
+	Notas      string                `json:"notas,omitempty"`
+	IDUsuario  *uint                 `json:"id_usuario,omitempty"`
+	Lineas     []ReceiptLineResponse `json:"lineas"`
+}
+
+// PurchaseOrderResponse; total es lo pedido al costo de la orden
+type PurchaseOrderResponse struct {
+	ID              uint                        `json:"id_orden_compra"`
+	IDProveedor     uint                        `json:"id_proveedor"`
+	NombreProveedor string                      `json:"nombre_proveedor"`
+	Estado          string                      `json:"estado"`
+	FechaEsperada   *time.Time                  `json:"fecha_esperada,omitempty"`
+	EnviadaEn       *time.Time                  `json:"enviada_en,omitempty"`
+	CerradaEn       *time.Time                  `json:"cerrada_en,omitempty"`
+	Notas           string                      `json:"notas,omitempty"`
+	IDUsuario       *uint                       `json:"id_usuario,omitempty"`
+	Total           float64                     `json:"total"`
+	Lineas          []PurchaseOrderLineResponse `json:"lineas"`
+	Recepciones     []ReceiptResponse           `json:"recepciones"`
+}
+
+type PurchaseOrderListResponse struct {
+	Orders []PurchaseOrderResponse `json:"ordenes"`
+	Total  int64                   `json:"total"`
+	Page   int                     `json:"pagina"`
+	Limit  int                     `json:"limite"`
+}
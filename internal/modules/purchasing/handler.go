@@ -0,0 +1,288 @@
+package purchasing
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/middleware"
+	"github.com/mordmora/expirapp/internal/modules/catalog"
+	api "github.com/mordmora/expirapp/internal/platform/http"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// CreateSupplier registra un proveedor
+// POST /api/v1/purchasing/suppliers
+func (h *Handler) CreateSupplier(c *gin.Context) {
+	var req CreateSupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	supplier, err := h.service.CreateSupplier(c.Request.Context(), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Created(c, h.service.ToSupplierResponse(supplier), "supplier_created")
+}
+
+// ListSuppliers lista los proveedores; inactivos=true incluye los inactivos
+// GET /api/v1/purchasing/suppliers?inactivos=true
+func (h *Handler) ListSuppliers(c *gin.Context) {
+	includeInactive, _ := strconv.ParseBool(c.DefaultQuery("inactivos", "false"))
+
+	suppliers, err := h.service.ListSuppliers(c.Request.Context(), includeInactive)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	responses := make([]SupplierResponse, len(suppliers))
+	for i := range suppliers {
+		responses[i] = h.service.ToSupplierResponse(&suppliers[i])
+	}
+
+	api.OK(c, responses)
+}
+
+// GetSupplier obtiene un proveedor
+// GET /api/v1/purchasing/suppliers/:id
+func (h *Handler) GetSupplier(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	supplier, err := h.service.GetSupplier(c.Request.Context(), uint(id))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, h.service.ToSupplierResponse(supplier))
+}
+
+// UpdateSupplier actualiza un proveedor; no se borran, se desactivan
+// PUT /api/v1/purchasing/suppliers/:id
+func (h *Handler) UpdateSupplier(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	var req UpdateSupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	supplier, err := h.service.UpdateSupplier(c.Request.Context(), uint(id), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, h.service.ToSupplierResponse(supplier), "supplier_updated")
+}
+
+// CreateOrder crea una orden de compra en borrador
+// POST /api/v1/purchasing/orders
+func (h *Handler) CreateOrder(c *gin.Context) {
+	var req CreatePurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	order, err := h.service.CreateOrder(c.Request.Context(), req, actorID(c))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Created(c, h.service.ToOrderResponse(order), "purchase_order_created")
+}
+
+// ListOrders lista las órdenes de compra con paginación
+// GET /api/v1/purchasing/orders?estado=enviada&proveedor=1&page=1&limit=10
+func (h *Handler) ListOrders(c *gin.Context) {
+	var req ListPurchaseOrdersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	orders, total, err := h.service.ListOrders(c.Request.Context(), &req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	responses := make([]PurchaseOrderResponse, len(orders))
+	for i := range orders {
+		responses[i] = h.service.ToOrderResponse(&orders[i])
+	}
+
+	api.OK(c, PurchaseOrderListResponse{
+		Orders: responses,
+		Total:  total,
+		Page:   req.Page,
+		Limit:  req.Limit,
+	})
+}
+
+// GetOrder obtiene una orden de compra con sus recepciones
+// GET /api/v1/purchasing/orders/:id
+func (h *Handler) GetOrder(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	order, err := h.service.GetOrder(c.Request.Context(), uint(id))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, h.service.ToOrderResponse(order))
+}
+
+// UpdateOrder cambia una orden de compra en borrador
+// PUT /api/v1/purchasing/orders/:id
+func (h *Handler) UpdateOrder(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	var req UpdatePurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	order, err := h.service.UpdateOrder(c.Request.Context(), uint(id), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, h.service.ToOrderResponse(order), "purchase_order_updated")
+}
+
+// SendOrder marca la orden como pedida al proveedor
+// POST /api/v1/purchasing/orders/:id/send
+func (h *Handler) SendOrder(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	order, err := h.service.SendOrder(c.Request.Context(), uint(id))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, h.service.ToOrderResponse(order), "purchase_order_sent")
+}
+
+// CancelOrder cancela lo que falta por llegar de una orden
+// POST /api/v1/purchasing/orders/:id/cancel
+func (h *Handler) CancelOrder(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	order, err := h.service.CancelOrder(c.Request.Context(), uint(id))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, h.service.ToOrderResponse(order), "purchase_order_cancelled")
+}
+
+// ReceiveGoods registra una entrega del proveedor y crea sus lotes
+// POST /api/v1/purchasing/orders/:id/receipts
+func (h *Handler) ReceiveGoods(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	var req CreateReceiptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	receipt, err := h.service.Receive(c.Request.Context(), uint(id), req, actorID(c))
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Created(c, h.service.ToReceiptResponse(receipt), "goods_received")
+}
+
+// GetReceiptLabels descarga las etiquetas de los lotes de una entrega
+// GET /api/v1/purchasing/orders/:id/receipts/:receiptId/labels?formato=pdf|zpl&copias=1
+func (h *Handler) GetReceiptLabels(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	receiptID, err := strconv.ParseUint(c.Param("receiptId"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("receiptId"))
+		return
+	}
+
+	copies, err := strconv.Atoi(c.DefaultQuery("copias", "1"))
+	if err != nil {
+		api.Error(c, domain.InvalidField("copias", "invalid_copies", "copias debe estar entre 1 y %d", catalog.MaxLabelCopies))
+		return
+	}
+
+	file, err := h.service.ReceiptLabels(c.Request.Context(), uint(id), uint(receiptID), c.DefaultQuery("formato", catalog.LabelFormatPDF), copies)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, file.Name))
+	c.Data(http.StatusOK, file.ContentType, file.Data)
+}
+
+// actorID es el usuario autenticado que hace el cambio
+func actorID(c *gin.Context) *uint {
+	principal, ok := middleware.CurrentPrincipal(c)
+	if !ok {
+		return nil
+	}
+	return &principal.UserID
+}
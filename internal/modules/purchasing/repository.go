@@ -0,0 +1,280 @@
+package purchasing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/modules/catalog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) CreateSupplier(ctx context.Context, supplier *domain.Supplier) error {
+	return r.db.WithContext(ctx).Create(supplier).Error
+}
+
+func (r *Repository) FindSupplier(ctx context.Context, id uint) (*domain.Supplier, error) {
+	var supplier domain.Supplier
+	err := r.db.WithContext(ctx).First(&supplier, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("supplier_not_found", "proveedor con id %d no encontrado", id)
+		}
+		return nil, err
+	}
+
+	return &supplier, nil
+}
+
+// FindSupplierByNIT devuelve nil sin error si el NIT está libre
+func (r *Repository) FindSupplierByNIT(ctx context.Context, nit string) (*domain.Supplier, error) {
+	var supplier domain.Supplier
+	err := r.db.WithContext(ctx).Where("nit = ?", nit).First(&supplier).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &supplier, nil
+}
+
+func (r *Repository) ListSuppliers(ctx context.Context, includeInactive bool) ([]domain.Supplier, error) {
+	var suppliers []domain.Supplier
+	query := r.db.WithContext(ctx).Order("nombre ASC")
+	if !includeInactive {
+		query = query.Where("activo")
+	}
+	err := query.Find(&suppliers).Error
+	return suppliers, err
+}
+
+func (r *Repository) UpdateSupplier(ctx context.Context, supplier *domain.Supplier) error {
+	return r.db.WithContext(ctx).Save(supplier).Error
+}
+
+// CreateOrder guarda la orden con sus líneas
+func (r *Repository) CreateOrder(ctx context.Context, order *domain.PurchaseOrder) error {
+	return r.db.WithContext(ctx).Omit("Supplier", "Lines.Product", "Receipts").Create(order).Error
+}
+
+// FindOrder trae la orden con el proveedor, las líneas y las recepciones
+func (r *Repository) FindOrder(ctx context.Context, id uint) (*domain.PurchaseOrder, error) {
+	var order domain.PurchaseOrder
+	err := r.db.WithContext(ctx).
+		Preload("Supplier").
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("id_detalle_orden ASC") }).
+		Preload("Lines.Product", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Receipts", func(db *gorm.DB) *gorm.DB { return db.Order("recibida_en ASC, id_recepcion ASC") }).
+		Preload("Receipts.Lines.Lot").
+		First(&order, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("purchase_order_not_found", "orden de compra %d no encontrada", id)
+		}
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// ListOrders lista las órdenes, de la más reciente a la más antigua, sin
+// las recepciones
+func (r *Repository) ListOrders(ctx context.Context, status string, supplierID uint, limit, offset int) ([]domain.PurchaseOrder, int64, error) {
+	query := r.db.WithContext(ctx).Model(&domain.PurchaseOrder{})
+	if status != "" {
+		query = query.Where("estado = ?", status)
+	}
+	if supplierID != 0 {
+		query = query.Where("id_proveedor = ?", supplierID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var orders []domain.PurchaseOrder
+	err := query.
+		Preload("Supplier").
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("id_detalle_orden ASC") }).
+		Preload("Lines.Product", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Order("id_orden_compra DESC").
+		Limit(limit).Offset(offset).
+		Find(&orders).Error
+	return orders, total, err
+}
+
+/*
+# UpdateDraft guarda la cabecera de una orden en borrador y, si lines no es
+# nil, reemplaza sus líneas
+* el estado se vuelve a comprobar al escribir, por si la orden se envió
+* mientras tanto
+*/
+func (r *Repository) UpdateDraft(ctx context.Context, order *domain.PurchaseOrder, lines []domain.PurchaseOrderLine) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(order).Where("estado = ?", domain.PurchaseDraft).
+			Updates(map[string]any{"fecha_esperada": order.FechaEsperada, "notas": order.Notas})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.Conflict("purchase_order_not_editable", "la orden de compra %d ya no está en borrador", order.ID)
+		}
+		if lines == nil {
+			return nil
+		}
+
+		if err := tx.Where("id_orden_compra = ?", order.ID).Delete(&domain.PurchaseOrderLine{}).Error; err != nil {
+			return err
+		}
+		for i := range lines {
+			lines[i].IDOrdenCompra = order.ID
+		}
+		if err := tx.Omit("Product").Create(&lines).Error; err != nil {
+			return err
+		}
+		order.Lines = lines
+		return nil
+	})
+}
+
+/*
+# Transition pasa la orden a to si sigue en alguno de los estados from
+* fields son las demás columnas que cambian con el estado (enviada_en,
+* cerrada_en)
+* devuelve false si la orden ya estaba en otro estado
+*/
+func (r *Repository) Transition(ctx context.Context, id uint, from []domain.PurchaseOrderStatus, to domain.PurchaseOrderStatus, fields map[string]any) (bool, error) {
+	updates := map[string]any{"estado": to}
+	for column, value := range fields {
+		updates[column] = value
+	}
+
+	result := r.db.WithContext(ctx).Model(&domain.PurchaseOrder{}).
+		Where("id_orden_compra = ? AND estado IN ?", id, from).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReceiptItem son las unidades de una línea de la orden que llegan en un lote
+type ReceiptItem struct {
+	LineID     uint
+	Quantity   int
+	LotNumber  string
+	Expiration time.Time
+	// Cost sin valor es el costo de la línea de la orden
+	Cost *float64
+}
+
+/*
+# Receive registra una entrega contra la orden orderID
+* con la orden bloqueada comprueba que pueda recibir y que ninguna línea
+* reciba más de lo que le falta
+* cada item entra al inventario como un lote (ver catalog.ReceiveLotTx), con
+* su vencimiento y su costo
+* la orden queda recibida si ya no falta nada, o parcial
+* todo va en una transacción: si un lote no se puede registrar no queda
+* nada de la entrega
+*/
+func (r *Repository) Receive(ctx context.Context, orderID uint, receipt *domain.GoodsReceipt, items []ReceiptItem) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var order domain.PurchaseOrder
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.NotFound("purchase_order_not_found", "orden de compra %d no encontrada", orderID)
+			}
+			return err
+		}
+		if !order.Estado.Receivable() {
+			return domain.Conflict("purchase_order_not_receivable", "la orden de compra %d está %s", order.ID, order.Estado)
+		}
+		if order.EnviadaEn != nil && receipt.RecibidaEn.Before(*order.EnviadaEn) {
+			return domain.InvalidField("recibida_en", "receipt_before_sent", "la entrega no puede ser anterior al envío de la orden")
+		}
+
+		var lines []domain.PurchaseOrderLine
+		if err := tx.Where("id_orden_compra = ?", orderID).Find(&lines).Error; err != nil {
+			return err
+		}
+		byID := make(map[uint]*domain.PurchaseOrderLine, len(lines))
+		for i := range lines {
+			byID[lines[i].ID] = &lines[i]
+		}
+
+		for _, item := range items {
+			line, ok := byID[item.LineID]
+			if !ok {
+				return domain.Unprocessable("purchase_line_not_found", "la línea %d no es de la orden de compra %d", item.LineID, orderID)
+			}
+			if item.Quantity > line.Pending() {
+				return domain.Unprocessable("receipt_exceeds_pending", "la línea %d tiene %d unidades pendientes", line.ID, line.Pending())
+			}
+
+			cost := line.CostoUnitario
+			if item.Cost != nil {
+				cost = *item.Cost
+			}
+			lot, err := catalog.ReceiveLotTx(tx, line.IDProducto, item.LotNumber, item.Expiration, item.Quantity, &cost)
+			if err != nil {
+				return err
+			}
+
+			line.CantidadRecibida += item.Quantity
+			receipt.Lines = append(receipt.Lines, domain.GoodsReceiptLine{
+				IDDetalleOrden: line.ID,
+				IDLote:         lot.ID,
+				Cantidad:       item.Quantity,
+				CostoUnitario:  cost,
+				Lot:            *lot,
+			})
+		}
+
+		receipt.IDOrdenCompra = orderID
+		if err := tx.Omit("Lines.Lot").Create(receipt).Error; err != nil {
+			return err
+		}
+
+		complete := true
+		for i := range lines {
+			err := tx.Model(&lines[i]).Update("cantidad_recibida", lines[i].CantidadRecibida).Error
+			if err != nil {
+				return err
+			}
+			if lines[i].Pending() > 0 {
+				complete = false
+			}
+		}
+
+		updates := map[string]any{"estado": domain.PurchasePartial}
+		if complete {
+			updates = map[string]any{"estado": domain.PurchaseReceived, "cerrada_en": receipt.RecibidaEn}
+		}
+		return tx.Model(&order).Updates(updates).Error
+	})
+}
+
+// FindReceipt trae una recepción con sus lotes
+func (r *Repository) FindReceipt(ctx context.Context, id uint) (*domain.GoodsReceipt, error) {
+	var receipt domain.GoodsReceipt
+	err := r.db.WithContext(ctx).Preload("Lines.Lot").First(&receipt, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("receipt_not_found", "recepción %d no encontrada", id)
+		}
+		return nil, err
+	}
+
+	return &receipt, nil
+}
@@ -0,0 +1,438 @@
+package purchasing
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/modules/catalog"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+)
+
+type Service struct {
+	repo           *Repository
+	catalogRepo    *catalog.Repository
+	catalogService *catalog.Service
+}
+
+func NewService(repo *Repository, catalogRepo *catalog.Repository, catalogService *catalog.Service) *Service {
+	return &Service{repo: repo, catalogRepo: catalogRepo, catalogService: catalogService}
+}
+
+func (s *Service) CreateSupplier(ctx context.Context, req CreateSupplierRequest) (_ *domain.Supplier, err error) {
+	ctx, span := tracing.Start(ctx, "purchasing.Service.CreateSupplier")
+	defer func() { tracing.End(span, err) }()
+
+	supplier := &domain.Supplier{
+		Nombre:      strings.TrimSpace(req.Nombre),
+		Contacto:    req.Contacto,
+		Email:       req.Email,
+		Telefono:    req.Telefono,
+		DiasEntrega: req.DiasEntrega,
+		Activo:      true,
+	}
+	if nit := strings.TrimSpace(req.NIT); nit != "" {
+		if err := s.checkNIT(ctx, nit, 0); err != nil {
+			return nil, err
+		}
+		supplier.NIT = &nit
+	}
+
+	if err := s.repo.CreateSupplier(ctx, supplier); err != nil {
+		return nil, err
+	}
+	return supplier, nil
+}
+
+func (s *Service) GetSupplier(ctx context.Context, id uint) (_ *domain.Supplier, err error) {
+	ctx, span := tracing.Start(ctx, "purchasing.Service.GetSupplier")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindSupplier(ctx, id)
+}
+
+// ListSuppliers lista los proveedores activos, o todos con includeInactive
+func (s *Service) ListSuppliers(ctx context.Context, includeInactive bool) (_ []domain.Supplier, err error) {
+	ctx, span := tracing.Start(ctx, "purchasing.Service.ListSuppliers")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.ListSuppliers(ctx, includeInactive)
+}
+
+// UpdateSupplier; nit vacío le quita el NIT al proveedor
+func (s *Service) UpdateSupplier(ctx context.Context, id uint, req UpdateSupplierRequest) (_ *domain.Supplier, err error) {
+	ctx, span := tracing.Start(ctx, "purchasing.Service.UpdateSupplier")
+	defer func() { tracing.End(span, err) }()
+
+	supplier, err := s.repo.FindSupplier(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := strings.TrimSpace(req.Nombre); name != "" {
+		supplier.Nombre = name
+	}
+	if req.NIT != nil {
+		nit := strings.TrimSpace(*req.NIT)
+		if nit == "" {
+			supplier.NIT = nil
+		} else {
+			if err := s.checkNIT(ctx, nit, id); err != nil {
+				return nil, err
+			}
+			supplier.NIT = &nit
+		}
+	}
+	if req.Contacto != nil {
+		supplier.Contacto = *req.Contacto
+	}
+	if req.Email != nil {
+		supplier.Email = *req.Email
+	}
+	if req.Telefono != nil {
+		supplier.Telefono = *req.Telefono
+	}
+	if req.DiasEntrega != nil {
+		supplier.DiasEntrega = req.DiasEntrega
+	}
+	if req.Activo != nil {
+		supplier.Activo = *req.Activo
+	}
+
+	if err := s.repo.UpdateSupplier(ctx, supplier); err != nil {
+		return nil, err
+	}
+	return supplier, nil
+}
+
+// checkNIT falla si otro proveedor distinto de self ya tiene el NIT
+func (s *Service) checkNIT(ctx context.Context, nit string, self uint) error {
+	existing, err := s.repo.FindSupplierByNIT(ctx, nit)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != self {
+		return domain.Conflict("supplier_nit_taken", "el NIT %s ya es del proveedor %d", nit, existing.ID)
+	}
+	return nil
+}
+
+/*
+# CreateOrder crea una orden de compra en borrador
+* el proveedor debe estar activo y los productos deben existir
+* un producto va en una sola línea
+*/
+func (s *Service) CreateOrder(ctx context.Context, req CreatePurchaseOrderRequest, actor *uint) (_ *domain.PurchaseOrder, err error) {
+	ctx, span := tracing.Start(ctx, "purchasing.Service.CreateOrder")
+	defer func() { tracing.End(span, err) }()
+
+	supplier, err := s.repo.FindSupplier(ctx, req.IDProveedor)
+	if err != nil {
+		return nil, err
+	}
+	if !supplier.Activo {
+		return nil, domain.Unprocessable("supplier_inactive", "el proveedor %d está inactivo", supplier.ID)
+	}
+
+	lines, err := s.orderLines(ctx, req.Lineas)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &domain.PurchaseOrder{
+		IDProveedor:   supplier.ID,
+		Estado:        domain.PurchaseDraft,
+		FechaEsperada: req.FechaEsperada,
+		Notas:         req.Notas,
+		IDUsuario:     actor,
+		Lines:         lines,
+	}
+	if err := s.repo.CreateOrder(ctx, order); err != nil {
+		return nil, err
+	}
+
+	return s.repo.FindOrder(ctx, order.ID)
+}
+
+// orderLines valida las líneas pedidas: sin productos repetidos y todos
+// existentes
+func (s *Service) orderLines(ctx context.Context, reqs []PurchaseOrderLineRequest) ([]domain.PurchaseOrderLine, error) {
+	ids := make([]uint, 0, len(reqs))
+	seen := make(map[uint]bool, len(reqs))
+	for _, line := range reqs {
+		if seen[line.IDProducto] {
+			return nil, domain.Unprocessable("duplicate_order_line", "el producto %d está en más de una línea", line.IDProducto)
+		}
+		seen[line.IDProducto] = true
+		ids = append(ids, line.IDProducto)
+	}
+
+	products, err := s.catalogRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[uint]bool, len(products))
+	for _, product := range products {
+		found[product.ID] = true
+	}
+
+	lines := make([]domain.PurchaseOrderLine, len(reqs))
+	for i, line := range reqs {
+		if !found[line.IDProducto] {
+			return nil, domain.NotFound("product_not_found", "producto con id %d no encontrado", line.IDProducto)
+		}
+		lines[i] = domain.PurchaseOrderLine{
+			IDProducto:    line.IDProducto,
+			Cantidad:      line.Cantidad,
+			CostoUnitario: line.CostoUnitario,
+		}
+	}
+	return lines, nil
+}
+
+func (s *Service) GetOrder(ctx context.Context, id uint) (_ *domain.PurchaseOrder, err error) {
+	ctx, span := tracing.Start(ctx, "purchasing.Service.GetOrder")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindOrder(ctx, id)
+}
+
+// ListOrders ajusta en req la página y el límite que se usaron
+func (s *Service) ListOrders(ctx context.Context, req *ListPurchaseOrdersRequest) (_ []domain.PurchaseOrder, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "purchasing.Service.ListOrders")
+	defer func() { tracing.End(span, err) }()
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 || req.Limit > 100 {
+		req.Limit = 10
+	}
+
+	offset := (req.Page - 1) * req.Limit
+	return s.repo.ListOrders(ctx, req.Estado, req.IDProveedor, req.Limit, offset)
+}
+
+// UpdateOrder cambia una orden que sigue en borrador
+func (s *Service) UpdateOrder(ctx context.Context, id uint, req UpdatePurchaseOrderRequest) (_ *domain.PurchaseOrder, err error) {
+	ctx, span := tracing.Start(ctx, "purchasing.Service.UpdateOrder")
+	defer func() { tracing.End(span, err) }()
+
+	order, err := s.repo.FindOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Estado != domain.PurchaseDraft {
+		return nil, domain.Conflict("purchase_order_not_editable", "la orden de compra %d ya no está en borrador", order.ID)
+	}
+
+	if req.FechaEsperada != nil {
+		order.FechaEsperada = req.FechaEsperada
+	}
+	if req.Notas != nil {
+		order.Notas = *req.Notas
+	}
+
+	var lines []domain.PurchaseOrderLine
+	if req.Lineas != nil {
+		if lines, err = s.orderLines(ctx, req.Lineas); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.UpdateDraft(ctx, order, lines); err != nil {
+		return nil, err
+	}
+	return s.repo.FindOrder(ctx, order.ID)
+}
+
+// SendOrder marca como pedida al proveedor una orden en borrador
+func (s *Service) SendOrder(ctx context.Context, id uint) (_ *domain.PurchaseOrder, err error) {
+	ctx, span := tracing.Start(ctx, "purchasing.Service.SendOrder")
+	defer func() { tracing.End(span, err) }()
+
+	order, err := s.repo.FindOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	from := []domain.PurchaseOrderStatus{domain.PurchaseDraft}
+	ok, err := s.repo.Transition(ctx, id, from, domain.PurchaseSent, map[string]any{"enviada_en": time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, domain.Conflict("purchase_order_not_sendable", "la orden de compra %d está %s", order.ID, order.Estado)
+	}
+
+	return s.repo.FindOrder(ctx, id)
+}
+
+// CancelOrder cierra una orden que no se ha recibido completa; lo que ya
+// llegó queda en el inventario
+func (s *Service) CancelOrder(ctx context.Context, id uint) (_ *domain.PurchaseOrder, err error) {
+	ctx, span := tracing.Start(ctx, "purchasing.Service.CancelOrder")
+	defer func() { tracing.End(span, err) }()
+
+	order, err := s.repo.FindOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	from := []domain.PurchaseOrderStatus{domain.PurchaseDraft, domain.PurchaseSent, domain.PurchasePartial}
+	ok, err := s.repo.Transition(ctx, id, from, domain.PurchaseCancelled, map[string]any{"cerrada_en": time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, domain.Conflict("purchase_order_not_cancellable", "la orden de compra %d está %s", order.ID, order.Estado)
+	}
+
+	return s.repo.FindOrder(ctx, id)
+}
+
+/*
+# Receive registra una entrega del proveedor
+* cada línea de la entrega crea (o completa) un lote del producto, así que
+* necesita número de lote y vencimiento
+* la entrega no puede ser futura ni traer lotes ya vencidos
+*/
+func (s *Service) Receive(ctx context.Context, orderID uint, req CreateReceiptRequest, actor *uint) (_ *domain.GoodsReceipt, err error) {
+	ctx, span := tracing.Start(ctx, "purchasing.Service.Receive")
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now()
+	receivedAt := now
+	if req.RecibidaEn != nil {
+		if req.RecibidaEn.After(now) {
+			return nil, domain.InvalidField("recibida_en", "receipt_in_future", "la entrega no puede ser en el futuro")
+		}
+		receivedAt = *req.RecibidaEn
+	}
+
+	items := make([]ReceiptItem, len(req.Lineas))
+	for i, line := range req.Lineas {
+		if domain.DaysUntil(line.FechaVencimiento, now) < 0 {
+			return nil, domain.InvalidField("fecha_vencimiento", "expiration_in_past", "la fecha de vencimiento no puede ser en el pasado")
+		}
+		items[i] = ReceiptItem{
+			LineID:     line.IDDetalleOrden,
+			Quantity:   line.Cantidad,
+			LotNumber:  strings.TrimSpace(line.NumeroLote),
+			Expiration: line.FechaVencimiento,
+			Cost:       line.CostoUnitario,
+		}
+	}
+
+	receipt := &domain.GoodsReceipt{
+		RecibidaEn: receivedAt,
+		Notas:      req.Notas,
+		IDUsuario:  actor,
+	}
+	if err := s.repo.Receive(ctx, orderID, receipt, items); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// ReceiptLabels genera las etiquetas de los lotes que llegaron en una entrega
+func (s *Service) ReceiptLabels(ctx context.Context, orderID, receiptID uint, format string, copies int) (_ *catalog.LabelFile, err error) {
+	ctx, span := tracing.Start(ctx, "purchasing.Service.ReceiptLabels")
+	defer func() { tracing.End(span, err) }()
+
+	receipt, err := s.repo.FindReceipt(ctx, receiptID)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.IDOrdenCompra != orderID {
+		return nil, domain.NotFound("receipt_not_found", "recepción %d no encontrada", receiptID)
+	}
+
+	lots := make([]uint, 0, len(receipt.Lines))
+	for _, line := range receipt.Lines {
+		if !slices.Contains(lots, line.IDLote) {
+			lots = append(lots, line.IDLote)
+		}
+	}
+
+	return s.catalogService.BatchLabels(ctx, catalog.LabelBatchRequest{
+		Formato: format,
+		Lotes:   lots,
+		Copias:  copies,
+	})
+}
+
+func (s *Service) ToSupplierResponse(supplier *domain.Supplier) SupplierResponse {
+	return SupplierResponse{
+		ID:          supplier.ID,
+		Nombre:      supplier.Nombre,
+		NIT:         supplier.NIT,
+		Contacto:    supplier.Contacto,
+		Email:       supplier.Email,
+		Telefono:    supplier.Telefono,
+		DiasEntrega: supplier.DiasEntrega,
+		Activo:      supplier.Activo,
+	}
+}
+
+func (s *Service) ToOrderResponse(order *domain.PurchaseOrder) PurchaseOrderResponse {
+	response := PurchaseOrderResponse{
+		ID:              order.ID,
+		IDProveedor:     order.IDProveedor,
+		NombreProveedor: order.Supplier.Nombre,
+		Estado:          string(order.Estado),
+		FechaEsperada:   order.FechaEsperada,
+		EnviadaEn:       order.EnviadaEn,
+		CerradaEn:       order.CerradaEn,
+		Notas:           order.Notas,
+		IDUsuario:       order.IDUsuario,
+		Lineas:          make([]PurchaseOrderLineResponse, len(order.Lines)),
+		Recepciones:     make([]ReceiptResponse, len(order.Receipts)),
+	}
+
+	for i, line := range order.Lines {
+		response.Lineas[i] = PurchaseOrderLineResponse{
+			ID:               line.ID,
+			IDProducto:       line.IDProducto,
+			NombreProducto:   line.Product.Nombre,
+			Cantidad:         line.Cantidad,
+			CantidadRecibida: line.CantidadRecibida,
+			Pendiente:        line.Pending(),
+			CostoUnitario:    line.CostoUnitario,
+		}
+		response.Total += float64(line.Cantidad) * line.CostoUnitario
+	}
+	response.Total = math.Round(response.Total*100) / 100
+
+	for i := range order.Receipts {
+		response.Recepciones[i] = s.ToReceiptResponse(&order.Receipts[i])
+	}
+
+	return response
+}
+
+func (s *Service) ToReceiptResponse(receipt *domain.GoodsReceipt) ReceiptResponse {
+	response := ReceiptResponse{
+		ID:         receipt.ID,
+		RecibidaEn: receipt.RecibidaEn,
+		Notas:      receipt.Notas,
+		IDUsuario:  receipt.IDUsuario,
+		Lineas:     make([]ReceiptLineResponse, len(receipt.Lines)),
+	}
+
+	for i, line := range receipt.Lines {
+		response.Lineas[i] = ReceiptLineResponse{
+			IDDetalleOrden:   line.IDDetalleOrden,
+			IDLote:           line.IDLote,
+			NumeroLote:       line.Lot.Numero,
+			FechaVencimiento: line.Lot.FechaVencimiento,
+			Cantidad:         line.Cantidad,
+			CostoUnitario:    line.CostoUnitario,
+		}
+	}
+
+	return response
+}
@@ -21,6 +21,15 @@ type TopProductResponse struct {
 	Revenue     float64 `json:"ingresos"`
 }
 
+// TopCategoryResponse; sin id_categoria son las ventas de productos sin categoría principal
+type TopCategoryResponse struct {
+	CategoryID   *uint   `json:"id_categoria,omitempty"`
+	CategoryName *string `json:"nombre_categoria,omitempty"`
+	Products     int64   `json:"productos"`
+	UnitsSold    int64   `json:"unidades_vendidas"`
+	Revenue      float64 `json:"ingresos"`
+}
+
 type InventoryStatusResponse struct {
 	ProductID   uint   `json:"id_producto"`
 	ProductName string `json:"nombre_producto"`
@@ -62,3 +71,139 @@ type ReportFilterRequest struct {
 	Limit     int       `form:"limite" binding:"omitempty,min=1,max=100"`
 }
 
+/*
+# CategoryReportRequest
+* las ventas se agrupan por la categoría principal de cada producto
+* con nivel se suben al ancestro de ese nivel (1 = raíces); las categorías
+* menos profundas quedan como están
+*/
+type CategoryReportRequest struct {
+	ReportFilterRequest
+	Level int `form:"nivel" binding:"omitempty,min=1,max=10"`
+}
+
+
+type PromotionUsageResponse struct {
+	PromotionID   uint    `json:"id_promocion"`
+	PromotionName string  `json:"nombre_promocion"`
+	CouponCode    *string `json:"codigo_cupon,omitempty"`
+	Orders        int64   `json:"total_ordenes"`
+	Customers     int64   `json:"total_clientes"`
+	TotalDiscount float64 `json:"total_descuento"`
+}
+
+/*
+# MarginPeriodRequest
+* periodo: dia, semana o mes (por defecto); semana empieza el lunes
+*/
+type MarginPeriodRequest struct {
+	SalesSummaryRequest
+	Period string `form:"periodo" binding:"omitempty,oneof=dia semana mes"`
+}
+
+/*
+# MarginResponse son las cifras de margen bruto de un grupo de ventas
+* costo es el de las unidades vendidas, tomado al registrar cada línea
+* margen_bruto y margen_porcentaje solo cuentan las líneas con costo;
+* unidades_sin_costo son las vendidas antes de llevar costos o sin costo
+* cargado, y margen_porcentaje no viene si ninguna línea tiene costo
+*/
+type MarginResponse struct {
+	UnitsSold        int64    `json:"unidades_vendidas"`
+	Revenue          float64  `json:"ingresos"`
+	Cost             float64  `json:"costo"`
+	GrossMargin      float64  `json:"margen_bruto"`
+	MarginPercent    *float64 `json:"margen_porcentaje,omitempty"`
+	UnitsWithoutCost int64    `json:"unidades_sin_costo"`
+}
+
+type ProductMarginResponse struct {
+	ProductID   uint   `json:"id_producto"`
+	ProductName string `json:"nombre_producto"`
+	MarginResponse
+}
+
+// CategoryMarginResponse; sin id_categoria son las ventas de productos sin categoría principal
+type CategoryMarginResponse struct {
+	CategoryID   *uint   `json:"id_categoria,omitempty"`
+	CategoryName *string `json:"nombre_categoria,omitempty"`
+	MarginResponse
+}
+
+// SellerMarginResponse; sin id_vendedor son las ventas sin vendedor asignado
+type SellerMarginResponse struct {
+	SellerID   *uint   `json:"id_vendedor,omitempty"`
+	SellerName *string `json:"nombre_vendedor,omitempty"`
+	Orders     int64   `json:"total_ordenes"`
+	MarginResponse
+}
+
+type PeriodMarginResponse struct {
+	Period time.Time `json:"periodo"`
+	MarginResponse
+}
+
+// InventoryValuationRequest; estado filtra el stock por vencimiento
+type InventoryValuationRequest struct {
+	Status string `form:"estado" binding:"omitempty,oneof=vencido por_vencer vigente"`
+}
+
+// ValuationResponse; valor_venta es al precio actual, sin rebajas
+type ValuationResponse struct {
+	Units            int64   `json:"unidades"`
+	CostValue        float64 `json:"valor_costo"`
+	RetailValue      float64 `json:"valor_venta"`
+	UnitsWithoutCost int64   `json:"unidades_sin_costo"`
+}
+
+type ValuationByStatusResponse struct {
+	Expired  ValuationResponse `json:"vencido"`
+	Expiring ValuationResponse `json:"por_vencer"`
+	Valid    ValuationResponse `json:"vigente"`
+}
+
+type ProductValuationResponse struct {
+	ProductID   uint   `json:"id_producto"`
+	ProductName string `json:"nombre_producto"`
+	ValuationResponse
+	ByStatus ValuationByStatusResponse `json:"por_estado"`
+}
+
+/*
+# InventoryValuationResponse es el valor del stock al costo
+* en_riesgo es lo vencido más lo por vencer
+* productos van del de mayor valor al costo al de menor
+*/
+type InventoryValuationResponse struct {
+	Date     time.Time                  `json:"fecha"`
+	Total    ValuationResponse          `json:"total"`
+	AtRisk   ValuationResponse          `json:"en_riesgo"`
+	ByStatus ValuationByStatusResponse  `json:"por_estado"`
+	Products []ProductValuationResponse `json:"productos"`
+}
+
+/*
+# SupplierReportResponse mide a un proveedor con las órdenes enviadas en el periodo
+* plazo_*_dias va del envío a la primera entrega; cumplimiento_promedio_dias,
+* del envío a la entrega que completó la orden
+* a_tiempo_porcentaje cuenta las órdenes completas con fecha esperada (o
+* con días de entrega pactados); no viene si no hay ninguna
+* unidades_vencidas son las de sus entregas que se dieron de baja por
+* vencimiento, avería o merma, más las que quedan en lotes vencidos;
+* desperdicio_porcentaje es sobre las unidades recibidas
+*/
+type SupplierReportResponse struct {
+	SupplierID        uint     `json:"id_proveedor"`
+	SupplierName      string   `json:"nombre_proveedor"`
+	Orders            int64    `json:"total_ordenes"`
+	DeliveredOrders   int64    `json:"ordenes_con_entregas"`
+	AvgLeadDays       *float64 `json:"plazo_promedio_dias,omitempty"`
+	MaxLeadDays       *float64 `json:"plazo_maximo_dias,omitempty"`
+	AvgCompletionDays *float64 `json:"cumplimiento_promedio_dias,omitempty"`
+	OnTimePercent     *float64 `json:"a_tiempo_porcentaje,omitempty"`
+	UnitsReceived     int64    `json:"unidades_recibidas"`
+	CostReceived      float64  `json:"costo_recibido"`
+	ExpiredUnits      float64  `json:"unidades_vencidas"`
+	ExpiredCost       float64  `json:"costo_vencido"`
+	WastePercent      *float64 `json:"desperdicio_porcentaje,omitempty"`
+}
@@ -1,10 +1,11 @@
 package reports
 
 import (
-	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	api "github.com/mordmora/expirapp/internal/platform/http"
 )
 
 type Handler struct {
@@ -18,150 +19,263 @@ func NewHandler(service *Service) *Handler {
 func (h *Handler) GetSalesSummary(c *gin.Context) {
 	var req SalesSummaryRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid query params",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 
-	summary, err := h.service.GetSalesSummary(req)
+	summary, err := h.service.GetSalesSummary(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "could not fetch sales summary",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": summary})
+	api.OK(c, summary)
 }
 
 func (h *Handler) GetTopProducts(c *gin.Context) {
 	var req ReportFilterRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid query params",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 	if req.Limit == 0 {
 		req.Limit = 5
 	}
 
-	products, err := h.service.GetTopProducts(req)
+	products, err := h.service.GetTopProducts(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "could not fetch top products",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": products})
+	api.OK(c, products)
+}
+
+func (h *Handler) GetTopCategories(c *gin.Context) {
+	var req CategoryReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 5
+	}
+
+	categories, err := h.service.GetTopCategories(c.Request.Context(), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, categories)
 }
 
 func (h *Handler) GetLowStock(c *gin.Context) {
 	threshold, err := strconv.Atoi(c.DefaultQuery("umbral", "10"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid threshold",
-			"message": "umbral must be a number",
-		})
+		api.Error(c, domain.InvalidField("umbral", "invalid_number", "%s debe ser un número", "umbral"))
 		return
 	}
 
-	products, err := h.service.GetLowStock(threshold)
+	products, err := h.service.GetLowStock(c.Request.Context(), threshold)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "could not fetch low stock products",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": products})
+	api.OK(c, products)
 }
 
 func (h *Handler) GetDailySales(c *gin.Context) {
 	var req SalesSummaryRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid query params",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 
-	sales, err := h.service.GetDailySales(req)
+	sales, err := h.service.GetDailySales(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "could not fetch daily sales",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": sales})
+	api.OK(c, sales)
 }
 
 func (h *Handler) GetTopCustomers(c *gin.Context) {
 	var req ReportFilterRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid query params",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 	if req.Limit == 0 {
 		req.Limit = 5
 	}
 
-	customers, err := h.service.GetTopCustomers(req)
+	customers, err := h.service.GetTopCustomers(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "could not fetch top customers",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": customers})
+	api.OK(c, customers)
 }
 
 func (h *Handler) GetPaymentMethodSummary(c *gin.Context) {
 	var req SalesSummaryRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid query params",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 
-	summary, err := h.service.GetPaymentMethodSummary(req)
+	summary, err := h.service.GetPaymentMethodSummary(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "could not fetch payment method summary",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": summary})
+	api.OK(c, summary)
 }
 
 func (h *Handler) GetPendingPayments(c *gin.Context) {
-	report, err := h.service.GetPendingPayments()
+	report, err := h.service.GetPendingPayments(c.Request.Context())
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, report)
+}
+
+func (h *Handler) GetPromotionUsage(c *gin.Context) {
+	var req SalesSummaryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	usage, err := h.service.GetPromotionUsage(c.Request.Context(), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, usage)
+}
+
+// GetProductMargins margen bruto por producto, de mayor a menor
+// GET /api/v1/reports/margins/products?fecha_inicio=...&fecha_fin=...&limite=5
+func (h *Handler) GetProductMargins(c *gin.Context) {
+	var req ReportFilterRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 5
+	}
+
+	margins, err := h.service.GetProductMargins(c.Request.Context(), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, margins)
+}
+
+// GetCategoryMargins margen bruto por categoría principal (o por su ancestro de nivel)
+// GET /api/v1/reports/margins/categories?fecha_inicio=...&fecha_fin=...&nivel=1
+func (h *Handler) GetCategoryMargins(c *gin.Context) {
+	var req CategoryReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 5
+	}
+
+	margins, err := h.service.GetCategoryMargins(c.Request.Context(), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, margins)
+}
+
+// GetSellerMargins margen bruto por vendedor
+// GET /api/v1/reports/margins/sellers?fecha_inicio=...&fecha_fin=...
+func (h *Handler) GetSellerMargins(c *gin.Context) {
+	var req ReportFilterRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 5
+	}
+
+	margins, err := h.service.GetSellerMargins(c.Request.Context(), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, margins)
+}
+
+// GetPeriodMargins margen bruto por día, semana o mes
+// GET /api/v1/reports/margins/periods?fecha_inicio=...&fecha_fin=...&periodo=mes
+func (h *Handler) GetPeriodMargins(c *gin.Context) {
+	var req MarginPeriodRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	margins, err := h.service.GetPeriodMargins(c.Request.Context(), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, margins)
+}
+
+// GetInventoryValuation valor del stock al costo, con lo que está en riesgo de vencer
+// GET /api/v1/reports/inventory/valuation?estado=por_vencer
+func (h *Handler) GetInventoryValuation(c *gin.Context) {
+	var req InventoryValuationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	valuation, err := h.service.GetInventoryValuation(c.Request.Context(), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, valuation)
+}
+
+// GetSupplierPerformance plazo de entrega y desperdicio de cada proveedor
+// GET /api/v1/reports/suppliers?fecha_inicio=...&fecha_fin=...
+func (h *Handler) GetSupplierPerformance(c *gin.Context) {
+	var req SalesSummaryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	suppliers, err := h.service.GetSupplierPerformance(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "could not fetch pending payments",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": report})
+	api.OK(c, suppliers)
 }
@@ -1,6 +1,7 @@
 package reports
 
 import (
+	"context"
 	"time"
 
 	"gorm.io/gorm"
@@ -21,19 +22,19 @@ type SalesSummary struct {
 	TotalItemsSold    int64   `json:"total_items_sold"`
 }
 
-func (r *Repository) GetSalesSummary(startDate, endDate time.Time) (*SalesSummary, error) {
+func (r *Repository) GetSalesSummary(ctx context.Context, startDate, endDate time.Time) (*SalesSummary, error) {
 	summary := SalesSummary{}
 
 	query := `
 		SELECT
 			COALESCE(COUNT(DISTINCT c.id_compra), 0) AS total_orders,
-			COALESCE(SUM(d.cantidad * d.precio_unitario), 0) AS total_revenue,
+			COALESCE(SUM(d.cantidad * d.precio_unitario - d.descuento), 0) AS total_revenue,
 			COALESCE(SUM(d.cantidad), 0) AS total_items_sold
 		FROM compra c
 		LEFT JOIN detalle_compra d ON d.id_compra = c.id_compra
 		WHERE c.fecha_compra BETWEEN ? AND ?`
 
-	if err := r.db.Raw(query, startDate, endDate).Scan(&summary).Error; err != nil {
+	if err := r.db.WithContext(ctx).Raw(query, startDate, endDate).Scan(&summary).Error; err != nil {
 		return nil, err
 	}
 
@@ -51,7 +52,7 @@ type TopProduct struct {
 	Revenue     float64 `json:"revenue"`
 }
 
-func (r *Repository) GetTopSellingProducts(startDate, endDate time.Time, limit int) ([]TopProduct, error) {
+func (r *Repository) GetTopSellingProducts(ctx context.Context, startDate, endDate time.Time, limit int) ([]TopProduct, error) {
 	if limit <= 0 {
 		limit = 5
 	}
@@ -62,7 +63,7 @@ func (r *Repository) GetTopSellingProducts(startDate, endDate time.Time, limit i
 			p.id_producto AS product_id,
 			p.nombre AS product_name,
 			COALESCE(SUM(d.cantidad), 0) AS units_sold,
-			COALESCE(SUM(d.cantidad * d.precio_unitario), 0) AS revenue
+			COALESCE(SUM(d.cantidad * d.precio_unitario - d.descuento), 0) AS revenue
 		FROM detalle_compra d
 		JOIN producto p ON p.id_producto = d.id_producto
 		JOIN compra c ON c.id_compra = d.id_compra
@@ -71,20 +72,64 @@ func (r *Repository) GetTopSellingProducts(startDate, endDate time.Time, limit i
 		ORDER BY revenue DESC
 		LIMIT ?`
 
-	if err := r.db.Raw(query, startDate, endDate, limit).Scan(&products).Error; err != nil {
+	if err := r.db.WithContext(ctx).Raw(query, startDate, endDate, limit).Scan(&products).Error; err != nil {
 		return nil, err
 	}
 
 	return products, nil
 }
 
+type TopCategory struct {
+	CategoryID   *uint   `json:"category_id"`
+	CategoryName *string `json:"category_name"`
+	Products     int64   `json:"products"`
+	UnitsSold    int64   `json:"units_sold"`
+	Revenue      float64 `json:"revenue"`
+}
+
+// GetTopSellingCategories agrupa las ventas por la categoría principal de cada
+// producto o, con level > 0, por su ancestro de ese nivel (ver categoria.ruta)
+func (r *Repository) GetTopSellingCategories(ctx context.Context, startDate, endDate time.Time, level, limit int) ([]TopCategory, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+
+	var categories []TopCategory
+	query := `
+		SELECT
+			g.id_categoria AS category_id,
+			g.nombre AS category_name,
+			COUNT(DISTINCT d.id_producto) AS products,
+			COALESCE(SUM(d.cantidad), 0) AS units_sold,
+			COALESCE(SUM(d.cantidad * d.precio_unitario - d.descuento), 0) AS revenue
+		FROM detalle_compra d
+		JOIN compra c ON c.id_compra = d.id_compra
+		LEFT JOIN producto_categoria pc ON pc.id_producto = d.id_producto AND pc.principal
+		LEFT JOIN categoria cat ON cat.id_categoria = pc.id_categoria
+		LEFT JOIN categoria g ON g.id_categoria = CASE
+			WHEN ? > 0 THEN COALESCE(NULLIF(split_part(cat.ruta, '/', ? + 1), '')::int, cat.id_categoria)
+			ELSE cat.id_categoria
+		END
+		WHERE c.fecha_compra BETWEEN ? AND ?
+		GROUP BY g.id_categoria, g.nombre
+		ORDER BY revenue DESC
+		LIMIT ?`
+
+	err := r.db.WithContext(ctx).Raw(query, level, level, startDate, endDate, limit).Scan(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
 type InventoryStatus struct {
 	ProductID   uint   `json:"product_id"`
 	ProductName string `json:"product_name"`
 	Stock       int    `json:"stock"`
 }
 
-func (r *Repository) GetLowStockProducts(threshold int) ([]InventoryStatus, error) {
+func (r *Repository) GetLowStockProducts(ctx context.Context, threshold int) ([]InventoryStatus, error) {
 	if threshold <= 0 {
 		threshold = 10
 	}
@@ -99,7 +144,7 @@ func (r *Repository) GetLowStockProducts(threshold int) ([]InventoryStatus, erro
 		WHERE stock <= ?
 		ORDER BY stock ASC`
 
-	if err := r.db.Raw(query, threshold).Scan(&products).Error; err != nil {
+	if err := r.db.WithContext(ctx).Raw(query, threshold).Scan(&products).Error; err != nil {
 		return nil, err
 	}
 
@@ -112,20 +157,20 @@ type DailySalesEntry struct {
 	Revenue     float64   `json:"revenue"`
 }
 
-func (r *Repository) GetDailySalesTrend(startDate, endDate time.Time) ([]DailySalesEntry, error) {
+func (r *Repository) GetDailySalesTrend(ctx context.Context, startDate, endDate time.Time) ([]DailySalesEntry, error) {
 	var entries []DailySalesEntry
 	query := `
 		SELECT
 			c.fecha_compra AS date,
 			COALESCE(COUNT(DISTINCT c.id_compra), 0) AS total_orders,
-			COALESCE(SUM(d.cantidad * d.precio_unitario), 0) AS revenue
+			COALESCE(SUM(d.cantidad * d.precio_unitario - d.descuento), 0) AS revenue
 		FROM compra c
 		LEFT JOIN detalle_compra d ON d.id_compra = c.id_compra
 		WHERE c.fecha_compra BETWEEN ? AND ?
 		GROUP BY c.fecha_compra
 		ORDER BY c.fecha_compra ASC`
 
-	if err := r.db.Raw(query, startDate, endDate).Scan(&entries).Error; err != nil {
+	if err := r.db.WithContext(ctx).Raw(query, startDate, endDate).Scan(&entries).Error; err != nil {
 		return nil, err
 	}
 
@@ -139,7 +184,7 @@ type CustomerRanking struct {
 	TotalSpent   float64 `json:"total_spent"`
 }
 
-func (r *Repository) GetTopCustomers(startDate, endDate time.Time, limit int) ([]CustomerRanking, error) {
+func (r *Repository) GetTopCustomers(ctx context.Context, startDate, endDate time.Time, limit int) ([]CustomerRanking, error) {
 	if limit <= 0 {
 		limit = 5
 	}
@@ -150,7 +195,7 @@ func (r *Repository) GetTopCustomers(startDate, endDate time.Time, limit int) ([
 			u.id_usuario AS customer_id,
 			u.nombre AS customer_name,
 			COALESCE(COUNT(DISTINCT c.id_compra), 0) AS orders_count,
-			COALESCE(SUM(d.cantidad * d.precio_unitario), 0) AS total_spent
+			COALESCE(SUM(d.cantidad * d.precio_unitario - d.descuento), 0) AS total_spent
 		FROM compra c
 		JOIN cliente cl ON cl.id_cliente = c.id_cliente
 		JOIN usuario u ON u.id_usuario = cl.id_cliente
@@ -160,7 +205,7 @@ func (r *Repository) GetTopCustomers(startDate, endDate time.Time, limit int) ([
 		ORDER BY total_spent DESC
 		LIMIT ?`
 
-	if err := r.db.Raw(query, startDate, endDate, limit).Scan(&rankings).Error; err != nil {
+	if err := r.db.WithContext(ctx).Raw(query, startDate, endDate, limit).Scan(&rankings).Error; err != nil {
 		return nil, err
 	}
 
@@ -174,7 +219,7 @@ type PaymentMethodSummary struct {
 	Payments    int64   `json:"payments"`
 }
 
-func (r *Repository) GetPaymentMethodSummary(startDate, endDate time.Time) ([]PaymentMethodSummary, error) {
+func (r *Repository) GetPaymentMethodSummary(ctx context.Context, startDate, endDate time.Time) ([]PaymentMethodSummary, error) {
 	var summaries []PaymentMethodSummary
 	query := `
 		SELECT
@@ -188,7 +233,7 @@ func (r *Repository) GetPaymentMethodSummary(startDate, endDate time.Time) ([]Pa
 		GROUP BY mp.id_metodo_pago, mp.nombre
 		ORDER BY total_amount DESC`
 
-	if err := r.db.Raw(query, startDate, endDate).Scan(&summaries).Error; err != nil {
+	if err := r.db.WithContext(ctx).Raw(query, startDate, endDate).Scan(&summaries).Error; err != nil {
 		return nil, err
 	}
 
@@ -204,13 +249,13 @@ type PendingPayment struct {
 	OrderDate     time.Time `json:"order_date"`
 }
 
-func (r *Repository) GetPendingPaymentsReport() ([]PendingPayment, error) {
+func (r *Repository) GetPendingPaymentsReport(ctx context.Context) ([]PendingPayment, error) {
 	var report []PendingPayment
 	query := `
 		WITH order_totals AS (
 			SELECT
 				id_compra,
-				SUM(cantidad * precio_unitario) AS total
+				SUM(cantidad * precio_unitario - descuento) AS total
 			FROM detalle_compra
 			GROUP BY id_compra
 		),
@@ -236,9 +281,369 @@ func (r *Repository) GetPendingPaymentsReport() ([]PendingPayment, error) {
 		WHERE COALESCE(ot.total, 0) > COALESCE(pt.total, 0)
 		ORDER BY c.fecha_compra DESC`
 
-	if err := r.db.Raw(query).Scan(&report).Error; err != nil {
+	if err := r.db.WithContext(ctx).Raw(query).Scan(&report).Error; err != nil {
 		return nil, err
 	}
 
 	return report, nil
 }
+
+type PromotionUsage struct {
+	PromotionID   uint    `json:"promotion_id"`
+	PromotionName string  `json:"promotion_name"`
+	CouponCode    *string `json:"coupon_code"`
+	Orders        int64   `json:"orders"`
+	Customers     int64   `json:"customers"`
+	TotalDiscount float64 `json:"total_discount"`
+}
+
+func (r *Repository) GetPromotionUsage(ctx context.Context, startDate, endDate time.Time) ([]PromotionUsage, error) {
+	var usages []PromotionUsage
+	query := `
+		SELECT
+			pr.id_promocion AS promotion_id,
+			pr.nombre AS promotion_name,
+			cu.codigo AS coupon_code,
+			COUNT(DISTINCT u.id_compra) AS orders,
+			COUNT(DISTINCT u.id_cliente) AS customers,
+			COALESCE(SUM(u.monto_descuento), 0) AS total_discount
+		FROM uso_promocion u
+		JOIN promocion pr ON pr.id_promocion = u.id_promocion
+		JOIN compra c ON c.id_compra = u.id_compra
+		LEFT JOIN cupon cu ON cu.id_cupon = u.id_cupon
+		WHERE c.fecha_compra BETWEEN ? AND ?
+		GROUP BY pr.id_promocion, pr.nombre, cu.codigo
+		ORDER BY total_discount DESC`
+
+	if err := r.db.WithContext(ctx).Raw(query, startDate, endDate).Scan(&usages).Error; err != nil {
+		return nil, err
+	}
+
+	return usages, nil
+}
+
+/*
+# marginColumns son las sumas de los reportes de margen bruto
+* el costo solo se conoce en las líneas con costo_unitario (las registradas
+* después de empezar a llevar costos); costed_revenue son los ingresos de
+* esas líneas, contra los que se calcula el margen
+*/
+const marginColumns = `
+			COALESCE(SUM(d.cantidad), 0) AS units_sold,
+			COALESCE(SUM(d.cantidad) FILTER (WHERE d.costo_unitario IS NULL), 0) AS units_without_cost,
+			COALESCE(SUM(d.cantidad * d.precio_unitario - d.descuento), 0) AS revenue,
+			COALESCE(SUM(d.cantidad * d.precio_unitario - d.descuento) FILTER (WHERE d.costo_unitario IS NOT NULL), 0) AS costed_revenue,
+			COALESCE(SUM(d.cantidad * d.costo_unitario), 0) AS cost`
+
+type MarginTotals struct {
+	UnitsSold        int64
+	UnitsWithoutCost int64
+	Revenue          float64
+	CostedRevenue    float64
+	Cost             float64
+}
+
+type ProductMargin struct {
+	ProductID   uint
+	ProductName string
+	MarginTotals
+}
+
+func (r *Repository) GetProductMargins(ctx context.Context, startDate, endDate time.Time, limit int) ([]ProductMargin, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+
+	var margins []ProductMargin
+	query := `
+		SELECT * FROM (
+			SELECT
+				p.id_producto AS product_id,
+				p.nombre AS product_name,` + marginColumns + `
+			FROM detalle_compra d
+			JOIN producto p ON p.id_producto = d.id_producto
+			JOIN compra c ON c.id_compra = d.id_compra
+			WHERE c.fecha_compra BETWEEN ? AND ?
+			GROUP BY p.id_producto, p.nombre
+		) m
+		ORDER BY costed_revenue - cost DESC
+		LIMIT ?`
+
+	if err := r.db.WithContext(ctx).Raw(query, startDate, endDate, limit).Scan(&margins).Error; err != nil {
+		return nil, err
+	}
+
+	return margins, nil
+}
+
+type CategoryMargin struct {
+	CategoryID   *uint
+	CategoryName *string
+	MarginTotals
+}
+
+// GetCategoryMargins agrupa como GetTopSellingCategories
+func (r *Repository) GetCategoryMargins(ctx context.Context, startDate, endDate time.Time, level, limit int) ([]CategoryMargin, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+
+	var margins []CategoryMargin
+	query := `
+		SELECT * FROM (
+			SELECT
+				g.id_categoria AS category_id,
+				g.nombre AS category_name,` + marginColumns + `
+			FROM detalle_compra d
+			JOIN compra c ON c.id_compra = d.id_compra
+			LEFT JOIN producto_categoria pc ON pc.id_producto = d.id_producto AND pc.principal
+			LEFT JOIN categoria cat ON cat.id_categoria = pc.id_categoria
+			LEFT JOIN categoria g ON g.id_categoria = CASE
+				WHEN ? > 0 THEN COALESCE(NULLIF(split_part(cat.ruta, '/', ? + 1), '')::int, cat.id_categoria)
+				ELSE cat.id_categoria
+			END
+			WHERE c.fecha_compra BETWEEN ? AND ?
+			GROUP BY g.id_categoria, g.nombre
+		) m
+		ORDER BY costed_revenue - cost DESC
+		LIMIT ?`
+
+	err := r.db.WithContext(ctx).Raw(query, level, level, startDate, endDate, limit).Scan(&margins).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return margins, nil
+}
+
+type SellerMargin struct {
+	SellerID   *uint
+	SellerName *string
+	Orders     int64
+	MarginTotals
+}
+
+// GetSellerMargins agrupa por el vendedor de la orden; sin id_vendedor son
+// las ventas sin vendedor asignado
+func (r *Repository) GetSellerMargins(ctx context.Context, startDate, endDate time.Time, limit int) ([]SellerMargin, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+
+	var margins []SellerMargin
+	query := `
+		SELECT * FROM (
+			SELECT
+				c.id_vendedor AS seller_id,
+				u.nombre AS seller_name,
+				COUNT(DISTINCT c.id_compra) AS orders,` + marginColumns + `
+			FROM detalle_compra d
+			JOIN compra c ON c.id_compra = d.id_compra
+			LEFT JOIN usuario u ON u.id_usuario = c.id_vendedor
+			WHERE c.fecha_compra BETWEEN ? AND ?
+			GROUP BY c.id_vendedor, u.nombre
+		) m
+		ORDER BY costed_revenue - cost DESC
+		LIMIT ?`
+
+	if err := r.db.WithContext(ctx).Raw(query, startDate, endDate, limit).Scan(&margins).Error; err != nil {
+		return nil, err
+	}
+
+	return margins, nil
+}
+
+type PeriodMargin struct {
+	Period time.Time
+	MarginTotals
+}
+
+// GetPeriodMargins agrupa por día, semana o mes (unit de date_trunc)
+func (r *Repository) GetPeriodMargins(ctx context.Context, startDate, endDate time.Time, unit string) ([]PeriodMargin, error) {
+	var margins []PeriodMargin
+	query := `
+		SELECT
+			date_trunc(?, c.fecha_compra)::date AS period,` + marginColumns + `
+		FROM detalle_compra d
+		JOIN compra c ON c.id_compra = d.id_compra
+		WHERE c.fecha_compra BETWEEN ? AND ?
+		GROUP BY 1
+		ORDER BY 1 ASC`
+
+	if err := r.db.WithContext(ctx).Raw(query, unit, startDate, endDate).Scan(&margins).Error; err != nil {
+		return nil, err
+	}
+
+	return margins, nil
+}
+
+type StockValuation struct {
+	ProductID        uint
+	ProductName      string
+	Status           string
+	Units            int64
+	UnitsWithoutCost int64
+	CostValue        float64
+	RetailValue      float64
+}
+
+/*
+# GetStockValuation valora el stock de cada producto al costo, por estado de vencimiento
+* cada lote con unidades se valora a su propio costo (FIFO por lote; las
+* ventas consumen los lotes que vencen primero) y el stock sin lote al
+* costo del producto, con el vencimiento del producto
+* un lote sin costo toma el del producto; si tampoco hay, sus unidades
+* quedan en units_without_cost
+* el estado usa los días de alerta de la categoría principal o del ancestro
+* más cercano que los defina, o defaultAlertDays
+*/
+func (r *Repository) GetStockValuation(ctx context.Context, today time.Time, defaultAlertDays int) ([]StockValuation, error) {
+	var valuations []StockValuation
+	query := `
+		WITH unidades AS (
+			SELECT l.id_producto, l.cantidad AS units, l.fecha_vencimiento AS expiration,
+				COALESCE(l.costo_unitario, p.costo) AS unit_cost
+			FROM lote l
+			JOIN producto p ON p.id_producto = l.id_producto
+			WHERE l.cantidad > 0 AND p.deleted_at IS NULL
+			UNION ALL
+			SELECT p.id_producto, p.stock - COALESCE(l.units, 0), p.fecha_vencimiento, p.costo
+			FROM producto p
+			LEFT JOIN (
+				SELECT id_producto, SUM(cantidad) AS units FROM lote WHERE cantidad > 0 GROUP BY id_producto
+			) l ON l.id_producto = p.id_producto
+			WHERE p.deleted_at IS NULL AND p.stock > COALESCE(l.units, 0)
+		),
+		alerta AS (
+			SELECT pc.id_producto, a.dias_alerta
+			FROM producto_categoria pc
+			JOIN categoria cat ON cat.id_categoria = pc.id_categoria
+			JOIN LATERAL (
+				SELECT anc.dias_alerta FROM categoria anc
+				WHERE cat.ruta LIKE anc.ruta || '%' AND anc.dias_alerta IS NOT NULL
+				ORDER BY length(anc.ruta) DESC
+				LIMIT 1
+			) a ON true
+			WHERE pc.principal
+		)
+		SELECT
+			u.id_producto AS product_id,
+			p.nombre AS product_name,
+			CASE
+				WHEN u.expiration < ?::date THEN 'vencido'
+				WHEN u.expiration <= ?::date + COALESCE(al.dias_alerta, ?) THEN 'por_vencer'
+				ELSE 'vigente'
+			END AS status,
+			SUM(u.units) AS units,
+			COALESCE(SUM(u.units) FILTER (WHERE u.unit_cost IS NULL), 0) AS units_without_cost,
+			COALESCE(SUM(u.units * u.unit_cost), 0) AS cost_value,
+			SUM(u.units * p.precio) AS retail_value
+		FROM unidades u
+		JOIN producto p ON p.id_producto = u.id_producto
+		LEFT JOIN alerta al ON al.id_producto = u.id_producto
+		GROUP BY u.id_producto, p.nombre, status
+		ORDER BY u.id_producto ASC`
+
+	day := today.Format("2006-01-02")
+	err := r.db.WithContext(ctx).Raw(query, day, day, defaultAlertDays).Scan(&valuations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return valuations, nil
+}
+
+type SupplierPerformance struct {
+	SupplierID        uint
+	SupplierName      string
+	Orders            int64
+	DeliveredOrders   int64
+	AvgLeadDays       *float64
+	MaxLeadDays       *float64
+	AvgCompletionDays *float64
+	CompletedOnTime   int64
+	CompletedWithDue  int64
+	UnitsReceived     int64
+	CostReceived      float64
+	ExpiredUnits      float64
+	ExpiredCost       float64
+}
+
+/*
+# GetSupplierPerformance mide a cada proveedor con las órdenes enviadas entre
+# startDate y endDate
+* el plazo de entrega va del envío a la primera recepción; el de
+* cumplimiento, del envío al cierre de las órdenes recibidas completas
+* una orden completa llegó a tiempo si se cerró a más tardar en la fecha
+* esperada, o en la de envío más los días de entrega del proveedor
+* el desperdicio de un lote son sus bajas (baja_lote) salvo las de conteo,
+* más lo que todavía queda en él si ya venció; un lote que recibió varias
+* entregas se reparte entre ellas según lo que aportó cada una
+*/
+func (r *Repository) GetSupplierPerformance(ctx context.Context, startDate, endDate, today time.Time) ([]SupplierPerformance, error) {
+	var performance []SupplierPerformance
+	query := `
+		WITH ordenes AS (
+			SELECT
+				oc.id_orden_compra, oc.id_proveedor, oc.estado, oc.enviada_en, oc.cerrada_en,
+				COALESCE(oc.fecha_esperada, (oc.enviada_en + make_interval(days => pr.dias_entrega))::date) AS due,
+				(SELECT MIN(r.recibida_en) FROM recepcion r WHERE r.id_orden_compra = oc.id_orden_compra) AS first_receipt
+			FROM orden_compra oc
+			JOIN proveedor pr ON pr.id_proveedor = oc.id_proveedor
+			WHERE oc.enviada_en BETWEEN ? AND ?
+		),
+		bajas AS (
+			SELECT id_lote, SUM(cantidad) AS units
+			FROM baja_lote
+			WHERE motivo <> 'conteo'
+			GROUP BY id_lote
+		),
+		perdido AS (
+			SELECT
+				l.id_lote, l.cantidad_recibida,
+				COALESCE(b.units, 0) + CASE WHEN l.fecha_vencimiento < ?::date THEN l.cantidad ELSE 0 END AS units
+			FROM lote l
+			LEFT JOIN bajas b ON b.id_lote = l.id_lote
+			WHERE l.cantidad_recibida > 0
+		),
+		recibido AS (
+			SELECT
+				r.id_orden_compra,
+				SUM(dr.cantidad) AS units,
+				SUM(dr.cantidad * dr.costo_unitario) AS cost,
+				SUM(p.units::numeric * dr.cantidad / p.cantidad_recibida) AS expired_units,
+				SUM(p.units::numeric * dr.cantidad / p.cantidad_recibida * dr.costo_unitario) AS expired_cost
+			FROM recepcion r
+			JOIN detalle_recepcion dr ON dr.id_recepcion = r.id_recepcion
+			JOIN perdido p ON p.id_lote = dr.id_lote
+			GROUP BY r.id_orden_compra
+		)
+		SELECT
+			pr.id_proveedor AS supplier_id,
+			pr.nombre AS supplier_name,
+			COUNT(o.id_orden_compra) AS orders,
+			COUNT(o.first_receipt) AS delivered_orders,
+			AVG(EXTRACT(EPOCH FROM o.first_receipt - o.enviada_en) / 86400) AS avg_lead_days,
+			MAX(EXTRACT(EPOCH FROM o.first_receipt - o.enviada_en) / 86400) AS max_lead_days,
+			AVG(EXTRACT(EPOCH FROM o.cerrada_en - o.enviada_en) / 86400)
+				FILTER (WHERE o.estado = 'recibida') AS avg_completion_days,
+			COUNT(*) FILTER (WHERE o.estado = 'recibida' AND o.cerrada_en::date <= o.due) AS completed_on_time,
+			COUNT(*) FILTER (WHERE o.estado = 'recibida' AND o.due IS NOT NULL) AS completed_with_due,
+			COALESCE(SUM(rc.units), 0) AS units_received,
+			COALESCE(SUM(rc.cost), 0) AS cost_received,
+			COALESCE(SUM(rc.expired_units), 0) AS expired_units,
+			COALESCE(SUM(rc.expired_cost), 0) AS expired_cost
+		FROM ordenes o
+		JOIN proveedor pr ON pr.id_proveedor = o.id_proveedor
+		LEFT JOIN recibido rc ON rc.id_orden_compra = o.id_orden_compra
+		GROUP BY pr.id_proveedor, pr.nombre
+		ORDER BY pr.nombre ASC`
+
+	day := today.Format("2006-01-02")
+	err := r.db.WithContext(ctx).Raw(query, startDate, endDate, day).Scan(&performance).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return performance, nil
+}
@@ -1,8 +1,15 @@
 package reports
 
 import (
+	"cmp"
+	"context"
 	"fmt"
+	"math"
+	"slices"
 	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
 )
 
 type Service struct {
@@ -15,18 +22,21 @@ func NewService(repo *Repository) *Service {
 
 func (s *Service) parseDates(start, end time.Time) (time.Time, time.Time, error) {
 	if end.Before(start) {
-		return time.Time{}, time.Time{}, fmt.Errorf("fecha_fin debe ser posterior a fecha_inicio")
+		return time.Time{}, time.Time{}, domain.InvalidField("fecha_fin", "invalid_date_range", "fecha_fin debe ser posterior a fecha_inicio")
 	}
 	return start, end, nil
 }
 
-func (s *Service) GetSalesSummary(req SalesSummaryRequest) (*SalesSummaryResponse, error) {
+func (s *Service) GetSalesSummary(ctx context.Context, req SalesSummaryRequest) (_ *SalesSummaryResponse, err error) {
+	ctx, span := tracing.Start(ctx, "reports.Service.GetSalesSummary")
+	defer func() { tracing.End(span, err) }()
+
 	start, end, err := s.parseDates(req.StartDate, req.EndDate)
 	if err != nil {
 		return nil, err
 	}
 
-	summary, err := s.repo.GetSalesSummary(start, end)
+	summary, err := s.repo.GetSalesSummary(ctx, start, end)
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo resumen de ventas: %w", err)
 	}
@@ -39,13 +49,16 @@ func (s *Service) GetSalesSummary(req SalesSummaryRequest) (*SalesSummaryRespons
 	}, nil
 }
 
-func (s *Service) GetTopProducts(req ReportFilterRequest) ([]TopProductResponse, error) {
+func (s *Service) GetTopProducts(ctx context.Context, req ReportFilterRequest) (_ []TopProductResponse, err error) {
+	ctx, span := tracing.Start(ctx, "reports.Service.GetTopProducts")
+	defer func() { tracing.End(span, err) }()
+
 	start, end, err := s.parseDates(req.StartDate, req.EndDate)
 	if err != nil {
 		return nil, err
 	}
 
-	products, err := s.repo.GetTopSellingProducts(start, end, req.Limit)
+	products, err := s.repo.GetTopSellingProducts(ctx, start, end, req.Limit)
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo productos top: %w", err)
 	}
@@ -57,8 +70,32 @@ func (s *Service) GetTopProducts(req ReportFilterRequest) ([]TopProductResponse,
 	return resp, nil
 }
 
-func (s *Service) GetLowStock(threshold int) ([]InventoryStatusResponse, error) {
-	products, err := s.repo.GetLowStockProducts(threshold)
+func (s *Service) GetTopCategories(ctx context.Context, req CategoryReportRequest) (_ []TopCategoryResponse, err error) {
+	ctx, span := tracing.Start(ctx, "reports.Service.GetTopCategories")
+	defer func() { tracing.End(span, err) }()
+
+	start, end, err := s.parseDates(req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := s.repo.GetTopSellingCategories(ctx, start, end, req.Level, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo categorías top: %w", err)
+	}
+
+	resp := make([]TopCategoryResponse, len(categories))
+	for i, c := range categories {
+		resp[i] = TopCategoryResponse(c)
+	}
+	return resp, nil
+}
+
+func (s *Service) GetLowStock(ctx context.Context, threshold int) (_ []InventoryStatusResponse, err error) {
+	ctx, span := tracing.Start(ctx, "reports.Service.GetLowStock")
+	defer func() { tracing.End(span, err) }()
+
+	products, err := s.repo.GetLowStockProducts(ctx, threshold)
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo productos con poco stock: %w", err)
 	}
@@ -70,13 +107,16 @@ func (s *Service) GetLowStock(threshold int) ([]InventoryStatusResponse, error)
 	return resp, nil
 }
 
-func (s *Service) GetDailySales(req SalesSummaryRequest) ([]DailySalesResponse, error) {
+func (s *Service) GetDailySales(ctx context.Context, req SalesSummaryRequest) (_ []DailySalesResponse, err error) {
+	ctx, span := tracing.Start(ctx, "reports.Service.GetDailySales")
+	defer func() { tracing.End(span, err) }()
+
 	start, end, err := s.parseDates(req.StartDate, req.EndDate)
 	if err != nil {
 		return nil, err
 	}
 
-	entries, err := s.repo.GetDailySalesTrend(start, end)
+	entries, err := s.repo.GetDailySalesTrend(ctx, start, end)
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo ventas diarias: %w", err)
 	}
@@ -88,13 +128,16 @@ func (s *Service) GetDailySales(req SalesSummaryRequest) ([]DailySalesResponse,
 	return resp, nil
 }
 
-func (s *Service) GetTopCustomers(req ReportFilterRequest) ([]CustomerRankingResponse, error) {
+func (s *Service) GetTopCustomers(ctx context.Context, req ReportFilterRequest) (_ []CustomerRankingResponse, err error) {
+	ctx, span := tracing.Start(ctx, "reports.Service.GetTopCustomers")
+	defer func() { tracing.End(span, err) }()
+
 	start, end, err := s.parseDates(req.StartDate, req.EndDate)
 	if err != nil {
 		return nil, err
 	}
 
-	customers, err := s.repo.GetTopCustomers(start, end, req.Limit)
+	customers, err := s.repo.GetTopCustomers(ctx, start, end, req.Limit)
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo clientes top: %w", err)
 	}
@@ -106,13 +149,16 @@ func (s *Service) GetTopCustomers(req ReportFilterRequest) ([]CustomerRankingRes
 	return resp, nil
 }
 
-func (s *Service) GetPaymentMethodSummary(req SalesSummaryRequest) ([]PaymentMethodSummaryResponse, error) {
+func (s *Service) GetPaymentMethodSummary(ctx context.Context, req SalesSummaryRequest) (_ []PaymentMethodSummaryResponse, err error) {
+	ctx, span := tracing.Start(ctx, "reports.Service.GetPaymentMethodSummary")
+	defer func() { tracing.End(span, err) }()
+
 	start, end, err := s.parseDates(req.StartDate, req.EndDate)
 	if err != nil {
 		return nil, err
 	}
 
-	summaries, err := s.repo.GetPaymentMethodSummary(start, end)
+	summaries, err := s.repo.GetPaymentMethodSummary(ctx, start, end)
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo resumen de métodos de pago: %w", err)
 	}
@@ -124,8 +170,11 @@ func (s *Service) GetPaymentMethodSummary(req SalesSummaryRequest) ([]PaymentMet
 	return resp, nil
 }
 
-func (s *Service) GetPendingPayments() ([]PendingPaymentResponse, error) {
-	pendings, err := s.repo.GetPendingPaymentsReport()
+func (s *Service) GetPendingPayments(ctx context.Context) (_ []PendingPaymentResponse, err error) {
+	ctx, span := tracing.Start(ctx, "reports.Service.GetPendingPayments")
+	defer func() { tracing.End(span, err) }()
+
+	pendings, err := s.repo.GetPendingPaymentsReport(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo pagos pendientes: %w", err)
 	}
@@ -137,3 +186,284 @@ func (s *Service) GetPendingPayments() ([]PendingPaymentResponse, error) {
 	return resp, nil
 }
 
+
+func (s *Service) GetPromotionUsage(ctx context.Context, req SalesSummaryRequest) (_ []PromotionUsageResponse, err error) {
+	ctx, span := tracing.Start(ctx, "reports.Service.GetPromotionUsage")
+	defer func() { tracing.End(span, err) }()
+
+	start, end, err := s.parseDates(req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	usages, err := s.repo.GetPromotionUsage(ctx, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo uso de promociones: %w", err)
+	}
+
+	resp := make([]PromotionUsageResponse, len(usages))
+	for i, u := range usages {
+		resp[i] = PromotionUsageResponse(u)
+	}
+	return resp, nil
+}
+
+// periodUnits traduce el periodo de la solicitud a la unidad de date_trunc
+var periodUnits = map[string]string{"dia": "day", "semana": "week", "mes": "month"}
+
+func (s *Service) GetProductMargins(ctx context.Context, req ReportFilterRequest) (_ []ProductMarginResponse, err error) {
+	ctx, span := tracing.Start(ctx, "reports.Service.GetProductMargins")
+	defer func() { tracing.End(span, err) }()
+
+	start, end, err := s.parseDates(req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	margins, err := s.repo.GetProductMargins(ctx, start, end, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo margen por producto: %w", err)
+	}
+
+	resp := make([]ProductMarginResponse, len(margins))
+	for i, m := range margins {
+		resp[i] = ProductMarginResponse{
+			ProductID:      m.ProductID,
+			ProductName:    m.ProductName,
+			MarginResponse: toMarginResponse(m.MarginTotals),
+		}
+	}
+	return resp, nil
+}
+
+func (s *Service) GetCategoryMargins(ctx context.Context, req CategoryReportRequest) (_ []CategoryMarginResponse, err error) {
+	ctx, span := tracing.Start(ctx, "reports.Service.GetCategoryMargins")
+	defer func() { tracing.End(span, err) }()
+
+	start, end, err := s.parseDates(req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	margins, err := s.repo.GetCategoryMargins(ctx, start, end, req.Level, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo margen por categoría: %w", err)
+	}
+
+	resp := make([]CategoryMarginResponse, len(margins))
+	for i, m := range margins {
+		resp[i] = CategoryMarginResponse{
+			CategoryID:     m.CategoryID,
+			CategoryName:   m.CategoryName,
+			MarginResponse: toMarginResponse(m.MarginTotals),
+		}
+	}
+	return resp, nil
+}
+
+func (s *Service) GetSellerMargins(ctx context.Context, req ReportFilterRequest) (_ []SellerMarginResponse, err error) {
+	ctx, span := tracing.Start(ctx, "reports.Service.GetSellerMargins")
+	defer func() { tracing.End(span, err) }()
+
+	start, end, err := s.parseDates(req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	margins, err := s.repo.GetSellerMargins(ctx, start, end, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo margen por vendedor: %w", err)
+	}
+
+	resp := make([]SellerMarginResponse, len(margins))
+	for i, m := range margins {
+		resp[i] = SellerMarginResponse{
+			SellerID:       m.SellerID,
+			SellerName:     m.SellerName,
+			Orders:         m.Orders,
+			MarginResponse: toMarginResponse(m.MarginTotals),
+		}
+	}
+	return resp, nil
+}
+
+func (s *Service) GetPeriodMargins(ctx context.Context, req MarginPeriodRequest) (_ []PeriodMarginResponse, err error) {
+	ctx, span := tracing.Start(ctx, "reports.Service.GetPeriodMargins")
+	defer func() { tracing.End(span, err) }()
+
+	start, end, err := s.parseDates(req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	if req.Period == "" {
+		req.Period = "mes"
+	}
+
+	margins, err := s.repo.GetPeriodMargins(ctx, start, end, periodUnits[req.Period])
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo margen por periodo: %w", err)
+	}
+
+	resp := make([]PeriodMarginResponse, len(margins))
+	for i, m := range margins {
+		resp[i] = PeriodMarginResponse{
+			Period:         m.Period,
+			MarginResponse: toMarginResponse(m.MarginTotals),
+		}
+	}
+	return resp, nil
+}
+
+func toMarginResponse(totals MarginTotals) MarginResponse {
+	margin := totals.CostedRevenue - totals.Cost
+	resp := MarginResponse{
+		UnitsSold:        totals.UnitsSold,
+		Revenue:          roundMoney(totals.Revenue),
+		Cost:             roundMoney(totals.Cost),
+		GrossMargin:      roundMoney(margin),
+		UnitsWithoutCost: totals.UnitsWithoutCost,
+	}
+	if totals.CostedRevenue > 0 {
+		percent := roundMoney(margin / totals.CostedRevenue * 100)
+		resp.MarginPercent = &percent
+	}
+	return resp
+}
+
+/*
+# GetInventoryValuation valora el stock al costo, por producto y por estado
+# de vencimiento
+* ver Repository.GetStockValuation para cómo se costea cada unidad
+*/
+func (s *Service) GetInventoryValuation(ctx context.Context, req InventoryValuationRequest) (_ *InventoryValuationResponse, err error) {
+	ctx, span := tracing.Start(ctx, "reports.Service.GetInventoryValuation")
+	defer func() { tracing.End(span, err) }()
+
+	today := time.Now()
+	rows, err := s.repo.GetStockValuation(ctx, today, domain.DefaultAlertDays)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo valoración del inventario: %w", err)
+	}
+
+	resp := &InventoryValuationResponse{Date: today, Products: []ProductValuationResponse{}}
+	byProduct := make(map[uint]int)
+	for _, row := range rows {
+		if req.Status != "" && row.Status != req.Status {
+			continue
+		}
+
+		i, ok := byProduct[row.ProductID]
+		if !ok {
+			i = len(resp.Products)
+			byProduct[row.ProductID] = i
+			resp.Products = append(resp.Products, ProductValuationResponse{
+				ProductID:   row.ProductID,
+				ProductName: row.ProductName,
+			})
+		}
+		product := &resp.Products[i]
+
+		product.ValuationResponse.add(row)
+		product.ByStatus.add(row)
+		resp.Total.add(row)
+		resp.ByStatus.add(row)
+		if row.Status != string(domain.ExpiryValid) {
+			resp.AtRisk.add(row)
+		}
+	}
+
+	slices.SortStableFunc(resp.Products, func(a, b ProductValuationResponse) int {
+		return cmp.Compare(b.CostValue, a.CostValue)
+	})
+	for i := range resp.Products {
+		resp.Products[i].ValuationResponse.round()
+		resp.Products[i].ByStatus.round()
+	}
+	resp.Total.round()
+	resp.AtRisk.round()
+	resp.ByStatus.round()
+	return resp, nil
+}
+
+func (v *ValuationResponse) add(row StockValuation) {
+	v.Units += row.Units
+	v.UnitsWithoutCost += row.UnitsWithoutCost
+	v.CostValue += row.CostValue
+	v.RetailValue += row.RetailValue
+}
+
+func (v *ValuationResponse) round() {
+	v.CostValue = roundMoney(v.CostValue)
+	v.RetailValue = roundMoney(v.RetailValue)
+}
+
+func (b *ValuationByStatusResponse) add(row StockValuation) {
+	switch domain.ExpiryStatus(row.Status) {
+	case domain.ExpiryExpired:
+		b.Expired.add(row)
+	case domain.ExpirySoon:
+		b.Expiring.add(row)
+	default:
+		b.Valid.add(row)
+	}
+}
+
+func (b *ValuationByStatusResponse) round() {
+	b.Expired.round()
+	b.Expiring.round()
+	b.Valid.round()
+}
+
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// GetSupplierPerformance plazos de entrega y desperdicio por proveedor
+func (s *Service) GetSupplierPerformance(ctx context.Context, req SalesSummaryRequest) (_ []SupplierReportResponse, err error) {
+	ctx, span := tracing.Start(ctx, "reports.Service.GetSupplierPerformance")
+	defer func() { tracing.End(span, err) }()
+
+	start, end, err := s.parseDates(req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.repo.GetSupplierPerformance(ctx, start, end, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo desempeño de proveedores: %w", err)
+	}
+
+	resp := make([]SupplierReportResponse, len(rows))
+	for i, row := range rows {
+		resp[i] = SupplierReportResponse{
+			SupplierID:        row.SupplierID,
+			SupplierName:      row.SupplierName,
+			Orders:            row.Orders,
+			DeliveredOrders:   row.DeliveredOrders,
+			AvgLeadDays:       roundPtr(row.AvgLeadDays),
+			MaxLeadDays:       roundPtr(row.MaxLeadDays),
+			AvgCompletionDays: roundPtr(row.AvgCompletionDays),
+			UnitsReceived:     row.UnitsReceived,
+			CostReceived:      roundMoney(row.CostReceived),
+			ExpiredUnits:      roundMoney(row.ExpiredUnits),
+			ExpiredCost:       roundMoney(row.ExpiredCost),
+		}
+		if row.CompletedWithDue > 0 {
+			percent := roundMoney(float64(row.CompletedOnTime) / float64(row.CompletedWithDue) * 100)
+			resp[i].OnTimePercent = &percent
+		}
+		if row.UnitsReceived > 0 {
+			percent := roundMoney(row.ExpiredUnits / float64(row.UnitsReceived) * 100)
+			resp[i].WastePercent = &percent
+		}
+	}
+	return resp, nil
+}
+
+func roundPtr(v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	rounded := roundMoney(*v)
+	return &rounded
+}
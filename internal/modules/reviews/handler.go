@@ -5,6 +5,9 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/middleware"
+	api "github.com/mordmora/expirapp/internal/platform/http"
 )
 
 type Handler struct {
@@ -19,133 +22,98 @@ func (h *Handler) CreateReview(c *gin.Context) {
 	var req CreateReviewRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid request",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 
-	review, err := h.service.Create(req)
+	if !middleware.CanActOn(c, req.ClientID, domain.RoleAdmin) {
+		api.Error(c, domain.Forbidden("insufficient_permissions", "permisos insuficientes"))
+		return
+	}
+
+	review, err := h.service.Create(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "error creating review",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
 	response := h.service.ToResponse(review)
-	c.JSON(http.StatusCreated, gin.H{
-		"data":    response,
-		"message": "review created successfully",
-	})
+	api.Created(c, response, "review_created")
 }
 
 func (h *Handler) GetReview(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid review id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
 		return
 	}
 
-	review, err := h.service.GetByID(uint(id))
+	review, err := h.service.GetByID(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "review not found",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
 	response := h.service.ToResponse(review)
-	c.JSON(http.StatusOK, gin.H{
-		"data": response,
-	})
+	api.OK(c, response)
 }
 
 func (h *Handler) UpdateReview(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid review id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	if !h.canModify(c, uint(id)) {
 		return
 	}
 
 	var req UpdateReviewRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid request",
-			"message": err.Error(),
-		})
+		api.BindError(c, err)
 		return
 	}
 
-	review, err := h.service.Update(uint(id), req)
+	review, err := h.service.Update(c.Request.Context(), uint(id), req)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "review not found" {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, gin.H{
-			"error":   "error updating review",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
 	response := h.service.ToResponse(review)
-	c.JSON(http.StatusOK, gin.H{
-		"data":    response,
-		"message": "review updated successfully",
-	})
+	api.Respond(c, http.StatusOK, response, "review_updated")
 }
 
 func (h *Handler) DeleteReview(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid review id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("id"))
+		return
+	}
+
+	if !h.canModify(c, uint(id)) {
 		return
 	}
 
-	if err := h.service.Delete(uint(id)); err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "review not found" {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, gin.H{
-			"error":   "error deleting review",
-			"message": err.Error(),
-		})
+	if err := h.service.Delete(c.Request.Context(), uint(id)); err != nil {
+		api.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "review deleted successfully",
-	})
+	api.Respond(c, http.StatusOK, nil, "review_deleted")
 }
 
 func (h *Handler) ListReviews(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
-	reviews, total, err := h.service.List(page, limit)
+	reviews, total, err := h.service.List(c.Request.Context(), page, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "error listing reviews",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
@@ -161,31 +129,23 @@ func (h *Handler) ListReviews(c *gin.Context) {
 		Limit:    limit,
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": response,
-	})
+	api.OK(c, response)
 }
 
 func (h *Handler) ListReviewsByProduct(c *gin.Context) {
 	productIDParam := c.Param("productId")
 	productID, err := strconv.ParseUint(productIDParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid product id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("productId"))
 		return
 	}
 
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
-	reviews, total, err := h.service.ListByProduct(uint(productID), page, limit)
+	reviews, total, err := h.service.ListByProduct(c.Request.Context(), uint(productID), page, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "error listing reviews",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
@@ -201,32 +161,37 @@ func (h *Handler) ListReviewsByProduct(c *gin.Context) {
 		Limit:    limit,
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": response,
-	})
+	api.OK(c, response)
 }
 
 func (h *Handler) GetProductRatingSummary(c *gin.Context) {
 	productIDParam := c.Param("productId")
 	productID, err := strconv.ParseUint(productIDParam, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid product id",
-			"message": "id must be a valid number",
-		})
+		api.Error(c, domain.InvalidID("productId"))
 		return
 	}
 
-	summary, err := h.service.GetProductRatingSummary(uint(productID))
+	summary, err := h.service.GetProductRatingSummary(c.Request.Context(), uint(productID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "error getting rating summary",
-			"message": err.Error(),
-		})
+		api.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": summary,
-	})
+	api.OK(c, summary)
+}
+
+// canModify comprueba que el principal sea el autor de la reseña o un
+// administrador; si no, responde el error y devuelve false
+func (h *Handler) canModify(c *gin.Context, id uint) bool {
+	review, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		api.Error(c, err)
+		return false
+	}
+	if !middleware.CanActOn(c, review.ClientID, domain.RoleAdmin) {
+		api.Error(c, domain.Forbidden("insufficient_permissions", "permisos insuficientes"))
+		return false
+	}
+	return true
 }
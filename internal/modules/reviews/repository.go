@@ -1,6 +1,7 @@
 package reviews
 
 import (
+	"context"
 	"errors"
 
 	"github.com/mordmora/expirapp/internal/domain"
@@ -15,16 +16,16 @@ func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) Create(review *domain.Review) error {
-	return r.db.Create(review).Error
+func (r *Repository) Create(ctx context.Context, review *domain.Review) error {
+	return r.db.WithContext(ctx).Create(review).Error
 }
 
-func (r *Repository) FindByID(id uint) (*domain.Review, error) {
+func (r *Repository) FindByID(ctx context.Context, id uint) (*domain.Review, error) {
 	var review domain.Review
-	err := r.db.Preload("Product").Preload("Client").First(&review, id).Error
+	err := r.db.WithContext(ctx).Preload("Product").Preload("Client").First(&review, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("review not found")
+			return nil, domain.NotFound("review_not_found", "reseña no encontrada")
 		}
 		return nil, err
 	}
@@ -32,48 +33,48 @@ func (r *Repository) FindByID(id uint) (*domain.Review, error) {
 	return &review, nil
 }
 
-func (r *Repository) Update(review *domain.Review) error {
-	return r.db.Save(review).Error
+func (r *Repository) Update(ctx context.Context, review *domain.Review) error {
+	return r.db.WithContext(ctx).Save(review).Error
 }
 
-func (r *Repository) Delete(id uint) error {
-	return r.db.Delete(&domain.Review{}, id).Error
+func (r *Repository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&domain.Review{}, id).Error
 }
 
-func (r *Repository) ExistsByClientAndProduct(clientID, productID uint) (bool, error) {
+func (r *Repository) ExistsByClientAndProduct(ctx context.Context, clientID, productID uint) (bool, error) {
 	var count int64
-	err := r.db.Model(&domain.Review{}).
+	err := r.db.WithContext(ctx).Model(&domain.Review{}).
 		Where("id_cliente = ? AND id_producto = ?", clientID, productID).
 		Count(&count).Error
 	return count > 0, err
 }
 
-func (r *Repository) List(limit, offset int) ([]domain.Review, int64, error) {
+func (r *Repository) List(ctx context.Context, limit, offset int) ([]domain.Review, int64, error) {
 	var reviews []domain.Review
 	var total int64
 
-	if err := r.db.Model(&domain.Review{}).Count(&total).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&domain.Review{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	err := r.db.Preload("Product").Preload("Client").
+	err := r.db.WithContext(ctx).Preload("Product").Preload("Client").
 		Limit(limit).Offset(offset).
 		Order("created_at DESC").
 		Find(&reviews).Error
 	return reviews, total, err
 }
 
-func (r *Repository) ListByProduct(productID uint, limit, offset int) ([]domain.Review, int64, error) {
+func (r *Repository) ListByProduct(ctx context.Context, productID uint, limit, offset int) ([]domain.Review, int64, error) {
 	var reviews []domain.Review
 	var total int64
 
-	if err := r.db.Model(&domain.Review{}).
+	if err := r.db.WithContext(ctx).Model(&domain.Review{}).
 		Where("id_producto = ?", productID).
 		Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	err := r.db.Preload("Client").
+	err := r.db.WithContext(ctx).Preload("Client").
 		Where("id_producto = ?", productID).
 		Limit(limit).Offset(offset).
 		Order("created_at DESC").
@@ -87,9 +88,9 @@ type RatingSummary struct {
 	Count   int64
 }
 
-func (r *Repository) GetProductRatingSummary(productID uint) (*RatingSummary, error) {
+func (r *Repository) GetProductRatingSummary(ctx context.Context, productID uint) (*RatingSummary, error) {
 	var summary RatingSummary
-	err := r.db.Model(&domain.Review{}).
+	err := r.db.WithContext(ctx).Model(&domain.Review{}).
 		Where("id_producto = ?", productID).
 		Select("COALESCE(AVG(calificacion), 0) AS average, COUNT(id_resena) AS count").
 		Scan(&summary).Error
@@ -1,10 +1,11 @@
 package reviews
 
 import (
-	"errors"
+	"context"
 	"fmt"
 
 	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
 )
 
 type Service struct {
@@ -15,13 +16,16 @@ func NewService(repo *Repository) *Service {
 	return &Service{repo: repo}
 }
 
-func (s *Service) Create(req CreateReviewRequest) (*domain.Review, error) {
-	exists, err := s.repo.ExistsByClientAndProduct(req.ClientID, req.ProductID)
+func (s *Service) Create(ctx context.Context, req CreateReviewRequest) (_ *domain.Review, err error) {
+	ctx, span := tracing.Start(ctx, "reviews.Service.Create")
+	defer func() { tracing.End(span, err) }()
+
+	exists, err := s.repo.ExistsByClientAndProduct(ctx, req.ClientID, req.ProductID)
 	if err != nil {
 		return nil, fmt.Errorf("error checking existing review: %w", err)
 	}
 	if exists {
-		return nil, errors.New("el cliente ya registró una reseña para este producto")
+		return nil, domain.Conflict("review_exists", "el cliente ya registró una reseña para este producto")
 	}
 
 	review := &domain.Review{
@@ -31,19 +35,25 @@ func (s *Service) Create(req CreateReviewRequest) (*domain.Review, error) {
 		Comment:   req.Comment,
 	}
 
-	if err := s.repo.Create(review); err != nil {
+	if err := s.repo.Create(ctx, review); err != nil {
 		return nil, fmt.Errorf("error creating review: %w", err)
 	}
 
 	return review, nil
 }
 
-func (s *Service) GetByID(id uint) (*domain.Review, error) {
-	return s.repo.FindByID(id)
+func (s *Service) GetByID(ctx context.Context, id uint) (_ *domain.Review, err error) {
+	ctx, span := tracing.Start(ctx, "reviews.Service.GetByID")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindByID(ctx, id)
 }
 
-func (s *Service) Update(id uint, req UpdateReviewRequest) (*domain.Review, error) {
-	review, err := s.repo.FindByID(id)
+func (s *Service) Update(ctx context.Context, id uint, req UpdateReviewRequest) (_ *domain.Review, err error) {
+	ctx, span := tracing.Start(ctx, "reviews.Service.Update")
+	defer func() { tracing.End(span, err) }()
+
+	review, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -56,35 +66,29 @@ func (s *Service) Update(id uint, req UpdateReviewRequest) (*domain.Review, erro
 		review.Comment = req.Comment
 	}
 
-	if err := s.repo.Update(review); err != nil {
+	if err := s.repo.Update(ctx, review); err != nil {
 		return nil, fmt.Errorf("error updating review: %w", err)
 	}
 
 	return review, nil
 }
 
-func (s *Service) Delete(id uint) error {
-	_, err := s.repo.FindByID(id)
+func (s *Service) Delete(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "reviews.Service.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.repo.FindByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	return s.repo.Delete(id)
+	return s.repo.Delete(ctx, id)
 }
 
-func (s *Service) List(page, limit int) ([]domain.Review, int64, error) {
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
-	}
-
-	offset := (page - 1) * limit
-	return s.repo.List(limit, offset)
-}
+func (s *Service) List(ctx context.Context, page, limit int) (_ []domain.Review, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "reviews.Service.List")
+	defer func() { tracing.End(span, err) }()
 
-func (s *Service) ListByProduct(productID uint, page, limit int) ([]domain.Review, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -93,107 +97,13 @@ func (s *Service) ListByProduct(productID uint, page, limit int) ([]domain.Revie
 	}
 
 	offset := (page - 1) * limit
-	return s.repo.ListByProduct(productID, limit, offset)
-}
-
-func (s *Service) GetProductRatingSummary(productID uint) (*ProductRatingSummary, error) {
-	summary, err := s.repo.GetProductRatingSummary(productID)
-	if err != nil {
-		return nil, fmt.Errorf("error obteniendo resumen de calificaciones: %w", err)
-	}
-
-	return &ProductRatingSummary{
-		ProductID:     productID,
-		AverageRating: summary.Average,
-		ReviewsCount:  summary.Count,
-	}, nil
-}
-
-func (s *Service) ToResponse(review *domain.Review) ReviewResponse {
-	return ReviewResponse{
-		ID:        review.ID,
-		ProductID: review.ProductID,
-		ClientID:  review.ClientID,
-		Rating:    review.Rating,
-		Comment:   review.Comment,
-		CreatedAt: review.CreatedAt,
-		UpdatedAt: review.UpdatedAt,
-	}
+	return s.repo.List(ctx, limit, offset)
 }
 
-import (
-	"errors"
-	"fmt"
-
-	"github.com/mordmora/expirapp/internal/domain"
-)
+func (s *Service) ListByProduct(ctx context.Context, productID uint, page, limit int) (_ []domain.Review, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "reviews.Service.ListByProduct")
+	defer func() { tracing.End(span, err) }()
 
-type Service struct {
-	repo *Repository
-}
-
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
-}
-
-func (s *Service) Create(req CreateReviewRequest) (*domain.Review, error) {
-	exists, err := s.repo.ExistsByClientAndProduct(req.ClientID, req.ProductID)
-	if err != nil {
-		return nil, fmt.Errorf("error checking existing review: %w", err)
-	}
-	if exists {
-		return nil, errors.New("el cliente ya registró una reseña para este producto")
-	}
-
-	review := &domain.Review{
-		ProductID: req.ProductID,
-		ClientID:  req.ClientID,
-		Rating:    req.Rating,
-		Comment:   req.Comment,
-	}
-
-	if err := s.repo.Create(review); err != nil {
-		return nil, fmt.Errorf("error creating review: %w", err)
-	}
-
-	return review, nil
-}
-
-func (s *Service) GetByID(id uint) (*domain.Review, error) {
-	return s.repo.FindByID(id)
-}
-
-func (s *Service) Update(id uint, req UpdateReviewRequest) (*domain.Review, error) {
-	review, err := s.repo.FindByID(id)
-	if err != nil {
-		return nil, err
-	}
-
-	if req.Rating > 0 {
-		review.Rating = req.Rating
-	}
-
-	if req.Comment != "" {
-		review.Comment = req.Comment
-	}
-
-	if err := s.repo.Update(review); err != nil {
-		return nil, fmt.Errorf("error updating review: %w", err)
-	}
-
-	return review, nil
-}
-
-func (s *Service) Delete(id uint) error {
-	_, err := s.repo.FindByID(id)
-	if err != nil {
-		return err
-	}
-
-	return s.repo.Delete(id)
-}
-
-func (s *Service) List(page, limit int) ([]domain.Review, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -202,31 +112,22 @@ func (s *Service) List(page, limit int) ([]domain.Review, int64, error) {
 	}
 
 	offset := (page - 1) * limit
-	return s.repo.List(limit, offset)
+	return s.repo.ListByProduct(ctx, productID, limit, offset)
 }
 
-func (s *Service) ListByProduct(productID uint, page, limit int) ([]domain.Review, int64, error) {
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
-	}
+func (s *Service) GetProductRatingSummary(ctx context.Context, productID uint) (_ *ProductRatingSummary, err error) {
+	ctx, span := tracing.Start(ctx, "reviews.Service.GetProductRatingSummary")
+	defer func() { tracing.End(span, err) }()
 
-	offset := (page - 1) * limit
-	return s.repo.ListByProduct(productID, limit, offset)
-}
-
-func (s *Service) GetProductRatingSummary(productID uint) (*ProductRatingSummary, error) {
-	summary, err := s.repo.GetProductRatingSummary(productID)
+	summary, err := s.repo.GetProductRatingSummary(ctx, productID)
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo resumen de calificaciones: %w", err)
 	}
 
 	return &ProductRatingSummary{
-		ProductID:      productID,
-		AverageRating:  summary.Average,
-		ReviewsCount:   summary.Count,
+		ProductID:     productID,
+		AverageRating: summary.Average,
+		ReviewsCount:  summary.Count,
 	}, nil
 }
 
@@ -241,4 +142,3 @@ func (s *Service) ToResponse(review *domain.Review) ReviewResponse {
 		UpdatedAt: review.UpdatedAt,
 	}
 }
-
@@ -0,0 +1,184 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+	"gorm.io/gorm"
+)
+
+/*
+# AccountConfig controla los flujos de recuperación y verificación
+* BaseURL: URL del frontend donde viven /reset-password y /verify-email
+* ResetTokenTTL / VerifyTokenTTL: vigencia de cada tipo de enlace
+* RequireVerifiedEmail: bloquea el login hasta verificar el correo
+* Issuer: nombre que muestra la app autenticadora
+* TwoFactorRequiredRoles: roles que no pueden iniciar sesión sin 2FA
+* Lockout: límites de intentos fallidos de login
+*/
+type AccountConfig struct {
+	BaseURL              string
+	ResetTokenTTL        time.Duration
+	VerifyTokenTTL       time.Duration
+	RequireVerifiedEmail bool
+
+	Issuer                 string
+	TwoFactorRequiredRoles []string
+
+	Lockout LockoutPolicy
+}
+
+func newOneTimeToken() (plain, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	plain = base64.RawURLEncoding.EncodeToString(b)
+	return plain, hashToken(plain), nil
+}
+
+func hashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueToken invalida los tokens previos del mismo tipo y guarda uno nuevo.
+// Devuelve el valor en claro para armar el enlace.
+func (s *Service) issueToken(ctx context.Context, userID uint, tipo domain.UserTokenType, ttl time.Duration) (string, error) {
+	now := time.Now()
+	if err := s.repo.InvalidateTokens(ctx, userID, tipo, now); err != nil {
+		return "", fmt.Errorf("error invalidating tokens: %w", err)
+	}
+
+	plain, hash, err := newOneTimeToken()
+	if err != nil {
+		return "", fmt.Errorf("error generating token: %w", err)
+	}
+
+	token := &domain.UserToken{
+		IDUsuario: userID,
+		Tipo:      tipo,
+		Hash:      hash,
+		ExpiraEn:  now.Add(ttl),
+	}
+	if err := s.repo.CreateToken(ctx, token); err != nil {
+		return "", fmt.Errorf("error saving token: %w", err)
+	}
+
+	return plain, nil
+}
+
+// RequestPasswordReset envía el enlace de recuperación. Si el correo no existe
+// no hace nada y no lo informa, para no revelar qué cuentas existen.
+func (s *Service) RequestPasswordReset(ctx context.Context, req ForgotPasswordRequest) (err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.RequestPasswordReset")
+	defer func() { tracing.End(span, err) }()
+
+	usr, err := s.repo.FindByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	plain, err := s.issueToken(ctx, usr.ID, domain.UserTokenPasswordReset, s.account.ResetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hola %s,\n\nPara restablecer tu contraseña abre este enlace:\n%s/reset-password?token=%s\n\nEl enlace vence en %s. Si no lo solicitaste, ignora este correo.\n",
+		usr.Name, s.account.BaseURL, plain, s.account.ResetTokenTTL)
+
+	// un fallo de envío solo se registra: responder distinto revelaría que
+	// la cuenta existe
+	if err := s.mailer.Send(usr.Email, "Restablecer contraseña", body); err != nil {
+		slog.ErrorContext(ctx, "could not send password reset email", "user_id", usr.ID, "error", err)
+	}
+	return nil
+}
+
+// ResetPassword canjea el token de recuperación y guarda la nueva contraseña
+func (s *Service) ResetPassword(ctx context.Context, req ResetPasswordRequest) (err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.ResetPassword")
+	defer func() { tracing.End(span, err) }()
+
+	hashedPass, err := s.hashPassword(req.NewPass)
+	if err != nil {
+		return fmt.Errorf("error to hash password: %w", err)
+	}
+
+	var resetUserID uint
+	err = s.repo.RedeemToken(ctx, hashToken(req.Token), domain.UserTokenPasswordReset, time.Now(), func(tx *gorm.DB, userID uint) error {
+		resetUserID = userID
+		return tx.Model(&domain.User{}).Where("id_usuario = ?", userID).Update("contrasena", hashedPass).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logEvent(ctx, domain.SecurityEventPasswordReset, &resetUserID, "", "", "restablecida con enlace de recuperación")
+	return nil
+}
+
+// sendVerification envía el enlace para confirmar el correo del usuario
+func (s *Service) sendVerification(ctx context.Context, usr *domain.User) error {
+	plain, err := s.issueToken(ctx, usr.ID, domain.UserTokenEmailVerification, s.account.VerifyTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hola %s,\n\nConfirma tu correo abriendo este enlace:\n%s/verify-email?token=%s\n\nEl enlace vence en %s.\n",
+		usr.Name, s.account.BaseURL, plain, s.account.VerifyTokenTTL)
+
+	return s.mailer.Send(usr.Email, "Confirma tu correo", body)
+}
+
+// ResendVerification reenvía el enlace de verificación. Igual que la
+// recuperación, no revela si el correo existe.
+func (s *Service) ResendVerification(ctx context.Context, req ForgotPasswordRequest) (err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.ResendVerification")
+	defer func() { tracing.End(span, err) }()
+
+	usr, err := s.repo.FindByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if usr.EmailVerified() {
+		return nil
+	}
+
+	return s.sendVerification(ctx, usr)
+}
+
+// VerifyEmail canjea el token de verificación y marca el correo como confirmado
+func (s *Service) VerifyEmail(ctx context.Context, req VerifyEmailRequest) (err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.VerifyEmail")
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now()
+	return s.repo.RedeemToken(ctx, hashToken(req.Token), domain.UserTokenEmailVerification, now, func(tx *gorm.DB, userID uint) error {
+		return tx.Model(&domain.User{}).Where("id_usuario = ?", userID).Update("correo_verificado_en", now).Error
+	})
+}
+
+// notifyVerification envía la verificación sin fallar la operación que la
+// originó; el usuario siempre puede pedir que se reenvíe.
+func (s *Service) notifyVerification(ctx context.Context, usr *domain.User) {
+	if err := s.sendVerification(ctx, usr); err != nil {
+		slog.ErrorContext(ctx, "could not send verification email", "user_id", usr.ID, "error", err)
+	}
+}
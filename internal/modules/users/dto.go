@@ -27,13 +27,129 @@ type ChangePasswordRequest struct {
 	NewPass     string `json:"contrasena_nueva" binding:"required,min=6"`
 }
 
+/*
+# LoginRequest; body para POST /auth/login
+*/
+type LoginRequest struct {
+	Email    string `json:"correo" binding:"required,email"`
+	Password string `json:"contrasena" binding:"required"`
+}
+
+/*
+# ForgotPasswordRequest; body para POST /auth/password/forgot
+# y POST /auth/email/resend
+*/
+type ForgotPasswordRequest struct {
+	Email string `json:"correo" binding:"required,email"`
+}
+
+/*
+# ResetPasswordRequest; body para POST /auth/password/reset
+*/
+type ResetPasswordRequest struct {
+	Token   string `json:"token" binding:"required"`
+	NewPass string `json:"contrasena_nueva" binding:"required,min=6"`
+}
+
+/*
+# VerifyEmailRequest; body para POST /auth/email/verify
+*/
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+/*
+# TwoFactorLoginRequest; body para POST /auth/login/2fa
+* Code acepta un código TOTP o un código de recuperación
+*/
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"token_2fa" binding:"required"`
+	Code           string `json:"codigo" binding:"required"`
+}
+
+/*
+# TwoFactorCodeRequest; body para confirmar, regenerar códigos y desactivar 2FA
+*/
+type TwoFactorCodeRequest struct {
+	Code string `json:"codigo"`
+}
+
+/*
+# LoginResponse
+* con 2FA activo trae ChallengeToken en vez de Token
+* si el rol exige 2FA y no está enrolado, Token solo sirve para enrolar
+*/
+type LoginResponse struct {
+	Token              string       `json:"token,omitempty"`
+	ChallengeToken     string       `json:"token_2fa,omitempty"`
+	RequiresTwoFactor  bool         `json:"requiere_2fa,omitempty"`
+	RequiresEnrollment bool         `json:"requiere_enrolamiento_2fa,omitempty"`
+	ExpiresAt          time.Time    `json:"expira_en"`
+	User               UserResponse `json:"usuario"`
+}
+
+/*
+# SSOCallbackRequest; body para POST /auth/sso/callback
+* Code y State son los que el IdP dejó en la URL de retorno del frontend
+*/
+type SSOCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+type SSOStartResponse struct {
+	AuthorizationURL string `json:"url_autorizacion"`
+	State            string `json:"state"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret          string `json:"secreto"`
+	ProvisioningURI string `json:"uri_aprovisionamiento"`
+}
+
+type RecoveryCodesResponse struct {
+	Codes []string `json:"codigos_recuperacion"`
+}
+
 type UserResponse struct {
 	ID        uint      `json:"id_usuario"`
 	Name      string    `json:"nombre"`
 	Email     string    `json:"correo"`
+	Roles     []string  `json:"roles"`
+	Verified  bool      `json:"correo_verificado"`
+	TwoFactor bool      `json:"doble_factor"`
 	CreatedAt time.Time `json:"fecha_registro"`
 }
 
+/*
+# SecurityEventFilter; query de GET /security/events
+* desde / hasta en formato RFC 3339
+*/
+type SecurityEventFilter struct {
+	Type   string     `form:"tipo"`
+	UserID *uint      `form:"id_usuario"`
+	IP     string     `form:"ip"`
+	From   *time.Time `form:"desde"`
+	To     *time.Time `form:"hasta"`
+}
+
+type SecurityEventResponse struct {
+	ID        uint      `json:"id_evento"`
+	Type      string    `json:"tipo"`
+	UserID    *uint     `json:"id_usuario,omitempty"`
+	Email     *string   `json:"correo,omitempty"`
+	IP        *string   `json:"ip,omitempty"`
+	Detail    string    `json:"detalle,omitempty"`
+	CreatedAt time.Time `json:"fecha"`
+}
+
+type SecurityEventListResponse struct {
+	Events []SecurityEventResponse `json:"eventos"`
+	Total  int64                   `json:"total"`
+	Page   int                     `json:"pagina"`
+	Limit  int                     `json:"limite"`
+}
+
 type UserListResponse struct {
 	Users []UserResponse `json:"usuarios"`
 	Total int64          `json:"total"`
@@ -1 +1,451 @@
 package users
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/middleware"
+	api "github.com/mordmora/expirapp/internal/platform/http"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Register crea una cuenta de usuario
+// POST /api/v1/users
+func (h *Handler) Register(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	user, err := h.service.Create(c.Request.Context(), req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Created(c, h.service.ToResponse(user), "user_created")
+}
+
+// Login valida correo y contraseña y devuelve un token de acceso
+// POST /api/v1/auth/login
+func (h *Handler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	resp, err := h.service.Login(c.Request.Context(), req, c.ClientIP())
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, resp)
+}
+
+// CompleteTwoFactorLogin completa el login con el segundo factor
+// POST /api/v1/auth/login/2fa
+func (h *Handler) CompleteTwoFactorLogin(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	resp, err := h.service.CompleteTwoFactorLogin(c.Request.Context(), req, c.ClientIP())
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, resp)
+}
+
+// StartSSOLogin devuelve la URL del IdP corporativo a la que se redirige
+// GET /api/v1/auth/sso/login
+func (h *Handler) StartSSOLogin(c *gin.Context) {
+	resp, err := h.service.StartSSOLogin(c.Request.Context())
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, resp)
+}
+
+// CompleteSSOLogin canjea el code y el state que devolvió el IdP
+// POST /api/v1/auth/sso/callback
+func (h *Handler) CompleteSSOLogin(c *gin.Context) {
+	var req SSOCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	resp, err := h.service.CompleteSSOLogin(c.Request.Context(), req, c.ClientIP())
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, resp)
+}
+
+// EnrollTOTP inicia el enrolamiento y devuelve el secreto y la URI del QR
+// POST /api/v1/users/:id/2fa/enroll
+func (h *Handler) EnrollTOTP(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.EnrollTOTP(c.Request.Context(), id)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, resp)
+}
+
+// ConfirmTOTP activa 2FA con un primer código y entrega los códigos de recuperación
+// POST /api/v1/users/:id/2fa/confirm
+func (h *Handler) ConfirmTOTP(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		api.Error(c, domain.Required("codigo"))
+		return
+	}
+
+	resp, err := h.service.ConfirmTOTP(c.Request.Context(), id, req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, resp, "two_factor_enabled")
+}
+
+// RegenerateRecoveryCodes reemplaza los códigos de recuperación
+// POST /api/v1/users/:id/2fa/recovery-codes
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		api.Error(c, domain.Required("codigo"))
+		return
+	}
+
+	resp, err := h.service.RegenerateRecoveryCodes(c.Request.Context(), id, req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, resp)
+}
+
+// DisableTOTP desactiva 2FA; el propio usuario debe enviar un código,
+// un administrador puede hacerlo sin él
+// DELETE /api/v1/users/:id/2fa
+func (h *Handler) DisableTOTP(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.BindError(c, err)
+			return
+		}
+	}
+
+	principal, _ := middleware.CurrentPrincipal(c)
+	skipCode := principal != nil && principal.UserID != id && principal.HasRole(domain.RoleAdmin)
+
+	if err := h.service.DisableTOTP(c.Request.Context(), id, req.Code, skipCode); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "two_factor_disabled")
+}
+
+// ForgotPassword envía un enlace de recuperación si el correo existe
+// POST /api/v1/auth/password/forgot
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	if err := h.service.RequestPasswordReset(c.Request.Context(), req); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "password_reset_requested")
+}
+
+// ResetPassword fija una nueva contraseña con el token recibido por correo
+// POST /api/v1/auth/password/reset
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	if err := h.service.ResetPassword(c.Request.Context(), req); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "password_reset")
+}
+
+// VerifyEmail confirma el correo con el token recibido
+// POST /api/v1/auth/email/verify
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	if err := h.service.VerifyEmail(c.Request.Context(), req); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "email_verified")
+}
+
+// ResendVerification reenvía el enlace de verificación
+// POST /api/v1/auth/email/resend
+func (h *Handler) ResendVerification(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	if err := h.service.ResendVerification(c.Request.Context(), req); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "verification_resent")
+}
+
+// GetMe obtiene el usuario dueño del token
+// GET /api/v1/users/me
+func (h *Handler) GetMe(c *gin.Context) {
+	principal, ok := middleware.CurrentPrincipal(c)
+	if !ok {
+		api.Error(c, domain.Unauthorized("missing_credentials", "falta el token de acceso"))
+		return
+	}
+
+	h.respondUser(c, principal.UserID)
+}
+
+// GetUser obtiene un usuario por ID
+// GET /api/v1/users/:id
+func (h *Handler) GetUser(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	h.respondUser(c, id)
+}
+
+// UpdateUser actualiza nombre y correo
+// PUT /api/v1/users/:id
+func (h *Handler) UpdateUser(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	user, err := h.service.Update(c.Request.Context(), id, req)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, h.service.ToResponse(user), "user_updated")
+}
+
+// ChangePassword cambia la contraseña validando la actual
+// PUT /api/v1/users/:id/password
+func (h *Handler) ChangePassword(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	if err := h.service.ChangePassword(c.Request.Context(), id, req); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "password_changed")
+}
+
+// DeleteUser elimina un usuario
+// DELETE /api/v1/users/:id
+func (h *Handler) DeleteUser(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "user_deleted")
+}
+
+// ListUsers lista los usuarios con paginación
+// GET /api/v1/users
+func (h *Handler) ListUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	users, total, err := h.service.List(c.Request.Context(), page, limit)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	responses := make([]UserResponse, len(users))
+	for i, user := range users {
+		responses[i] = h.service.ToResponse(&user)
+	}
+
+	api.OK(c, UserListResponse{
+		Users: responses,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	})
+}
+
+// UnlockUser borra los intentos fallidos de una cuenta
+// POST /api/v1/users/:id/unlock
+func (h *Handler) UnlockUser(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	principal, _ := middleware.CurrentPrincipal(c)
+	if err := h.service.UnlockUser(c.Request.Context(), id, principal.UserID); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "user_unlocked")
+}
+
+// UnlockIP borra los intentos fallidos de una dirección IP
+// DELETE /api/v1/security/lockouts/ip/:ip
+func (h *Handler) UnlockIP(c *gin.Context) {
+	principal, _ := middleware.CurrentPrincipal(c)
+	if err := h.service.UnlockIP(c.Request.Context(), c.Param("ip"), principal.UserID); err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.Respond(c, http.StatusOK, nil, "ip_unlocked")
+}
+
+// ListSecurityEvents consulta el log de seguridad
+// GET /api/v1/security/events
+func (h *Handler) ListSecurityEvents(c *gin.Context) {
+	var filter SecurityEventFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		api.BindError(c, err)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	events, total, err := h.service.ListSecurityEvents(c.Request.Context(), filter, page, limit)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	responses := make([]SecurityEventResponse, len(events))
+	for i, event := range events {
+		responses[i] = h.service.ToSecurityEventResponse(&event)
+	}
+
+	api.OK(c, SecurityEventListResponse{
+		Events: responses,
+		Total:  total,
+		Page:   page,
+		Limit:  limit,
+	})
+}
+
+func (h *Handler) respondUser(c *gin.Context, id uint) {
+	user, err := h.service.GetById(c.Request.Context(), id)
+	if err != nil {
+		api.Error(c, err)
+		return
+	}
+
+	api.OK(c, h.service.ToResponse(user))
+}
+
+func parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.Error(c, domain.InvalidID("id"))
+		return 0, false
+	}
+
+	return uint(id), true
+}
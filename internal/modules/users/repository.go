@@ -1,10 +1,13 @@
 package users
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"github.com/mordmora/expirapp/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
@@ -15,16 +18,16 @@ func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) Create(user *domain.User) error {
-	return r.db.Create(user).Error
+func (r *Repository) Create(ctx context.Context, user *domain.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
-func (r *Repository) FindByID(id uint) (*domain.User, error) {
+func (r *Repository) FindByID(ctx context.Context, id uint) (*domain.User, error) {
 	var user domain.User
-	err := r.db.First(&user, id).Error
+	err := r.db.WithContext(ctx).Preload("Roles").First(&user, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, domain.NotFound("user_not_found", "usuario no encontrado")
 		}
 		return nil, err
 	}
@@ -32,13 +35,13 @@ func (r *Repository) FindByID(id uint) (*domain.User, error) {
 	return &user, nil
 }
 
-func (r *Repository) FindByEmail(email string) (*domain.User, error) {
+func (r *Repository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
 	var user domain.User
 
-	err := r.db.Where("correo = ?", email).First(&user).Error
+	err := r.db.WithContext(ctx).Where("correo = ?", email).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, domain.NotFound("user_not_found", "usuario no encontrado")
 		}
 		return nil, err
 	}
@@ -46,28 +49,297 @@ func (r *Repository) FindByEmail(email string) (*domain.User, error) {
 	return &user, nil
 }
 
-func (r *Repository) Update(user *domain.User) error {
-	return r.db.Save(user).Error
+func (r *Repository) FindByEmailWithRoles(ctx context.Context, email string) (*domain.User, error) {
+	var user domain.User
+
+	err := r.db.WithContext(ctx).Preload("Roles").Where("correo = ?", email).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("user_not_found", "usuario no encontrado")
+		}
+		return nil, err
+	}
+
+	return &user, nil
 }
 
-func (r *Repository) Delete(id int) error {
-	return r.db.Delete(&domain.User{}, id).Error
+func (r *Repository) Update(ctx context.Context, user *domain.User) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(user).Error
 }
 
-func (r *Repository) List(limit, offset int) ([]domain.User, int64, error) {
+func (r *Repository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Delete(&domain.User{}, id).Error
+}
+
+func (r *Repository) List(ctx context.Context, limit, offset int) ([]domain.User, int64, error) {
 	var users []domain.User
 	var total int64
 
-	if err := r.db.Model(&domain.User{}).Count(&total).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&domain.User{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	err := r.db.Limit(limit).Offset(offset).Find(&users).Error
+	err := r.db.WithContext(ctx).Preload("Roles").Order("id_usuario ASC").Limit(limit).Offset(offset).Find(&users).Error
 	return users, total, err
 }
 
-func (r *Repository) ExistsByEmail(email string) (bool, error) {
+func (r *Repository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	var c int64
-	err := r.db.Model(&domain.User{}).Where("correo = ?", email).Count(&c).Error
+	err := r.db.WithContext(ctx).Model(&domain.User{}).Where("correo = ?", email).Count(&c).Error
 	return c > 0, err
 }
+
+func (r *Repository) CreateToken(ctx context.Context, token *domain.UserToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// InvalidateTokens marca como usados los tokens pendientes del tipo dado, para
+// que solo el último enlace enviado sirva.
+func (r *Repository) InvalidateTokens(ctx context.Context, userID uint, tipo domain.UserTokenType, now time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.UserToken{}).
+		Where("id_usuario = ? AND tipo = ? AND usado_en IS NULL", userID, tipo).
+		Update("usado_en", now).Error
+}
+
+// RedeemToken canjea el token con ese hash y ejecuta apply en la misma
+// transacción. El UPDATE condicional garantiza que dos solicitudes
+// concurrentes no canjeen el mismo token.
+func (r *Repository) RedeemToken(ctx context.Context, hash string, tipo domain.UserTokenType, now time.Time, apply func(tx *gorm.DB, userID uint) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var token domain.UserToken
+		result := tx.Model(&token).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "id_usuario"}}}).
+			Where("hash = ? AND tipo = ? AND usado_en IS NULL AND expira_en > ?", hash, tipo, now).
+			Update("usado_en", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.Unauthorized("invalid_token", "token inválido o vencido")
+		}
+
+		return apply(tx, token.IDUsuario)
+	})
+}
+
+// SetPendingTOTP guarda un secreto nuevo sin activarlo; el segundo factor
+// sigue apagado hasta que el usuario confirme un código.
+func (r *Repository) SetPendingTOTP(ctx context.Context, userID uint, secret string) error {
+	return r.db.WithContext(ctx).Model(&domain.User{}).Where("id_usuario = ?", userID).Updates(map[string]interface{}{
+		"totp_secreto":     secret,
+		"totp_activado_en": nil,
+		"totp_ultimo_paso": 0,
+	}).Error
+}
+
+// ActivateTOTP enciende el segundo factor y reemplaza los códigos de
+// recuperación en una sola transacción.
+func (r *Repository) ActivateTOTP(ctx context.Context, userID uint, step int64, codes []domain.RecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.User{}).Where("id_usuario = ?", userID).Updates(map[string]interface{}{
+			"totp_activado_en": time.Now(),
+			"totp_ultimo_paso": step,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+}
+
+func (r *Repository) DisableTOTP(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.User{}).Where("id_usuario = ?", userID).Updates(map[string]interface{}{
+			"totp_secreto":     nil,
+			"totp_activado_en": nil,
+			"totp_ultimo_paso": 0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("id_usuario = ?", userID).Delete(&domain.RecoveryCode{}).Error
+	})
+}
+
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []domain.RecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codes []domain.RecoveryCode) error {
+	if err := tx.Where("id_usuario = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&codes).Error
+}
+
+// AdvanceTOTPStep registra el paso de tiempo del último código aceptado. Solo
+// avanza hacia adelante, así un código ya usado no se puede repetir.
+func (r *Repository) AdvanceTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.User{}).
+		Where("id_usuario = ? AND totp_ultimo_paso < ?", userID, step).
+		Update("totp_ultimo_paso", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *Repository) UseRecoveryCode(ctx context.Context, userID uint, hash string, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.RecoveryCode{}).
+		Where("id_usuario = ? AND hash = ? AND usado_en IS NULL", userID, hash).
+		Update("usado_en", now)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *Repository) CountRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	var c int64
+	err := r.db.WithContext(ctx).Model(&domain.RecoveryCode{}).Where("id_usuario = ? AND usado_en IS NULL", userID).Count(&c).Error
+	return c, err
+}
+
+// ActiveLockout devuelve el mayor bloqueado_hasta vigente entre las claves
+func (r *Repository) ActiveLockout(ctx context.Context, keys []string, now time.Time) (*time.Time, error) {
+	var until *time.Time
+	err := r.db.WithContext(ctx).Model(&domain.LoginAttempt{}).
+		Select("MAX(bloqueado_hasta)").
+		Where("clave IN ? AND bloqueado_hasta > ?", keys, now).
+		Scan(&until).Error
+	return until, err
+}
+
+// RecordFailure suma un fallo a la clave y devuelve el total. Si el último
+// fallo es más viejo que window el contador vuelve a empezar.
+func (r *Repository) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	var failures int
+	query := `
+		INSERT INTO intento_login (clave, fallos, ultimo_fallo)
+		VALUES (?, 1, ?)
+		ON CONFLICT (clave) DO UPDATE SET
+			fallos = CASE
+				WHEN intento_login.ultimo_fallo < ? THEN 1
+				ELSE intento_login.fallos + 1
+			END,
+			ultimo_fallo = EXCLUDED.ultimo_fallo
+		RETURNING fallos`
+
+	err := r.db.WithContext(ctx).Raw(query, key, now, now.Add(-window)).Scan(&failures).Error
+	return failures, err
+}
+
+func (r *Repository) LockUntil(ctx context.Context, key string, until time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.LoginAttempt{}).Where("clave = ?", key).Update("bloqueado_hasta", until).Error
+}
+
+func (r *Repository) ClearAttempts(ctx context.Context, key string) (int64, error) {
+	result := r.db.WithContext(ctx).Where("clave = ?", key).Delete(&domain.LoginAttempt{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *Repository) CreateSecurityEvent(ctx context.Context, event *domain.SecurityEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *Repository) ListSecurityEvents(ctx context.Context, filter SecurityEventFilter, limit, offset int) ([]domain.SecurityEvent, int64, error) {
+	var events []domain.SecurityEvent
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.SecurityEvent{})
+	if filter.Type != "" {
+		query = query.Where("tipo = ?", filter.Type)
+	}
+	if filter.UserID != nil {
+		query = query.Where("id_usuario = ?", *filter.UserID)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at <= ?", *filter.To)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&events).Error
+	return events, total, err
+}
+
+func (r *Repository) CreateSSOState(ctx context.Context, state *domain.SSOState) error {
+	return r.db.WithContext(ctx).Create(state).Error
+}
+
+// ConsumeSSOState borra y devuelve el inicio de sesión; cada state sirve una vez
+func (r *Repository) ConsumeSSOState(ctx context.Context, hash string, now time.Time) (*domain.SSOState, error) {
+	var states []domain.SSOState
+	err := r.db.WithContext(ctx).Clauses(clause.Returning{}).
+		Where("estado = ? AND expira_en > ?", hash, now).
+		Delete(&states).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, domain.Unauthorized("invalid_sso_state", "state inválido o vencido")
+	}
+
+	return &states[0], nil
+}
+
+func (r *Repository) PurgeSSOStates(ctx context.Context, now time.Time) error {
+	return r.db.WithContext(ctx).Where("expira_en <= ?", now).Delete(&domain.SSOState{}).Error
+}
+
+func (r *Repository) FindByExternalIdentity(ctx context.Context, issuer, subject string) (*domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).Preload("Roles").
+		Joins("JOIN identidad_externa ie ON ie.id_usuario = usuario.id_usuario").
+		Where("ie.emisor = ? AND ie.sujeto = ?", issuer, subject).
+		First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NotFound("user_not_found", "usuario no encontrado")
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *Repository) LinkExternalIdentity(ctx context.Context, userID uint, issuer, subject string) error {
+	return r.db.WithContext(ctx).Create(&domain.ExternalIdentity{IDUsuario: userID, Emisor: issuer, Sujeto: subject}).Error
+}
+
+// CreateWithExternalIdentity crea el usuario aprovisionado y su enlace juntos
+func (r *Repository) CreateWithExternalIdentity(ctx context.Context, user *domain.User, issuer, subject string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Roles").Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&domain.ExternalIdentity{IDUsuario: user.ID, Emisor: issuer, Sujeto: subject}).Error
+	})
+}
+
+/*
+# SetRoles deja, entre los roles de managed, exactamente los de want
+* los roles fuera de managed no se modifican
+*/
+func (r *Repository) SetRoles(ctx context.Context, userID uint, managed, want []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`DELETE FROM usuario_rol
+			WHERE id_usuario = ?
+			AND id_rol IN (SELECT id_rol FROM rol WHERE nombre IN ?)`, userID, managed).Error
+		if err != nil {
+			return err
+		}
+
+		if len(want) == 0 {
+			return nil
+		}
+
+		return tx.Exec(`INSERT INTO usuario_rol (id_usuario, id_rol)
+			SELECT ?, id_rol FROM rol WHERE nombre IN ?
+			ON CONFLICT DO NOTHING`, userID, want).Error
+	})
+}
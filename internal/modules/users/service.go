@@ -1,19 +1,31 @@
 package users
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
 	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/platform/mail"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Service struct {
-	repo *Repository
+	repo    *Repository
+	tokens  *TokenManager
+	mailer  mail.Sender
+	account AccountConfig
+	events  securityEventWriter
+	factors secondFactorStore
+
+	sso       IdentityProvider
+	ssoRepo   ssoRepository
+	ssoConfig SSOConfig
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, tokens *TokenManager, mailer mail.Sender, account AccountConfig) *Service {
+	return &Service{repo: repo, tokens: tokens, mailer: mailer, account: account, events: repo, factors: repo, ssoRepo: repo}
 }
 
 func (s *Service) hashPassword(pass string) (string, error) {
@@ -24,13 +36,16 @@ func (s *Service) hashPassword(pass string) (string, error) {
 	return string(hashedBytes), nil
 }
 
-func (s *Service) Create(req CreateUserRequest) (*domain.User, error) {
-	exists, err := s.repo.ExistsByEmail(req.Email)
+func (s *Service) Create(ctx context.Context, req CreateUserRequest) (_ *domain.User, err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.Create")
+	defer func() { tracing.End(span, err) }()
+
+	exists, err := s.repo.ExistsByEmail(ctx, req.Email)
 	if err != nil {
 		return nil, fmt.Errorf("error checking email existence: %w", err)
 	}
 	if exists {
-		return nil, fmt.Errorf("email already in use")
+		return nil, domain.Conflict("email_in_use", "el correo ya está registrado")
 	}
 
 	hashedPass, err := s.hashPassword(req.Password)
@@ -44,23 +59,34 @@ func (s *Service) Create(req CreateUserRequest) (*domain.User, error) {
 		Password: hashedPass,
 	}
 
-	if err := s.repo.Create(user); err != nil {
+	if err := s.repo.Create(ctx, user); err != nil {
 		return nil, fmt.Errorf("error creating user: %w", err)
 	}
 
+	s.notifyVerification(ctx, user)
+
 	return user, nil
 }
 
-func (s *Service) GetById(id uint) (*domain.User, error) {
-	return s.repo.FindByID(id)
+func (s *Service) GetById(ctx context.Context, id uint) (_ *domain.User, err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.GetById")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindByID(ctx, id)
 }
 
-func (s *Service) GetByEmail(email string) (*domain.User, error) {
-	return s.repo.FindByEmail(email)
+func (s *Service) GetByEmail(ctx context.Context, email string) (_ *domain.User, err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.GetByEmail")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindByEmail(ctx, email)
 }
 
-func (s *Service) Update(id uint, req UpdateUserRequest) (*domain.User, error) {
-	usr, err := s.repo.FindByID(id)
+func (s *Service) Update(ctx context.Context, id uint, req UpdateUserRequest) (_ *domain.User, err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.Update")
+	defer func() { tracing.End(span, err) }()
+
+	usr, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -69,32 +95,115 @@ func (s *Service) Update(id uint, req UpdateUserRequest) (*domain.User, error) {
 		usr.Name = req.Name
 	}
 
+	emailChanged := false
 	if req.Email != "" && req.Email != usr.Email {
-		exists, err := s.repo.ExistsByEmail(req.Email)
+		exists, err := s.repo.ExistsByEmail(ctx, req.Email)
 		if err != nil {
 			return nil, fmt.Errorf("error checking email existence: %w", err)
 		}
 		if exists {
-			return nil, errors.New("email already in use")
+			return nil, domain.Conflict("email_in_use", "el correo ya está registrado")
 		}
+		usr.Email = req.Email
+		usr.CorreoVerificadoEn = nil
+		emailChanged = true
 	}
 
-	if err := s.repo.Update(usr); err != nil {
+	if err := s.repo.Update(ctx, usr); err != nil {
 		return nil, fmt.Errorf("error updating user: %w", err)
 	}
 
+	if emailChanged {
+		s.notifyVerification(ctx, usr)
+	}
+
 	return usr, nil
 }
 
-func (s *Service) ChangePassword(id uint, req ChangePasswordRequest) error {
+// Login valida las credenciales y emite un token de acceso con los roles
+// actuales del usuario.
+func (s *Service) Login(ctx context.Context, req LoginRequest, ip string) (_ *LoginResponse, err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.Login")
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.checkLockout(ctx, req.Email, ip); err != nil {
+		return nil, err
+	}
 
-	usr, err := s.repo.FindByID(id)
+	usr, err := s.repo.FindByEmailWithRoles(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			s.registerFailure(ctx, nil, req.Email, ip, "correo no registrado")
+			return nil, domain.Unauthorized("invalid_credentials", "correo o contraseña incorrectos")
+		}
+		return nil, err
+	}
+
+	if !s.verifyPassword(usr.Password, req.Password) {
+		s.registerFailure(ctx, &usr.ID, req.Email, ip, "contraseña incorrecta")
+		return nil, domain.Unauthorized("invalid_credentials", "correo o contraseña incorrectos")
+	}
+
+	if s.account.RequireVerifiedEmail && !usr.EmailVerified() {
+		return nil, domain.Forbidden("email_not_verified", "el correo no ha sido verificado")
+	}
+
+	/*
+		# con 2FA activo el primer paso solo entrega un token de desafío;
+		# si el rol lo exige y aún no está activo, el token solo permite enrolar
+	*/
+	if usr.TwoFactorEnabled() {
+		challenge, expiresAt, err := s.tokens.IssueScoped(usr, scopeTwoFactorChallenge, challengeTTL)
+		if err != nil {
+			return nil, fmt.Errorf("error issuing token: %w", err)
+		}
+		return &LoginResponse{
+			ChallengeToken:    challenge,
+			RequiresTwoFactor: true,
+			ExpiresAt:         expiresAt,
+			User:              s.ToResponse(usr),
+		}, nil
+	}
+
+	if s.requiresTwoFactor(usr) {
+		s.registerSuccess(ctx, req.Email)
+		token, expiresAt, err := s.tokens.IssueScoped(usr, scopeTwoFactorEnroll, challengeTTL)
+		if err != nil {
+			return nil, fmt.Errorf("error issuing token: %w", err)
+		}
+		return &LoginResponse{
+			Token:              token,
+			RequiresEnrollment: true,
+			ExpiresAt:          expiresAt,
+			User:               s.ToResponse(usr),
+		}, nil
+	}
+
+	s.registerSuccess(ctx, req.Email)
+
+	token, expiresAt, err := s.tokens.Issue(usr)
+	if err != nil {
+		return nil, fmt.Errorf("error issuing token: %w", err)
+	}
+
+	return &LoginResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      s.ToResponse(usr),
+	}, nil
+}
+
+func (s *Service) ChangePassword(ctx context.Context, id uint, req ChangePasswordRequest) (err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.ChangePassword")
+	defer func() { tracing.End(span, err) }()
+
+	usr, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	if !s.verifyPassword(usr.Password, req.CurrentPass) {
-		return errors.New("current password is incorrect")
+		return domain.Unauthorized("wrong_password", "la contraseña actual es incorrecta")
 	}
 
 	hashedPass, err := s.hashPassword(req.NewPass)
@@ -103,20 +212,30 @@ func (s *Service) ChangePassword(id uint, req ChangePasswordRequest) error {
 	}
 
 	usr.Password = hashedPass
-	return s.repo.Update(usr)
+	if err := s.repo.Update(ctx, usr); err != nil {
+		return err
+	}
+
+	s.logEvent(ctx, domain.SecurityEventPasswordChanged, &usr.ID, usr.Email, "", "cambio con la contraseña actual")
+	return nil
 }
 
-func (s *Service) Delete(id uint) error {
+func (s *Service) Delete(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.Delete")
+	defer func() { tracing.End(span, err) }()
 
-	_, err := s.repo.FindByID(id)
+	_, err = s.repo.FindByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	return s.repo.Delete(int(id))
+	return s.repo.Delete(ctx, int(id))
 }
 
-func (s *Service) List(page, limit int) ([]domain.User, int64, error) {
+func (s *Service) List(ctx context.Context, page, limit int) (_ []domain.User, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.List")
+	defer func() { tracing.End(span, err) }()
+
 	if page < 1 {
 		page = 1
 	}
@@ -125,7 +244,7 @@ func (s *Service) List(page, limit int) ([]domain.User, int64, error) {
 	}
 
 	offset := (page - 1) * limit
-	return s.repo.List(limit, offset)
+	return s.repo.List(ctx, limit, offset)
 }
 
 func (s *Service) verifyPassword(hashedP, password string) bool {
@@ -134,10 +253,18 @@ func (s *Service) verifyPassword(hashedP, password string) bool {
 }
 
 func (s *Service) ToResponse(usr *domain.User) UserResponse {
+	roles := make([]string, len(usr.Roles))
+	for i, role := range usr.Roles {
+		roles[i] = role.RoleName
+	}
+
 	return UserResponse{
 		ID:        usr.ID,
 		Name:      usr.Name,
 		Email:     usr.Email,
+		Roles:     roles,
+		Verified:  usr.EmailVerified(),
+		TwoFactor: usr.TwoFactorEnabled(),
 		CreatedAt: usr.CreatedAt,
 	}
 }
@@ -0,0 +1,262 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/platform/oidc"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+)
+
+/*
+# IdentityProvider es el IdP del login corporativo
+* lo implementa oidc.Provider; en pruebas puede apuntar a un IdP de httptest
+*/
+type IdentityProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, verifier, nonce string) (*oidc.Identity, error)
+}
+
+/*
+# ssoRepository es lo que el login corporativo usa de la base de datos
+* lo implementa Repository; las pruebas usan uno en memoria
+*/
+type ssoRepository interface {
+	securityEventWriter
+	CreateSSOState(ctx context.Context, state *domain.SSOState) error
+	ConsumeSSOState(ctx context.Context, hash string, now time.Time) (*domain.SSOState, error)
+	PurgeSSOStates(ctx context.Context, now time.Time) error
+	FindByID(ctx context.Context, id uint) (*domain.User, error)
+	FindByEmail(ctx context.Context, email string) (*domain.User, error)
+	FindByExternalIdentity(ctx context.Context, issuer, subject string) (*domain.User, error)
+	LinkExternalIdentity(ctx context.Context, userID uint, issuer, subject string) error
+	CreateWithExternalIdentity(ctx context.Context, user *domain.User, issuer, subject string) error
+	SetRoles(ctx context.Context, userID uint, managed, want []string) error
+}
+
+/*
+# SSOConfig define cómo se traducen las identidades externas a cuentas locales
+* RoleMap: valor del claim de grupos -> nombre de rol local
+* DefaultRoles: roles de un usuario nuevo cuando ningún grupo coincide; no
+* deberían repetir roles de RoleMap porque la sincronización los quitaría
+* los roles que aparecen en RoleMap se sincronizan en cada login; el resto
+* de roles del usuario no se toca
+*/
+type SSOConfig struct {
+	RoleMap      map[string]string
+	DefaultRoles []string
+}
+
+const ssoStateTTL = 10 * time.Minute
+
+// EnableSSO activa el login OIDC junto al login con contraseña
+func (s *Service) EnableSSO(provider IdentityProvider, cfg SSOConfig) {
+	s.sso = provider
+	s.ssoConfig = cfg
+}
+
+func randomURLString(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// StartSSOLogin genera state, nonce y code_verifier, los guarda y devuelve la
+// URL del IdP a la que el frontend debe redirigir
+func (s *Service) StartSSOLogin(ctx context.Context) (_ *SSOStartResponse, err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.StartSSOLogin")
+	defer func() { tracing.End(span, err) }()
+
+	if s.sso == nil {
+		return nil, domain.NotFound("sso_not_configured", "el inicio de sesión corporativo no está configurado")
+	}
+
+	state, err := randomURLString(32)
+	if err != nil {
+		return nil, fmt.Errorf("error generating state: %w", err)
+	}
+	nonce, err := randomURLString(32)
+	if err != nil {
+		return nil, fmt.Errorf("error generating nonce: %w", err)
+	}
+	verifier, err := randomURLString(48)
+	if err != nil {
+		return nil, fmt.Errorf("error generating code verifier: %w", err)
+	}
+
+	now := time.Now()
+	if err := s.ssoRepo.PurgeSSOStates(ctx, now); err != nil {
+		return nil, fmt.Errorf("error purging sso states: %w", err)
+	}
+	if err := s.ssoRepo.CreateSSOState(ctx, &domain.SSOState{
+		Estado:      hashToken(state),
+		Nonce:       nonce,
+		Verificador: verifier,
+		ExpiraEn:    now.Add(ssoStateTTL),
+	}); err != nil {
+		return nil, fmt.Errorf("error saving sso state: %w", err)
+	}
+
+	authURL, err := s.sso.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return nil, fmt.Errorf("error building authorization url: %w", err)
+	}
+
+	return &SSOStartResponse{AuthorizationURL: authURL, State: state}, nil
+}
+
+/*
+# CompleteSSOLogin canjea el código que devolvió el IdP por un token de acceso
+* el segundo factor lo exige el IdP, así que aquí no se pide TOTP
+*/
+func (s *Service) CompleteSSOLogin(ctx context.Context, req SSOCallbackRequest, ip string) (_ *LoginResponse, err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.CompleteSSOLogin")
+	defer func() { tracing.End(span, err) }()
+
+	if s.sso == nil {
+		return nil, domain.NotFound("sso_not_configured", "el inicio de sesión corporativo no está configurado")
+	}
+
+	state, err := s.ssoRepo.ConsumeSSOState(ctx, hashToken(req.State), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := s.sso.Exchange(ctx, req.Code, state.Verificador, state.Nonce)
+	if err != nil {
+		s.logEvent(ctx, domain.SecurityEventLoginFailed, nil, "", ip, "sso: "+err.Error())
+		return nil, domain.Unauthorized("sso_login_failed", "no se pudo iniciar sesión con el proveedor de identidad")
+	}
+
+	usr, err := s.resolveIdentity(ctx, identity)
+	if err != nil {
+		s.logEvent(ctx, domain.SecurityEventLoginFailed, nil, identity.Email, ip, "sso: "+err.Error())
+		return nil, err
+	}
+
+	if err := s.syncSSORoles(ctx, usr, identity.Groups); err != nil {
+		return nil, fmt.Errorf("error syncing roles: %w", err)
+	}
+
+	usr, err = s.ssoRepo.FindByID(ctx, usr.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, expiresAt, err := s.tokens.Issue(usr)
+	if err != nil {
+		return nil, fmt.Errorf("error issuing token: %w", err)
+	}
+
+	return &LoginResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      s.ToResponse(usr),
+	}, nil
+}
+
+/*
+# resolveIdentity busca o crea el usuario local de una identidad externa
+* primero por (emisor, sujeto); luego por correo, solo si el IdP lo verificó,
+* para no entregar una cuenta local a quien ponga ese correo en el IdP
+* si no existe se crea sin contraseña utilizable
+*/
+func (s *Service) resolveIdentity(ctx context.Context, identity *oidc.Identity) (*domain.User, error) {
+	usr, err := s.ssoRepo.FindByExternalIdentity(ctx, identity.Issuer, identity.Subject)
+	if err == nil {
+		if usr.AnonimizadoEn != nil {
+			return nil, domain.Unauthorized("user_anonymized", "los datos del usuario fueron anonimizados")
+		}
+		return usr, nil
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+	if email == "" {
+		return nil, domain.Unauthorized("sso_email_missing", "el proveedor de identidad no devolvió un correo")
+	}
+
+	existing, err := s.ssoRepo.FindByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if !identity.EmailVerified {
+			return nil, domain.Conflict("email_in_use", "el correo ya está registrado")
+		}
+		if existing.AnonimizadoEn != nil {
+			return nil, domain.Unauthorized("user_anonymized", "los datos del usuario fueron anonimizados")
+		}
+		if err := s.ssoRepo.LinkExternalIdentity(ctx, existing.ID, identity.Issuer, identity.Subject); err != nil {
+			return nil, fmt.Errorf("error linking identity: %w", err)
+		}
+		return existing, nil
+
+	case !errors.Is(err, domain.ErrNotFound):
+		return nil, err
+	}
+
+	name := identity.Name
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+
+	usr = &domain.User{
+		Name:  name,
+		Email: email,
+		// "!" no es un hash bcrypt: el login con contraseña nunca coincide
+		Password: "!",
+	}
+	if identity.EmailVerified {
+		now := time.Now()
+		usr.CorreoVerificadoEn = &now
+	}
+
+	if err := s.ssoRepo.CreateWithExternalIdentity(ctx, usr, identity.Issuer, identity.Subject); err != nil {
+		return nil, fmt.Errorf("error provisioning user: %w", err)
+	}
+
+	if len(s.mappedRoles(identity.Groups)) == 0 && len(s.ssoConfig.DefaultRoles) > 0 {
+		if err := s.ssoRepo.SetRoles(ctx, usr.ID, s.ssoConfig.DefaultRoles, s.ssoConfig.DefaultRoles); err != nil {
+			return nil, fmt.Errorf("error assigning default roles: %w", err)
+		}
+	}
+
+	return usr, nil
+}
+
+func (s *Service) mappedRoles(groups []string) []string {
+	var roles []string
+	for _, group := range groups {
+		if role, ok := s.ssoConfig.RoleMap[group]; ok && !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// syncSSORoles deja los roles administrados por el IdP iguales a los grupos
+// actuales de la identidad
+func (s *Service) syncSSORoles(ctx context.Context, usr *domain.User, groups []string) error {
+	if len(s.ssoConfig.RoleMap) == 0 {
+		return nil
+	}
+
+	var managed []string
+	for _, role := range s.ssoConfig.RoleMap {
+		if !slices.Contains(managed, role) {
+			managed = append(managed, role)
+		}
+	}
+
+	return s.ssoRepo.SetRoles(ctx, usr.ID, managed, s.mappedRoles(groups))
+}
@@ -0,0 +1,295 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/platform/oidc/oidctest"
+)
+
+// memorySSORepository guarda usuarios, identidades y states en memoria
+type memorySSORepository struct {
+	users      map[uint]*domain.User
+	identities map[[2]string]uint
+	states     map[string]domain.SSOState
+	events     []domain.SecurityEvent
+	nextID     uint
+}
+
+func newMemorySSORepository() *memorySSORepository {
+	return &memorySSORepository{
+		users:      map[uint]*domain.User{},
+		identities: map[[2]string]uint{},
+		states:     map[string]domain.SSOState{},
+	}
+}
+
+func (r *memorySSORepository) addUser(user *domain.User) *domain.User {
+	r.nextID++
+	user.ID = r.nextID
+	r.users[user.ID] = user
+	return user
+}
+
+func (r *memorySSORepository) CreateSecurityEvent(_ context.Context, event *domain.SecurityEvent) error {
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func (r *memorySSORepository) CreateSSOState(_ context.Context, state *domain.SSOState) error {
+	r.states[state.Estado] = *state
+	return nil
+}
+
+func (r *memorySSORepository) ConsumeSSOState(_ context.Context, hash string, now time.Time) (*domain.SSOState, error) {
+	state, ok := r.states[hash]
+	delete(r.states, hash)
+	if !ok || !state.ExpiraEn.After(now) {
+		return nil, domain.Unauthorized("invalid_sso_state", "state inválido o vencido")
+	}
+	return &state, nil
+}
+
+func (r *memorySSORepository) PurgeSSOStates(_ context.Context, now time.Time) error {
+	for hash, state := range r.states {
+		if !state.ExpiraEn.After(now) {
+			delete(r.states, hash)
+		}
+	}
+	return nil
+}
+
+func (r *memorySSORepository) FindByID(_ context.Context, id uint) (*domain.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, domain.NotFound("user_not_found", "usuario no encontrado")
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *memorySSORepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	for id, user := range r.users {
+		if user.Email == email {
+			return r.FindByID(ctx, id)
+		}
+	}
+	return nil, domain.NotFound("user_not_found", "usuario no encontrado")
+}
+
+func (r *memorySSORepository) FindByExternalIdentity(ctx context.Context, issuer, subject string) (*domain.User, error) {
+	id, ok := r.identities[[2]string{issuer, subject}]
+	if !ok {
+		return nil, domain.NotFound("user_not_found", "usuario no encontrado")
+	}
+	return r.FindByID(ctx, id)
+}
+
+func (r *memorySSORepository) LinkExternalIdentity(_ context.Context, userID uint, issuer, subject string) error {
+	r.identities[[2]string{issuer, subject}] = userID
+	return nil
+}
+
+func (r *memorySSORepository) CreateWithExternalIdentity(ctx context.Context, user *domain.User, issuer, subject string) error {
+	stored := *user
+	r.addUser(&stored)
+	user.ID = stored.ID
+	return r.LinkExternalIdentity(ctx, user.ID, issuer, subject)
+}
+
+func (r *memorySSORepository) SetRoles(_ context.Context, userID uint, managed, want []string) error {
+	user := r.users[userID]
+	user.Roles = slices.DeleteFunc(user.Roles, func(role domain.Role) bool {
+		return slices.Contains(managed, role.RoleName)
+	})
+	for _, name := range want {
+		user.Roles = append(user.Roles, domain.Role{RoleName: name})
+	}
+	return nil
+}
+
+func (r *memorySSORepository) roles(userID uint) []string {
+	var names []string
+	for _, role := range r.users[userID].Roles {
+		names = append(names, role.RoleName)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func newSSOService(t *testing.T) (*Service, *memorySSORepository, *oidctest.IdP) {
+	t.Helper()
+
+	repo := newMemorySSORepository()
+	idp := oidctest.New(t)
+
+	s := &Service{
+		tokens:  NewTokenManager("test-secret", time.Hour),
+		events:  repo,
+		ssoRepo: repo,
+	}
+	s.EnableSSO(idp.Provider(), SSOConfig{
+		RoleMap:      map[string]string{"tienda-admins": domain.RoleAdmin, "tienda-vendedores": domain.RoleSeller},
+		DefaultRoles: []string{domain.RoleClient},
+	})
+	return s, repo, idp
+}
+
+// ssoLogin inicia el login, pasa por el IdP con claims y completa el callback
+func ssoLogin(t *testing.T, s *Service, idp *oidctest.IdP, claims jwt.MapClaims) (*LoginResponse, error) {
+	t.Helper()
+
+	ctx := context.Background()
+	start, err := s.StartSSOLogin(ctx)
+	if err != nil {
+		t.Fatalf("StartSSOLogin: %v", err)
+	}
+	code := idp.Authorize(start.AuthorizationURL, claims)
+	return s.CompleteSSOLogin(ctx, SSOCallbackRequest{Code: code, State: start.State}, "10.0.0.1")
+}
+
+func errorCode(err error) string {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Code
+	}
+	return ""
+}
+
+func TestSSOLoginProvisionsUser(t *testing.T) {
+	s, repo, idp := newSSOService(t)
+
+	resp, err := ssoLogin(t, s, idp, jwt.MapClaims{
+		"email":  "Ana@Example.com",
+		"groups": []string{"tienda-vendedores", "otro-grupo"},
+	})
+	if err != nil {
+		t.Fatalf("CompleteSSOLogin: %v", err)
+	}
+	if resp.Token == "" {
+		t.Error("no access token issued")
+	}
+	if resp.User.Email != "ana@example.com" || resp.User.Name != "Ana" || !resp.User.Verified {
+		t.Errorf("unexpected user %+v", resp.User)
+	}
+
+	user := repo.users[resp.User.ID]
+	if user.Password != "!" {
+		t.Errorf("provisioned user has a usable password %q", user.Password)
+	}
+	if id := repo.identities[[2]string{idp.Issuer, "ext-1"}]; id != user.ID {
+		t.Errorf("identity linked to %d, want %d", id, user.ID)
+	}
+	// un grupo mapeado reemplaza los roles por defecto
+	if got := repo.roles(user.ID); !slices.Equal(got, []string{domain.RoleSeller}) {
+		t.Errorf("roles = %v, want [%s]", got, domain.RoleSeller)
+	}
+
+	// el segundo login encuentra la identidad y sincroniza los grupos
+	resp, err = ssoLogin(t, s, idp, jwt.MapClaims{"groups": []string{"tienda-admins"}})
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if resp.User.ID != user.ID || len(repo.users) != 1 {
+		t.Fatalf("second login created another user")
+	}
+	if got := repo.roles(user.ID); !slices.Equal(got, []string{domain.RoleAdmin}) {
+		t.Errorf("roles after sync = %v, want [%s]", got, domain.RoleAdmin)
+	}
+}
+
+func TestSSOLoginAssignsDefaultRoles(t *testing.T) {
+	s, repo, idp := newSSOService(t)
+
+	resp, err := ssoLogin(t, s, idp, nil)
+	if err != nil {
+		t.Fatalf("CompleteSSOLogin: %v", err)
+	}
+	if got := repo.roles(resp.User.ID); !slices.Equal(got, []string{domain.RoleClient}) {
+		t.Errorf("roles = %v, want [%s]", got, domain.RoleClient)
+	}
+}
+
+func TestSSOLoginLinksVerifiedEmail(t *testing.T) {
+	s, repo, idp := newSSOService(t)
+	existing := repo.addUser(&domain.User{Name: "Ana", Email: "ana@example.com", Password: "hash"})
+
+	resp, err := ssoLogin(t, s, idp, nil)
+	if err != nil {
+		t.Fatalf("CompleteSSOLogin: %v", err)
+	}
+	if resp.User.ID != existing.ID || len(repo.users) != 1 {
+		t.Fatalf("login resolved to user %d, want existing %d", resp.User.ID, existing.ID)
+	}
+	if id := repo.identities[[2]string{idp.Issuer, "ext-1"}]; id != existing.ID {
+		t.Errorf("identity linked to %d, want %d", id, existing.ID)
+	}
+}
+
+func TestSSOLoginDoesNotLinkUnverifiedEmail(t *testing.T) {
+	s, repo, idp := newSSOService(t)
+	repo.addUser(&domain.User{Name: "Ana", Email: "ana@example.com", Password: "hash"})
+
+	_, err := ssoLogin(t, s, idp, jwt.MapClaims{"email_verified": false})
+	if code := errorCode(err); code != "email_in_use" {
+		t.Fatalf("err = %v, want email_in_use", err)
+	}
+	if len(repo.identities) != 0 {
+		t.Errorf("unverified email was linked: %v", repo.identities)
+	}
+	if len(repo.events) != 1 || repo.events[0].Tipo != domain.SecurityEventLoginFailed {
+		t.Errorf("events = %+v, want one failed login", repo.events)
+	}
+}
+
+func TestSSOLoginRejectsInvalidIDToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+	}{
+		{"nonce", jwt.MapClaims{"nonce": "otro"}},
+		{"issuer", jwt.MapClaims{"iss": "https://evil.example.com"}},
+		{"audience", jwt.MapClaims{"aud": "otra-app"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo, idp := newSSOService(t)
+
+			_, err := ssoLogin(t, s, idp, tt.claims)
+			if code := errorCode(err); code != "sso_login_failed" {
+				t.Fatalf("err = %v, want sso_login_failed", err)
+			}
+			if len(repo.users) != 0 {
+				t.Error("a user was provisioned from an invalid id_token")
+			}
+			if len(repo.events) != 1 || repo.events[0].Tipo != domain.SecurityEventLoginFailed {
+				t.Errorf("events = %+v, want one failed login", repo.events)
+			}
+		})
+	}
+}
+
+func TestSSOStateIsSingleUse(t *testing.T) {
+	s, _, idp := newSSOService(t)
+	ctx := context.Background()
+
+	start, err := s.StartSSOLogin(ctx)
+	if err != nil {
+		t.Fatalf("StartSSOLogin: %v", err)
+	}
+	code := idp.Authorize(start.AuthorizationURL, nil)
+	if _, err := s.CompleteSSOLogin(ctx, SSOCallbackRequest{Code: code, State: start.State}, ""); err != nil {
+		t.Fatalf("CompleteSSOLogin: %v", err)
+	}
+
+	_, err = s.CompleteSSOLogin(ctx, SSOCallbackRequest{Code: code, State: start.State}, "")
+	if code := errorCode(err); code != "invalid_sso_state" {
+		t.Fatalf("err = %v, want invalid_sso_state", err)
+	}
+}
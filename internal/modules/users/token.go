@@ -0,0 +1,87 @@
+package users
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/middleware"
+)
+
+type tokenClaims struct {
+	Roles []string `json:"roles"`
+	Scope string   `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// scopes de tokens restringidos; un token sin scope es de acceso completo
+const (
+	scopeTwoFactorChallenge = "2fa_challenge"
+	scopeTwoFactorEnroll    = middleware.ScopeTwoFactorEnroll
+)
+
+// TokenManager firma y valida los JWT de acceso. Implementa
+// middleware.Authenticator.
+type TokenManager struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewTokenManager(secret string, ttl time.Duration) *TokenManager {
+	return &TokenManager{secret: []byte(secret), ttl: ttl}
+}
+
+func (m *TokenManager) Issue(user *domain.User) (string, time.Time, error) {
+	return m.IssueScoped(user, "", m.ttl)
+}
+
+// IssueScoped emite un token que solo sirve para el paso indicado por scope
+// (p. ej. completar el segundo factor del login).
+func (m *TokenManager) IssueScoped(user *domain.User, scope string, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	roles := make([]string, len(user.Roles))
+	for i, role := range user.Roles {
+		roles[i] = role.RoleName
+	}
+
+	claims := tokenClaims{
+		Roles: roles,
+		Scope: scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+func (m *TokenManager) Authenticate(token string) (*middleware.Principal, error) {
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, domain.Unauthorized("invalid_token", "token inválido o vencido")
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return nil, domain.Unauthorized("invalid_token", "token inválido o vencido")
+	}
+
+	return &middleware.Principal{
+		UserID: uint(userID),
+		Roles:  claims.Roles,
+		Scope:  claims.Scope,
+	}, nil
+}
@@ -0,0 +1,317 @@
+package users
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+)
+
+/*
+# TOTP según RFC 6238: HMAC-SHA1, pasos de 30 segundos, 6 dígitos
+* se acepta un paso de tolerancia hacia atrás y hacia adelante
+*/
+const (
+	totpPeriod        = 30
+	totpDigits        = 6
+	totpSkew          = 1
+	recoveryCodeCount = 10
+	challengeTTL      = 5 * time.Minute
+)
+
+var base32NoPad = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func newTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32NoPad.EncodeToString(b), nil
+}
+
+// totpCode calcula el código HOTP (RFC 4226) para el contador dado
+func totpCode(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// matchTOTP devuelve el paso de tiempo en que code es válido, o false
+func matchTOTP(secret, code string, now time.Time) (int64, bool) {
+	key, err := base32NoPad.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for delta := int64(-totpSkew); delta <= totpSkew; delta++ {
+		step := current + delta
+		if hmac.Equal([]byte(totpCode(key, uint64(step))), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func (s *Service) provisioningURI(email, secret string) string {
+	label := url.PathEscape(s.account.Issuer + ":" + email)
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", s.account.Issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// newRecoveryCodes genera códigos con formato xxxxx-xxxxx y devuelve los
+// valores en claro junto con las filas a guardar
+func newRecoveryCodes(userID uint) ([]string, []domain.RecoveryCode, error) {
+	plain := make([]string, recoveryCodeCount)
+	rows := make([]domain.RecoveryCode, recoveryCodeCount)
+
+	for i := range plain {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(base32NoPad.EncodeToString(b))[:10]
+		plain[i] = code[:5] + "-" + code[5:]
+		rows[i] = domain.RecoveryCode{IDUsuario: userID, Hash: hashToken(code)}
+	}
+
+	return plain, rows, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// requiresTwoFactor indica si algún rol del usuario obliga a usar 2FA
+func (s *Service) requiresTwoFactor(usr *domain.User) bool {
+	for _, role := range usr.Roles {
+		if slices.Contains(s.account.TwoFactorRequiredRoles, role.RoleName) {
+			return true
+		}
+	}
+	return false
+}
+
+// secondFactorStore marca como usados los códigos que acepta
+// verifySecondFactor; lo implementa Repository
+type secondFactorStore interface {
+	AdvanceTOTPStep(ctx context.Context, userID uint, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID uint, hash string, now time.Time) (bool, error)
+}
+
+// verifySecondFactor acepta un código TOTP o, si no coincide, un código de
+// recuperación sin usar
+func (s *Service) verifySecondFactor(ctx context.Context, usr *domain.User, code string) error {
+	if usr.TOTPSecreto == nil || !usr.TwoFactorEnabled() {
+		return domain.Conflict("two_factor_not_enabled", "la autenticación en dos pasos no está activa")
+	}
+
+	now := time.Now()
+	if step, ok := matchTOTP(*usr.TOTPSecreto, strings.TrimSpace(code), now); ok {
+		advanced, err := s.factors.AdvanceTOTPStep(ctx, usr.ID, step)
+		if err != nil {
+			return err
+		}
+		if !advanced {
+			return domain.Unauthorized("invalid_two_factor_code", "código de verificación inválido")
+		}
+		return nil
+	}
+
+	used, err := s.factors.UseRecoveryCode(ctx, usr.ID, hashToken(normalizeRecoveryCode(code)), now)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return domain.Unauthorized("invalid_two_factor_code", "código de verificación inválido")
+	}
+	return nil
+}
+
+// EnrollTOTP genera un secreto pendiente y la URI otpauth:// para el QR
+func (s *Service) EnrollTOTP(ctx context.Context, userID uint) (_ *TOTPEnrollmentResponse, err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.EnrollTOTP")
+	defer func() { tracing.End(span, err) }()
+
+	usr, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if usr.TwoFactorEnabled() {
+		return nil, domain.Conflict("two_factor_already_enabled", "la autenticación en dos pasos ya está activa")
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("error generating totp secret: %w", err)
+	}
+
+	if err := s.repo.SetPendingTOTP(ctx, usr.ID, secret); err != nil {
+		return nil, fmt.Errorf("error saving totp secret: %w", err)
+	}
+
+	return &TOTPEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningURI: s.provisioningURI(usr.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP activa el segundo factor si el código corresponde al secreto
+// pendiente y entrega los códigos de recuperación
+func (s *Service) ConfirmTOTP(ctx context.Context, userID uint, req TwoFactorCodeRequest) (_ *RecoveryCodesResponse, err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.ConfirmTOTP")
+	defer func() { tracing.End(span, err) }()
+
+	usr, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if usr.TwoFactorEnabled() {
+		return nil, domain.Conflict("two_factor_already_enabled", "la autenticación en dos pasos ya está activa")
+	}
+	if usr.TOTPSecreto == nil {
+		return nil, domain.Conflict("two_factor_enrollment_not_started", "no se ha iniciado la activación de la autenticación en dos pasos")
+	}
+
+	step, ok := matchTOTP(*usr.TOTPSecreto, strings.TrimSpace(req.Code), time.Now())
+	if !ok {
+		return nil, domain.Unauthorized("invalid_two_factor_code", "código de verificación inválido")
+	}
+
+	plain, rows, err := newRecoveryCodes(usr.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error generating recovery codes: %w", err)
+	}
+
+	if err := s.repo.ActivateTOTP(ctx, usr.ID, step, rows); err != nil {
+		return nil, fmt.Errorf("error enabling two-factor authentication: %w", err)
+	}
+
+	s.logEvent(ctx, domain.SecurityEventTwoFactorOn, &usr.ID, usr.Email, "", "")
+
+	return &RecoveryCodesResponse{Codes: plain}, nil
+}
+
+// RegenerateRecoveryCodes invalida los códigos anteriores y emite nuevos
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID uint, req TwoFactorCodeRequest) (_ *RecoveryCodesResponse, err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.RegenerateRecoveryCodes")
+	defer func() { tracing.End(span, err) }()
+
+	usr, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verifySecondFactor(ctx, usr, req.Code); err != nil {
+		return nil, err
+	}
+
+	plain, rows, err := newRecoveryCodes(usr.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error generating recovery codes: %w", err)
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(ctx, usr.ID, rows); err != nil {
+		return nil, fmt.Errorf("error saving recovery codes: %w", err)
+	}
+
+	return &RecoveryCodesResponse{Codes: plain}, nil
+}
+
+// DisableTOTP apaga el segundo factor. Sin skipCode exige un código válido;
+// los administradores lo omiten para reiniciar cuentas que perdieron el
+// autenticador.
+func (s *Service) DisableTOTP(ctx context.Context, userID uint, code string, skipCode bool) (err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.DisableTOTP")
+	defer func() { tracing.End(span, err) }()
+
+	usr, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !usr.TwoFactorEnabled() {
+		return domain.Conflict("two_factor_not_enabled", "la autenticación en dos pasos no está activa")
+	}
+
+	detail := "desactivado por el usuario"
+	if skipCode {
+		detail = "desactivado por un administrador"
+	} else if err := s.verifySecondFactor(ctx, usr, code); err != nil {
+		return err
+	}
+
+	if err := s.repo.DisableTOTP(ctx, usr.ID); err != nil {
+		return err
+	}
+
+	s.logEvent(ctx, domain.SecurityEventTwoFactorOff, &usr.ID, usr.Email, "", detail)
+	return nil
+}
+
+// CompleteTwoFactorLogin canjea el token de desafío del primer paso más un
+// código TOTP o de recuperación por un token de acceso
+func (s *Service) CompleteTwoFactorLogin(ctx context.Context, req TwoFactorLoginRequest, ip string) (_ *LoginResponse, err error) {
+	ctx, span := tracing.Start(ctx, "users.Service.CompleteTwoFactorLogin")
+	defer func() { tracing.End(span, err) }()
+
+	principal, err := s.tokens.Authenticate(req.ChallengeToken)
+	if err != nil || principal.Scope != scopeTwoFactorChallenge {
+		return nil, domain.Unauthorized("invalid_token", "token inválido o vencido")
+	}
+
+	usr, err := s.repo.FindByID(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkLockout(ctx, usr.Email, ip); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifySecondFactor(ctx, usr, req.Code); err != nil {
+		if domain.ErrorCode(err) == "invalid_two_factor_code" {
+			s.registerFailure(ctx, &usr.ID, usr.Email, ip, "código de segundo factor incorrecto")
+		}
+		return nil, err
+	}
+
+	s.registerSuccess(ctx, usr.Email)
+
+	token, expiresAt, err := s.tokens.Issue(usr)
+	if err != nil {
+		return nil, fmt.Errorf("error issuing token: %w", err)
+	}
+
+	return &LoginResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      s.ToResponse(usr),
+	}, nil
+}
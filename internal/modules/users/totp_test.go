@@ -0,0 +1,121 @@
+package users
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mordmora/expirapp/internal/domain"
+)
+
+// rfcSecret es la clave SHA-1 de los vectores del apéndice B de RFC 6238,
+// "12345678901234567890", en base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPMatchesRFC6238Vectors(t *testing.T) {
+	// el RFC publica códigos de 8 dígitos; los de 6 son sus últimos 6
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	key, err := base32NoPad.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatalf("decoding secret: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			step := tt.unix / totpPeriod
+			if got := totpCode(key, uint64(step)); got != tt.code {
+				t.Errorf("totpCode(%d) = %s, want %s", step, got, tt.code)
+			}
+
+			got, ok := matchTOTP(rfcSecret, tt.code, time.Unix(tt.unix, 0))
+			if !ok || got != step {
+				t.Errorf("matchTOTP = (%d, %v), want (%d, true)", got, ok, step)
+			}
+		})
+	}
+}
+
+func TestMatchTOTPDriftWindow(t *testing.T) {
+	key, _ := base32NoPad.DecodeString(rfcSecret)
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		ok     bool
+	}{
+		{"current step", rfcSecret, totpCode(key, uint64(current)), true},
+		{"one step behind", rfcSecret, totpCode(key, uint64(current-1)), true},
+		{"one step ahead", rfcSecret, totpCode(key, uint64(current+1)), true},
+		{"two steps behind", rfcSecret, totpCode(key, uint64(current-2)), false},
+		{"two steps ahead", rfcSecret, totpCode(key, uint64(current+2)), false},
+		{"lower-case secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", totpCode(key, uint64(current)), true},
+		{"short code", rfcSecret, "00592", false},
+		{"long code", rfcSecret, "0059240", false},
+		{"invalid secret", "not base32!", "005924", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := matchTOTP(tt.secret, tt.code, now); ok != tt.ok {
+				t.Errorf("matchTOTP(%q) ok = %v, want %v", tt.code, ok, tt.ok)
+			}
+		})
+	}
+}
+
+// memoryFactors guarda el último paso aceptado como lo hace totp_ultimo_paso
+type memoryFactors struct {
+	lastStep int64
+}
+
+func (f *memoryFactors) AdvanceTOTPStep(_ context.Context, _ uint, step int64) (bool, error) {
+	if f.lastStep >= step {
+		return false, nil
+	}
+	f.lastStep = step
+	return true, nil
+}
+
+func (f *memoryFactors) UseRecoveryCode(context.Context, uint, string, time.Time) (bool, error) {
+	return false, nil
+}
+
+func TestVerifySecondFactorRejectsReplay(t *testing.T) {
+	ctx := context.Background()
+	s := &Service{factors: &memoryFactors{}}
+
+	secret := rfcSecret
+	enabled := time.Now()
+	usr := &domain.User{ID: 1, TOTPSecreto: &secret, TOTPActivadoEn: &enabled}
+
+	key, _ := base32NoPad.DecodeString(rfcSecret)
+	current := time.Now().Unix() / totpPeriod
+	code := totpCode(key, uint64(current))
+
+	if err := s.verifySecondFactor(ctx, usr, code); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := s.verifySecondFactor(ctx, usr, code); errorCode(err) != "invalid_two_factor_code" {
+		t.Errorf("replayed code: err = %v, want invalid_two_factor_code", err)
+	}
+
+	// un código anterior dentro de la tolerancia tampoco sirve una vez
+	// aceptado uno más nuevo
+	if err := s.verifySecondFactor(ctx, usr, totpCode(key, uint64(current-1))); errorCode(err) != "invalid_two_factor_code" {
+		t.Errorf("older code: err = %v, want invalid_two_factor_code", err)
+	}
+}
@@ -2,7 +2,7 @@ package database
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
@@ -10,6 +10,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// RunMigrations aplica en orden los archivos .sql de migrationsPath.
+// Cada archivo aplicado queda registrado en schema_migrations para no
+// volver a ejecutarlo en el siguiente arranque.
 func RunMigrations(db *gorm.DB, migrationsPath string) error {
 
 	files, err := filepath.Glob(filepath.Join(migrationsPath, "*.sql"))
@@ -18,29 +21,56 @@ func RunMigrations(db *gorm.DB, migrationsPath string) error {
 	}
 
 	if len(files) == 0 {
-		log.Println("No migration files found in %s", migrationsPath)
+		slog.Warn("no migration files found", "path", migrationsPath)
 		return nil
 	}
 
 	sort.Strings(files)
 
-	log.Println("running migrations...")
+	err = db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		nombre VARCHAR(255) PRIMARY KEY,
+		aplicada_en TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`).Error
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var applied []string
+	if err := db.Raw("SELECT nombre FROM schema_migrations").Scan(&applied).Error; err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	done := make(map[string]bool, len(applied))
+	for _, name := range applied {
+		done[name] = true
+	}
+
+	slog.Info("running migrations")
 
 	for _, file := range files {
 		fileName := filepath.Base(file)
+		if done[fileName] {
+			continue
+		}
 
 		content, err := os.ReadFile(file)
 		if err != nil {
 			return fmt.Errorf("failed to read migration file %s: %w", fileName, err)
 		}
 
-		if err = db.Exec(string(content)).Error; err != nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(string(content)).Error; err != nil {
+				return err
+			}
+			return tx.Exec("INSERT INTO schema_migrations (nombre) VALUES (?)", fileName).Error
+		})
+		if err != nil {
 			return fmt.Errorf("failed to execute migration %s: %w", fileName, err)
 		}
 
-		log.Printf("applied migration: %s", fileName)
+		slog.Info("applied migration", "file", fileName)
 	}
 
-	log.Println("all migrations applied successfully")
+	slog.Info("all migrations applied successfully")
 	return nil
 }
@@ -2,12 +2,13 @@ package database
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
 	"time"
 
+	"github.com/mordmora/expirapp/internal/platform/logger"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 type Config struct {
@@ -18,6 +19,9 @@ type Config struct {
 	DBName   string
 	SSLMode  string
 	TimeZone string
+
+	// SlowQueryThreshold: las consultas más lentas salen en warn
+	SlowQueryThreshold time.Duration
 }
 
 func New(cfg Config) *gorm.DB {
@@ -33,15 +37,22 @@ func New(cfg Config) *gorm.DB {
 	)
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: logger.NewGormLogger(slog.Default(), cfg.SlowQueryThreshold),
 	})
 	if err != nil {
-		log.Fatalf("failed to connect database: %v", err)
+		slog.Error("failed to connect database", "error", err)
+		os.Exit(1)
+	}
+
+	if err := db.Use(tracingPlugin{}); err != nil {
+		slog.Error("failed to register tracing plugin", "error", err)
+		os.Exit(1)
 	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("failed to get database instance: %v", err)
+		slog.Error("failed to get database instance", "error", err)
+		os.Exit(1)
 	}
 
 	sqlDB.SetMaxIdleConns(10)
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mordmora/expirapp/internal/platform/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const parentCtxKey = "expirapp:tracing_parent"
+
+/*
+# tracingPlugin abre un span por cada sentencia SQL que ejecuta gorm
+* cuelga del contexto que recibió la consulta (db.WithContext(ctx)), así la
+* consulta aparece dentro del span del servicio y de la solicitud
+* el texto va con placeholders ($1), nunca con los valores
+*/
+type tracingPlugin struct{}
+
+func (tracingPlugin) Name() string {
+	return "expirapp:tracing"
+}
+
+func (p tracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"select", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+
+	for _, h := range hooks {
+		if err := h.before("expirapp:before_"+h.operation, p.before(h.operation)); err != nil {
+			return err
+		}
+		if err := h.after("expirapp:after_"+h.operation, p.after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tracingPlugin) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		parent := db.Statement.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+
+		ctx, _ := tracing.Start(parent, "sql."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemNamePostgreSQL,
+				semconv.DBOperationName(operation),
+			),
+		)
+
+		// un mismo Statement puede ejecutar varias sentencias (Count y luego
+		// Find); el padre se restaura al terminar para no anidarlas
+		db.InstanceSet(parentCtxKey, parent)
+		db.Statement.Context = ctx
+	}
+}
+
+func (tracingPlugin) after(db *gorm.DB) {
+	parent, ok := db.InstanceGet(parentCtxKey)
+	if !ok {
+		return
+	}
+	span := trace.SpanFromContext(db.Statement.Context)
+	db.Statement.Context = parent.(context.Context)
+	if !span.IsRecording() {
+		span.End()
+		return
+	}
+
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	if db.Statement.Table != "" {
+		span.SetAttributes(semconv.DBCollectionName(db.Statement.Table))
+	}
+
+	var err error
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		err = db.Error
+	}
+	tracing.End(span, err)
+}
@@ -0,0 +1,267 @@
+package gs1
+
+/*
+Este archivo interpreta las cadenas de elementos GS1-128 que entrega un
+lector al escanear la etiqueta de un lote
+*/
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GroupSeparator es el FNC1 que los lectores transmiten como ASCII 29 para
+// cerrar un campo de largo variable
+const GroupSeparator = '\x1d'
+
+const (
+	AIGTIN       = "01"
+	AIBatch      = "10"
+	AIExpiration = "17"
+)
+
+var ErrInvalidElementString = errors.New("gs1: invalid element string")
+
+/*
+# aiLengths son los identificadores de aplicación que se reconocen
+* un largo positivo es fijo; uno negativo es el máximo de un campo variable
+* además de 01/10/17 se reconocen los que suelen acompañarlos en una
+* etiqueta logística, para poder saltarlos
+*/
+var aiLengths = map[string]int{
+	"00": 18,  // SSCC
+	"01": 14,  // GTIN
+	"02": 14,  // GTIN de lo contenido
+	"10": -20, // lote
+	"11": 6,   // fecha de producción
+	"13": 6,   // fecha de empaque
+	"15": 6,   // consumir preferentemente antes de
+	"16": 6,   // vender antes de
+	"17": 6,   // fecha de vencimiento
+	"20": 2,   // variante
+	"21": -20, // serial
+	"30": -8,  // cantidad variable
+	"37": -8,  // unidades contenidas
+}
+
+// Label son los datos de una etiqueta GS1-128
+type Label struct {
+	GTIN       string
+	Batch      string
+	Expiration *time.Time
+	// Elements son todos los campos leídos, por identificador de aplicación
+	Elements map[string]string
+}
+
+// LooksLikeElementString dice si code parece una cadena GS1-128 y no un
+// GTIN o SKU suelto
+func LooksLikeElementString(code string) bool {
+	switch {
+	case strings.HasPrefix(code, "]C1"), strings.HasPrefix(code, "]d2"), strings.HasPrefix(code, "]Q3"):
+		return true
+	case strings.HasPrefix(code, "("), strings.ContainsRune(code, GroupSeparator):
+		return true
+	}
+	return len(code) > 16 && strings.HasPrefix(code, AIGTIN) && IsDigits(code[:16])
+}
+
+/*
+# Parse interpreta una cadena GS1-128
+* acepta la forma cruda del lector (con o sin el prefijo ]C1 y con FNC1
+* como ASCII 29) y la forma legible "(01)...(17)...(10)..."
+* el GTIN se valida y se normaliza a 14 dígitos; la fecha 17 con día 00
+* es el último día del mes, como dice la norma
+*/
+func Parse(code string) (*Label, error) {
+	code = strings.TrimSpace(code)
+	for _, prefix := range []string{"]C1", "]d2", "]Q3"} {
+		code = strings.TrimPrefix(code, prefix)
+	}
+
+	var elements map[string]string
+	var err error
+	if strings.HasPrefix(code, "(") {
+		elements, err = parseHumanReadable(code)
+	} else {
+		elements, err = parseRaw(code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	label := &Label{Elements: elements, Batch: elements[AIBatch]}
+	if gtin, ok := elements[AIGTIN]; ok {
+		label.GTIN, err = NormalizeGTIN(gtin)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if date, ok := elements[AIExpiration]; ok {
+		expiration, err := parseDate(date)
+		if err != nil {
+			return nil, err
+		}
+		label.Expiration = &expiration
+	}
+	return label, nil
+}
+
+func parseRaw(code string) (map[string]string, error) {
+	elements := make(map[string]string)
+	for code != "" {
+		if code[0] == GroupSeparator {
+			code = code[1:]
+			continue
+		}
+		if len(code) < 2 {
+			return nil, fmt.Errorf("%w: truncated application identifier", ErrInvalidElementString)
+		}
+
+		ai := code[:2]
+		length, ok := aiLengths[ai]
+		if !ok {
+			return nil, fmt.Errorf("%w: unsupported application identifier %s", ErrInvalidElementString, ai)
+		}
+		code = code[2:]
+
+		var value string
+		if length > 0 {
+			if len(code) < length {
+				return nil, fmt.Errorf("%w: (%s) needs %d characters", ErrInvalidElementString, ai, length)
+			}
+			value, code = code[:length], code[length:]
+		} else {
+			end := strings.IndexRune(code, GroupSeparator)
+			if end < 0 {
+				end = len(code)
+			}
+			value, code = code[:end], code[end:]
+			if len(value) > -length {
+				return nil, fmt.Errorf("%w: (%s) allows up to %d characters", ErrInvalidElementString, ai, -length)
+			}
+		}
+
+		if err := checkValue(ai, value); err != nil {
+			return nil, err
+		}
+		elements[ai] = value
+	}
+	return elements, nil
+}
+
+func parseHumanReadable(code string) (map[string]string, error) {
+	elements := make(map[string]string)
+	for code != "" {
+		if code[0] != '(' {
+			return nil, fmt.Errorf("%w: expected (", ErrInvalidElementString)
+		}
+		end := strings.IndexByte(code, ')')
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unclosed application identifier", ErrInvalidElementString)
+		}
+
+		ai := code[1:end]
+		length, ok := aiLengths[ai]
+		if !ok {
+			return nil, fmt.Errorf("%w: unsupported application identifier %s", ErrInvalidElementString, ai)
+		}
+		code = code[end+1:]
+
+		next := strings.IndexByte(code, '(')
+		if next < 0 {
+			next = len(code)
+		}
+		value := code[:next]
+		code = code[next:]
+
+		if (length > 0 && len(value) != length) || (length < 0 && len(value) > -length) {
+			return nil, fmt.Errorf("%w: invalid length for (%s)", ErrInvalidElementString, ai)
+		}
+		if err := checkValue(ai, value); err != nil {
+			return nil, err
+		}
+		elements[ai] = value
+	}
+	return elements, nil
+}
+
+// checkValue valida el contenido de los campos numéricos
+func checkValue(ai, value string) error {
+	if value == "" {
+		return fmt.Errorf("%w: (%s) is empty", ErrInvalidElementString, ai)
+	}
+	if length := aiLengths[ai]; length > 0 && !IsDigits(value) {
+		return fmt.Errorf("%w: (%s) must be numeric", ErrInvalidElementString, ai)
+	}
+	return nil
+}
+
+// parseDate lee una fecha AAMMDD; el siglo es el más cercano a hoy dentro
+// de la ventana de la norma (-49/+50 años)
+func parseDate(value string) (time.Time, error) {
+	yy := int(value[0]-'0')*10 + int(value[1]-'0')
+	month := time.Month(int(value[2]-'0')*10 + int(value[3]-'0'))
+	day := int(value[4]-'0')*10 + int(value[5]-'0')
+	if month < 1 || month > 12 || day > 31 {
+		return time.Time{}, fmt.Errorf("%w: invalid date %s", ErrInvalidElementString, value)
+	}
+
+	current := time.Now().Year()
+	year := current/100*100 + yy
+	switch diff := year - current; {
+	case diff > 50:
+		year -= 100
+	case diff < -49:
+		year += 100
+	}
+
+	if day == 0 {
+		// último día del mes
+		return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC), nil
+	}
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if date.Day() != day {
+		return time.Time{}, fmt.Errorf("%w: invalid date %s", ErrInvalidElementString, value)
+	}
+	return date, nil
+}
+
+// Element es un campo de una etiqueta GS1-128
+type Element struct {
+	AI    string
+	Value string
+}
+
+/*
+# Encode arma la cadena cruda de elements, como la transmite un lector
+* después de cada campo de largo variable que no sea el último va un
+* GroupSeparator; conviene dejar los variables (como el lote) al final
+*/
+func Encode(elements []Element) string {
+	var b strings.Builder
+	for i, e := range elements {
+		b.WriteString(e.AI)
+		b.WriteString(e.Value)
+		if aiLengths[e.AI] < 0 && i < len(elements)-1 {
+			b.WriteByte(GroupSeparator)
+		}
+	}
+	return b.String()
+}
+
+// HumanReadable arma la forma legible "(01)...(17)...(10)..." que se
+// imprime bajo las barras
+func HumanReadable(elements []Element) string {
+	var b strings.Builder
+	for _, e := range elements {
+		b.WriteString("(" + e.AI + ")" + e.Value)
+	}
+	return b.String()
+}
+
+// FormatDate escribe una fecha como AAMMDD
+func FormatDate(t time.Time) string {
+	return t.Format("060102")
+}
@@ -0,0 +1,122 @@
+package gs1
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	gs := string(GroupSeparator)
+
+	tests := []struct {
+		name       string
+		code       string
+		gtin       string
+		batch      string
+		expiration time.Time
+	}{
+		{
+			name:       "raw with symbology prefix",
+			code:       "]C10109506000134352" + "17261231" + "10ABC123",
+			gtin:       "09506000134352",
+			batch:      "ABC123",
+			expiration: time.Date(2026, time.December, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "raw with batch first",
+			code:       "10ABC123" + gs + "0109506000134352" + "17261231",
+			gtin:       "09506000134352",
+			batch:      "ABC123",
+			expiration: time.Date(2026, time.December, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "human readable",
+			code:       "(01)09506000134352(17)280315(10)L-7",
+			gtin:       "09506000134352",
+			batch:      "L-7",
+			expiration: time.Date(2028, time.March, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "day 00 is the last day of the month",
+			code:       "(01)09506000134352(17)280200",
+			gtin:       "09506000134352",
+			expiration: time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "other identifiers are skipped",
+			code:  "0109506000134352" + "11250101" + "21SER1" + gs + "10B1",
+			gtin:  "09506000134352",
+			batch: "B1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			label, err := Parse(tt.code)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if label.GTIN != tt.gtin || label.Batch != tt.batch {
+				t.Errorf("GTIN, batch = %q, %q, want %q, %q", label.GTIN, label.Batch, tt.gtin, tt.batch)
+			}
+			switch {
+			case tt.expiration.IsZero() && label.Expiration != nil:
+				t.Errorf("expiration = %v, want none", label.Expiration)
+			case !tt.expiration.IsZero() && (label.Expiration == nil || !label.Expiration.Equal(tt.expiration)):
+				t.Errorf("expiration = %v, want %v", label.Expiration, tt.expiration)
+			}
+		})
+	}
+}
+
+func TestParseRejectsMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		err  error
+	}{
+		{"truncated GTIN", "01095060001343", ErrInvalidElementString},
+		{"truncated identifier", "0109506000134352" + "1", ErrInvalidElementString},
+		{"unknown identifier", "99ABC", ErrInvalidElementString},
+		{"wrong check digit", "0109506000134353", ErrInvalidGTIN},
+		{"non-numeric date", "171231AB", ErrInvalidElementString},
+		{"month 13", "17261331", ErrInvalidElementString},
+		{"february 30", "17260230", ErrInvalidElementString},
+		{"batch too long", "10ABCDEFGHIJKLMNOPQRSTU", ErrInvalidElementString},
+		{"empty batch", "(10)", ErrInvalidElementString},
+		{"unclosed identifier", "(01", ErrInvalidElementString},
+		{"text before identifier", "(01)09506000134352x(10)A", ErrInvalidElementString},
+		{"short human readable date", "(17)2612", ErrInvalidElementString},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.code); !errors.Is(err, tt.err) {
+				t.Errorf("Parse(%q) err = %v, want %v", tt.code, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	expiration := time.Date(2027, time.January, 9, 0, 0, 0, 0, time.UTC)
+	elements := []Element{
+		{AIGTIN, "09506000134352"},
+		{AIBatch, "LOTE-1"},
+		{AIExpiration, FormatDate(expiration)},
+	}
+
+	for _, code := range []string{Encode(elements), HumanReadable(elements)} {
+		if !LooksLikeElementString(code) {
+			t.Errorf("LooksLikeElementString(%q) = false", code)
+		}
+		label, err := Parse(code)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", code, err)
+		}
+		if label.GTIN != "09506000134352" || label.Batch != "LOTE-1" || label.Expiration == nil || !label.Expiration.Equal(expiration) {
+			t.Errorf("Parse(%q) = %+v", code, label)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package gs1
+
+/*
+Este archivo valida y normaliza GTIN (EAN-8, UPC-A, EAN-13 y GTIN-14)
+*/
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidGTIN = errors.New("gs1: invalid GTIN")
+
+// IsDigits dice si s no está vacío y solo tiene dígitos
+func IsDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsGTINLength dice si n es el largo de algún GTIN
+func IsGTINLength(n int) bool {
+	return n == 8 || n == 12 || n == 13 || n == 14
+}
+
+/*
+# CheckDigit calcula el dígito de control de los dígitos sin él
+* desde la derecha los pesos alternan 3 y 1; el dígito completa la suma
+* al siguiente múltiplo de 10
+*/
+func CheckDigit(digits string) byte {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// ValidGTIN dice si code es un GTIN de largo válido con el dígito de control correcto
+func ValidGTIN(code string) bool {
+	if !IsDigits(code) || !IsGTINLength(len(code)) {
+		return false
+	}
+	return CheckDigit(code[:len(code)-1]) == code[len(code)-1]
+}
+
+/*
+# NormalizeGTIN lleva un GTIN a 14 dígitos con ceros a la izquierda
+* así un EAN-13 impreso en el producto y el GTIN-14 de una etiqueta
+* GS1-128 del mismo artículo son la misma clave
+*/
+func NormalizeGTIN(code string) (string, error) {
+	code = strings.TrimSpace(code)
+	if !ValidGTIN(code) {
+		return "", ErrInvalidGTIN
+	}
+	return strings.Repeat("0", 14-len(code)) + code, nil
+}
+
+// ShortGTIN quita los ceros de relleno de un GTIN-14 para imprimirlo como
+// EAN-13 (o EAN-8) cuando se puede
+func ShortGTIN(gtin string) string {
+	switch {
+	case len(gtin) == 14 && strings.HasPrefix(gtin, "000000"):
+		return gtin[6:]
+	case len(gtin) == 14 && gtin[0] == '0':
+		return gtin[1:]
+	}
+	return gtin
+}
@@ -0,0 +1,92 @@
+package gs1
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckDigit(t *testing.T) {
+	tests := []struct {
+		name string
+		gtin string
+	}{
+		{"GTIN-8", "96385074"},
+		{"GTIN-12", "036000291452"},
+		{"GTIN-13", "4006381333931"},
+		{"GTIN-13 with zero check digit", "5901234123457"},
+		{"GTIN-14", "10012345678902"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, want := tt.gtin[:len(tt.gtin)-1], tt.gtin[len(tt.gtin)-1]
+			if got := CheckDigit(body); got != want {
+				t.Errorf("CheckDigit(%s) = %c, want %c", body, got, want)
+			}
+			if !ValidGTIN(tt.gtin) {
+				t.Errorf("ValidGTIN(%s) = false", tt.gtin)
+			}
+		})
+	}
+}
+
+func TestValidGTINRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"wrong check digit", "4006381333932"},
+		{"unsupported length", "400638133393"},
+		{"too long", "400638133393100"},
+		{"letters", "40063813339A1"},
+		{"empty", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ValidGTIN(tt.code) {
+				t.Errorf("ValidGTIN(%q) = true", tt.code)
+			}
+		})
+	}
+}
+
+func TestNormalizeGTIN(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+		err  error
+	}{
+		{"96385074", "00000096385074", nil},
+		{"036000291452", "00036000291452", nil},
+		{" 4006381333931 ", "04006381333931", nil},
+		{"10012345678902", "10012345678902", nil},
+		{"4006381333932", "", ErrInvalidGTIN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			got, err := NormalizeGTIN(tt.code)
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Errorf("NormalizeGTIN(%q) = (%q, %v), want (%q, %v)", tt.code, got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestShortGTIN(t *testing.T) {
+	tests := []struct {
+		gtin string
+		want string
+	}{
+		{"00000096385074", "96385074"},
+		{"04006381333931", "4006381333931"},
+		{"10012345678902", "10012345678902"},
+	}
+
+	for _, tt := range tests {
+		if got := ShortGTIN(tt.gtin); got != tt.want {
+			t.Errorf("ShortGTIN(%s) = %s, want %s", tt.gtin, got, tt.want)
+		}
+	}
+}
@@ -1 +1,291 @@
 package http
+
+/*
+Este archivo define el sobre JSON común de las respuestas y traduce los
+errores de dominio a estados HTTP
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	nethttp "net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/platform/i18n"
+	"github.com/mordmora/expirapp/internal/platform/logger"
+)
+
+/*
+# Envelope es la forma de toda respuesta JSON del API
+* Data y Message en las respuestas exitosas
+* Error en las fallidas
+* RequestID para cruzar la respuesta con el log
+*/
+type Envelope struct {
+	Data      any        `json:"data,omitempty"`
+	Message   string     `json:"message,omitempty"`
+	Error     *ErrorBody `json:"error,omitempty"`
+	RequestID string     `json:"request_id,omitempty"`
+}
+
+/*
+# ErrorBody
+* Code es estable y sirve para decidir en el cliente; Message es para mostrar
+* Fields solo viene en los errores de validación
+*/
+type ErrorBody struct {
+	Code    string              `json:"code"`
+	Message string              `json:"message"`
+	Fields  []domain.FieldError `json:"fields,omitempty"`
+}
+
+// kinds asocia cada error base con su estado y su código por defecto
+var kinds = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{domain.ErrValidation, nethttp.StatusBadRequest, "validation_error"},
+	{domain.ErrNotFound, nethttp.StatusNotFound, "not_found"},
+	{domain.ErrConflict, nethttp.StatusConflict, "conflict"},
+	{domain.ErrUnprocessable, nethttp.StatusUnprocessableEntity, "unprocessable_entity"},
+	{domain.ErrInsufficientStock, nethttp.StatusConflict, "insufficient_stock"},
+	{domain.ErrUnauthorized, nethttp.StatusUnauthorized, "unauthorized"},
+	{domain.ErrForbidden, nethttp.StatusForbidden, "forbidden"},
+	{domain.ErrGone, nethttp.StatusGone, "gone"},
+	{domain.ErrTooManyRequests, nethttp.StatusTooManyRequests, "too_many_requests"},
+	{domain.ErrUnavailable, nethttp.StatusServiceUnavailable, "service_unavailable"},
+}
+
+/*
+# Respond escribe una respuesta exitosa
+* message es una clave de los catálogos de i18n; se traduce al idioma de
+* la solicitud
+*/
+func Respond(c *gin.Context, status int, data any, message string) {
+	if message != "" {
+		message = i18n.Message(i18n.Language(c.Request.Context()), message)
+	}
+	c.JSON(status, Envelope{
+		Data:      data,
+		Message:   message,
+		RequestID: logger.RequestID(c.Request.Context()),
+	})
+}
+
+func OK(c *gin.Context, data any) {
+	Respond(c, nethttp.StatusOK, data, "")
+}
+
+func Created(c *gin.Context, data any, message string) {
+	Respond(c, nethttp.StatusCreated, data, message)
+}
+
+// StatusClientClosedRequest es el estado (el 499 de nginx) de una solicitud
+// cuyo cliente cortó la conexión antes de la respuesta
+const StatusClientClosedRequest = 499
+
+/*
+# Error responde con el estado que corresponde a err
+* los *domain.Error y los errores base usan su código y mensaje, traducido
+* al idioma de la solicitud
+* si el cliente se desconectó, la consulta se cancela con su contexto; eso
+* no es una falla del servidor y sale como 499 sin cuerpo
+* cualquier otro error es un 500: el detalle va al log, no al cliente
+*/
+func Error(c *gin.Context, err error) {
+	if errors.Is(err, context.Canceled) && c.Request.Context().Err() != nil {
+		_ = c.Error(err)
+		c.AbortWithStatus(StatusClientClosedRequest)
+		return
+	}
+
+	status, body, retryAfter := describe(err, i18n.Language(c.Request.Context()))
+
+	if status >= nethttp.StatusInternalServerError {
+		slog.ErrorContext(c.Request.Context(), "request failed",
+			"error", err,
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+		)
+	}
+	if retryAfter > 0 {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(max(seconds, 1)))
+	}
+
+	_ = c.Error(err)
+	c.JSON(status, Envelope{
+		Error:     body,
+		RequestID: logger.RequestID(c.Request.Context()),
+	})
+}
+
+// Abort es Error para los middlewares: además corta la cadena de handlers
+func Abort(c *gin.Context, err error) {
+	Error(c, err)
+	c.Abort()
+}
+
+func describe(err error, lang string) (int, *ErrorBody, time.Duration) {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		for _, kind := range kinds {
+			if errors.Is(domainErr.Kind, kind.err) {
+				code := domainErr.Code
+				if code == "" {
+					code = kind.code
+				}
+				return kind.status, &ErrorBody{
+					Code:    code,
+					Message: translate(lang, code, domainErr.Args, domainErr.Message),
+					Fields:  translateFields(lang, domainErr.Fields),
+				}, domainErr.RetryAfter
+			}
+		}
+	}
+
+	for _, kind := range kinds {
+		if errors.Is(err, kind.err) {
+			return kind.status, &ErrorBody{Code: kind.code, Message: translate(lang, kind.code, nil, err.Error())}, 0
+		}
+	}
+
+	return nethttp.StatusInternalServerError, &ErrorBody{
+		Code:    "internal_error",
+		Message: translate(lang, "internal_error", nil, "error interno del servidor"),
+	}, 0
+}
+
+// translate busca code en el catálogo de lang; sin entrada deja fallback
+func translate(lang, code string, args []any, fallback string) string {
+	if msg, ok := i18n.ErrorMessage(lang, code, args); ok {
+		return msg
+	}
+	return fallback
+}
+
+func translateFields(lang string, fields []domain.FieldError) []domain.FieldError {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	translated := make([]domain.FieldError, len(fields))
+	for i, field := range fields {
+		translated[i] = field
+		if field.Args != nil {
+			translated[i].Message = translate(lang, field.Code, field.Args, field.Message)
+		}
+	}
+	return translated
+}
+
+/*
+# BindError responde 400 ante un body o una query que no se pudo leer
+* los errores del validador salen campo por campo con el nombre JSON
+*/
+func BindError(c *gin.Context, err error) {
+	Error(c, BindingError(err, i18n.Language(c.Request.Context())))
+}
+
+/*
+# BindingError convierte el error de ShouldBind* en un *domain.Error
+* los mensajes del validador salen ya traducidos a lang
+*/
+func BindingError(err error, lang string) *domain.Error {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]domain.FieldError, len(validationErrs))
+		for i, fe := range validationErrs {
+			fields[i] = domain.FieldError{
+				Field:   fieldPath(fe),
+				Code:    fe.Tag(),
+				Message: i18n.FieldMessage(lang, fe),
+			}
+		}
+		domainErr := domain.Validation("invalid_request", "la solicitud tiene campos inválidos")
+		domainErr.Fields = fields
+		return domainErr
+
+	case errors.As(err, &typeErr):
+		domainErr := domain.InvalidField(typeErr.Field, "invalid_type",
+			"%s debe ser de tipo %s", typeErr.Field, jsonType(typeErr.Type))
+		return domainErr
+
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return domain.Validation("malformed_json", "el body no es un JSON válido")
+
+	case errors.Is(err, io.EOF):
+		return domain.Validation("empty_body", "el body es obligatorio")
+
+	default:
+		return domain.Validation("invalid_request", "la solicitud tiene campos inválidos")
+	}
+}
+
+// fieldPath quita el nombre del struct raíz: "CreateOrderRequest.items[0].cantidad" -> "items[0].cantidad"
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if _, rest, found := strings.Cut(namespace, "."); found {
+		return rest
+	}
+	return fe.Field()
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	default:
+		return "number"
+	}
+}
+
+/*
+# RegisterValidator hace que el validador de gin reporte los campos con su
+# nombre JSON (o el de la query) en vez del nombre Go, y le carga las
+# traducciones de los mensajes
+*/
+func RegisterValidator() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form", "uri"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+	if err := i18n.RegisterValidator(v); err != nil {
+		slog.Warn("validator messages will not be translated", "error", err)
+	}
+}
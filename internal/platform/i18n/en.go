@@ -0,0 +1,269 @@
+package i18n
+
+// english es para el panel de administración y los integradores
+var english = catalog{
+	errors: map[string]string{
+		// genéricos
+		"internal_error":       "internal server error",
+		"validation_error":     "the request is not valid",
+		"not_found":            "resource not found",
+		"conflict":             "the operation conflicts with the current state",
+		"unprocessable_entity": "the request cannot be processed",
+		"unauthorized":         "unauthorized",
+		"forbidden":            "access denied",
+		"gone":                 "the resource is no longer available",
+		"too_many_requests":    "too many requests, try again later",
+		"rate_limited":         "too many requests, try again in %d seconds",
+		"service_unavailable":  "service unavailable",
+
+		// solicitud y campos
+		"invalid_request":          "the request has invalid fields",
+		"malformed_json":           "the body is not valid JSON",
+		"empty_body":               "the body is required",
+		"invalid_type":             "%s must be of type %s",
+		"invalid_field":            "%s is not valid (%s)",
+		"invalid_id":               "%s must be a valid number",
+		"required":                 "%s is required",
+		"must_be_positive":         "%s must be greater than zero",
+		"invalid_date":             "the date must use the YYYY-MM-DD format",
+		"invalid_date_range":       "fecha_fin must be after fecha_inicio",
+		"invalid_price_range":      "precio_max must be greater than or equal to precio_min",
+		"invalid_expiration_range": "vence_hasta must be after vence_desde",
+		"expiration_in_past":       "the date must be in the future",
+		"invalid_number":           "%s must be a number",
+		"invalid_format":           "formato must be json or zip",
+		"out_of_range":             "the discount percentage must be between 0 and 100",
+
+		// autenticación
+		"missing_credentials":               "missing access token",
+		"invalid_token":                     "invalid or expired token",
+		"token_scope_invalid":               "the token is not valid for this operation",
+		"insufficient_permissions":          "insufficient permissions",
+		"invalid_credentials":               "invalid email or password",
+		"email_not_verified":                "email not verified",
+		"email_in_use":                      "email already in use",
+		"wrong_password":                    "current password is incorrect",
+		"too_many_attempts":                 "too many failed attempts, try again later",
+		"lockout_not_found":                 "no failed attempts for this ip",
+		"invalid_two_factor_code":           "invalid two-factor code",
+		"two_factor_already_enabled":        "two-factor authentication is already enabled",
+		"two_factor_not_enabled":            "two-factor authentication is not enabled",
+		"two_factor_enrollment_not_started": "two-factor enrollment not started",
+		"sso_not_configured":                "single sign-on is not configured",
+		"sso_login_failed":                  "could not sign in with the identity provider",
+		"sso_email_missing":                 "the identity provider did not return an email",
+		"invalid_sso_state":                 "invalid or expired state",
+		"user_anonymized":                   "the user's data was anonymized",
+
+		// claves de API
+		"invalid_api_key":           "invalid api key",
+		"api_key_not_found":         "api key not found",
+		"api_key_inactive":          "the api key is not active",
+		"api_key_revoked":           "the api key is revoked",
+		"api_key_rotated":           "the api key was already rotated",
+		"api_key_ip_denied":         "the api key is not allowed from this ip",
+		"api_key_permission_denied": "the api key lacks the %s:%s permission",
+		"invalid_permission":        "invalid permission %q",
+		"invalid_ip_range":          "invalid ip range %q",
+
+		// perfiles
+		"user_not_found":    "user not found",
+		"client_not_found":  "client with id %d not found",
+		"seller_not_found":  "seller with id %d not found",
+		"admin_not_found":   "admin not found",
+		"already_client":    "the user is already a client",
+		"already_seller":    "the user is already a seller",
+		"already_admin":     "the user is already an admin",
+		"client_has_orders": "the client has orders and cannot be deleted",
+		"seller_has_orders": "the seller has orders and cannot be deleted",
+
+		// catálogo, carrito y órdenes
+		"product_not_found":              "product %v not found",
+		"insufficient_stock":             "insufficient stock for product %s (id: %d). Available: %d, requested: %d",
+		"invalid_gtin":                   "the GTIN must have 8, 12, 13 or 14 digits with a correct check digit",
+		"invalid_sku":                    "the SKU may only contain letters, digits, dots, hyphens and underscores, and cannot look like a GTIN",
+		"gtin_taken":                     "a product with GTIN %s already exists",
+		"sku_taken":                      "a product with SKU %s already exists",
+		"invalid_gs1_label":              "the GS1-128 label could not be parsed",
+		"gs1_without_gtin":               "the GS1-128 label has no GTIN (01)",
+		"lot_expiration_mismatch":        "lot %s already exists with expiration %s",
+		"lot_not_found":                  "lot %v not found",
+		"invalid_label_format":           "formato must be pdf or zpl",
+		"invalid_copies":                 "copias must be between 1 and %d",
+		"labels_empty":                   "at least one product or lot is required",
+		"too_many_labels":                "%d labels requested; the maximum per print is %d",
+		"invalid_sheet_format":           "formato must be csv or xlsx",
+		"invalid_sheet":                  "the file is not a valid %s",
+		"import_too_large":               "the file exceeds the %d MB limit",
+		"import_empty":                   "the file has no header row",
+		"import_missing_columns":         "missing columns: %s",
+		"too_many_rows":                  "the file exceeds the %d row limit",
+		"malformed_row":                  "the row could not be read",
+		"invalid_sheet_date":             "%s must be a YYYY-MM-DD or DD/MM/YYYY date, or an Excel date",
+		"invalid_integer":                "%s must be a whole number",
+		"duplicate_in_file":              "%s %s already appears in row %d",
+		"import_not_found":               "import %v not found",
+		"import_failed":                  "the import could not be completed",
+		"price_unchanged":                "the product already has price %v",
+		"price_already_scheduled":        "a price change is already scheduled for %s",
+		"scheduled_price_not_found":      "scheduled price change %d not found",
+		"scheduled_price_not_pending":    "scheduled price change %d is no longer pending",
+		"schedule_in_past":               "vigente_desde must be a future date",
+		"supplier_not_found":             "supplier %v not found",
+		"supplier_nit_taken":             "NIT %s already belongs to supplier %v",
+		"supplier_inactive":              "supplier %v is inactive",
+		"purchase_order_not_found":       "purchase order %v not found",
+		"purchase_order_not_editable":    "purchase order %v is no longer a draft",
+		"purchase_order_not_sendable":    "purchase order %v is %s and cannot be sent",
+		"purchase_order_not_receivable":  "purchase order %v is %s and cannot receive goods",
+		"purchase_order_not_cancellable": "purchase order %v is %s and cannot be cancelled",
+		"duplicate_order_line":           "product %v appears in more than one line",
+		"purchase_line_not_found":        "line %v does not belong to purchase order %v",
+		"receipt_exceeds_pending":        "line %v has %d units pending",
+		"receipt_in_future":              "recibida_en cannot be a future date",
+		"receipt_before_sent":            "the receipt cannot be earlier than the order was sent",
+		"receipt_not_found":              "receipt %v not found",
+		"database_unavailable":           "the database is unavailable",
+		"idempotency_requires_auth":      "Idempotency-Key is only accepted on authenticated requests",
+		"cart_not_found":                 "cart not found",
+		"cart_item_not_found":            "cart item not found",
+		"cart_empty":                     "the cart is empty",
+		"order_not_found":                "order with id %d not found",
+		"order_item_not_found":           "order item not found",
+
+		// categorías
+		"category_not_found":      "category with id %d not found",
+		"category_slug_taken":     "a category with slug %s already exists",
+		"invalid_slug":            "the slug may only contain lowercase letters, digits and hyphens",
+		"category_cycle":          "a category cannot be placed inside itself or one of its subcategories",
+		"category_has_children":   "the category has subcategories and cannot be deleted",
+		"category_has_promotions": "the category has promotions and cannot be deleted",
+		"duplicate_markdown_rule": "there are two markdown rules for %d days before expiration",
+		"principal_not_assigned":  "the principal category must be one of the product's categories",
+
+		// promociones
+		"promotion_not_found":      "promotion not found",
+		"invalid_promotion_type":   "unknown promotion type: %s",
+		"invalid_quantities":       "cantidad_compra and cantidad_gratis must be greater than zero",
+		"promotion_without_coupon": "coupons can only be created for promotions that require one",
+		"promotion_scope_conflict": "a promotion applies to a product or to a category, not both",
+		"coupon_not_found":         "coupon %v does not exist",
+		"coupon_exists":            "a coupon with code %s already exists",
+		"coupon_inactive":          "coupon %s is not active",
+		"coupon_expired":           "coupon %s is not currently valid",
+		"coupon_exhausted":         "coupon %s reached its usage limit",
+		"coupon_client_limit":      "the client already used coupon %s the maximum number of times",
+
+		// pagos
+		"payment_not_found":        "payment not found",
+		"payment_method_not_found": "payment method %v not found",
+		"payment_method_exists":    "a payment method with that name already exists",
+		"amount_exceeds_balance":   "the amount exceeds the balance due. Requested: %.2f, due: %.2f",
+		"amount_exceeds_total":     "the new amount would exceed the order total. Order total: %.2f, new total paid: %.2f",
+		"transaction_id_required":  "transaction ID is required",
+		"signature_required":       "signature is required",
+		"empty_payload":            "empty payload",
+		"gateway_not_implemented":  "gateway type '%s' is not implemented yet",
+		"unknown_gateway":          "unknown gateway type: %s",
+
+		// reseñas
+		"review_not_found": "review not found",
+		"review_exists":    "the client already reviewed this product",
+
+		// claves de idempotencia
+		"invalid_idempotency_key":         "Idempotency-Key must have between 1 and 255 printable characters",
+		"idempotency_key_reused":          "the idempotency key was already used with a different request",
+		"idempotency_request_in_progress": "a request with this idempotency key is still being processed",
+	},
+
+	messages: map[string]string{
+		"user_created":             "user created successfully",
+		"user_updated":             "user updated successfully",
+		"user_deleted":             "user deleted successfully",
+		"user_unlocked":            "user unlocked successfully",
+		"ip_unlocked":              "ip unlocked successfully",
+		"password_changed":         "password changed successfully",
+		"password_reset":           "password reset successfully",
+		"password_reset_requested": "if the email exists, a reset link was sent",
+		"email_verified":           "email verified successfully",
+		"verification_resent":      "if the email is pending verification, a link was sent",
+		"two_factor_enabled":       "two-factor authentication enabled successfully",
+		"two_factor_disabled":      "two-factor authentication disabled successfully",
+
+		"client_created": "client created successfully",
+		"client_updated": "client updated successfully",
+		"client_deleted": "client deleted successfully",
+		"seller_created": "seller created successfully",
+		"seller_updated": "seller updated successfully",
+		"seller_deleted": "seller deleted successfully",
+		"admin_created":  "admin created successfully",
+		"admin_updated":  "admin updated successfully",
+		"admin_deleted":  "admin deleted successfully",
+
+		"api_key_created": "api key created successfully",
+		"api_key_updated": "api key updated successfully",
+		"api_key_rotated": "api key rotated successfully",
+		"api_key_revoked": "api key revoked successfully",
+
+		"consent_recorded":     "consent recorded successfully",
+		"user_data_anonymized": "user data anonymized successfully",
+
+		"product_created":           "product created successfully",
+		"product_updated":           "product updated successfully",
+		"product_deleted":           "product deleted successfully",
+		"stock_updated":             "stock updated successfully",
+		"lot_received":              "lot received",
+		"products_imported":         "products imported",
+		"import_validated":          "file validated; no changes were saved",
+		"import_queued":             "import queued",
+		"price_changed":             "price updated",
+		"price_scheduled":           "price change scheduled",
+		"scheduled_price_cancelled": "scheduled price change cancelled",
+		"supplier_created":          "supplier created",
+		"supplier_updated":          "supplier updated",
+		"purchase_order_created":    "purchase order created",
+		"purchase_order_updated":    "purchase order updated",
+		"purchase_order_sent":       "purchase order sent",
+		"purchase_order_cancelled":  "purchase order cancelled",
+		"goods_received":            "goods received",
+		"api_running":               "API v1 is working",
+		"database_ok":               "the database is reachable",
+
+		"category_created":           "category created",
+		"category_updated":           "category updated",
+		"category_deleted":           "category deleted",
+		"product_categories_updated": "product categories updated",
+
+		"cart_item_added":   "item added to cart successfully",
+		"cart_item_updated": "cart item updated successfully",
+		"cart_item_removed": "cart item removed successfully",
+		"cart_cleared":      "cart cleared successfully",
+		"coupon_applied":    "coupon applied successfully",
+		"coupon_removed":    "coupon removed successfully",
+
+		"order_created":      "order created successfully",
+		"order_updated":      "order updated successfully",
+		"order_deleted":      "order deleted successfully",
+		"order_item_added":   "item added to order successfully",
+		"order_item_updated": "order item updated successfully",
+		"order_item_deleted": "order item deleted successfully",
+
+		"promotion_created": "promotion created successfully",
+		"promotion_updated": "promotion updated successfully",
+		"promotion_deleted": "promotion deleted successfully",
+		"coupon_created":    "coupon created successfully",
+		"coupon_updated":    "coupon updated successfully",
+		"coupon_deleted":    "coupon deleted successfully",
+
+		"payment_created":        "payment created successfully",
+		"payment_updated":        "payment updated successfully",
+		"payment_deleted":        "payment deleted successfully",
+		"payment_method_created": "payment method created successfully",
+		"payment_method_updated": "payment method updated successfully",
+		"payment_method_deleted": "payment method deleted successfully",
+
+		"review_created": "review created successfully",
+		"review_updated": "review updated successfully",
+		"review_deleted": "review deleted successfully",
+	},
+}
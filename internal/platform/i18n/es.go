@@ -0,0 +1,269 @@
+package i18n
+
+// spanish es el idioma de la app de clientes y el idioma por defecto
+var spanish = catalog{
+	errors: map[string]string{
+		// genéricos
+		"internal_error":       "error interno del servidor",
+		"validation_error":     "la solicitud no es válida",
+		"not_found":            "recurso no encontrado",
+		"conflict":             "la operación entra en conflicto con el estado actual",
+		"unprocessable_entity": "la solicitud no se puede procesar",
+		"unauthorized":         "no autorizado",
+		"forbidden":            "acceso denegado",
+		"gone":                 "el recurso ya no está disponible",
+		"too_many_requests":    "demasiadas solicitudes, intenta más tarde",
+		"rate_limited":         "demasiadas solicitudes, intenta de nuevo en %d segundos",
+		"service_unavailable":  "servicio no disponible",
+
+		// solicitud y campos
+		"invalid_request":          "la solicitud tiene campos inválidos",
+		"malformed_json":           "el body no es un JSON válido",
+		"empty_body":               "el body es obligatorio",
+		"invalid_type":             "%s debe ser de tipo %s",
+		"invalid_field":            "%s no es válido (%s)",
+		"invalid_id":               "%s debe ser un número válido",
+		"required":                 "%s es obligatorio",
+		"must_be_positive":         "%s debe ser mayor a cero",
+		"invalid_date":             "la fecha debe tener el formato AAAA-MM-DD",
+		"invalid_date_range":       "fecha_fin debe ser posterior a fecha_inicio",
+		"invalid_price_range":      "precio_max debe ser mayor o igual a precio_min",
+		"invalid_expiration_range": "vence_hasta debe ser posterior a vence_desde",
+		"expiration_in_past":       "la fecha debe ser futura",
+		"invalid_number":           "%s debe ser un número",
+		"invalid_format":           "formato debe ser json o zip",
+		"out_of_range":             "el porcentaje de descuento debe estar entre 0 y 100",
+
+		// autenticación
+		"missing_credentials":               "falta el token de acceso",
+		"invalid_token":                     "token inválido o vencido",
+		"token_scope_invalid":               "el token no sirve para esta operación",
+		"insufficient_permissions":          "permisos insuficientes",
+		"invalid_credentials":               "correo o contraseña incorrectos",
+		"email_not_verified":                "el correo no ha sido verificado",
+		"email_in_use":                      "el correo ya está registrado",
+		"wrong_password":                    "la contraseña actual es incorrecta",
+		"too_many_attempts":                 "demasiados intentos fallidos, intenta más tarde",
+		"lockout_not_found":                 "no hay intentos fallidos para esta ip",
+		"invalid_two_factor_code":           "código de verificación inválido",
+		"two_factor_already_enabled":        "la autenticación en dos pasos ya está activa",
+		"two_factor_not_enabled":            "la autenticación en dos pasos no está activa",
+		"two_factor_enrollment_not_started": "no se ha iniciado la activación de la autenticación en dos pasos",
+		"sso_not_configured":                "el inicio de sesión corporativo no está configurado",
+		"sso_login_failed":                  "no se pudo iniciar sesión con el proveedor de identidad",
+		"sso_email_missing":                 "el proveedor de identidad no devolvió un correo",
+		"invalid_sso_state":                 "state inválido o vencido",
+		"user_anonymized":                   "los datos del usuario fueron anonimizados",
+
+		// claves de API
+		"invalid_api_key":           "clave de API inválida",
+		"api_key_not_found":         "clave de API no encontrada",
+		"api_key_inactive":          "la clave de API no está activa",
+		"api_key_revoked":           "la clave de API está revocada",
+		"api_key_rotated":           "la clave de API ya fue rotada",
+		"api_key_ip_denied":         "la clave de API no está permitida desde esta ip",
+		"api_key_permission_denied": "la clave de API no tiene el permiso %s:%s",
+		"invalid_permission":        "permiso inválido %q",
+		"invalid_ip_range":          "rango de ip inválido %q",
+
+		// perfiles
+		"user_not_found":    "usuario no encontrado",
+		"client_not_found":  "cliente con id %d no encontrado",
+		"seller_not_found":  "vendedor con id %d no encontrado",
+		"admin_not_found":   "administrador no encontrado",
+		"already_client":    "el usuario ya es cliente",
+		"already_seller":    "el usuario ya es vendedor",
+		"already_admin":     "el usuario ya es administrador",
+		"client_has_orders": "el cliente tiene órdenes y no se puede eliminar",
+		"seller_has_orders": "el vendedor tiene órdenes y no se puede eliminar",
+
+		// catálogo, carrito y órdenes
+		"product_not_found":              "producto %v no encontrado",
+		"insufficient_stock":             "stock insuficiente para el producto %s (id: %d). Disponible: %d, solicitado: %d",
+		"invalid_gtin":                   "el GTIN debe tener 8, 12, 13 o 14 dígitos con su dígito de control correcto",
+		"invalid_sku":                    "el SKU solo puede tener letras, números, puntos, guiones y guiones bajos, y no puede tener la forma de un GTIN",
+		"gtin_taken":                     "ya existe un producto con el GTIN %s",
+		"sku_taken":                      "ya existe un producto con el SKU %s",
+		"invalid_gs1_label":              "la etiqueta GS1-128 no se pudo interpretar",
+		"gs1_without_gtin":               "la etiqueta GS1-128 no trae GTIN (01)",
+		"lot_expiration_mismatch":        "el lote %s ya existe con vencimiento %s",
+		"lot_not_found":                  "lote %v no encontrado",
+		"invalid_label_format":           "formato debe ser pdf o zpl",
+		"invalid_copies":                 "copias debe estar entre 1 y %d",
+		"labels_empty":                   "indica al menos un producto o un lote",
+		"too_many_labels":                "se pidieron %d etiquetas; el máximo por impresión es %d",
+		"invalid_sheet_format":           "formato debe ser csv o xlsx",
+		"invalid_sheet":                  "el archivo no es un %s válido",
+		"import_too_large":               "el archivo supera el máximo de %d MB",
+		"import_empty":                   "el archivo no tiene encabezado",
+		"import_missing_columns":         "faltan las columnas: %s",
+		"too_many_rows":                  "el archivo supera el máximo de %d filas",
+		"malformed_row":                  "la fila no se pudo leer",
+		"invalid_sheet_date":             "%s debe ser una fecha AAAA-MM-DD, DD/MM/AAAA o una fecha de Excel",
+		"invalid_integer":                "%s debe ser un número entero",
+		"duplicate_in_file":              "%s %s ya aparece en la fila %d",
+		"import_not_found":               "importación %v no encontrada",
+		"import_failed":                  "la importación no se pudo completar",
+		"price_unchanged":                "el producto ya tiene el precio %v",
+		"price_already_scheduled":        "ya hay un cambio de precio programado para %s",
+		"scheduled_price_not_found":      "cambio de precio programado %d no encontrado",
+		"scheduled_price_not_pending":    "el cambio de precio programado %d ya no está pendiente",
+		"schedule_in_past":               "vigente_desde debe ser una fecha futura",
+		"supplier_not_found":             "proveedor %v no encontrado",
+		"supplier_nit_taken":             "el NIT %s ya es del proveedor %v",
+		"supplier_inactive":              "el proveedor %v está inactivo",
+		"purchase_order_not_found":       "orden de compra %v no encontrada",
+		"purchase_order_not_editable":    "la orden de compra %v ya no está en borrador",
+		"purchase_order_not_sendable":    "la orden de compra %v está %s y no se puede enviar",
+		"purchase_order_not_receivable":  "la orden de compra %v está %s y no puede recibir mercancía",
+		"purchase_order_not_cancellable": "la orden de compra %v está %s y no se puede cancelar",
+		"duplicate_order_line":           "el producto %v está en más de una línea",
+		"purchase_line_not_found":        "la línea %v no es de la orden de compra %v",
+		"receipt_exceeds_pending":        "la línea %v tiene %d unidades pendientes",
+		"receipt_in_future":              "recibida_en no puede ser una fecha futura",
+		"receipt_before_sent":            "la entrega no puede ser anterior al envío de la orden",
+		"receipt_not_found":              "recepción %v no encontrada",
+		"database_unavailable":           "la base de datos no está disponible",
+		"idempotency_requires_auth":      "Idempotency-Key solo se acepta en solicitudes autenticadas",
+		"cart_not_found":                 "carrito no encontrado",
+		"cart_item_not_found":            "item del carrito no encontrado",
+		"cart_empty":                     "el carrito está vacío",
+		"order_not_found":                "orden con id %d no encontrada",
+		"order_item_not_found":           "item de la orden no encontrado",
+
+		// categorías
+		"category_not_found":      "categoría con id %d no encontrada",
+		"category_slug_taken":     "ya existe una categoría con el slug %s",
+		"invalid_slug":            "el slug solo puede tener minúsculas, números y guiones",
+		"category_cycle":          "una categoría no puede quedar dentro de sí misma ni de una de sus subcategorías",
+		"category_has_children":   "la categoría tiene subcategorías y no se puede eliminar",
+		"category_has_promotions": "hay promociones de la categoría y no se puede eliminar",
+		"duplicate_markdown_rule": "hay dos reglas de rebaja para %d días antes del vencimiento",
+		"principal_not_assigned":  "la categoría principal debe estar entre las categorías del producto",
+
+		// promociones
+		"promotion_not_found":      "promoción no encontrada",
+		"invalid_promotion_type":   "tipo de promoción desconocido: %s",
+		"invalid_quantities":       "cantidad_compra y cantidad_gratis deben ser mayores a cero",
+		"promotion_without_coupon": "solo se pueden crear cupones para promociones que requieren cupón",
+		"promotion_scope_conflict": "una promoción aplica a un producto o a una categoría, no a ambos",
+		"coupon_not_found":         "el cupón %v no existe",
+		"coupon_exists":            "ya existe un cupón con el código %s",
+		"coupon_inactive":          "el cupón %s no está activo",
+		"coupon_expired":           "el cupón %s no está vigente",
+		"coupon_exhausted":         "el cupón %s alcanzó su límite de usos",
+		"coupon_client_limit":      "el cliente ya usó el cupón %s el máximo de veces permitido",
+
+		// pagos
+		"payment_not_found":        "pago no encontrado",
+		"payment_method_not_found": "método de pago %v no encontrado",
+		"payment_method_exists":    "ya existe un método de pago con ese nombre",
+		"amount_exceeds_balance":   "el monto excede el pendiente. Monto solicitado: %.2f, Pendiente: %.2f",
+		"amount_exceeds_total":     "el nuevo monto excedería el total de la orden. Total orden: %.2f, Nuevo total pagado: %.2f",
+		"transaction_id_required":  "transaction ID es requerido",
+		"signature_required":       "signature es requerida",
+		"empty_payload":            "payload vacío",
+		"gateway_not_implemented":  "gateway tipo '%s' no implementado aún",
+		"unknown_gateway":          "tipo de gateway desconocido: %s",
+
+		// reseñas
+		"review_not_found": "reseña no encontrada",
+		"review_exists":    "el cliente ya registró una reseña para este producto",
+
+		// claves de idempotencia
+		"invalid_idempotency_key":         "Idempotency-Key debe tener entre 1 y 255 caracteres imprimibles",
+		"idempotency_key_reused":          "la clave de idempotencia ya se usó con otra solicitud",
+		"idempotency_request_in_progress": "una solicitud con esta clave de idempotencia todavía se está procesando",
+	},
+
+	messages: map[string]string{
+		"user_created":             "usuario creado",
+		"user_updated":             "usuario actualizado",
+		"user_deleted":             "usuario eliminado",
+		"user_unlocked":            "usuario desbloqueado",
+		"ip_unlocked":              "ip desbloqueada",
+		"password_changed":         "contraseña actualizada",
+		"password_reset":           "contraseña restablecida",
+		"password_reset_requested": "si el correo existe, se envió un enlace de recuperación",
+		"email_verified":           "correo verificado",
+		"verification_resent":      "si el correo está pendiente de verificación, se envió un enlace",
+		"two_factor_enabled":       "autenticación en dos pasos activada",
+		"two_factor_disabled":      "autenticación en dos pasos desactivada",
+
+		"client_created": "cliente creado",
+		"client_updated": "cliente actualizado",
+		"client_deleted": "cliente eliminado",
+		"seller_created": "vendedor creado",
+		"seller_updated": "vendedor actualizado",
+		"seller_deleted": "vendedor eliminado",
+		"admin_created":  "administrador creado",
+		"admin_updated":  "administrador actualizado",
+		"admin_deleted":  "administrador eliminado",
+
+		"api_key_created": "clave de API creada",
+		"api_key_updated": "clave de API actualizada",
+		"api_key_rotated": "clave de API rotada",
+		"api_key_revoked": "clave de API revocada",
+
+		"consent_recorded":     "consentimiento registrado",
+		"user_data_anonymized": "datos del usuario anonimizados",
+
+		"product_created":           "producto creado",
+		"product_updated":           "producto actualizado",
+		"product_deleted":           "producto eliminado",
+		"stock_updated":             "stock actualizado",
+		"lot_received":              "lote recibido",
+		"products_imported":         "productos importados",
+		"import_validated":          "archivo validado; no se guardó ningún cambio",
+		"import_queued":             "importación en cola",
+		"price_changed":             "precio actualizado",
+		"price_scheduled":           "cambio de precio programado",
+		"scheduled_price_cancelled": "cambio de precio programado cancelado",
+		"supplier_created":          "proveedor creado",
+		"supplier_updated":          "proveedor actualizado",
+		"purchase_order_created":    "orden de compra creada",
+		"purchase_order_updated":    "orden de compra actualizada",
+		"purchase_order_sent":       "orden de compra enviada",
+		"purchase_order_cancelled":  "orden de compra cancelada",
+		"goods_received":            "mercancía recibida",
+		"api_running":               "la API v1 está funcionando",
+		"database_ok":               "la base de datos responde",
+
+		"category_created":           "categoría creada",
+		"category_updated":           "categoría actualizada",
+		"category_deleted":           "categoría eliminada",
+		"product_categories_updated": "categorías del producto actualizadas",
+
+		"cart_item_added":   "producto agregado al carrito",
+		"cart_item_updated": "item del carrito actualizado",
+		"cart_item_removed": "item eliminado del carrito",
+		"cart_cleared":      "carrito vaciado",
+		"coupon_applied":    "cupón aplicado",
+		"coupon_removed":    "cupón retirado",
+
+		"order_created":      "orden creada",
+		"order_updated":      "orden actualizada",
+		"order_deleted":      "orden eliminada",
+		"order_item_added":   "item agregado a la orden",
+		"order_item_updated": "item de la orden actualizado",
+		"order_item_deleted": "item de la orden eliminado",
+
+		"promotion_created": "promoción creada",
+		"promotion_updated": "promoción actualizada",
+		"promotion_deleted": "promoción eliminada",
+		"coupon_created":    "cupón creado",
+		"coupon_updated":    "cupón actualizado",
+		"coupon_deleted":    "cupón eliminado",
+
+		"payment_created":        "pago registrado",
+		"payment_updated":        "pago actualizado",
+		"payment_deleted":        "pago eliminado",
+		"payment_method_created": "método de pago creado",
+		"payment_method_updated": "método de pago actualizado",
+		"payment_method_deleted": "método de pago eliminado",
+
+		"review_created": "reseña creada",
+		"review_updated": "reseña actualizada",
+		"review_deleted": "reseña eliminada",
+	},
+}
@@ -0,0 +1,102 @@
+package i18n
+
+/*
+Este archivo elige el idioma de cada solicitud y traduce los mensajes del
+API con los catálogos de es.go y en.go
+*/
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	Spanish = "es"
+	English = "en"
+)
+
+/*
+# catalog son los textos de un idioma
+* errors: por código de error de dominio; son formatos de fmt que reciben
+* los Args del error en el mismo orden
+* messages: mensajes de éxito, por clave
+*/
+type catalog struct {
+	errors   map[string]string
+	messages map[string]string
+}
+
+var catalogs = map[string]catalog{
+	Spanish: spanish,
+	English: english,
+}
+
+// Supported indica si hay catálogo para lang
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+/*
+# Match elige el idioma a partir de un encabezado Accept-Language
+* recorre los idiomas en el orden de preferencia (q) del cliente
+* "en-US" o "es-CO" valen por su idioma base
+* si ninguno está soportado, o el encabezado no se entiende, devuelve def
+*/
+func Match(acceptLanguage, def string) string {
+	if strings.TrimSpace(acceptLanguage) == "" {
+		return def
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil {
+		return def
+	}
+	for _, tag := range tags {
+		base, _ := tag.Base()
+		if Supported(base.String()) {
+			return base.String()
+		}
+	}
+	return def
+}
+
+type ctxKey struct{}
+
+// WithLanguage guarda el idioma de la solicitud en ctx
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, lang)
+}
+
+// Language devuelve el idioma guardado en ctx, o español si no hay
+func Language(ctx context.Context) string {
+	if lang, ok := ctx.Value(ctxKey{}).(string); ok && lang != "" {
+		return lang
+	}
+	return Spanish
+}
+
+/*
+# ErrorMessage traduce el mensaje de un error de dominio
+* args son los Args del error
+* el bool es false si el catálogo no tiene el código; el llamador usa
+* entonces el mensaje original
+*/
+func ErrorMessage(lang, code string, args []any) (string, bool) {
+	format, ok := catalogs[lang].errors[code]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf(format, args...), true
+}
+
+// Message traduce un mensaje de éxito; sin traducción devuelve key
+func Message(lang, key string) string {
+	if text, ok := catalogs[lang].messages[key]; ok {
+		return text
+	}
+	return key
+}
@@ -0,0 +1,57 @@
+package i18n
+
+/*
+Este archivo traduce los errores de go-playground/validator con los
+traductores de universal-translator
+*/
+
+import (
+	"fmt"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	entranslations "github.com/go-playground/validator/v10/translations/en"
+	estranslations "github.com/go-playground/validator/v10/translations/es"
+)
+
+var universal *ut.UniversalTranslator
+
+/*
+# RegisterValidator carga en v las traducciones por defecto de cada idioma
+* se llama una vez al arrancar, antes de atender solicitudes
+*/
+func RegisterValidator(v *validator.Validate) error {
+	universal = ut.New(es.New(), es.New(), en.New())
+
+	registrations := map[string]func(*validator.Validate, ut.Translator) error{
+		Spanish: estranslations.RegisterDefaultTranslations,
+		English: entranslations.RegisterDefaultTranslations,
+	}
+	for lang, register := range registrations {
+		trans, _ := universal.GetTranslator(lang)
+		if err := register(v, trans); err != nil {
+			return fmt.Errorf("error registering %s validator translations: %w", lang, err)
+		}
+	}
+	return nil
+}
+
+/*
+# FieldMessage traduce el error de un campo al idioma lang
+* las etiquetas sin traducción salen con el mensaje genérico invalid_field
+*/
+func FieldMessage(lang string, fe validator.FieldError) string {
+	if universal != nil {
+		trans, found := universal.GetTranslator(lang)
+		if found {
+			if msg := fe.Translate(trans); msg != fe.Error() {
+				return msg
+			}
+		}
+	}
+
+	msg, _ := ErrorMessage(lang, "invalid_field", []any{fe.Field(), fe.Tag()})
+	return msg
+}
@@ -0,0 +1,154 @@
+package idempotency
+
+/*
+Este archivo guarda en Postgres las claves de idempotencia y la respuesta
+de la primera solicitud que llegó con cada una
+*/
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// processingTimeout es cuánto se respeta una solicitud en curso; pasado ese
+// tiempo se asume que el proceso murió y la clave se puede volver a tomar
+const processingTimeout = time.Minute
+
+const (
+	StatusProcessing = "en_proceso"
+	StatusCompleted  = "completada"
+)
+
+/*
+# Record es una clave de idempotencia
+* Scope separa las claves por ruta y usuario: la misma clave en otra ruta
+* es otra solicitud
+* Fingerprint es el hash del método, la ruta y el body
+* StatusCode, ContentType y Body son la respuesta guardada (solo completada)
+*/
+type Record struct {
+	Scope       string    `gorm:"column:alcance;primaryKey"`
+	Key         string    `gorm:"column:clave;primaryKey"`
+	Fingerprint string    `gorm:"column:huella"`
+	Status      string    `gorm:"column:estado"`
+	StatusCode  int       `gorm:"column:codigo_http"`
+	ContentType string    `gorm:"column:tipo_contenido"`
+	Body        []byte    `gorm:"column:respuesta"`
+	LockedUntil time.Time `gorm:"column:bloqueada_hasta"`
+	ExpiresAt   time.Time `gorm:"column:expira_en"`
+	CreatedAt   time.Time `gorm:"column:created_at"`
+}
+
+func (Record) TableName() string {
+	return "clave_idempotencia"
+}
+
+/*
+# Store guarda las claves durante ttl
+* una clave vencida se puede reutilizar con cualquier solicitud
+*/
+type Store struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+func NewStore(db *gorm.DB, ttl time.Duration) *Store {
+	return &Store{db: db, ttl: ttl}
+}
+
+/*
+# Begin reserva la clave para esta solicitud
+* si la clave es nueva, venció, o quedó en proceso de una solicitud con la
+* misma huella que no terminó, devuelve acquired true y el handler corre
+* si no, devuelve el registro existente para decidir qué responder
+*/
+func (s *Store) Begin(ctx context.Context, scope, key, fingerprint string) (*Record, bool, error) {
+	var claimed []Record
+	err := s.db.WithContext(ctx).Raw(`
+		INSERT INTO clave_idempotencia (alcance, clave, huella, estado, bloqueada_hasta, expira_en)
+		VALUES (?, ?, ?, ?, now() + ? * interval '1 second', now() + ? * interval '1 second')
+		ON CONFLICT (alcance, clave) DO UPDATE SET
+			huella = EXCLUDED.huella,
+			estado = EXCLUDED.estado,
+			codigo_http = 0,
+			tipo_contenido = '',
+			respuesta = NULL,
+			bloqueada_hasta = EXCLUDED.bloqueada_hasta,
+			expira_en = EXCLUDED.expira_en,
+			created_at = now()
+		WHERE clave_idempotencia.expira_en <= now()
+			OR (clave_idempotencia.estado = ?
+				AND clave_idempotencia.bloqueada_hasta <= now()
+				AND clave_idempotencia.huella = EXCLUDED.huella)
+		RETURNING alcance, clave`,
+		scope, key, fingerprint, StatusProcessing, processingTimeout.Seconds(), s.ttl.Seconds(), StatusProcessing,
+	).Scan(&claimed).Error
+	if err != nil {
+		return nil, false, err
+	}
+	if len(claimed) > 0 {
+		return nil, true, nil
+	}
+
+	var record Record
+	err = s.db.WithContext(ctx).Where("alcance = ? AND clave = ?", scope, key).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// se borró entre las dos consultas; que el cliente reintente
+		return &Record{Status: StatusProcessing, Fingerprint: fingerprint}, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &record, false, nil
+}
+
+// Complete guarda la respuesta que se repetirá a los reintentos
+func (s *Store) Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error {
+	return s.db.WithContext(ctx).Model(&Record{}).
+		Where("alcance = ? AND clave = ?", scope, key).
+		Updates(map[string]any{
+			"estado":         StatusCompleted,
+			"codigo_http":    statusCode,
+			"tipo_contenido": contentType,
+			"respuesta":      body,
+		}).Error
+}
+
+// Release libera la clave de una solicitud que falló para que se pueda reintentar
+func (s *Store) Release(ctx context.Context, scope, key string) error {
+	return s.db.WithContext(ctx).
+		Where("alcance = ? AND clave = ? AND estado = ?", scope, key, StatusProcessing).
+		Delete(&Record{}).Error
+}
+
+// Cleanup borra las claves vencidas y devuelve cuántas borró
+func (s *Store) Cleanup(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).Where("expira_en <= now()").Delete(&Record{})
+	return result.RowsAffected, result.Error
+}
+
+// Run limpia cada hora hasta que ctx se cancela
+func (s *Store) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, err := s.Cleanup(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "idempotency: error removing expired keys", "error", err)
+				continue
+			}
+			if removed > 0 {
+				slog.InfoContext(ctx, "idempotency: removed expired keys", "removed", removed)
+			}
+		}
+	}
+}
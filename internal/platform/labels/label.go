@@ -0,0 +1,169 @@
+package labels
+
+/*
+Este paquete dibuja las etiquetas de góndola y de lote: nombre, precio (con
+la rebaja vigente), código de barras y fecha de vencimiento. Se generan en
+el servidor, sin servicios externos, como hojas PDF o como ZPL para
+impresoras térmicas
+*/
+
+import (
+	"errors"
+	"image/color"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/ean"
+	"github.com/mordmora/expirapp/internal/platform/gs1"
+)
+
+var ErrNoLabels = errors.New("labels: nothing to print")
+
+type Symbology string
+
+const (
+	EAN13   Symbology = "ean13"
+	EAN8    Symbology = "ean8"
+	GS1128  Symbology = "gs1-128"
+	Code128 Symbology = "code128"
+)
+
+/*
+# Barcode es el código que lleva la etiqueta
+* en EAN-13/EAN-8 Data son los dígitos con el de control
+* en Code128 Data es el texto tal cual (el SKU)
+* en GS1-128 los campos van en Elements y Data queda vacío
+*/
+type Barcode struct {
+	Symbology Symbology
+	Data      string
+	Elements  []gs1.Element
+}
+
+/*
+# ProductBarcode elige el código de un producto
+* un GTIN que cabe en EAN-13 o EAN-8 se imprime así, que es lo que lee
+* cualquier caja; un GTIN-14 de verdad va en GS1-128 (01)
+* sin GTIN se imprime el SKU en Code128; sin ninguno, nil
+*/
+func ProductBarcode(gtin, sku string) *Barcode {
+	if gtin != "" {
+		switch short := gs1.ShortGTIN(gtin); len(short) {
+		case 13:
+			return &Barcode{Symbology: EAN13, Data: short}
+		case 8:
+			return &Barcode{Symbology: EAN8, Data: short}
+		default:
+			return &Barcode{Symbology: GS1128, Elements: []gs1.Element{{AI: gs1.AIGTIN, Value: gtin}}}
+		}
+	}
+	if sku != "" {
+		return &Barcode{Symbology: Code128, Data: sku}
+	}
+	return nil
+}
+
+// batchPattern son los caracteres que GS1 admite en el lote (10), sin los
+// paréntesis que confundirían la forma legible
+var batchPattern = regexp.MustCompile(`^[A-Za-z0-9!"%&'*+,./:;<=>?_-]{1,20}$`)
+
+/*
+# LotBarcode es la etiqueta GS1-128 de un lote: GTIN (01), vencimiento (17)
+# y lote (10), este al final por ser de largo variable
+* es la que interpreta el escaneo del catálogo
+* sin GTIN, o con un número de lote que GS1 no admite, se usa el código
+* del producto
+*/
+func LotBarcode(gtin, sku string, expiration time.Time, lot string) *Barcode {
+	if gtin == "" || !batchPattern.MatchString(lot) {
+		return ProductBarcode(gtin, sku)
+	}
+	return &Barcode{Symbology: GS1128, Elements: []gs1.Element{
+		{AI: gs1.AIGTIN, Value: gtin},
+		{AI: gs1.AIExpiration, Value: gs1.FormatDate(expiration)},
+		{AI: gs1.AIBatch, Value: lot},
+	}}
+}
+
+// Text es la línea legible que va bajo las barras
+func (b *Barcode) Text() string {
+	if b.Symbology == GS1128 {
+		return gs1.HumanReadable(b.Elements)
+	}
+	return b.Data
+}
+
+// modules codifica el código y devuelve sus módulos de izquierda a derecha;
+// true es una barra
+func (b *Barcode) modules() ([]bool, error) {
+	var code barcode.Barcode
+	var err error
+	switch b.Symbology {
+	case EAN13, EAN8:
+		code, err = ean.Encode(b.Data)
+	case GS1128:
+		raw := strings.ReplaceAll(gs1.Encode(b.Elements), string(gs1.GroupSeparator), string(code128.FNC1))
+		code, err = code128.Encode(string(code128.FNC1) + raw)
+	default:
+		code, err = code128.Encode(b.Data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := code.Bounds()
+	modules := make([]bool, bounds.Dx())
+	for x := range modules {
+		gray := color.GrayModel.Convert(code.At(bounds.Min.X+x, bounds.Min.Y)).(color.Gray)
+		modules[x] = gray.Y < 128
+	}
+	return modules, nil
+}
+
+/*
+# Label es una etiqueta
+* RegularPrice solo se imprime (tachado) cuando es mayor que Price, es
+* decir cuando hay una rebaja vigente; Discount es su porcentaje
+* Lot va vacío en las etiquetas de producto
+*/
+type Label struct {
+	Name         string
+	Price        float64
+	RegularPrice float64
+	Discount     float64
+	Expiration   time.Time
+	Lot          string
+	Barcode      *Barcode
+}
+
+func (l Label) marked() bool {
+	return l.RegularPrice > l.Price
+}
+
+// FormatPrice escribe un precio en pesos: "$12.500" o "$12.500,50"
+func FormatPrice(price float64) string {
+	cents := int64(math.Round(price * 100))
+	units := strconv.FormatInt(cents/100, 10)
+
+	var b strings.Builder
+	b.WriteString("$")
+	for i, r := range units {
+		if i > 0 && (len(units)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(r)
+	}
+	if rest := cents % 100; rest != 0 {
+		b.WriteString("," + strconv.FormatInt(rest/10, 10) + strconv.FormatInt(rest%10, 10))
+	}
+	return b.String()
+}
+
+func formatDiscount(discount float64) string {
+	return "-" + strconv.FormatFloat(discount, 'f', -1, 64) + "%"
+}
@@ -0,0 +1,190 @@
+package labels
+
+import (
+	"io"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+/*
+# hoja A4 de 3 x 8 etiquetas de 70 x 37 mm, el formato adhesivo más común
+* las medidas van en milímetros
+*/
+const (
+	sheetColumns = 3
+	sheetRows    = 8
+	labelWidth   = 70.0
+	labelHeight  = 37.0
+	sheetTop     = (297.0 - sheetRows*labelHeight) / 2
+	labelPadding = 3.0
+
+	// el módulo de las barras no pasa de 0,33 mm (EAN-13 al 100 %)
+	maxModuleWidth = 0.33
+	barHeight      = 9.0
+)
+
+/*
+# RenderPDF escribe las etiquetas en hojas A4, de izquierda a derecha y de
+# arriba abajo
+* se usan las fuentes base del PDF con la página de códigos cp1252, que
+* cubre el español sin incrustar fuentes
+*/
+func RenderPDF(w io.Writer, labels []Label) error {
+	if len(labels) == 0 {
+		return ErrNoLabels
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Etiquetas", true)
+	pdf.SetCreator("expirapp", true)
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	perSheet := sheetColumns * sheetRows
+	for i, label := range labels {
+		if i%perSheet == 0 {
+			pdf.AddPage()
+		}
+		slot := i % perSheet
+		x := float64(slot%sheetColumns) * labelWidth
+		y := sheetTop + float64(slot/sheetColumns)*labelHeight
+		if err := drawLabel(pdf, tr, x, y, label); err != nil {
+			return err
+		}
+	}
+
+	return pdf.Output(w)
+}
+
+/*
+# drawLabel dibuja una etiqueta con su esquina superior izquierda en x, y
+* arriba el nombre, en hasta dos líneas
+* al medio el precio a la izquierda y el vencimiento a la derecha, en un
+* recuadro negro con letra blanca para que se vea de lejos
+* abajo las barras con su texto legible
+*/
+func drawLabel(pdf *fpdf.Fpdf, tr func(string) string, x, y float64, label Label) error {
+	inner := labelWidth - 2*labelPadding
+	left := x + labelPadding
+	top := y + labelPadding
+
+	// nombre
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Helvetica", "B", 9)
+	for i, line := range wrap(pdf, tr(label.Name), inner, 2) {
+		pdf.SetXY(left, top+float64(i)*3.8)
+		pdf.CellFormat(inner, 3.8, line, "", 0, "L", false, 0, "")
+	}
+
+	// precio
+	priceTop := top + 8.5
+	priceWidth := inner * 0.52
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.SetXY(left, priceTop)
+	pdf.CellFormat(priceWidth, 7, tr(FormatPrice(label.Price)), "", 0, "L", false, 0, "")
+	if label.marked() {
+		regular := tr(FormatPrice(label.RegularPrice))
+		pdf.SetFont("Helvetica", "", 7)
+		pdf.SetXY(left, priceTop+7)
+		pdf.CellFormat(priceWidth, 3.5, regular+"  "+formatDiscount(label.Discount), "", 0, "L", false, 0, "")
+		pdf.SetLineWidth(0.2)
+		pdf.Line(left, priceTop+8.75, left+pdf.GetStringWidth(regular), priceTop+8.75)
+	}
+
+	// vencimiento
+	boxLeft := left + priceWidth + 1
+	boxWidth := inner - priceWidth - 1
+	pdf.SetFillColor(0, 0, 0)
+	pdf.Rect(boxLeft, priceTop, boxWidth, 8, "F")
+	pdf.SetTextColor(255, 255, 255)
+	pdf.SetFont("Helvetica", "B", 6)
+	pdf.SetXY(boxLeft, priceTop+0.4)
+	pdf.CellFormat(boxWidth, 2.6, "VENCE", "", 0, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.SetXY(boxLeft, priceTop+3)
+	pdf.CellFormat(boxWidth, 4.6, label.Expiration.Format("02/01/2006"), "", 0, "C", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	if label.Lot != "" {
+		pdf.SetFont("Helvetica", "", 7)
+		pdf.SetXY(boxLeft, priceTop+8.2)
+		pdf.CellFormat(boxWidth, 3.3, tr("Lote "+label.Lot), "", 0, "C", false, 0, "")
+	}
+
+	if label.Barcode == nil {
+		return nil
+	}
+
+	// código de barras
+	modules, err := label.Barcode.modules()
+	if err != nil {
+		return err
+	}
+	module := min(maxModuleWidth, inner/float64(len(modules)))
+	barsLeft := x + (labelWidth-module*float64(len(modules)))/2
+	barsTop := y + labelHeight - labelPadding - barHeight - 2.5
+	for i := 0; i < len(modules); {
+		if !modules[i] {
+			i++
+			continue
+		}
+		// las barras contiguas van en un solo rectángulo
+		start := i
+		for i < len(modules) && modules[i] {
+			i++
+		}
+		pdf.Rect(barsLeft+float64(start)*module, barsTop, float64(i-start)*module, barHeight, "F")
+	}
+	pdf.SetFont("Helvetica", "", 6)
+	pdf.SetXY(x, barsTop+barHeight)
+	pdf.CellFormat(labelWidth, 2.5, tr(label.Barcode.Text()), "", 0, "C", false, 0, "")
+
+	return nil
+}
+
+/*
+# wrap reparte text (ya en cp1252) en líneas de hasta width, cortando entre
+# palabras
+* si no cabe en maxLines, la última termina en "..."
+* SplitText de fpdf no sirve aquí porque espera UTF-8
+*/
+func wrap(pdf *fpdf.Fpdf, text string, width float64, maxLines int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case pdf.GetStringWidth(line+" "+word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	truncated := len(lines) > maxLines
+	if truncated {
+		lines = lines[:maxLines]
+	}
+	for i, l := range lines {
+		if pdf.GetStringWidth(l) > width || (truncated && i == len(lines)-1) {
+			lines[i] = ellipsize(pdf, l, width)
+		}
+	}
+	return lines
+}
+
+// ellipsize recorta line para que quepa en width con "..." al final
+func ellipsize(pdf *fpdf.Fpdf, line string, width float64) string {
+	const dots = "..."
+	line = strings.TrimSpace(line)
+	for line != "" && pdf.GetStringWidth(line+dots) > width {
+		line = strings.TrimSpace(line[:len(line)-1])
+	}
+	return line + dots
+}
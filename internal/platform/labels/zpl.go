@@ -0,0 +1,108 @@
+package labels
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*
+# etiqueta térmica de 4 x 2 pulgadas a 203 dpi; las medidas van en puntos
+* las barras las dibuja la impresora con sus propios comandos, así salen
+* con la resolución del cabezal
+*/
+const (
+	zplWidth   = 812
+	zplHeight  = 406
+	zplMargin  = 24
+	zplBarsTop = 236
+	zplBarsH   = 100
+)
+
+// RenderZPL escribe un formato ^XA...^XZ por etiqueta, en UTF-8 (^CI28)
+func RenderZPL(w io.Writer, labels []Label) error {
+	if len(labels) == 0 {
+		return ErrNoLabels
+	}
+
+	out := bufio.NewWriter(w)
+	for _, label := range labels {
+		if err := writeZPL(out, label); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+func writeZPL(w *bufio.Writer, label Label) error {
+	inner := zplWidth - 2*zplMargin
+	priceWidth := inner * 52 / 100
+	boxLeft := zplMargin + priceWidth + 8
+	boxWidth := zplWidth - zplMargin - boxLeft
+
+	fmt.Fprintf(w, "^XA^CI28^PW%d^LL%d^LH0,0\n", zplWidth, zplHeight)
+
+	// nombre en hasta dos líneas
+	fmt.Fprintf(w, "^FO%d,20^A0N,34,34^FB%d,2,4,L^FH^FD%s^FS\n", zplMargin, inner, zplText(label.Name))
+
+	// precio
+	fmt.Fprintf(w, "^FO%d,100^A0N,64,64^FB%d,1,0,L^FH^FD%s^FS\n", zplMargin, priceWidth, zplText(FormatPrice(label.Price)))
+	if label.marked() {
+		text := "Antes " + FormatPrice(label.RegularPrice) + " " + formatDiscount(label.Discount)
+		fmt.Fprintf(w, "^FO%d,172^A0N,26,26^FB%d,1,0,L^FH^FD%s^FS\n", zplMargin, priceWidth, zplText(text))
+	}
+
+	// vencimiento en blanco sobre negro
+	fmt.Fprintf(w, "^FO%d,96^GB%d,96,96^FS\n", boxLeft, boxWidth)
+	fmt.Fprintf(w, "^FO%d,106^A0N,22,22^FB%d,1,0,C^FR^FDVENCE^FS\n", boxLeft, boxWidth)
+	fmt.Fprintf(w, "^FO%d,134^A0N,48,48^FB%d,1,0,C^FR^FD%s^FS\n", boxLeft, boxWidth, label.Expiration.Format("02/01/2006"))
+	if label.Lot != "" {
+		fmt.Fprintf(w, "^FO%d,200^A0N,24,24^FB%d,1,0,C^FH^FD%s^FS\n", boxLeft, boxWidth, zplText("Lote "+label.Lot))
+	}
+
+	if label.Barcode != nil {
+		if err := writeZPLBarcode(w, label.Barcode); err != nil {
+			return err
+		}
+	}
+
+	_, err := w.WriteString("^XZ\n")
+	return err
+}
+
+/*
+# writeZPLBarcode centra el código con el ancho de módulo más grande que
+# quepa (de 1 a 3 puntos)
+* EAN-13 y EAN-8 van sin dígito de control: la impresora lo calcula
+* GS1-128 usa el modo D de ^BC, que pone los FNC1 a partir de la forma
+* legible con paréntesis
+*/
+func writeZPLBarcode(w *bufio.Writer, b *Barcode) error {
+	modules, err := b.modules()
+	if err != nil {
+		return err
+	}
+	module := min(3, max(1, (zplWidth-2*zplMargin)/len(modules)))
+	left := max(0, (zplWidth-module*len(modules))/2)
+
+	fmt.Fprintf(w, "^BY%d,3,%d^FO%d,%d", module, zplBarsH, left, zplBarsTop)
+	switch b.Symbology {
+	case EAN13:
+		fmt.Fprintf(w, "^BEN,%d,Y,N^FD%s^FS\n", zplBarsH, b.Data[:12])
+	case EAN8:
+		fmt.Fprintf(w, "^B8N,%d,Y,N^FD%s^FS\n", zplBarsH, b.Data[:7])
+	case GS1128:
+		fmt.Fprintf(w, "^BCN,%d,Y,N,N,D^FD%s^FS\n", zplBarsH, b.Text())
+	default:
+		fmt.Fprintf(w, "^BCN,%d,Y,N,N,A^FD%s^FS\n", zplBarsH, b.Data)
+	}
+	return nil
+}
+
+// zplEscaper escapa (con ^FH) los caracteres que ZPL interpreta como comandos
+var zplEscaper = strings.NewReplacer("_", "_5F", "^", "_5E", "~", "_7E")
+
+func zplText(s string) string {
+	return zplEscaper.Replace(s)
+}
@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+/*
+# GormLogger envía los registros de GORM al logger de la aplicación
+* las consultas que fallan van en error (menos ErrRecordNotFound)
+* las que tardan más que SlowThreshold van en warn
+* el resto en debug, así LOG_LEVEL=info no imprime cada SQL
+*/
+type GormLogger struct {
+	logger        *slog.Logger
+	level         gormlogger.LogLevel
+	SlowThreshold time.Duration
+}
+
+func NewGormLogger(l *slog.Logger, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{logger: l, level: gormlogger.Info, SlowThreshold: slowThreshold}
+}
+
+func (g *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *g
+	clone.level = level
+	return &clone
+}
+
+func (g *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= gormlogger.Info {
+		g.logger.InfoContext(ctx, fmt.Sprintf(msg, args...), "component", "gorm")
+	}
+}
+
+func (g *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= gormlogger.Warn {
+		g.logger.WarnContext(ctx, fmt.Sprintf(msg, args...), "component", "gorm")
+	}
+}
+
+func (g *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= gormlogger.Error {
+		g.logger.ErrorContext(ctx, fmt.Sprintf(msg, args...), "component", "gorm")
+	}
+}
+
+func (g *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if g.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	sql, rows := fc()
+	attrs := []any{
+		"component", "gorm",
+		"sql", sql,
+		"rows", rows,
+		"duration_ms", float64(elapsed.Microseconds()) / 1000,
+	}
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && g.level >= gormlogger.Error:
+		g.logger.ErrorContext(ctx, "query failed", append(attrs, "error", err)...)
+	case g.SlowThreshold > 0 && elapsed > g.SlowThreshold && g.level >= gormlogger.Warn:
+		g.logger.WarnContext(ctx, "slow query", append(attrs, "threshold_ms", g.SlowThreshold.Milliseconds())...)
+	case g.level >= gormlogger.Info:
+		g.logger.DebugContext(ctx, "query", attrs...)
+	}
+}
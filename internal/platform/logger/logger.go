@@ -1 +1,97 @@
 package logger
+
+/*
+Este archivo configura el logger estructurado (log/slog) de la aplicación
+*/
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+/*
+# Config del logger
+* Level: debug, info, warn o error (por defecto info)
+* Format: json para producción, text para leer en consola
+*/
+type Config struct {
+	Level  string
+	Format string
+}
+
+type ctxKey struct{}
+
+// WithRequestID guarda el id de la solicitud en ctx
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// RequestID devuelve el id de la solicitud guardado en ctx, o ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// New crea un logger que escribe en w y agrega request_id y trace_id a cada
+// registro hecho con un contexto que los tenga (slog.InfoContext y similares)
+func New(cfg Config, w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(cfg.Level)}
+
+	var handler slog.Handler
+	if strings.EqualFold(cfg.Format, "text") {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+
+	return slog.New(contextHandler{handler})
+}
+
+// Setup crea el logger sobre stdout y lo deja como slog.Default; el paquete
+// log estándar también pasa a escribir a través de él
+func Setup(cfg Config) *slog.Logger {
+	l := New(cfg, os.Stdout)
+	slog.SetDefault(l)
+	return l
+}
+
+// contextHandler toma del contexto los atributos de la solicitud
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,79 @@
+package mail
+
+/*
+Este archivo define el envío de correos de la aplicación
+*/
+
+import (
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/smtp"
+	"strings"
+)
+
+/*
+# Sender envía un correo de texto plano
+* la implementación real es SMTP; LogSender sirve para desarrollo
+*/
+type Sender interface {
+	Send(to, subject, body string) error
+}
+
+/*
+# Config de SMTP
+* Host vacío significa "no hay SMTP" y New devuelve un LogSender
+* Username vacío envía sin autenticación (p. ej. MailHog en localhost:1025)
+*/
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func New(cfg Config) Sender {
+	if cfg.Host == "" {
+		return LogSender{}
+	}
+	return &SMTPSender{cfg: cfg}
+}
+
+type SMTPSender struct {
+	cfg Config
+}
+
+func (s *SMTPSender) Send(to, subject, body string) error {
+	addr := fmt.Sprintf("%s:%d", s.cfg.Host, s.cfg.Port)
+
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(body)
+
+	if err := smtp.SendMail(addr, auth, s.cfg.From, []string{to}, []byte(msg.String())); err != nil {
+		return fmt.Errorf("error sending mail to %s: %w", to, err)
+	}
+	return nil
+}
+
+/*
+# LogSender anota en el log que el correo no se envió
+* solo destinatario y asunto: el cuerpo lleva enlaces con tokens de un solo
+* uso (restablecer contraseña, verificar correo) que no deben quedar en logs
+*/
+type LogSender struct{}
+
+func (LogSender) Send(to, subject, body string) error {
+	slog.Info("mail not sent, no smtp configured", "to", to, "subject", subject)
+	return nil
+}
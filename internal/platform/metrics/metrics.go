@@ -0,0 +1,123 @@
+package metrics
+
+/*
+Este archivo define las métricas de Prometheus de la aplicación y el
+registro en el que se publican
+*/
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "expirapp"
+
+// Registry tiene todas las métricas; se publica en el listener de administración
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests cuenta las solicitudes por ruta (la plantilla, no la URL) y estado
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Solicitudes HTTP atendidas, por método, ruta y estado.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration mide la latencia de las solicitudes por ruta y estado
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latencia de las solicitudes HTTP, por método, ruta y estado.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// OrdersCreated cuenta las órdenes creadas, directas o desde el carrito
+	OrdersCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_created_total",
+		Help:      "Órdenes de compra creadas.",
+	})
+
+	// Payments cuenta los pagos por resultado (PaymentStatus)
+	Payments = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_total",
+		Help:      "Pagos procesados, por resultado.",
+	}, []string{"status"})
+
+	// StockWriteOffs suma las unidades dadas de baja con ajustes negativos de stock
+	StockWriteOffs = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stock_write_off_units_total",
+		Help:      "Unidades dadas de baja con ajustes negativos de stock.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		OrdersCreated,
+		Payments,
+		StockWriteOffs,
+	)
+}
+
+// RegisterDB publica las estadísticas del pool de conexiones (sql.DB.Stats)
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+/*
+# RegisterExpiringUnits publica las unidades que vencen en los próximos 7 días
+* count se consulta en cada scrape con un límite de collectTimeout; si
+* falla, la métrica se omite y el error queda en el log
+*/
+func RegisterExpiringUnits(count func(ctx context.Context) (int64, error)) error {
+	return Registry.Register(&expiringCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "units_expiring_7d"),
+			"Unidades en stock que vencen en los próximos 7 días.",
+			nil, nil,
+		),
+		count: count,
+	})
+}
+
+// collectTimeout acota las consultas a la base de datos durante un scrape
+const collectTimeout = 5 * time.Second
+
+type expiringCollector struct {
+	desc  *prometheus.Desc
+	count func(ctx context.Context) (int64, error)
+}
+
+func (c *expiringCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *expiringCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	units, err := c.count(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "could not count expiring units", "error", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(units))
+}
+
+// Handler sirve el Registry en formato de texto de Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
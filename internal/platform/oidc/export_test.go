@@ -0,0 +1,10 @@
+package oidc
+
+import "time"
+
+// AgeKeys simula que pasó el intervalo de recarga del JWKS
+func AgeKeys(p *Provider) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keysFetched = p.keysFetched.Add(-keysRefreshInterval - time.Second)
+}
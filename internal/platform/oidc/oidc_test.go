@@ -0,0 +1,183 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mordmora/expirapp/internal/platform/oidc"
+	"github.com/mordmora/expirapp/internal/platform/oidc/oidctest"
+)
+
+const (
+	testState    = "state-1"
+	testNonce    = "nonce-1"
+	testVerifier = "verifier-with-enough-entropy-for-pkce-0123456789"
+)
+
+// login hace el recorrido completo: URL de autorización, código y canje
+func login(t *testing.T, idp *oidctest.IdP, provider *oidc.Provider, claims jwt.MapClaims) (*oidc.Identity, error) {
+	t.Helper()
+
+	ctx := context.Background()
+	authURL, err := provider.AuthCodeURL(ctx, testState, testNonce, testVerifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code := idp.Authorize(authURL, claims)
+	return provider.Exchange(ctx, code, testVerifier, testNonce)
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	idp := oidctest.New(t)
+
+	authURL, err := idp.Provider().AuthCodeURL(context.Background(), testState, testNonce, testVerifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parsing url: %v", err)
+	}
+	query := parsed.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             oidctest.ClientID,
+		"redirect_uri":          oidctest.RedirectURL,
+		"scope":                 "openid email profile",
+		"state":                 testState,
+		"nonce":                 testNonce,
+		"code_challenge":        oidc.Challenge(testVerifier),
+		"code_challenge_method": "S256",
+	}
+	for name, value := range want {
+		if got := query.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if !strings.HasPrefix(authURL, idp.Server.URL+"/authorize?") {
+		t.Errorf("url %q does not point to the authorization endpoint", authURL)
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	// ejemplo del apéndice B de RFC 7636
+	got := oidc.Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("Challenge = %q, want %q", got, want)
+	}
+}
+
+func TestExchangeWithPKCE(t *testing.T) {
+	idp := oidctest.New(t)
+
+	identity, err := login(t, idp, idp.Provider(), jwt.MapClaims{
+		"sub":    "ext-42",
+		"groups": []string{"tienda-admins", "todos"},
+	})
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	if identity.Issuer != idp.Issuer || identity.Subject != "ext-42" {
+		t.Errorf("identity = %s/%s, want %s/ext-42", identity.Issuer, identity.Subject, idp.Issuer)
+	}
+	if identity.Email != "ana@example.com" || !identity.EmailVerified || identity.Name != "Ana" {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+	if len(identity.Groups) != 2 || identity.Groups[0] != "tienda-admins" {
+		t.Errorf("groups = %v", identity.Groups)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	idp := oidctest.New(t)
+	provider := idp.Provider()
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, testState, testNonce, testVerifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code := idp.Authorize(authURL, nil)
+
+	_, err = provider.Exchange(ctx, code, "another-verifier", testNonce)
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Fatalf("err = %v, want invalid_grant", err)
+	}
+}
+
+func TestExchangeRejectsInvalidIDToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		want   string
+	}{
+		{"nonce", jwt.MapClaims{"nonce": "otro"}, "nonce mismatch"},
+		{"missing nonce", jwt.MapClaims{"nonce": ""}, "nonce mismatch"},
+		{"issuer", jwt.MapClaims{"iss": "https://evil.example.com"}, "issuer"},
+		{"audience", jwt.MapClaims{"aud": "otra-app"}, "audience"},
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}, "expired"},
+		{"subject", jwt.MapClaims{"sub": ""}, "missing sub"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := oidctest.New(t)
+
+			_, err := login(t, idp, idp.Provider(), tt.claims)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	idp := oidctest.New(t)
+	idp.Issuer = "https://evil.example.com"
+
+	_, err := idp.Provider().AuthCodeURL(context.Background(), testState, testNonce, testVerifier)
+	if err == nil || !strings.Contains(err.Error(), "issuer mismatch") {
+		t.Fatalf("err = %v, want issuer mismatch", err)
+	}
+}
+
+func TestExchangeAfterKeyRotation(t *testing.T) {
+	idp := oidctest.New(t)
+	provider := idp.Provider()
+
+	if _, err := login(t, idp, provider, nil); err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+
+	kid := idp.Rotate()
+
+	// el JWKS recién se pidió: una llave desconocida no dispara otra descarga
+	_, err := login(t, idp, provider, nil)
+	if err == nil || !strings.Contains(err.Error(), kid) {
+		t.Fatalf("err = %v, want unknown signing key %q", err, kid)
+	}
+
+	oidc.AgeKeys(provider)
+
+	if _, err := login(t, idp, provider, nil); err != nil {
+		t.Fatalf("login after rotation: %v", err)
+	}
+}
+
+func TestEmailVerifiedAsString(t *testing.T) {
+	idp := oidctest.New(t)
+
+	identity, err := login(t, idp, idp.Provider(), jwt.MapClaims{"email_verified": "false"})
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.EmailVerified {
+		t.Error("email_verified \"false\" was read as verified")
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Package oidctest levanta un proveedor de identidad OIDC en httptest para las
+pruebas del login corporativo: discovery, JWKS y token endpoint con PKCE
+*/
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mordmora/expirapp/internal/platform/oidc"
+)
+
+const (
+	ClientID    = "expirapp"
+	RedirectURL = "http://localhost:5173/sso/callback"
+)
+
+// authorization es lo que el IdP recuerda de un código emitido
+type authorization struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+/*
+# IdP es un proveedor de identidad en memoria
+* firma los id_token con RS256 y publica sus llaves en /jwks
+* Authorize simula el paso del usuario por la pantalla de login del IdP
+*/
+type IdP struct {
+	t      *testing.T
+	Server *httptest.Server
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	signKid string
+	codes   map[string]authorization
+	seq     int
+
+	// Issuer es el que publica el discovery; por defecto la URL del servidor
+	Issuer string
+}
+
+// New arranca el IdP y lo cierra al terminar la prueba
+func New(t *testing.T) *IdP {
+	t.Helper()
+
+	idp := &IdP{t: t, keys: map[string]*rsa.PrivateKey{}, codes: map[string]authorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("GET /jwks", idp.jwks)
+	mux.HandleFunc("POST /token", idp.token)
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Server.Close)
+
+	idp.Issuer = idp.Server.URL
+	idp.Rotate()
+	return idp
+}
+
+// Config devuelve la configuración del cliente que apunta a este IdP
+func (idp *IdP) Config() oidc.Config {
+	return oidc.Config{
+		IssuerURL:   idp.Server.URL,
+		ClientID:    ClientID,
+		RedirectURL: RedirectURL,
+		RolesClaim:  "groups",
+	}
+}
+
+// Provider crea el cliente OIDC contra este IdP
+func (idp *IdP) Provider() *oidc.Provider {
+	return oidc.New(idp.Config(), idp.Server.Client())
+}
+
+/*
+# Rotate genera una llave nueva y firma con ella desde ahora
+* la llave anterior deja de publicarse, como tras una rotación completa
+*/
+func (idp *IdP) Rotate() string {
+	idp.t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		idp.t.Fatalf("generating rsa key: %v", err)
+	}
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+
+	idp.seq++
+	kid := fmt.Sprintf("key-%d", idp.seq)
+	idp.keys = map[string]*rsa.PrivateKey{kid: key}
+	idp.signKid = kid
+	return kid
+}
+
+/*
+# Authorize recibe la URL de AuthCodeURL y devuelve el código de autorización
+* claims se agregan al id_token y reemplazan los valores por defecto (iss,
+* aud, sub, nonce, exp, email...), para simular un IdP que responde mal
+*/
+func (idp *IdP) Authorize(authURL string, claims jwt.MapClaims) string {
+	idp.t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		idp.t.Fatalf("parsing authorization url: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("client_id") != ClientID || query.Get("redirect_uri") != RedirectURL {
+		idp.t.Fatalf("unexpected client in authorization url: %s", authURL)
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		idp.t.Fatalf("authorization url without pkce: %s", authURL)
+	}
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+
+	final := jwt.MapClaims{
+		"iss":            idp.Issuer,
+		"aud":            ClientID,
+		"sub":            "ext-1",
+		"nonce":          query.Get("nonce"),
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(5 * time.Minute).Unix(),
+		"email":          "ana@example.com",
+		"email_verified": true,
+		"name":           "Ana",
+	}
+	for name, value := range claims {
+		final[name] = value
+	}
+
+	idp.seq++
+	code := fmt.Sprintf("code-%d", idp.seq)
+	idp.codes[code] = authorization{challenge: query.Get("code_challenge"), claims: final}
+	return code
+}
+
+func (idp *IdP) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 idp.Issuer,
+		"authorization_endpoint": idp.Server.URL + "/authorize",
+		"token_endpoint":         idp.Server.URL + "/token",
+		"jwks_uri":               idp.Server.URL + "/jwks",
+	})
+}
+
+func (idp *IdP) jwks(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+
+	keys := make([]map[string]string, 0, len(idp.keys))
+	for kid, key := range idp.keys {
+		keys = append(keys, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"keys": keys})
+}
+
+// token canjea el código una sola vez y exige el code_verifier de PKCE
+func (idp *IdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+
+	auth, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	switch {
+	case r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("client_id") != ClientID:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	case !ok || r.PostForm.Get("redirect_uri") != RedirectURL:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	case oidc.Challenge(r.PostForm.Get("code_verifier")) != auth.challenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "invalid_grant", "error_description": "pkce verification failed",
+		})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, auth.claims)
+	token.Header["kid"] = idp.signKid
+	signed, err := token.SignedString(idp.keys[idp.signKid])
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": "opaque",
+		"token_type":   "Bearer",
+		"id_token":     signed,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
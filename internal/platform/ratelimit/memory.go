@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+/*
+# MemoryStore guarda los buckets en memoria
+* sirve para una sola instancia: cada réplica llevaría su propia cuenta
+* Run borra los buckets que ya se llenaron para que el mapa no crezca
+*/
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = newBucket(limit, now)
+		s.buckets[key] = b
+	}
+	return b.take(limit, now), nil
+}
+
+// Cleanup borra los buckets llenos y devuelve cuántos borró
+func (s *MemoryStore) Cleanup() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	removed := 0
+	for key, b := range s.buckets {
+		if !b.full.After(now) {
+			delete(s.buckets, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Run limpia cada minuto hasta que ctx se cancela
+func (s *MemoryStore) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if removed := s.Cleanup(); removed > 0 {
+				slog.DebugContext(ctx, "rate limit: removed full buckets", "removed", removed)
+			}
+		}
+	}
+}
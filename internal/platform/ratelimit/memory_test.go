@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// clock es la hora que ve un MemoryStore de prueba
+type clock struct{ now time.Time }
+
+func (c *clock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestStore() (*MemoryStore, *clock) {
+	c := &clock{now: time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)}
+	s := NewMemoryStore()
+	s.now = func() time.Time { return c.now }
+	return s, c
+}
+
+// take pide una ficha y falla si el resultado no es el esperado
+func take(t *testing.T, s *MemoryStore, key string, limit Limit, allowed bool, remaining int) Result {
+	t.Helper()
+
+	result, err := s.Take(context.Background(), key, limit)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if result.Allowed != allowed || result.Remaining != remaining {
+		t.Fatalf("Take = allowed %v, remaining %d; want %v, %d", result.Allowed, result.Remaining, allowed, remaining)
+	}
+	return result
+}
+
+func TestMemoryStoreAllowsBurst(t *testing.T) {
+	s, _ := newTestStore()
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+
+	first := take(t, s, "ip:10.0.0.1", limit, true, 2)
+	if first.Reset != time.Second {
+		t.Errorf("Reset = %v, want 1s", first.Reset)
+	}
+	take(t, s, "ip:10.0.0.1", limit, true, 1)
+	take(t, s, "ip:10.0.0.1", limit, true, 0)
+
+	denied := take(t, s, "ip:10.0.0.1", limit, false, 0)
+	if denied.RetryAfter != time.Second || denied.Reset != 3*time.Second {
+		t.Errorf("RetryAfter, Reset = %v, %v; want 1s, 3s", denied.RetryAfter, denied.Reset)
+	}
+
+	// cada clave lleva su propio bucket
+	take(t, s, "ip:10.0.0.2", limit, true, 2)
+}
+
+func TestMemoryStoreRefills(t *testing.T) {
+	s, c := newTestStore()
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+
+	for range 3 {
+		s.Take(context.Background(), "k", limit)
+	}
+	take(t, s, "k", limit, false, 0)
+
+	// a una ficha por segundo, medio segundo no alcanza
+	c.advance(500 * time.Millisecond)
+	denied := take(t, s, "k", limit, false, 0)
+	if denied.RetryAfter != 500*time.Millisecond {
+		t.Errorf("RetryAfter = %v, want 500ms", denied.RetryAfter)
+	}
+
+	c.advance(500 * time.Millisecond)
+	take(t, s, "k", limit, true, 0)
+
+	// la recarga no pasa de Requests aunque pase mucho tiempo
+	c.advance(time.Hour)
+	take(t, s, "k", limit, true, 2)
+	take(t, s, "k", limit, true, 1)
+	take(t, s, "k", limit, true, 0)
+	take(t, s, "k", limit, false, 0)
+}
+
+func TestMemoryStoreCleanupRemovesFullBuckets(t *testing.T) {
+	s, c := newTestStore()
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+
+	s.Take(context.Background(), "a", limit)
+	s.Take(context.Background(), "b", limit)
+	s.Take(context.Background(), "b", limit)
+
+	c.advance(time.Second)
+	if removed := s.Cleanup(); removed != 1 {
+		t.Errorf("Cleanup after 1s removed %d, want 1", removed)
+	}
+	c.advance(time.Second)
+	if removed := s.Cleanup(); removed != 1 {
+		t.Errorf("Cleanup after 2s removed %d, want 1", removed)
+	}
+	if len(s.buckets) != 0 {
+		t.Errorf("%d buckets left", len(s.buckets))
+	}
+}
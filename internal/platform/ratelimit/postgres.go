@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/*
+# PostgresStore guarda los buckets en la tabla limite_solicitudes
+* lo comparten todas las réplicas, así el límite es global
+* la hora es la de la base de datos para no depender del reloj de cada
+* réplica; la fila queda bloqueada durante la transacción, así dos
+* réplicas no gastan la misma ficha
+*/
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	var result Result
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var row struct {
+			Tokens        float64
+			ActualizadoEn time.Time
+			Ahora         time.Time
+		}
+
+		// crea el bucket lleno si no existe y, en los dos casos, bloquea la fila
+		err := tx.Raw(`
+			INSERT INTO limite_solicitudes (clave, tokens, actualizado_en, lleno_en)
+			VALUES (?, ?, now(), now())
+			ON CONFLICT (clave) DO UPDATE SET clave = EXCLUDED.clave
+			RETURNING tokens, actualizado_en, now() AS ahora`,
+			key, float64(limit.Requests)).Scan(&row).Error
+		if err != nil {
+			return err
+		}
+
+		b := bucket{tokens: row.Tokens, updated: row.ActualizadoEn}
+		result = b.take(limit, row.Ahora)
+
+		return tx.Exec(`
+			UPDATE limite_solicitudes
+			SET tokens = ?, actualizado_en = ?, lleno_en = ?
+			WHERE clave = ?`,
+			b.tokens, b.updated, b.full, key).Error
+	})
+	return result, err
+}
+
+// Cleanup borra los buckets llenos y devuelve cuántos borró
+func (s *PostgresStore) Cleanup(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).Exec("DELETE FROM limite_solicitudes WHERE lleno_en <= now()")
+	return result.RowsAffected, result.Error
+}
+
+// Run limpia cada minuto hasta que ctx se cancela
+func (s *PostgresStore) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, err := s.Cleanup(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "rate limit: error removing full buckets", "error", err)
+				continue
+			}
+			if removed > 0 {
+				slog.DebugContext(ctx, "rate limit: removed full buckets", "removed", removed)
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package ratelimit
+
+/*
+Este archivo define los límites de solicitudes (token bucket) y el
+contrato de los almacenes donde se guardan los buckets
+*/
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+# Limit permite Requests solicitudes por Period
+* es un token bucket: caben Requests fichas y se recargan de forma
+* continua, así una ráfaga de Requests pasa de una vez y después el ritmo
+* queda en Requests/Period
+* el Limit cero no limita
+*/
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// rate son las fichas que se recargan por segundo
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// String devuelve el límite como en el encabezado RateLimit-Policy ("10;w=60")
+func (l Limit) String() string {
+	return fmt.Sprintf("%d;w=%d", l.Requests, int(math.Ceil(l.Period.Seconds())))
+}
+
+/*
+# Policy es el límite de una ruta o grupo de rutas
+* PerIP se cuenta por IP del cliente; PerUser por usuario autenticado, si
+* la solicitud trae uno
+* las solicitudes deben pasar los dos
+*/
+type Policy struct {
+	PerIP   Limit
+	PerUser Limit
+}
+
+func (p Policy) Enabled() bool {
+	return p.PerIP.Enabled() || p.PerUser.Enabled()
+}
+
+/*
+# Result es lo que queda del bucket después de pedir una ficha
+* Remaining: fichas enteras que quedan
+* Reset: cuánto falta para que el bucket vuelva a estar lleno
+* RetryAfter: si no se permitió, cuánto falta para la siguiente ficha
+*/
+type Result struct {
+	Allowed    bool
+	Limit      Limit
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Store guarda los buckets; Take descuenta una ficha de key si hay
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+/*
+# bucket es el estado de un token bucket
+* full es cuándo se llena; desde ahí el bucket equivale a uno nuevo y
+* se puede borrar
+*/
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+func newBucket(limit Limit, now time.Time) *bucket {
+	return &bucket{tokens: float64(limit.Requests), updated: now, full: now}
+}
+
+// take recarga las fichas ganadas desde updated y descuenta una si alcanza
+func (b *bucket) take(limit Limit, now time.Time) Result {
+	rate := limit.rate()
+	elapsed := max(now.Sub(b.updated).Seconds(), 0)
+
+	b.tokens = min(float64(limit.Requests), b.tokens+elapsed*rate)
+	b.updated = now
+
+	result := Result{Limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((float64(limit.Requests) - b.tokens) / rate)
+	b.full = now.Add(result.Reset)
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+/*
+# ParseLimit lee un límite como "10/m"
+* el periodo es s, m, h o una duración de Go ("10/30s")
+*/
+func ParseLimit(s string) (Limit, error) {
+	count, period, found := strings.Cut(strings.TrimSpace(s), "/")
+	if !found {
+		return Limit{}, fmt.Errorf("invalid limit %q: expected requests/period", s)
+	}
+
+	requests, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || requests < 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q: bad request count", s)
+	}
+
+	var d time.Duration
+	switch period = strings.TrimSpace(period); period {
+	case "s":
+		d = time.Second
+	case "m":
+		d = time.Minute
+	case "h":
+		d = time.Hour
+	default:
+		d, err = time.ParseDuration(period)
+		if err != nil || d <= 0 {
+			return Limit{}, fmt.Errorf("invalid limit %q: bad period", s)
+		}
+	}
+
+	return Limit{Requests: requests, Period: d}, nil
+}
+
+// ParsePolicy lee una política como "ip=10/m,user=5/m"; lo que falte no limita
+func ParsePolicy(s string) (Policy, error) {
+	var policy Policy
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+
+		scope, value, found := strings.Cut(part, "=")
+		if !found {
+			return Policy{}, fmt.Errorf("invalid policy entry %q: expected ip=... or user=...", part)
+		}
+
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return Policy{}, err
+		}
+
+		switch strings.TrimSpace(scope) {
+		case "ip":
+			policy.PerIP = limit
+		case "user":
+			policy.PerUser = limit
+		default:
+			return Policy{}, fmt.Errorf("invalid policy entry %q: unknown scope", part)
+		}
+	}
+	return policy, nil
+}
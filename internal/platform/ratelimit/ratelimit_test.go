@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Policy
+	}{
+		{"ip=10/m,user=5/m", Policy{PerIP: Limit{10, time.Minute}, PerUser: Limit{5, time.Minute}}},
+		{" ip = 20/30s ", Policy{PerIP: Limit{20, 30 * time.Second}}},
+		{"user=100/h", Policy{PerUser: Limit{100, time.Hour}}},
+		{"ip=2/s,", Policy{PerIP: Limit{2, time.Second}}},
+		{"ip=0/m", Policy{PerIP: Limit{0, time.Minute}}},
+		{"", Policy{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePolicy(tt.in)
+			if err != nil {
+				t.Fatalf("ParsePolicy: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePolicy(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePolicyRejectsInvalid(t *testing.T) {
+	tests := []string{
+		"ip=10",
+		"ip=ten/m",
+		"ip=-1/m",
+		"ip=10/fortnight",
+		"ip=10/0s",
+		"ip=10/-5s",
+		"ip10/m",
+		"host=10/m",
+		"ip=10/m,user",
+	}
+
+	for _, in := range tests {
+		t.Run(in, func(t *testing.T) {
+			if policy, err := ParsePolicy(in); err == nil {
+				t.Errorf("ParsePolicy(%q) = %+v, want an error", in, policy)
+			}
+		})
+	}
+}
+
+func TestPolicyEnabled(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		want   bool
+	}{
+		{Policy{}, false},
+		{Policy{PerIP: Limit{0, time.Minute}}, false},
+		{Policy{PerUser: Limit{5, 0}}, false},
+		{Policy{PerUser: Limit{5, time.Minute}}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.policy.Enabled(); got != tt.want {
+			t.Errorf("%+v.Enabled() = %v, want %v", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestLimitString(t *testing.T) {
+	tests := []struct {
+		limit Limit
+		want  string
+	}{
+		{Limit{10, time.Minute}, "10;w=60"},
+		{Limit{1, 1500 * time.Millisecond}, "1;w=2"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.limit.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package spreadsheet
+
+/*
+Este paquete lee y escribe hojas de cálculo fila por fila, en CSV o XLSX,
+para importar y exportar datos sin armar toda la tabla en memoria
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("spreadsheet: unsupported format")
+	ErrInvalidDate       = errors.New("spreadsheet: invalid date")
+	// ErrMalformedRow es una fila que no se pudo leer; se puede seguir con la siguiente
+	ErrMalformedRow = errors.New("spreadsheet: malformed row")
+)
+
+// Supported dice si format es csv o xlsx
+func Supported(format string) bool {
+	return format == FormatCSV || format == FormatXLSX
+}
+
+// Reader devuelve las filas de la primera hoja una por una
+type Reader interface {
+	// Read devuelve la siguiente fila, o io.EOF al terminar
+	Read() ([]string, error)
+	Close() error
+}
+
+func NewReader(format string, r io.Reader) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatXLSX:
+		return newXLSXReader(r)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+type csvReader struct {
+	csv *csv.Reader
+}
+
+/*
+# newCSVReader lee CSV en UTF-8, con o sin BOM
+* el separador es coma o punto y coma: Excel en español guarda los CSV con
+* punto y coma; se elige el que más aparece en la primera línea
+*/
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	buffered := bufio.NewReader(r)
+	if bom, err := buffered.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		_, _ = buffered.Discard(3)
+	}
+
+	// Peek devuelve lo que haya aunque el archivo sea más corto que el buffer
+	line, _ := buffered.Peek(buffered.Size())
+	if end := bytes.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+	delimiter := ','
+	if bytes.Count(line, []byte(";")) > bytes.Count(line, []byte(",")) {
+		delimiter = ';'
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return &csvReader{csv: reader}, nil
+}
+
+func (r *csvReader) Read() ([]string, error) {
+	record, err := r.csv.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedRow, err)
+	}
+	return record, err
+}
+
+func (r *csvReader) Close() error {
+	return nil
+}
+
+type xlsxReader struct {
+	file *excelize.File
+	rows *excelize.Rows
+}
+
+/*
+# newXLSXReader abre la primera hoja del libro
+* un XLSX es un zip con el índice al final, así que el archivo se lee
+* completo; las filas sí se recorren de a una
+* las celdas salen con su valor crudo: los números sin formato y las
+* fechas como número de serie de Excel (ver ParseDate)
+*/
+func newXLSXReader(r io.Reader) (*xlsxReader, error) {
+	file, err := excelize.OpenReader(r, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := file.Rows(file.GetSheetName(0))
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &xlsxReader{file: file, rows: rows}, nil
+}
+
+func (r *xlsxReader) Read() ([]string, error) {
+	if !r.rows.Next() {
+		if err := r.rows.Error(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return r.rows.Columns(excelize.Options{RawCellValue: true})
+}
+
+func (r *xlsxReader) Close() error {
+	if err := r.rows.Close(); err != nil {
+		_ = r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+/*
+# ParseDate lee una fecha de una celda
+* acepta AAAA-MM-DD, DD/MM/AAAA y el número de serie con el que Excel
+* guarda las fechas
+*/
+func ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{"2006-01-02", "02/01/2006", "2/1/2006"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 0 {
+		t, err := excelize.ExcelDateToTime(serial, false)
+		if err == nil {
+			return t.Truncate(24 * time.Hour), nil
+		}
+	}
+	return time.Time{}, ErrInvalidDate
+}
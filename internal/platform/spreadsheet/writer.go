@@ -0,0 +1,122 @@
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+/*
+# Writer escribe filas una por una
+* los valores pueden ser string, int, int64, float64, time.Time (fecha) o
+* nil (celda vacía); en XLSX los números y las fechas quedan con su tipo
+* Close termina el archivo; en XLSX es recién ahí cuando se escribe en w
+*/
+type Writer interface {
+	Write(row []any) error
+	Close() error
+}
+
+// NewWriter; el CSV empieza con BOM para que Excel lo abra como UTF-8
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return nil, err
+		}
+		return &csvWriter{csv: csv.NewWriter(w)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+type csvWriter struct {
+	csv    *csv.Writer
+	record []string
+}
+
+func (w *csvWriter) Write(row []any) error {
+	w.record = w.record[:0]
+	for _, value := range row {
+		w.record = append(w.record, formatCell(value))
+	}
+	return w.csv.Write(w.record)
+}
+
+func (w *csvWriter) Close() error {
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func formatCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format("2006-01-02")
+	}
+	return ""
+}
+
+type xlsxWriter struct {
+	out       io.Writer
+	file      *excelize.File
+	stream    *excelize.StreamWriter
+	dateStyle int
+	row       int
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter(file.GetSheetName(0))
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	dateFormat := "yyyy-mm-dd"
+	dateStyle, err := file.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &xlsxWriter{out: w, file: file, stream: stream, dateStyle: dateStyle}, nil
+}
+
+func (w *xlsxWriter) Write(row []any) error {
+	w.row++
+	cells := make([]any, len(row))
+	for i, value := range row {
+		if t, ok := value.(time.Time); ok {
+			value = excelize.Cell{StyleID: w.dateStyle, Value: t}
+		}
+		cells[i] = value
+	}
+
+	cell, err := excelize.CoordinatesToCellName(1, w.row)
+	if err != nil {
+		return err
+	}
+	return w.stream.SetRow(cell, cells)
+}
+
+func (w *xlsxWriter) Close() error {
+	defer w.file.Close()
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+	return w.file.Write(w.out)
+}
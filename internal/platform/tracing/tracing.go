@@ -0,0 +1,114 @@
+package tracing
+
+/*
+Este archivo configura las trazas de OpenTelemetry de la aplicación
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/mordmora/expirapp"
+
+/*
+# Config de las trazas
+* Exporter: none (por defecto), stdout o file; los dos últimos escriben
+* cada span como JSON y no necesitan un collector
+* File: archivo al que se agregan los spans con Exporter file
+* SampleRatio: fracción de trazas nuevas que se guardan (0 a 1); una
+* solicitud que llega con traceparent respeta la decisión de quien llama
+* ServiceName: service.name de los spans
+*/
+type Config struct {
+	Exporter    string
+	File        string
+	SampleRatio float64
+	ServiceName string
+}
+
+/*
+# Setup registra el TracerProvider global y la propagación W3C (traceparent)
+* devuelve la función que vacía y cierra el exportador al apagar
+* con Exporter none los spans no se graban, pero el contexto se propaga igual
+*/
+func Setup(cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var out io.Writer
+	var file *os.File
+
+	switch strings.ToLower(strings.TrimSpace(cfg.Exporter)) {
+	case "", "none", "off":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		out = os.Stdout
+	case "file":
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("could not open trace file: %w", err)
+		}
+		out, file = f, f
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(out))
+	if err != nil {
+		return nil, fmt.Errorf("could not create trace exporter: %w", err)
+	}
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			err = errors.Join(err, file.Close())
+		}
+		return err
+	}, nil
+}
+
+// Start abre un span hijo del que venga en ctx
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, opts...)
+}
+
+/*
+# End cierra el span y lo marca como fallido si err no es nil
+* pensado para usar con retornos nombrados:
+* defer func() { tracing.End(span, err) }()
+*/
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
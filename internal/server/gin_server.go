@@ -6,7 +6,7 @@ Este archivo contiene la implementación del servidor Gin
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,22 +14,42 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/middleware"
+	"github.com/mordmora/expirapp/internal/modules/catalog"
+	api "github.com/mordmora/expirapp/internal/platform/http"
+	"github.com/mordmora/expirapp/internal/platform/metrics"
+	"github.com/mordmora/expirapp/internal/platform/ratelimit"
 	"gorm.io/gorm"
 )
 
 /*
 # Server representa el servidor Gin
 * httpServer: el servidor HTTP subyacente
+* adminServer: listener aparte para /metrics; nil si AdminPort está vacío
 * engine: el motor Gin
 * db: la conexión a la base de datos
 * config: la configuración del servidor
+* workers: tareas en segundo plano que viven mientras el servidor corre
+* rateLimits: almacén de los límites de solicitudes; nil si están apagados
 */
 
 type Server struct {
-	httpServer *http.Server
-	engine     *gin.Engine
-	db         *gorm.DB
-	config     Config
+	httpServer  *http.Server
+	adminServer *http.Server
+	engine      *gin.Engine
+	db          *gorm.DB
+	config      Config
+	workers     []Worker
+	rateLimits  ratelimit.Store
+}
+
+/*
+# Worker es una tarea en segundo plano (p. ej. liberar reservas vencidas)
+* Run debe terminar cuando ctx se cancela
+*/
+type Worker interface {
+	Run(ctx context.Context)
 }
 
 /*
@@ -39,8 +59,9 @@ type Server struct {
 */
 func New(db *gorm.DB, cfg Config) *Server {
 	gin.SetMode(cfg.Mode)
+	api.RegisterValidator()
 
-	engine := gin.New()
+	engine := newEngine(cfg)
 
 	/*
 		# Crea una instancia de un servidorr gin
@@ -60,23 +81,134 @@ func New(db *gorm.DB, cfg Config) *Server {
 
 	server.setupMiddlewares()
 
+	server.setupRateLimits()
+
 	server.setupRouter()
 
+	server.setupMetrics()
+
 	return server
 }
 
+/*
+# newEngine crea el motor Gin con los proxies de confianza
+* c.ClientIP() solo lee X-Forwarded-For si la conexión viene de uno de
+* cfg.TrustedProxies; por defecto no hay ninguno, porque Gin confía en todos
+* y cualquiera podría elegir su IP para saltarse la lista de IPs de una clave
+* de API, los límites por IP o el bloqueo de login
+*/
+func newEngine(cfg Config) *gin.Engine {
+	engine := gin.New()
+	if err := engine.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		slog.Warn("invalid trusted proxies, trusting none", "proxies", cfg.TrustedProxies, "error", err)
+		_ = engine.SetTrustedProxies(nil)
+	}
+	return engine
+}
+
 func (s *Server) setupMiddlewares() {
 	/*
-		# evita que el servidor reviente
-		# si hay un panic en alguna parte del código
+		# asigna el X-Request-ID antes que nada para que
+		# todos los registros de la solicitud lo lleven
+	*/
+	s.engine.Use(middleware.RequestID())
+
+	/*
+		# span por solicitud; va antes del log de acceso para que
+		# sus registros lleven el trace_id
 	*/
-	s.engine.Use(gin.Recovery())
+	s.engine.Use(middleware.Tracing())
 
 	/*
-		# registra las solicitudes entrantes
-		# solo para depurar
+		# idioma de los mensajes según Accept-Language
 	*/
-	s.engine.Use(gin.Logger())
+	s.engine.Use(middleware.Locale(s.config.DefaultLanguage))
+
+	/*
+		# registra cada solicitud con latencia, estado y usuario
+	*/
+	s.engine.Use(middleware.AccessLog(slog.Default()))
+
+	/*
+		# conteo y latencia por ruta para Prometheus
+	*/
+	s.engine.Use(middleware.Metrics())
+
+	/*
+		# evita que el servidor reviente
+		# si hay un panic en alguna parte del código
+	*/
+	s.engine.Use(middleware.Recovery(slog.Default()))
+}
+
+/*
+# setupMetrics prepara el listener de administración con /metrics
+* va en otro puerto para que Prometheus lo lea sin autenticación sin
+* exponerlo junto al API
+* registra las estadísticas del pool y las unidades por vencer
+*/
+func (s *Server) setupMetrics() {
+	if s.config.AdminPort == "" {
+		return
+	}
+
+	if s.db != nil {
+		if sqlDB, err := s.db.DB(); err == nil {
+			if err := metrics.RegisterDB(sqlDB, "expirapp"); err != nil {
+				slog.Warn("could not register db metrics", "error", err)
+			}
+		}
+
+		catalogRepo := catalog.NewRepository(s.db)
+		err := metrics.RegisterExpiringUnits(func(ctx context.Context) (int64, error) {
+			return catalogRepo.CountUnitsExpiringSoon(ctx, 7)
+		})
+		if err != nil {
+			slog.Warn("could not register expiring units metric", "error", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	s.adminServer = &http.Server{
+		Addr:         s.config.AdminPort,
+		Handler:      mux,
+		ReadTimeout:  s.config.ReadTimeout,
+		WriteTimeout: s.config.WriteTimeout,
+	}
+}
+
+/*
+# setupRateLimits crea el almacén de los límites de solicitudes
+* memory cuenta en este proceso; postgres comparte la cuenta entre réplicas
+* los dos borran sus buckets llenos como worker
+*/
+func (s *Server) setupRateLimits() {
+	switch s.config.RateLimitStore {
+	case "":
+		return
+	case "memory":
+		store := ratelimit.NewMemoryStore()
+		s.rateLimits = store
+		s.workers = append(s.workers, store)
+	case "postgres":
+		store := ratelimit.NewPostgresStore(s.db)
+		s.rateLimits = store
+		s.workers = append(s.workers, store)
+	default:
+		slog.Warn("unknown rate limit store, rate limiting disabled", "store", s.config.RateLimitStore)
+	}
+}
+
+// rateLimit es el middleware de la política name; sin almacén o sin
+// política no limita
+func (s *Server) rateLimit(name string) gin.HandlerFunc {
+	policy, ok := s.config.RateLimits[name]
+	if s.rateLimits == nil || !ok || !policy.Enabled() {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return middleware.RateLimit(s.rateLimits, name, policy)
 }
 
 /*
@@ -102,43 +234,25 @@ func corsMiddleware() gin.HandlerFunc {
 	}
 }
 
-func (s *Server) setupRouter() {
-
-	s.engine.GET("/health", s.healthCheck)
-
-	v1 := s.engine.Group("/api/v1")
-	{
-		v1.GET("/", func(c *gin.Context) {
-			c.JSON(http.StatusOK, gin.H{
-				"message": "API v1 is working!",
-				"status":  "running",
-			})
-		})
-	}
-
+// HealthResponse es el cuerpo de /health y de la raíz del API
+type HealthResponse struct {
+	Status string `json:"status"`
 }
 
+// healthCheck responde 200 si la base de datos contesta y 503 si no
 func (s *Server) healthCheck(c *gin.Context) {
-
 	sqlDB, err := s.db.DB()
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"status":  "error",
-			"message": "database connection failed"})
+		api.Error(c, domain.Unavailable("database_unavailable", "la base de datos no está disponible"))
 		return
 	}
 
-	if err := sqlDB.Ping(); err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"status":  "error",
-			"message": "database ping failed"})
+	if err := sqlDB.PingContext(c.Request.Context()); err != nil {
+		api.Error(c, domain.Unavailable("database_unavailable", "la base de datos no está disponible"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "healthy",
-		"message": "dabase is ok"})
-
+	api.Respond(c, http.StatusOK, HealthResponse{Status: "healthy"}, "database_ok")
 }
 
 func (s *Server) Start() error {
@@ -146,24 +260,51 @@ func (s *Server) Start() error {
 
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	for _, w := range s.workers {
+		go w.Run(workersCtx)
+	}
+
 	go func() {
-		log.Printf("Starting server on %s", s.config.Port)
+		slog.Info("starting server", "addr", s.config.Port)
 
 		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Could not listen on %s: %v\n", s.config.Port, err)
+			slog.Error("could not listen", "addr", s.config.Port, "error", err)
+			os.Exit(1)
 		}
 	}()
 
+	if s.adminServer != nil {
+		go func() {
+			slog.Info("starting admin server", "addr", s.config.AdminPort)
+
+			if err := s.adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("could not listen", "addr", s.config.AdminPort, "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
 	<-quit
-	log.Println("Shutting down server...")
+	slog.Info("shutting down server")
+	stopWorkers()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if s.adminServer != nil {
+		if err := s.adminServer.Shutdown(ctx); err != nil {
+			slog.Warn("admin server forced to shutdown", "error", err)
+		}
+	}
+
 	if err := s.httpServer.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		slog.Error("server forced to shutdown", "error", err)
+		os.Exit(1)
 	}
-	log.Println("Server exiting")
+	slog.Info("server exiting")
 	return nil
 }
 
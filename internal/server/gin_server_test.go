@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/middleware"
+	"github.com/mordmora/expirapp/internal/platform/ratelimit"
+)
+
+// allowlistKeys acepta cualquier clave que llegue desde allowed
+type allowlistKeys struct {
+	allowed string
+}
+
+func (k allowlistKeys) AuthenticateKey(_ context.Context, _, ip string) (*middleware.Principal, error) {
+	if ip != k.allowed {
+		return nil, domain.Forbidden("api_key_ip_not_allowed", "la clave de API no se puede usar desde %s", ip)
+	}
+	return &middleware.Principal{UserID: 1, APIKeyID: 1, Permissions: []string{"catalog:*"}}, nil
+}
+
+func keyRequest(engine *gin.Engine, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/catalog/products", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-API-Key", "exp_test")
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAPIKeyAllowlistUsesTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		proxies      []string
+		remoteAddr   string
+		forwardedFor string
+		want         int
+	}{
+		{"direct from allowed ip", nil, "10.0.0.5:4000", "", http.StatusOK},
+		{"forged header from untrusted peer", nil, "203.0.113.9:4000", "10.0.0.5", http.StatusForbidden},
+		{"forged header with proxies configured", []string{"192.168.1.1"}, "203.0.113.9:4000", "10.0.0.5", http.StatusForbidden},
+		{"header from trusted proxy", []string{"192.168.1.0/24"}, "192.168.1.1:4000", "10.0.0.5", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newEngine(Config{TrustedProxies: tt.proxies})
+			engine.GET("/api/v1/catalog/products", middleware.Auth(nil, allowlistKeys{allowed: "10.0.0.5"}), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			if got := keyRequest(engine, tt.remoteAddr, tt.forwardedFor); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitIgnoresForgedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := newEngine(Config{})
+	policy := ratelimit.Policy{PerIP: ratelimit.Limit{Requests: 2, Period: time.Minute}}
+	engine.POST("/api/v1/users/login", middleware.RateLimit(ratelimit.NewMemoryStore(), rateLimitAuth, policy), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	// cada intento llega con otro X-Forwarded-For desde la misma conexión
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/users/login", nil)
+		req.RemoteAddr = "203.0.113.9:4000"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("10.0.0.%d", i+1))
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+
+		if w.Code != want {
+			t.Fatalf("request %d: status = %d, want %d", i+1, w.Code, want)
+		}
+	}
+}
@@ -1 +1,386 @@
 package server
+
+/*
+Este archivo registra las rutas de cada módulo
+*/
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mordmora/expirapp/internal/domain"
+	"github.com/mordmora/expirapp/internal/middleware"
+	"github.com/mordmora/expirapp/internal/modules/apikeys"
+	"github.com/mordmora/expirapp/internal/modules/cart"
+	"github.com/mordmora/expirapp/internal/modules/catalog"
+	"github.com/mordmora/expirapp/internal/modules/categories"
+	"github.com/mordmora/expirapp/internal/modules/orders"
+	"github.com/mordmora/expirapp/internal/modules/payments"
+	"github.com/mordmora/expirapp/internal/modules/privacy"
+	"github.com/mordmora/expirapp/internal/modules/profiles"
+	"github.com/mordmora/expirapp/internal/modules/promotions"
+	"github.com/mordmora/expirapp/internal/modules/purchasing"
+	"github.com/mordmora/expirapp/internal/modules/reports"
+	"github.com/mordmora/expirapp/internal/modules/reviews"
+	"github.com/mordmora/expirapp/internal/modules/users"
+	api "github.com/mordmora/expirapp/internal/platform/http"
+	"github.com/mordmora/expirapp/internal/platform/idempotency"
+	"github.com/mordmora/expirapp/internal/platform/mail"
+	"github.com/mordmora/expirapp/internal/platform/oidc"
+)
+
+func (s *Server) setupRouter() {
+
+	s.engine.GET("/health", s.healthCheck)
+
+	/*
+		# repositorios y servicios de cada módulo
+	*/
+	tokens := users.NewTokenManager(s.config.JWTSecret, s.config.TokenTTL)
+	usersService := users.NewService(users.NewRepository(s.db), tokens, mail.New(s.config.Mail), users.AccountConfig{
+		BaseURL:              s.config.AppBaseURL,
+		ResetTokenTTL:        time.Hour,
+		VerifyTokenTTL:       48 * time.Hour,
+		RequireVerifiedEmail: s.config.RequireVerifiedEmail,
+
+		Issuer:                 "Expirapp",
+		TwoFactorRequiredRoles: s.config.TwoFactorRequiredRoles,
+
+		Lockout: users.DefaultLockoutPolicy(),
+	})
+	if s.config.OIDC.Enabled() {
+		usersService.EnableSSO(oidc.New(s.config.OIDC, nil), users.SSOConfig{
+			RoleMap:      s.config.SSORoleMap,
+			DefaultRoles: s.config.SSODefaultRoles,
+		})
+	}
+
+	apiKeysService := apikeys.NewService(apikeys.NewRepository(s.db))
+
+	catalogRepo := catalog.NewRepository(s.db)
+	catalogService := catalog.NewService(catalogRepo)
+	categoriesRepo := categories.NewRepository(s.db)
+	categoriesService := categories.NewService(categoriesRepo, catalogRepo)
+	catalogService.SetCategoryDefaults(categoriesService)
+	s.workers = append(s.workers, catalog.NewImportWorker(catalogService, s.config.ImportPollInterval))
+	s.workers = append(s.workers, catalog.NewPriceScheduler(catalogService, s.config.PriceSchedulerInterval))
+
+	profilesService := profiles.NewService(profiles.NewRepository(s.db))
+	promotionsService := promotions.NewService(promotions.NewRepository(s.db), catalogRepo, categoriesRepo)
+
+	ordersRepo := orders.NewRepository(s.db)
+	ordersService := orders.NewService(ordersRepo, catalogRepo, catalogService, promotionsService, profilesService)
+
+	cartRepo := cart.NewRepository(s.db)
+	cartService := cart.NewService(cartRepo, ordersService, catalogService, promotionsService, s.config.CartReservationTTL)
+	ordersService.SetReservations(cartRepo)
+	s.workers = append(s.workers, cart.NewSweeper(cartService, s.config.CartSweepInterval))
+
+	paymentsService := payments.NewService(payments.NewRepository(s.db), ordersRepo)
+	purchasingService := purchasing.NewService(purchasing.NewRepository(s.db), catalogRepo, catalogService)
+
+	/*
+		# las rutas que crean órdenes o pagos aceptan Idempotency-Key para
+		# que un reintento del cliente no duplique el efecto
+	*/
+	idempotencyStore := idempotency.NewStore(s.db, s.config.IdempotencyTTL)
+	s.workers = append(s.workers, idempotencyStore)
+	idempotent := middleware.Idempotency(idempotencyStore)
+	reviewsService := reviews.NewService(reviews.NewRepository(s.db))
+	reportsService := reports.NewService(reports.NewRepository(s.db))
+
+	usersHandler := users.NewHandler(usersService)
+	apiKeysHandler := apikeys.NewHandler(apiKeysService)
+	catalogHandler := catalog.NewHandler(catalogService)
+	categoriesHandler := categories.NewHandler(categoriesService)
+	profilesHandler := profiles.NewHandler(profilesService)
+	privacyHandler := privacy.NewHandler(privacy.NewService(privacy.NewRepository(s.db)))
+	ordersHandler := orders.NewHandler(ordersService)
+	cartHandler := cart.NewHandler(cartService, ordersService)
+	promotionsHandler := promotions.NewHandler(promotionsService)
+	paymentsHandler := payments.NewHandler(paymentsService)
+	purchasingHandler := purchasing.NewHandler(purchasingService)
+	reviewsHandler := reviews.NewHandler(reviewsService)
+	reportsHandler := reports.NewHandler(reportsService)
+
+	v1 := s.engine.Group("/api/v1")
+	{
+		v1.GET("/", func(c *gin.Context) {
+			api.Respond(c, http.StatusOK, HealthResponse{Status: "running"}, "api_running")
+		})
+
+		/*
+			# la lectura del catálogo es pública; todo lo que escribe en él
+			# (precios, inventario, categorías) lo hace el personal de la
+			# tienda, y los cambios de precio quedan a nombre del usuario
+			# las lecturas que traen productos o lotes pasan por identified:
+			# los costos solo salen si quien pide es administrador
+		*/
+		authenticated := middleware.Auth(tokens, apiKeysService)
+		identified := middleware.OptionalAuth(tokens, apiKeysService)
+		adminOnly := middleware.RequireRole(domain.RoleAdmin)
+		selfOrAdmin := middleware.RequireSelfOrRole("id", domain.RoleAdmin)
+		staff := middleware.RequireRole(domain.RoleAdmin, domain.RoleSeller)
+
+		products := v1.Group("/catalog/products")
+		{
+			products.POST("", authenticated, staff, catalogHandler.CreateProduct)
+			products.GET("", identified, catalogHandler.ListProducts)
+			products.GET("/search", identified, catalogHandler.SearchProducts)
+			products.GET("/expiring-soon", identified, catalogHandler.GetExpiringSoon)
+			products.GET("/name/:name", identified, catalogHandler.GetProductByName)
+			products.GET("/expiration/:date", identified, catalogHandler.GetProductsByExpirationDate)
+			products.GET("/:id", identified, catalogHandler.GetProduct)
+			products.PUT("/:id", authenticated, staff, catalogHandler.UpdateProduct)
+			products.DELETE("/:id", authenticated, staff, catalogHandler.DeleteProduct)
+			products.PUT("/:id/stock", authenticated, staff, catalogHandler.UpdateStock)
+			products.GET("/:id/categories", categoriesHandler.GetProductCategories)
+			products.PUT("/:id/categories", authenticated, staff, categoriesHandler.SetProductCategories)
+			products.POST("/:id/lots", authenticated, staff, catalogHandler.ReceiveLot)
+			products.GET("/:id/lots", identified, catalogHandler.ListLots)
+			products.GET("/:id/label", catalogHandler.GetProductLabel)
+			products.GET("/:id/lots/:lotId/label", catalogHandler.GetLotLabel)
+			products.GET("/:id/prices", catalogHandler.GetPriceTimeline)
+		}
+
+		v1.GET("/catalog/scan/:code", identified, catalogHandler.ScanCode)
+		v1.POST("/catalog/labels", authenticated, staff, catalogHandler.PrintLabels)
+		v1.POST("/catalog/imports", authenticated, staff, catalogHandler.ImportProducts)
+		v1.GET("/catalog/imports/:id", authenticated, staff, catalogHandler.GetImport)
+		v1.GET("/catalog/export", authenticated, staff, catalogHandler.ExportProducts)
+
+		categoriesGroup := v1.Group("/catalog/categories")
+		{
+			categoriesGroup.POST("", authenticated, staff, categoriesHandler.CreateCategory)
+			categoriesGroup.GET("", categoriesHandler.ListCategories)
+			categoriesGroup.GET("/:id", categoriesHandler.GetCategory)
+			categoriesGroup.PUT("/:id", authenticated, staff, categoriesHandler.UpdateCategory)
+			categoriesGroup.DELETE("/:id", authenticated, staff, categoriesHandler.DeleteCategory)
+			categoriesGroup.GET("/:id/products", identified, catalogHandler.ListProductsByCategory)
+		}
+
+		/*
+			# los cambios de precio explícitos exigen saber quién los hace
+		*/
+		pricesGroup := v1.Group("/catalog/products/:id", authenticated, staff)
+		{
+			pricesGroup.PUT("/price", catalogHandler.ChangeProductPrice)
+			pricesGroup.POST("/prices/scheduled", catalogHandler.SchedulePrice)
+			pricesGroup.DELETE("/prices/scheduled/:scheduledId", catalogHandler.CancelScheduledPrice)
+		}
+
+		/*
+			# compras: proveedores, órdenes de compra y recepciones
+			# una recepción crea los lotes en el inventario
+		*/
+		purchasingGroup := v1.Group("/purchasing", authenticated, staff)
+		{
+			purchasingGroup.POST("/suppliers", purchasingHandler.CreateSupplier)
+			purchasingGroup.GET("/suppliers", purchasingHandler.ListSuppliers)
+			purchasingGroup.GET("/suppliers/:id", purchasingHandler.GetSupplier)
+			purchasingGroup.PUT("/suppliers/:id", purchasingHandler.UpdateSupplier)
+			purchasingGroup.POST("/orders", purchasingHandler.CreateOrder)
+			purchasingGroup.GET("/orders", purchasingHandler.ListOrders)
+			purchasingGroup.GET("/orders/:id", purchasingHandler.GetOrder)
+			purchasingGroup.PUT("/orders/:id", purchasingHandler.UpdateOrder)
+			purchasingGroup.POST("/orders/:id/send", purchasingHandler.SendOrder)
+			purchasingGroup.POST("/orders/:id/cancel", purchasingHandler.CancelOrder)
+			purchasingGroup.POST("/orders/:id/receipts", purchasingHandler.ReceiveGoods)
+			purchasingGroup.GET("/orders/:id/receipts/:receiptId/labels", purchasingHandler.GetReceiptLabels)
+		}
+
+		/*
+			# usuarios y perfiles
+			# el registro y el login son públicos; el resto exige token o clave de API
+			# los endpoints de credenciales y los que envían correos
+			# comparten el límite por IP
+		*/
+		authLimit := s.rateLimit(rateLimitAuth)
+
+		authGroup := v1.Group("/auth")
+		{
+			authGroup.POST("/login", authLimit, usersHandler.Login)
+			authGroup.POST("/login/2fa", authLimit, usersHandler.CompleteTwoFactorLogin)
+			authGroup.GET("/sso/login", usersHandler.StartSSOLogin)
+			authGroup.POST("/sso/callback", authLimit, usersHandler.CompleteSSOLogin)
+			authGroup.POST("/password/forgot", authLimit, usersHandler.ForgotPassword)
+			authGroup.POST("/password/reset", authLimit, usersHandler.ResetPassword)
+			authGroup.POST("/email/verify", usersHandler.VerifyEmail)
+			authGroup.POST("/email/resend", authLimit, usersHandler.ResendVerification)
+		}
+
+		usersGroup := v1.Group("/users")
+		{
+			usersGroup.POST("", s.rateLimit(rateLimitRegister), usersHandler.Register)
+			usersGroup.GET("", authenticated, adminOnly, usersHandler.ListUsers)
+			usersGroup.GET("/me", authenticated, usersHandler.GetMe)
+			usersGroup.GET("/:id", authenticated, selfOrAdmin, usersHandler.GetUser)
+			usersGroup.PUT("/:id", authenticated, selfOrAdmin, usersHandler.UpdateUser)
+			usersGroup.PUT("/:id/password", authenticated, middleware.RequireSelfOrRole("id"), usersHandler.ChangePassword)
+			usersGroup.DELETE("/:id", authenticated, selfOrAdmin, usersHandler.DeleteUser)
+
+			enrolling := middleware.AuthWithScopes(tokens, middleware.ScopeTwoFactorEnroll)
+			usersGroup.POST("/:id/2fa/enroll", enrolling, middleware.RequireSelfOrRole("id"), usersHandler.EnrollTOTP)
+			usersGroup.POST("/:id/2fa/confirm", enrolling, middleware.RequireSelfOrRole("id"), usersHandler.ConfirmTOTP)
+			usersGroup.POST("/:id/2fa/recovery-codes", authenticated, middleware.RequireSelfOrRole("id"), usersHandler.RegenerateRecoveryCodes)
+			usersGroup.DELETE("/:id/2fa", authenticated, selfOrAdmin, usersHandler.DisableTOTP)
+			usersGroup.POST("/:id/unlock", authenticated, adminOnly, usersHandler.UnlockUser)
+		}
+
+		privacyGroup := v1.Group("/privacy/users/:id", authenticated, selfOrAdmin)
+		{
+			privacyGroup.GET("/export", privacyHandler.ExportData)
+			privacyGroup.POST("/anonymize", privacyHandler.AnonymizeUser)
+			privacyGroup.POST("/consents", privacyHandler.RecordConsent)
+			privacyGroup.GET("/consents", privacyHandler.ListConsents)
+			privacyGroup.GET("/requests", privacyHandler.ListDataRequests)
+		}
+
+		securityGroup := v1.Group("/security", authenticated, adminOnly)
+		{
+			securityGroup.GET("/events", usersHandler.ListSecurityEvents)
+			securityGroup.DELETE("/lockouts/ip/:ip", usersHandler.UnlockIP)
+		}
+
+		apiKeysGroup := v1.Group("/api-keys", authenticated, adminOnly)
+		{
+			apiKeysGroup.POST("", apiKeysHandler.CreateAPIKey)
+			apiKeysGroup.GET("", apiKeysHandler.ListAPIKeys)
+			apiKeysGroup.GET("/:id", apiKeysHandler.GetAPIKey)
+			apiKeysGroup.PUT("/:id", apiKeysHandler.UpdateAPIKey)
+			apiKeysGroup.POST("/:id/rotate", apiKeysHandler.RotateAPIKey)
+			apiKeysGroup.DELETE("/:id", apiKeysHandler.RevokeAPIKey)
+		}
+
+		profilesGroup := v1.Group("/profiles", authenticated)
+		{
+			profilesGroup.POST("/clients", profilesHandler.CreateClient)
+			profilesGroup.GET("/clients", staff, profilesHandler.ListClients)
+			profilesGroup.GET("/clients/:id", middleware.RequireSelfOrRole("id", domain.RoleAdmin, domain.RoleSeller), profilesHandler.GetClient)
+			profilesGroup.PUT("/clients/:id", selfOrAdmin, profilesHandler.UpdateClient)
+			profilesGroup.DELETE("/clients/:id", adminOnly, profilesHandler.DeleteClient)
+
+			profilesGroup.POST("/sellers", adminOnly, profilesHandler.CreateSeller)
+			profilesGroup.GET("/sellers", adminOnly, profilesHandler.ListSellers)
+			profilesGroup.GET("/sellers/:id", selfOrAdmin, profilesHandler.GetSeller)
+			profilesGroup.PUT("/sellers/:id", adminOnly, profilesHandler.UpdateSeller)
+			profilesGroup.DELETE("/sellers/:id", adminOnly, profilesHandler.DeleteSeller)
+
+			profilesGroup.POST("/admins", adminOnly, profilesHandler.CreateAdmin)
+			profilesGroup.GET("/admins", adminOnly, profilesHandler.ListAdmins)
+			profilesGroup.GET("/admins/:id", adminOnly, profilesHandler.GetAdmin)
+			profilesGroup.PUT("/admins/:id", adminOnly, profilesHandler.UpdateAdmin)
+			profilesGroup.DELETE("/admins/:id", adminOnly, profilesHandler.DeleteAdmin)
+		}
+
+		/*
+			# el cliente crea y consulta sus propias órdenes; modificarlas
+			# después de creadas es cosa del personal de la tienda
+		*/
+		ordersGroup := v1.Group("/orders", authenticated)
+		{
+			ordersGroup.POST("", idempotent, ordersHandler.CreateOrder)
+			ordersGroup.GET("", staff, ordersHandler.ListOrders)
+			ordersGroup.GET("/client/:clientId", middleware.RequireSelfOrRole("clientId", domain.RoleAdmin, domain.RoleSeller), ordersHandler.ListOrdersByClient)
+			ordersGroup.GET("/seller/:sellerId", middleware.RequireSelfOrRole("sellerId", domain.RoleAdmin), ordersHandler.ListOrdersBySeller)
+			ordersGroup.GET("/:id", ordersHandler.GetOrder)
+			ordersGroup.PUT("/:id", staff, ordersHandler.UpdateOrder)
+			ordersGroup.DELETE("/:id", staff, ordersHandler.DeleteOrder)
+			ordersGroup.POST("/:id/items", staff, ordersHandler.AddOrderItem)
+			ordersGroup.PUT("/:id/items/:itemId", staff, ordersHandler.UpdateOrderItem)
+			ordersGroup.DELETE("/:id/items/:itemId", staff, ordersHandler.DeleteOrderItem)
+		}
+
+		cartGroup := v1.Group("/cart/:clientId", authenticated, middleware.RequireSelfOrRole("clientId", domain.RoleAdmin, domain.RoleSeller))
+		{
+			cartGroup.GET("", cartHandler.GetCart)
+			cartGroup.DELETE("", cartHandler.ClearCart)
+			cartGroup.POST("/items", cartHandler.AddItem)
+			cartGroup.PUT("/items/:itemId", cartHandler.UpdateItem)
+			cartGroup.DELETE("/items/:itemId", cartHandler.RemoveItem)
+			cartGroup.PUT("/coupon", cartHandler.ApplyCoupon)
+			cartGroup.DELETE("/coupon", cartHandler.RemoveCoupon)
+			cartGroup.POST("/checkout", idempotent, cartHandler.Checkout)
+		}
+
+		/*
+			# las promociones y cupones los administra el admin; validar un
+			# cupón es lo único que hace el cliente
+		*/
+		v1.GET("/promotions/coupons/validate/:code", promotionsHandler.ValidateCoupon)
+
+		promotionsGroup := v1.Group("/promotions", authenticated, adminOnly)
+		{
+			promotionsGroup.POST("", promotionsHandler.CreatePromotion)
+			promotionsGroup.GET("", promotionsHandler.ListPromotions)
+			promotionsGroup.PUT("/coupons/:couponId", promotionsHandler.UpdateCoupon)
+			promotionsGroup.DELETE("/coupons/:couponId", promotionsHandler.DeleteCoupon)
+			promotionsGroup.GET("/:id", promotionsHandler.GetPromotion)
+			promotionsGroup.PUT("/:id", promotionsHandler.UpdatePromotion)
+			promotionsGroup.DELETE("/:id", promotionsHandler.DeletePromotion)
+			promotionsGroup.POST("/:id/coupons", promotionsHandler.CreateCoupon)
+			promotionsGroup.GET("/:id/coupons", promotionsHandler.ListCoupons)
+		}
+
+		/*
+			# el cliente paga y consulta los pagos de sus órdenes; los métodos
+			# de pago los administra el admin
+			# los límites de pagos y reseñas son también por usuario, así que
+			# van después de authenticated
+		*/
+		paymentsGroup := v1.Group("/payments", authenticated)
+		{
+			paymentsGroup.POST("", s.rateLimit(rateLimitPayments), idempotent, paymentsHandler.CreatePayment)
+			paymentsGroup.GET("", staff, paymentsHandler.ListPayments)
+			paymentsGroup.POST("/methods", adminOnly, paymentsHandler.CreatePaymentMethod)
+			paymentsGroup.GET("/methods", paymentsHandler.ListPaymentMethods)
+			paymentsGroup.GET("/methods/:id", paymentsHandler.GetPaymentMethod)
+			paymentsGroup.PUT("/methods/:id", adminOnly, paymentsHandler.UpdatePaymentMethod)
+			paymentsGroup.DELETE("/methods/:id", adminOnly, paymentsHandler.DeletePaymentMethod)
+			paymentsGroup.GET("/order/:orderId", paymentsHandler.GetPaymentsByOrder)
+			paymentsGroup.GET("/order/:orderId/status", paymentsHandler.GetPaymentStatusByOrder)
+			paymentsGroup.GET("/:id", paymentsHandler.GetPayment)
+			paymentsGroup.PUT("/:id", staff, paymentsHandler.UpdatePayment)
+			paymentsGroup.DELETE("/:id", staff, paymentsHandler.DeletePayment)
+		}
+
+		/*
+			# las reseñas se leen sin credenciales; solo su autor las escribe
+		*/
+		reviewsGroup := v1.Group("/reviews")
+		{
+			reviewsGroup.POST("", authenticated, s.rateLimit(rateLimitReviews), reviewsHandler.CreateReview)
+			reviewsGroup.GET("", reviewsHandler.ListReviews)
+			reviewsGroup.GET("/product/:productId", reviewsHandler.ListReviewsByProduct)
+			reviewsGroup.GET("/product/:productId/summary", reviewsHandler.GetProductRatingSummary)
+			reviewsGroup.GET("/:id", reviewsHandler.GetReview)
+			reviewsGroup.PUT("/:id", authenticated, reviewsHandler.UpdateReview)
+			reviewsGroup.DELETE("/:id", authenticated, reviewsHandler.DeleteReview)
+		}
+
+		/*
+			# los reportes son del personal de la tienda; los que muestran
+			# costos (márgenes y valorización del inventario) solo del admin
+		*/
+		reportsGroup := v1.Group("/reports", authenticated, staff)
+		{
+			reportsGroup.GET("/sales/summary", reportsHandler.GetSalesSummary)
+			reportsGroup.GET("/sales/daily", reportsHandler.GetDailySales)
+			reportsGroup.GET("/products/top", reportsHandler.GetTopProducts)
+			reportsGroup.GET("/categories/top", reportsHandler.GetTopCategories)
+			reportsGroup.GET("/inventory/low-stock", reportsHandler.GetLowStock)
+			reportsGroup.GET("/inventory/valuation", adminOnly, reportsHandler.GetInventoryValuation)
+			reportsGroup.GET("/customers/top", reportsHandler.GetTopCustomers)
+			reportsGroup.GET("/payments/methods", reportsHandler.GetPaymentMethodSummary)
+			reportsGroup.GET("/payments/pending", reportsHandler.GetPendingPayments)
+			reportsGroup.GET("/promotions/usage", reportsHandler.GetPromotionUsage)
+			reportsGroup.GET("/margins/products", adminOnly, reportsHandler.GetProductMargins)
+			reportsGroup.GET("/margins/categories", adminOnly, reportsHandler.GetCategoryMargins)
+			reportsGroup.GET("/margins/sellers", adminOnly, reportsHandler.GetSellerMargins)
+			reportsGroup.GET("/margins/periods", adminOnly, reportsHandler.GetPeriodMargins)
+			reportsGroup.GET("/suppliers", reportsHandler.GetSupplierPerformance)
+		}
+	}
+
+}
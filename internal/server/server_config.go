@@ -2,32 +2,130 @@ package server
 
 //Este archivo contiene la configuración del servidor Gin
 
-import "time"
+import (
+	"time"
+
+	"github.com/mordmora/expirapp/internal/platform/mail"
+	"github.com/mordmora/expirapp/internal/platform/oidc"
+	"github.com/mordmora/expirapp/internal/platform/ratelimit"
+)
+
+// grupos de rutas con límite de solicitudes propio
+const (
+	rateLimitAuth     = "auth"
+	rateLimitRegister = "register"
+	rateLimitReviews  = "reviews"
+	rateLimitPayments = "payments"
+)
 
 /*
 # Config representa la configuración del servidor Gin
 * Port: el puerto en el que se ejecuta el servidor
+* AdminPort: puerto del listener de administración (/metrics); vacío lo apaga
 * ReadTimeout: el tiempo máximo para leer la solicitud completa, incluyendo el cuerpo
 * WriteTimeout: el tiempo máximo para escribir la respuesta completa
 * IdleTimeout: el tiempo máximo para esperar la próxima solicitud cuando keep-alives están habilitados
 * Mode: el modo de ejecución de Gin (debug, release, test)
+* CartReservationTTL: cuánto tiempo aparta stock una línea del carrito
+* CartSweepInterval: cada cuánto se liberan las reservas vencidas
+* JWTSecret: llave para firmar los tokens de acceso
+* TokenTTL: vigencia de un token de acceso
+* AppBaseURL: URL usada en los enlaces de recuperación y verificación
+* RequireVerifiedEmail: exige correo verificado para iniciar sesión
+* Mail: configuración SMTP; sin Host los correos solo se registran en el log
+* TwoFactorRequiredRoles: roles que deben tener 2FA para iniciar sesión
+* OIDC: IdP del login corporativo; sin IssuerURL el SSO queda apagado
+* SSORoleMap / SSODefaultRoles: cómo se asignan roles a las cuentas SSO
+* DefaultLanguage: idioma de las respuestas si Accept-Language no indica uno soportado
+* RateLimitStore: dónde se cuentan las solicitudes: memory (una instancia),
+* postgres (varias réplicas) o vacío para no limitar
+* RateLimits: política de cada grupo de rutas (auth, register, reviews, payments)
+* IdempotencyTTL: cuánto se guarda una Idempotency-Key con su respuesta
+* ImportPollInterval: cada cuánto se buscan importaciones de productos pendientes
+* PriceSchedulerInterval: cada cuánto se aplican los cambios de precio programados
+* TrustedProxies: proxies (IP o CIDR) a los que se les cree X-Forwarded-For;
+* vacío usa siempre la IP de la conexión
 */
 
 type Config struct {
 	Port         string
+	AdminPort    string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
 	Mode         string
+
+	CartReservationTTL time.Duration
+	CartSweepInterval  time.Duration
+
+	JWTSecret string
+	TokenTTL  time.Duration
+
+	AppBaseURL           string
+	RequireVerifiedEmail bool
+	Mail                 mail.Config
+
+	TwoFactorRequiredRoles []string
+
+	OIDC            oidc.Config
+	SSORoleMap      map[string]string
+	SSODefaultRoles []string
+
+	DefaultLanguage string
+
+	RateLimitStore string
+	RateLimits     map[string]ratelimit.Policy
+
+	IdempotencyTTL time.Duration
+
+	ImportPollInterval     time.Duration
+	PriceSchedulerInterval time.Duration
+
+	TrustedProxies []string
 }
 
 //configuracion por defecto, de momento esa esta bien
 func DefConfig() Config {
 	return Config{
 		Port:         ":8080",
+		AdminPort:    ":9090",
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
 		Mode:         "debug",
+
+		CartReservationTTL: 15 * time.Minute,
+		CartSweepInterval:  time.Minute,
+
+		TokenTTL: 24 * time.Hour,
+
+		AppBaseURL: "http://localhost:8080",
+
+		TwoFactorRequiredRoles: []string{"administrador"},
+
+		DefaultLanguage: "es",
+
+		RateLimitStore: "memory",
+		RateLimits: map[string]ratelimit.Policy{
+			rateLimitAuth: {
+				PerIP: ratelimit.Limit{Requests: 10, Period: time.Minute},
+			},
+			rateLimitRegister: {
+				PerIP: ratelimit.Limit{Requests: 10, Period: time.Hour},
+			},
+			rateLimitReviews: {
+				PerIP:   ratelimit.Limit{Requests: 30, Period: time.Hour},
+				PerUser: ratelimit.Limit{Requests: 10, Period: time.Hour},
+			},
+			rateLimitPayments: {
+				PerIP:   ratelimit.Limit{Requests: 30, Period: time.Minute},
+				PerUser: ratelimit.Limit{Requests: 10, Period: time.Minute},
+			},
+		},
+
+		IdempotencyTTL: 24 * time.Hour,
+
+		ImportPollInterval:     5 * time.Second,
+		PriceSchedulerInterval: time.Minute,
 	}
 }
@@ -0,0 +1,27 @@
+CREATE TABLE carrito (
+    id_carrito SERIAL PRIMARY KEY,
+    id_cliente INT UNIQUE NOT NULL REFERENCES cliente(id_cliente) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE detalle_carrito (
+    id_detalle_carrito SERIAL PRIMARY KEY,
+    id_carrito INT NOT NULL REFERENCES carrito(id_carrito) ON DELETE CASCADE,
+    id_producto INT NOT NULL REFERENCES producto(id_producto),
+    cantidad INT NOT NULL CHECK (cantidad > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (id_carrito, id_producto)
+);
+
+CREATE TABLE reserva_stock (
+    id_reserva SERIAL PRIMARY KEY,
+    id_detalle_carrito INT UNIQUE NOT NULL REFERENCES detalle_carrito(id_detalle_carrito) ON DELETE CASCADE,
+    id_producto INT NOT NULL REFERENCES producto(id_producto),
+    cantidad INT NOT NULL CHECK (cantidad > 0),
+    expira_en TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_reserva_stock_producto ON reserva_stock (id_producto, expira_en);
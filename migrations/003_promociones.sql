@@ -0,0 +1,56 @@
+CREATE TABLE promocion (
+    id_promocion SERIAL PRIMARY KEY,
+    nombre VARCHAR(100) NOT NULL,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('porcentaje', 'monto_fijo', 'lleve_x_pague_y')),
+    valor NUMERIC(10,2) NOT NULL DEFAULT 0 CHECK (valor >= 0),
+    cantidad_compra INT NOT NULL DEFAULT 0 CHECK (cantidad_compra >= 0),
+    cantidad_gratis INT NOT NULL DEFAULT 0 CHECK (cantidad_gratis >= 0),
+    id_producto INT REFERENCES producto(id_producto),
+    requiere_cupon BOOLEAN NOT NULL DEFAULT false,
+    fecha_inicio TIMESTAMPTZ NOT NULL,
+    fecha_fin TIMESTAMPTZ,
+    activa BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_promocion_deleted_at ON promocion (deleted_at);
+
+CREATE TABLE cupon (
+    id_cupon SERIAL PRIMARY KEY,
+    codigo VARCHAR(50) UNIQUE NOT NULL,
+    id_promocion INT NOT NULL REFERENCES promocion(id_promocion) ON DELETE CASCADE,
+    usos_maximos INT CHECK (usos_maximos > 0),
+    usos_por_cliente INT CHECK (usos_por_cliente > 0),
+    fecha_inicio TIMESTAMPTZ,
+    fecha_fin TIMESTAMPTZ,
+    activo BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE detalle_compra
+    ADD COLUMN descuento NUMERIC(10,2) NOT NULL DEFAULT 0 CHECK (descuento >= 0);
+
+CREATE TABLE descuento_detalle (
+    id_descuento_detalle SERIAL PRIMARY KEY,
+    id_detalle INT NOT NULL REFERENCES detalle_compra(id_detalle) ON DELETE CASCADE,
+    id_promocion INT NOT NULL REFERENCES promocion(id_promocion),
+    id_cupon INT REFERENCES cupon(id_cupon),
+    monto NUMERIC(10,2) NOT NULL CHECK (monto >= 0)
+);
+
+CREATE TABLE uso_promocion (
+    id_uso SERIAL PRIMARY KEY,
+    id_promocion INT NOT NULL REFERENCES promocion(id_promocion),
+    id_cupon INT REFERENCES cupon(id_cupon),
+    id_cliente INT NOT NULL REFERENCES cliente(id_cliente),
+    id_compra INT NOT NULL REFERENCES compra(id_compra) ON DELETE CASCADE,
+    monto_descuento NUMERIC(10,2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_uso_promocion_cupon ON uso_promocion (id_cupon, id_cliente);
+
+ALTER TABLE carrito ADD COLUMN codigo_cupon VARCHAR(50);
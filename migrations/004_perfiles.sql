@@ -0,0 +1,15 @@
+INSERT INTO rol (nombre) VALUES ('cliente'), ('vendedor'), ('administrador')
+ON CONFLICT (nombre) DO NOTHING;
+
+-- los perfiles creados antes de esta migración reciben su rol
+INSERT INTO usuario_rol (id_usuario, id_rol)
+SELECT c.id_cliente, r.id_rol FROM cliente c JOIN rol r ON r.nombre = 'cliente'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO usuario_rol (id_usuario, id_rol)
+SELECT v.id_vendedor, r.id_rol FROM vendedor v JOIN rol r ON r.nombre = 'vendedor'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO usuario_rol (id_usuario, id_rol)
+SELECT a.id_admin, r.id_rol FROM administrador a JOIN rol r ON r.nombre = 'administrador'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,16 @@
+ALTER TABLE usuario ADD COLUMN correo_verificado_en TIMESTAMPTZ;
+
+-- las cuentas existentes se consideran verificadas
+UPDATE usuario SET correo_verificado_en = now();
+
+CREATE TABLE token_usuario (
+    id_token SERIAL PRIMARY KEY,
+    id_usuario INT NOT NULL REFERENCES usuario(id_usuario) ON DELETE CASCADE,
+    tipo VARCHAR(20) NOT NULL,
+    hash CHAR(64) UNIQUE NOT NULL,
+    expira_en TIMESTAMPTZ NOT NULL,
+    usado_en TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_token_usuario_usuario ON token_usuario (id_usuario, tipo);
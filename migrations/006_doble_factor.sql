@@ -0,0 +1,13 @@
+ALTER TABLE usuario
+    ADD COLUMN totp_secreto VARCHAR(64),
+    ADD COLUMN totp_activado_en TIMESTAMPTZ,
+    ADD COLUMN totp_ultimo_paso BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE codigo_recuperacion (
+    id_codigo SERIAL PRIMARY KEY,
+    id_usuario INT NOT NULL REFERENCES usuario(id_usuario) ON DELETE CASCADE,
+    hash CHAR(64) NOT NULL,
+    usado_en TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (id_usuario, hash)
+);
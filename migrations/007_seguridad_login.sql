@@ -0,0 +1,19 @@
+CREATE TABLE intento_login (
+    clave VARCHAR(150) PRIMARY KEY,
+    fallos INT NOT NULL DEFAULT 0,
+    ultimo_fallo TIMESTAMPTZ NOT NULL,
+    bloqueado_hasta TIMESTAMPTZ
+);
+
+CREATE TABLE evento_seguridad (
+    id_evento SERIAL PRIMARY KEY,
+    tipo VARCHAR(40) NOT NULL,
+    id_usuario INT REFERENCES usuario(id_usuario) ON DELETE SET NULL,
+    correo VARCHAR(100),
+    ip VARCHAR(45),
+    detalle TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_evento_seguridad_fecha ON evento_seguridad (created_at);
+CREATE INDEX idx_evento_seguridad_usuario ON evento_seguridad (id_usuario, created_at);
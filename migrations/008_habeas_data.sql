@@ -0,0 +1,25 @@
+ALTER TABLE usuario ADD COLUMN anonimizado_en TIMESTAMPTZ;
+
+CREATE TABLE consentimiento (
+    id_consentimiento SERIAL PRIMARY KEY,
+    id_usuario INT NOT NULL REFERENCES usuario(id_usuario) ON DELETE CASCADE,
+    tipo VARCHAR(50) NOT NULL,
+    otorgado BOOLEAN NOT NULL,
+    version VARCHAR(20),
+    ip VARCHAR(45),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_consentimiento_usuario ON consentimiento (id_usuario, tipo, created_at);
+
+-- sin llave foránea a usuario: la constancia debe sobrevivir al titular
+CREATE TABLE solicitud_datos (
+    id_solicitud SERIAL PRIMARY KEY,
+    id_usuario INT NOT NULL,
+    tipo VARCHAR(20) NOT NULL,
+    solicitado_por INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completada_en TIMESTAMPTZ
+);
+
+CREATE INDEX idx_solicitud_datos_usuario ON solicitud_datos (id_usuario);
@@ -0,0 +1,17 @@
+CREATE TABLE clave_api (
+    id_clave SERIAL PRIMARY KEY,
+    id_usuario INT NOT NULL REFERENCES usuario(id_usuario) ON DELETE CASCADE,
+    nombre VARCHAR(100) NOT NULL,
+    prefijo VARCHAR(16) NOT NULL UNIQUE,
+    hash CHAR(64) NOT NULL,
+    permisos JSONB NOT NULL DEFAULT '[]',
+    rangos_ip JSONB NOT NULL DEFAULT '[]',
+    expira_en TIMESTAMPTZ,
+    revocada_en TIMESTAMPTZ,
+    reemplazada_por INT REFERENCES clave_api(id_clave) ON DELETE SET NULL,
+    ultimo_uso TIMESTAMPTZ,
+    ultima_ip VARCHAR(45),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_clave_api_usuario ON clave_api (id_usuario);
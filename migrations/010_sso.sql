@@ -0,0 +1,20 @@
+CREATE TABLE identidad_externa (
+    id_identidad SERIAL PRIMARY KEY,
+    id_usuario INT NOT NULL REFERENCES usuario(id_usuario) ON DELETE CASCADE,
+    emisor VARCHAR(255) NOT NULL,
+    sujeto VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (emisor, sujeto)
+);
+
+CREATE INDEX idx_identidad_externa_usuario ON identidad_externa (id_usuario);
+
+CREATE TABLE inicio_sso (
+    estado CHAR(64) PRIMARY KEY,
+    nonce VARCHAR(64) NOT NULL,
+    verificador VARCHAR(128) NOT NULL,
+    expira_en TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_inicio_sso_expira ON inicio_sso (expira_en);
@@ -0,0 +1,8 @@
+CREATE TABLE limite_solicitudes (
+    clave VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    actualizado_en TIMESTAMPTZ NOT NULL,
+    lleno_en TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_limite_solicitudes_lleno ON limite_solicitudes (lleno_en);
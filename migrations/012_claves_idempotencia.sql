@@ -0,0 +1,15 @@
+CREATE TABLE clave_idempotencia (
+    alcance VARCHAR(255) NOT NULL,
+    clave VARCHAR(255) NOT NULL,
+    huella CHAR(64) NOT NULL,
+    estado VARCHAR(20) NOT NULL,
+    codigo_http INT NOT NULL DEFAULT 0,
+    tipo_contenido VARCHAR(255) NOT NULL DEFAULT '',
+    respuesta BYTEA,
+    bloqueada_hasta TIMESTAMPTZ NOT NULL,
+    expira_en TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (alcance, clave)
+);
+
+CREATE INDEX idx_clave_idempotencia_expira ON clave_idempotencia (expira_en);
@@ -0,0 +1,23 @@
+-- el modelo de producto usa borrado lógico y marcas de tiempo que la tabla
+-- original no tenía; sin deleted_at las consultas de gorm fallan
+ALTER TABLE producto ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE producto ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE producto ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_producto_deleted_at ON producto (deleted_at);
+
+-- búsqueda en español sin distinguir tildes: "azucar" encuentra "azúcar"
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+CREATE TEXT SEARCH CONFIGURATION es_unaccent (COPY = spanish);
+ALTER TEXT SEARCH CONFIGURATION es_unaccent
+    ALTER MAPPING FOR hword, hword_part, word WITH unaccent, spanish_stem;
+
+-- el nombre pesa más que la descripción en la relevancia
+ALTER TABLE producto ADD COLUMN busqueda tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('es_unaccent', coalesce(nombre, '')), 'A') ||
+    setweight(to_tsvector('es_unaccent', coalesce(descripcion, '')), 'B')
+) STORED;
+
+CREATE INDEX idx_producto_busqueda ON producto USING GIN (busqueda);
+CREATE INDEX idx_producto_precio ON producto (precio);
+CREATE INDEX idx_producto_vencimiento ON producto (fecha_vencimiento);
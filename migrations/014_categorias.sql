@@ -0,0 +1,29 @@
+-- ruta guarda los ids desde la raíz ("/1/4/9/"): los descendientes de una
+-- categoría son las filas cuya ruta empieza por la suya
+CREATE TABLE categoria (
+    id_categoria SERIAL PRIMARY KEY,
+    id_padre INT REFERENCES categoria(id_categoria),
+    nombre VARCHAR(100) NOT NULL,
+    slug VARCHAR(120) UNIQUE NOT NULL,
+    descripcion TEXT,
+    activa BOOLEAN NOT NULL DEFAULT true,
+    ruta TEXT NOT NULL DEFAULT '',
+    dias_alerta INT CHECK (dias_alerta > 0),
+    clase_impuesto VARCHAR(20) CHECK (clase_impuesto IN ('general', 'reducido', 'exento')),
+    reglas_rebaja JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_categoria_padre ON categoria (id_padre);
+CREATE INDEX idx_categoria_ruta ON categoria (ruta text_pattern_ops);
+
+CREATE TABLE producto_categoria (
+    id_producto INT NOT NULL REFERENCES producto(id_producto) ON DELETE CASCADE,
+    id_categoria INT NOT NULL REFERENCES categoria(id_categoria) ON DELETE CASCADE,
+    principal BOOLEAN NOT NULL DEFAULT false,
+    PRIMARY KEY (id_producto, id_categoria)
+);
+
+CREATE INDEX idx_producto_categoria_categoria ON producto_categoria (id_categoria);
+CREATE UNIQUE INDEX uq_producto_categoria_principal ON producto_categoria (id_producto) WHERE principal;
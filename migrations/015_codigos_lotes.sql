@@ -0,0 +1,21 @@
+-- gtin se guarda normalizado a 14 dígitos; un producto borrado libera sus códigos
+ALTER TABLE producto
+    ADD COLUMN gtin VARCHAR(14) CHECK (gtin ~ '^[0-9]{14}$'),
+    ADD COLUMN sku VARCHAR(64);
+
+CREATE UNIQUE INDEX uq_producto_gtin ON producto (gtin) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX uq_producto_sku ON producto (sku) WHERE deleted_at IS NULL;
+
+CREATE TABLE lote (
+    id_lote SERIAL PRIMARY KEY,
+    id_producto INT NOT NULL REFERENCES producto(id_producto) ON DELETE CASCADE,
+    numero VARCHAR(20) NOT NULL,
+    fecha_vencimiento DATE NOT NULL,
+    cantidad_recibida INT NOT NULL CHECK (cantidad_recibida > 0),
+    cantidad INT NOT NULL CHECK (cantidad >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (id_producto, numero)
+);
+
+CREATE INDEX idx_lote_vencimiento ON lote (id_producto, fecha_vencimiento) WHERE cantidad > 0;
@@ -0,0 +1,23 @@
+-- importaciones de productos en segundo plano; archivo se borra al procesarla
+CREATE TABLE importacion_producto (
+    id_importacion SERIAL PRIMARY KEY,
+    estado VARCHAR(12) NOT NULL CHECK (estado IN ('pendiente', 'procesando', 'completada', 'fallida')),
+    formato VARCHAR(4) NOT NULL CHECK (formato IN ('csv', 'xlsx')),
+    nombre_archivo VARCHAR(255),
+    solo_validar BOOLEAN NOT NULL DEFAULT FALSE,
+    idioma VARCHAR(5) NOT NULL,
+    archivo BYTEA,
+    filas INT NOT NULL DEFAULT 0,
+    creados INT NOT NULL DEFAULT 0,
+    actualizados INT NOT NULL DEFAULT 0,
+    con_error INT NOT NULL DEFAULT 0,
+    errores JSONB,
+    mensaje TEXT,
+    iniciada_en TIMESTAMPTZ,
+    terminada_en TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_importacion_producto_pendiente ON importacion_producto (created_at)
+    WHERE estado IN ('pendiente', 'procesando');
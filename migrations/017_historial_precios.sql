@@ -0,0 +1,42 @@
+CREATE TABLE precio_programado (
+    id_precio_programado SERIAL PRIMARY KEY,
+    id_producto INT NOT NULL REFERENCES producto(id_producto) ON DELETE CASCADE,
+    precio NUMERIC(10,2) NOT NULL CHECK (precio >= 0),
+    vigente_desde TIMESTAMPTZ NOT NULL,
+    motivo VARCHAR(255) NOT NULL,
+    id_usuario INT REFERENCES usuario(id_usuario) ON DELETE SET NULL,
+    estado VARCHAR(10) NOT NULL DEFAULT 'pendiente' CHECK (estado IN ('pendiente', 'aplicado', 'cancelado')),
+    aplicado_en TIMESTAMPTZ,
+    cancelado_por INT REFERENCES usuario(id_usuario) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- un solo cambio pendiente por producto en el mismo instante
+CREATE UNIQUE INDEX uq_precio_programado_pendiente ON precio_programado (id_producto, vigente_desde)
+    WHERE estado = 'pendiente';
+CREATE INDEX idx_precio_programado_vence ON precio_programado (vigente_desde) WHERE estado = 'pendiente';
+
+CREATE TABLE precio_historial (
+    id_cambio_precio SERIAL PRIMARY KEY,
+    id_producto INT NOT NULL REFERENCES producto(id_producto) ON DELETE CASCADE,
+    precio_anterior NUMERIC(10,2),
+    precio NUMERIC(10,2) NOT NULL CHECK (precio >= 0),
+    vigente_desde TIMESTAMPTZ NOT NULL,
+    origen VARCHAR(12) NOT NULL CHECK (origen IN ('alta', 'manual', 'importacion', 'programado')),
+    motivo VARCHAR(255),
+    id_usuario INT REFERENCES usuario(id_usuario) ON DELETE SET NULL,
+    id_precio_programado INT REFERENCES precio_programado(id_precio_programado) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_precio_historial_producto ON precio_historial (id_producto, vigente_desde);
+CREATE INDEX idx_precio_historial_vigente ON precio_historial (vigente_desde);
+
+-- la historia de los productos que ya existen arranca con su precio actual
+INSERT INTO precio_historial (id_producto, precio, vigente_desde, origen, motivo)
+SELECT id_producto, precio, created_at, 'alta', 'precio vigente al activar el historial'
+FROM producto;
+
+-- quién subió cada importación, para atribuirle los cambios de precio
+ALTER TABLE importacion_producto ADD COLUMN id_usuario INT REFERENCES usuario(id_usuario) ON DELETE SET NULL;
@@ -0,0 +1,5 @@
+-- costo unitario: el de reposición del producto, el de cada lote al recibirlo
+-- y el de cada línea de venta al registrarla (para el margen bruto)
+ALTER TABLE producto ADD COLUMN costo NUMERIC(12,4) CHECK (costo >= 0);
+ALTER TABLE lote ADD COLUMN costo_unitario NUMERIC(12,4) CHECK (costo_unitario >= 0);
+ALTER TABLE detalle_compra ADD COLUMN costo_unitario NUMERIC(12,4) CHECK (costo_unitario >= 0);
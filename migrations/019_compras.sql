@@ -0,0 +1,65 @@
+CREATE TABLE proveedor (
+    id_proveedor SERIAL PRIMARY KEY,
+    nombre VARCHAR(150) NOT NULL,
+    nit VARCHAR(20),
+    contacto VARCHAR(100) NOT NULL DEFAULT '',
+    email VARCHAR(150) NOT NULL DEFAULT '',
+    telefono VARCHAR(30) NOT NULL DEFAULT '',
+    dias_entrega INT CHECK (dias_entrega > 0),
+    activo BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX uq_proveedor_nit ON proveedor (nit) WHERE nit IS NOT NULL;
+
+CREATE TABLE orden_compra (
+    id_orden_compra SERIAL PRIMARY KEY,
+    id_proveedor INT NOT NULL REFERENCES proveedor(id_proveedor),
+    estado VARCHAR(10) NOT NULL DEFAULT 'borrador'
+        CHECK (estado IN ('borrador', 'enviada', 'parcial', 'recibida', 'cancelada')),
+    fecha_esperada DATE,
+    enviada_en TIMESTAMPTZ,
+    cerrada_en TIMESTAMPTZ,
+    notas TEXT NOT NULL DEFAULT '',
+    id_usuario INT REFERENCES usuario(id_usuario) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_orden_compra_proveedor ON orden_compra (id_proveedor, enviada_en);
+CREATE INDEX idx_orden_compra_abierta ON orden_compra (estado) WHERE estado IN ('borrador', 'enviada', 'parcial');
+
+CREATE TABLE detalle_orden_compra (
+    id_detalle_orden SERIAL PRIMARY KEY,
+    id_orden_compra INT NOT NULL REFERENCES orden_compra(id_orden_compra) ON DELETE CASCADE,
+    id_producto INT NOT NULL REFERENCES producto(id_producto),
+    cantidad INT NOT NULL CHECK (cantidad > 0),
+    cantidad_recibida INT NOT NULL DEFAULT 0 CHECK (cantidad_recibida >= 0),
+    costo_unitario NUMERIC(12,4) NOT NULL CHECK (costo_unitario >= 0),
+    UNIQUE (id_orden_compra, id_producto)
+);
+
+CREATE TABLE recepcion (
+    id_recepcion SERIAL PRIMARY KEY,
+    id_orden_compra INT NOT NULL REFERENCES orden_compra(id_orden_compra),
+    recibida_en TIMESTAMPTZ NOT NULL,
+    notas TEXT NOT NULL DEFAULT '',
+    id_usuario INT REFERENCES usuario(id_usuario) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_recepcion_orden ON recepcion (id_orden_compra, recibida_en);
+
+-- cada línea recibida queda ligada al lote donde entró, para saber de qué
+-- proveedor viene el stock que se vence
+CREATE TABLE detalle_recepcion (
+    id_detalle_recepcion SERIAL PRIMARY KEY,
+    id_recepcion INT NOT NULL REFERENCES recepcion(id_recepcion) ON DELETE CASCADE,
+    id_detalle_orden INT NOT NULL REFERENCES detalle_orden_compra(id_detalle_orden),
+    id_lote INT NOT NULL REFERENCES lote(id_lote),
+    cantidad INT NOT NULL CHECK (cantidad > 0),
+    costo_unitario NUMERIC(12,4) NOT NULL CHECK (costo_unitario >= 0)
+);
+
+CREATE INDEX idx_detalle_recepcion_lote ON detalle_recepcion (id_lote);
@@ -0,0 +1,8 @@
+-- una promoción aplica a un producto, a una categoría (con sus
+-- subcategorías, por categoria.ruta) o, sin ninguno de los dos, a todo el
+-- catálogo
+ALTER TABLE promocion
+    ADD COLUMN id_categoria INT REFERENCES categoria(id_categoria),
+    ADD CONSTRAINT chk_promocion_alcance CHECK (id_producto IS NULL OR id_categoria IS NULL);
+
+CREATE INDEX idx_promocion_categoria ON promocion (id_categoria) WHERE id_categoria IS NOT NULL;
@@ -0,0 +1,15 @@
+-- cada ajuste negativo de stock deja aquí lo que sacó de cada lote y por
+-- qué, así el desperdicio de un lote no desaparece cuando queda en cero
+CREATE TABLE baja_lote (
+    id_baja_lote SERIAL PRIMARY KEY,
+    id_lote INT NOT NULL REFERENCES lote(id_lote),
+    id_producto INT NOT NULL REFERENCES producto(id_producto),
+    cantidad INT NOT NULL CHECK (cantidad > 0),
+    costo_unitario NUMERIC(12,4) CHECK (costo_unitario >= 0),
+    motivo VARCHAR(12) NOT NULL CHECK (motivo IN ('vencimiento', 'averia', 'merma', 'conteo')),
+    fecha DATE NOT NULL,
+    id_usuario INT REFERENCES usuario(id_usuario) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_baja_lote_lote ON baja_lote (id_lote);
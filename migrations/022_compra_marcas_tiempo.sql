@@ -0,0 +1,13 @@
+-- el modelo de pedido y sus líneas usan borrado lógico y marcas de tiempo
+-- que las tablas originales no tenían; sin deleted_at fallan las consultas
+-- de gorm y el conteo de usos de un cupón, que ignora pedidos borrados
+-- IF NOT EXISTS la deja aplicar sobre bases que ya tenían estas columnas
+ALTER TABLE compra ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE compra ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE compra ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_compra_deleted_at ON compra (deleted_at);
+
+ALTER TABLE detalle_compra ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE detalle_compra ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE detalle_compra ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_detalle_compra_deleted_at ON detalle_compra (deleted_at);
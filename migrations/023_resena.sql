@@ -0,0 +1,16 @@
+-- el módulo de reseñas usaba la tabla sin que ninguna migración la creara;
+-- IF NOT EXISTS la deja aplicar sobre bases donde ya se creó a mano
+CREATE TABLE IF NOT EXISTS resena (
+    id_resena SERIAL PRIMARY KEY,
+    id_producto INT NOT NULL REFERENCES producto(id_producto),
+    id_cliente INT NOT NULL REFERENCES usuario(id_usuario) ON DELETE CASCADE,
+    calificacion INT NOT NULL CHECK (calificacion BETWEEN 1 AND 5),
+    comentario TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_resena_producto ON resena (id_producto, created_at);
+CREATE INDEX IF NOT EXISTS idx_resena_cliente ON resena (id_cliente);
+CREATE INDEX IF NOT EXISTS idx_resena_deleted_at ON resena (deleted_at);
@@ -0,0 +1,10 @@
+-- de qué lotes salieron las unidades de cada línea vendida; al cancelar una
+-- orden o bajar una línea las unidades vuelven a esos lotes y no al stock
+-- sin lote. Las líneas anteriores a esta tabla devuelven todo sin lote
+CREATE TABLE detalle_compra_lote (
+    id_detalle_lote SERIAL PRIMARY KEY,
+    id_detalle INT NOT NULL REFERENCES detalle_compra(id_detalle) ON DELETE CASCADE,
+    id_lote INT NOT NULL REFERENCES lote(id_lote),
+    cantidad INT NOT NULL CHECK (cantidad > 0),
+    UNIQUE (id_detalle, id_lote)
+);
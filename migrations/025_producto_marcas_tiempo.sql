@@ -0,0 +1,9 @@
+-- el modelo de producto usa borrado lógico y marcas de tiempo que la tabla
+-- original no tenía; sin deleted_at las consultas de gorm fallan
+-- 013_busqueda_productos ya las agrega y no se edita porque las migraciones
+-- aplicadas se registran por nombre; esta migración las deja declaradas
+-- aparte y IF NOT EXISTS la vuelve un no-op donde ya existen
+ALTER TABLE producto ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE producto ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE producto ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_producto_deleted_at ON producto (deleted_at);